@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/andresbott/etna/internal/filestore"
+	"github.com/spf13/cobra"
+)
+
+// maxAttachmentBytes returns the configured upload limit, 10MB by default.
+func maxAttachmentBytes(cfg AppCfg) int64 {
+	if cfg.Settings.MaxAttachmentSizeMB > 0 {
+		return int64(cfg.Settings.MaxAttachmentSizeMB * 1024 * 1024)
+	}
+	return 10 * 1024 * 1024
+}
+
+// attachmentBackend creates the attachment storage backend with the given name ("local" or "s3").
+func attachmentBackend(cfg AppCfg, name string) (filestore.Backend, error) {
+	switch name {
+	case "", attachmentBackendLocal:
+		return filestore.NewLocalBackend(filepath.Join(cfg.DataDir, attachmentsDir))
+	case attachmentBackendS3:
+		return filestore.NewS3Backend(filestore.S3Config{
+			Endpoint:  cfg.Attachments.S3.Endpoint,
+			Region:    cfg.Attachments.S3.Region,
+			Bucket:    cfg.Attachments.S3.Bucket,
+			AccessKey: cfg.Attachments.S3.AccessKey,
+			SecretKey: cfg.Attachments.S3.SecretKey,
+			Prefix:    cfg.Attachments.S3.Prefix,
+			PathStyle: cfg.Attachments.S3.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown attachment backend %q", name)
+	}
+}
+
+// attachmentStoreOptions returns the encryption options of the attachment store.
+func attachmentStoreOptions(cfg AppCfg) ([]filestore.Option, error) {
+	enc := cfg.Attachments.Encryption
+	if enc.Secret == "" && len(enc.PreviousSecrets) == 0 {
+		return nil, nil
+	}
+	c, err := filestore.NewCipher(enc.Secret, enc.PreviousSecrets...)
+	if err != nil {
+		return nil, err
+	}
+	return []filestore.Option{filestore.WithEncryption(c), filestore.WithEncryptedBackups(enc.EncryptBackups)}, nil
+}
+
+// openAttachmentStore opens the database and an attachment store on the given backend.
+func openAttachmentStore(cfg AppCfg, backend filestore.Backend) (*filestore.Store, error) {
+	l, err := defaultLogger(GetLogLevel(cfg.Env.LogLevel))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := attachmentStoreOptions(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("attachment encryption: %w", err)
+	}
+	db, err := openDatabase(cfg.DataDir, l)
+	if err != nil {
+		return nil, err
+	}
+	store, err := filestore.NewWithBackend(db, backend, maxAttachmentBytes(cfg), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("attachment store: %w", err)
+	}
+	return store, nil
+}
+
+func attachmentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attachments",
+		Short: "manage attachment storage",
+	}
+	cmd.AddCommand(attachmentsMigrateCmd(), attachmentsRotateKeyCmd())
+	return cmd
+}
+
+func attachmentsMigrateCmd() *cobra.Command {
+	var configFile = "./config.yaml"
+	var from, to string
+	var deleteSource bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "copy attachment files between storage backends",
+		Long: "copy all attachment files and thumbnails from one storage backend to another, e.g. from the local " +
+			"data directory to the S3 bucket configured under Attachments.S3. Stop the server first and set " +
+			"Attachments.Backend to the target backend once the migration succeeded.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAttachmentsMigrate(cmd.Context(), configFile, from, to, deleteSource, cmd)
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", configFile, "config file")
+	cmd.Flags().StringVar(&from, "from", attachmentBackendLocal, "source backend: local | s3")
+	cmd.Flags().StringVar(&to, "to", attachmentBackendS3, "target backend: local | s3")
+	cmd.Flags().BoolVar(&deleteSource, "delete-source", false, "remove files from the source backend once copied")
+	return cmd
+}
+
+func runAttachmentsMigrate(ctx context.Context, configFile, from, to string, deleteSource bool, cmd *cobra.Command) error {
+	if from == to {
+		return fmt.Errorf("source and target backend are both %q", from)
+	}
+	cfg, err := getAppCfg(configFile)
+	if err != nil {
+		return err
+	}
+	src, err := attachmentBackend(cfg, from)
+	if err != nil {
+		return fmt.Errorf("source backend: %w", err)
+	}
+	dst, err := attachmentBackend(cfg, to)
+	if err != nil {
+		return fmt.Errorf("target backend: %w", err)
+	}
+
+	store, err := openAttachmentStore(cfg, src)
+	if err != nil {
+		return err
+	}
+
+	res, err := store.MigrateTo(ctx, dst, deleteSource)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("copied %d files from %s to %s\n", res.Copied, src.Name(), dst.Name())
+	for _, key := range res.Missing {
+		cmd.Printf("missing in %s: %s\n", src.Name(), key)
+	}
+	if cfg.Attachments.Backend != to {
+		cmd.Printf("set Attachments.Backend to %q to use the migrated files\n", to)
+	}
+	return nil
+}
+
+func attachmentsRotateKeyCmd() *cobra.Command {
+	var configFile = "./config.yaml"
+	cmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "re-encrypt all attachment files with the current encryption secret",
+		Long: "rewrite all attachment files and thumbnails with the key derived from Attachments.Encryption.Secret. " +
+			"To rotate the secret, set the new one as Secret, move the old one to PreviousSecrets, stop the server and run " +
+			"this command; the old secret can be removed afterwards. It also encrypts files stored before encryption was " +
+			"enabled, and with an empty Secret it decrypts all files again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := getAppCfg(configFile)
+			if err != nil {
+				return err
+			}
+			if cfg.Attachments.Encryption.Secret == "" && len(cfg.Attachments.Encryption.PreviousSecrets) == 0 {
+				return fmt.Errorf("attachment encryption is not configured")
+			}
+			backend, err := attachmentBackend(cfg, cfg.Attachments.Backend)
+			if err != nil {
+				return err
+			}
+			store, err := openAttachmentStore(cfg, backend)
+			if err != nil {
+				return err
+			}
+			res, err := store.Reencrypt(cmd.Context())
+			if err != nil {
+				return err
+			}
+			cmd.Printf("rewrote %d files\n", res.Copied)
+			for _, key := range res.Missing {
+				cmd.Printf("missing: %s\n", key)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", configFile, "config file")
+	return cmd
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/andresbott/etna/internal/filestore"
 	"github.com/go-bumbu/config"
 	"github.com/gorilla/securecookie"
 	"golang.org/x/text/currency"
@@ -19,13 +20,53 @@ type AppCfg struct {
 	Env                 Env
 	Settings            AppSettings
 	MarketDataImporters MarketDataImportersCfg
+	Attachments         AttachmentsCfg
 	Msgs                []Msg
 	DataDir             string
 }
 
-// MarketDataImportersCfg holds named importer configs. Supported importers: Massive.
+// AttachmentsCfg selects where attachment files are stored; their metadata always stays in the database.
+type AttachmentsCfg struct {
+	Backend    string // "local" (default, files in DataDir/attachments) | "s3"
+	S3         S3Cfg
+	Encryption EncryptionCfg
+}
+
+// EncryptionCfg enables encryption at rest of attachment files.
+type EncryptionCfg struct {
+	Secret          string   // empty = files are stored in plain
+	PreviousSecrets []string // still accepted to read files, until "etna attachments rotate-key" rewrote them
+	EncryptBackups  bool     // keep attachments encrypted inside backups instead of exporting them in plain
+}
+
+// S3Cfg configures an S3-compatible bucket used as attachment storage.
+type S3Cfg struct {
+	Endpoint  string // e.g. https://s3.eu-central-1.amazonaws.com or http://nas:9000
+	Region    string // default us-east-1
+	Bucket    string
+	Prefix    string // optional key prefix, allows sharing a bucket
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address the bucket as endpoint/bucket; required by most self-hosted services
+}
+
+// MarketDataImportersCfg holds named importer configs. Supported importers: Massive, a generic
+// HTTP feed, the ECB reference rates (rates only) and Yahoo. When several are configured, each kind
+// of data is fetched from the first one that has it, by default in that order; Priority changes it.
 type MarketDataImportersCfg struct {
-	Massive MarketDataImporterConfig
+	Massive  MarketDataImporterConfig
+	Yahoo    YahooImporterCfg
+	HTTP     HTTPImporterCfg
+	ECB      ECBImporterCfg
+	Priority ImporterPriorityCfg
+}
+
+// ImporterPriorityCfg orders the importers per kind of data by name: massive, http, ecb, yahoo.
+// Configured importers missing from a list are tried after the listed ones, in the default order.
+type ImporterPriorityCfg struct {
+	Prices    []string
+	Rates     []string
+	Reference []string // ticker details
 }
 
 // MarketDataImporterConfig holds per-importer settings (e.g. API keys).
@@ -33,6 +74,47 @@ type MarketDataImporterConfig struct {
 	ApiKeys []string
 }
 
+// YahooImporterCfg enables the keyless Yahoo Finance chart API for prices, rates and ticker details.
+type YahooImporterCfg struct {
+	Enabled bool
+}
+
+// ECBImporterCfg enables the keyless ECB euro reference rates; other pairs are derived through EUR.
+type ECBImporterCfg struct {
+	Enabled bool
+}
+
+// HTTPImporterCfg describes a self-hosted or alternative feed of daily prices and rates.
+// The URLs accept {symbol}, {main}, {secondary}, {start}, {end}, {startUnix} and {endUnix}.
+type HTTPImporterCfg struct {
+	PricesURL    string
+	RatesURL     string
+	Format       string // json (default) | csv
+	DateLayout   string // Go layout of {start} and {end}, default 2006-01-02
+	CsvSeparator string
+	AuthHeader   string // e.g. Authorization
+	AuthValue    string // e.g. "Bearer <token>"
+	Fields       HTTPImporterFields
+}
+
+// HTTPImporterFields maps the fields of the HTTP feed; JSON keys or CSV column headers.
+type HTTPImporterFields struct {
+	Items      string // JSON dot path to the rows, empty = the document
+	Date       string // "$key" when the rows are an object keyed by date
+	DateFormat string // Go layout, unix or unixms; default 2006-01-02
+	Open       string
+	High       string
+	Low        string
+	Close      string
+	Volume     string
+	Rate       string // rate field of the rates feed, default Close
+}
+
+// configured reports whether a feed URL is set.
+func (c HTTPImporterCfg) configured() bool {
+	return c.PricesURL != "" || c.RatesURL != ""
+}
+
 type AppSettings struct {
 	DateFormat           string
 	MainCurrency         string
@@ -134,6 +216,9 @@ var defaultCfg = AppCfg{
 		FinancialSimulator:                false,
 	},
 	MarketDataImporters: MarketDataImportersCfg{}, // set Massive with ApiKeys in YAML to enable backfill
+	Attachments: AttachmentsCfg{
+		Backend: attachmentBackendLocal,
+	},
 }
 
 type Msg struct {
@@ -192,6 +277,40 @@ func validateSettings(s AppSettings) error {
 	return nil
 }
 
+const (
+	attachmentBackendLocal = "local"
+	attachmentBackendS3    = "s3"
+)
+
+func validateAttachments(c AttachmentsCfg) error {
+	switch c.Backend {
+	case "", attachmentBackendLocal:
+		return nil
+	case attachmentBackendS3:
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			return fmt.Errorf("s3 backend requires Endpoint and Bucket")
+		}
+		if c.S3.AccessKey == "" || c.S3.SecretKey == "" {
+			return fmt.Errorf("s3 backend requires AccessKey and SecretKey")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown attachment backend %q: must be %q or %q", c.Backend, attachmentBackendLocal, attachmentBackendS3)
+	}
+}
+
+func validateEncryption(c EncryptionCfg) error {
+	for _, secret := range append([]string{c.Secret}, c.PreviousSecrets...) {
+		if secret != "" && len(secret) < filestore.MinSecretLength {
+			return fmt.Errorf("encryption secrets must be at least %d characters long", filestore.MinSecretLength)
+		}
+	}
+	if c.EncryptBackups && c.Secret == "" {
+		return fmt.Errorf("EncryptBackups requires an encryption Secret")
+	}
+	return nil
+}
+
 // applyAuthDisabledDefaults sets default values when auth is disabled.
 func applyAuthDisabledDefaults(cfg *AppCfg) {
 	if cfg.Auth.DefaultUser == "" {
@@ -260,5 +379,12 @@ func getAppCfg(file string) (AppCfg, error) {
 		return cfg, fmt.Errorf("settings validation: %w", err)
 	}
 
+	if err := validateAttachments(cfg.Attachments); err != nil {
+		return cfg, fmt.Errorf("attachments validation: %w", err)
+	}
+	if err := validateEncryption(cfg.Attachments.Encryption); err != nil {
+		return cfg, fmt.Errorf("attachments validation: %w", err)
+	}
+
 	return cfg, nil
 }
@@ -166,3 +166,50 @@ func TestValidateSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAttachments(t *testing.T) {
+	s3 := S3Cfg{Endpoint: "http://nas:9000", Bucket: "etna", AccessKey: "key", SecretKey: "secret"}
+	tests := []struct {
+		name    string
+		cfg     AttachmentsCfg
+		wantErr bool
+	}{
+		{name: "default", cfg: AttachmentsCfg{}},
+		{name: "local", cfg: AttachmentsCfg{Backend: "local"}},
+		{name: "s3", cfg: AttachmentsCfg{Backend: "s3", S3: s3}},
+		{name: "s3 without bucket", cfg: AttachmentsCfg{Backend: "s3", S3: S3Cfg{Endpoint: "http://nas:9000", AccessKey: "key", SecretKey: "secret"}}, wantErr: true},
+		{name: "s3 without credentials", cfg: AttachmentsCfg{Backend: "s3", S3: S3Cfg{Endpoint: "http://nas:9000", Bucket: "etna"}}, wantErr: true},
+		{name: "unknown backend", cfg: AttachmentsCfg{Backend: "ftp"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttachments(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAttachments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEncryption(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     EncryptionCfg
+		wantErr bool
+	}{
+		{name: "disabled", cfg: EncryptionCfg{}},
+		{name: "secret", cfg: EncryptionCfg{Secret: "0123456789abcdef", EncryptBackups: true}},
+		{name: "decrypt only", cfg: EncryptionCfg{PreviousSecrets: []string{"0123456789abcdef"}}},
+		{name: "short secret", cfg: EncryptionCfg{Secret: "short"}, wantErr: true},
+		{name: "short previous secret", cfg: EncryptionCfg{Secret: "0123456789abcdef", PreviousSecrets: []string{"short"}}, wantErr: true},
+		{name: "encrypted backups without secret", cfg: EncryptionCfg{EncryptBackups: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEncryption(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEncryption() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -127,15 +127,84 @@ Auth:
       - Name: "admin"
         Pw:   "admin"
 
+# -----------------------------------------------------------------------------
+# Attachments — where uploaded files are stored
+# -----------------------------------------------------------------------------
+# Metadata always stays in the database; only the file content is moved.
+# To switch an existing installation, stop the server and run:
+#   etna attachments migrate --from local --to s3 -c config.yaml
+Attachments:
+  # Backend: "local" (files in DataDir/attachments) or "s3" (S3-compatible bucket).
+  Backend: "local"
+
+  # Only used when Backend: "s3". Works with AWS S3, MinIO, Garage and similar services.
+  # S3:
+  #   Endpoint: "http://nas:9000"
+  #   Region: "us-east-1"
+  #   Bucket: "etna"
+  #   # Optional key prefix, allows sharing a bucket.
+  #   Prefix: "attachments"
+  #   AccessKey: ""
+  #   SecretKey: ""   # or ETNA_ATTACHMENTS_S3_SECRETKEY
+  #   # Address the bucket as Endpoint/Bucket; required by most self-hosted services.
+  #   PathStyle: true
+
+  # Encrypt attachment files at rest with AES-256-GCM; the key is derived from Secret.
+  # Files stored before encryption was enabled stay readable; to encrypt them, or to rotate
+  # the secret (new one in Secret, old one in PreviousSecrets), stop the server and run:
+  #   etna attachments rotate-key -c config.yaml
+  # Losing the secret makes the files unreadable.
+  # Encryption:
+  #   Secret: ""   # at least 16 characters, or ETNA_ATTACHMENTS_ENCRYPTION_SECRET
+  #   PreviousSecrets: []
+  #   # Keep attachments encrypted inside backups; restoring them then requires the same secret.
+  #   EncryptBackups: false
+
 # -----------------------------------------------------------------------------
 # MarketDataImporters — external market data sources
 # -----------------------------------------------------------------------------
-# Configure API keys for market data providers.
+# Configure the market data providers. When several are configured, prices, rates
+# and ticker details are fetched from the first one that has them, by default in the
+# order Massive, HTTP, ECB, Yahoo; if a provider is down or lacks a symbol, the next
+# one is tried. The provider that served a symbol is tried first next time and is
+# recorded as the "source" label of the series.
 # Keys can also be set via environment variables:
 #   ETNA_MARKETDATAIMPORTERS_MASSIVE_APIKEYS_0=your_api_key
 MarketDataImporters:
   Massive:
     ApiKeys: []
+  # Yahoo Finance needs no key; the API is unofficial and may throttle requests.
+  Yahoo:
+    Enabled: false
+  # ECB euro reference rates, no key needed; rates only, other pairs are derived through EUR.
+  ECB:
+    Enabled: false
+  # Any HTTP feed of daily prices and/or rates, e.g. a self-hosted service.
+  # URL placeholders: {symbol} {main} {secondary} {start} {end} {startUnix} {endUnix}
+  # HTTP:
+  #   PricesURL: "https://feed.example.com/prices/{symbol}?from={start}&to={end}"
+  #   RatesURL: "https://feed.example.com/fx/{main}/{secondary}?from={start}&to={end}"
+  #   Format: json            # json | csv
+  #   DateLayout: 2006-01-02  # format of {start} and {end}, Go layout
+  #   CsvSeparator: ","
+  #   AuthHeader: Authorization
+  #   AuthValue: "Bearer <token>"
+  #   Fields:
+  #     Items: data.rows      # JSON dot path to the rows; empty = the document
+  #     Date: date            # "$key" when the rows are an object keyed by date
+  #     DateFormat: 2006-01-02  # Go layout, unix or unixms
+  #     Open: open
+  #     High: high
+  #     Low: low
+  #     Close: close
+  #     Volume: volume
+  #     Rate: rate            # rate field of the rates feed, default Close
+  # Order of the providers per kind of data: massive, http, ecb, yahoo.
+  # Configured providers not listed are tried after the listed ones.
+  # Priority:
+  #   Prices: [massive, yahoo]
+  #   Rates: [ecb, massive, yahoo]
+  #   Reference: [massive, yahoo]
 `
 
 func generateConfigCmd() *cobra.Command {
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andresbott/etna/internal/marketdata/importer"
+)
+
+// marketDataProviders are the clients of the configured market data importers; a nil client
+// means no configured importer offers that kind of data.
+type marketDataProviders struct {
+	prices       importer.Client
+	fx           importer.FXClient
+	fundamentals importer.FundamentalsClient
+	reference    importer.ReferenceClient
+}
+
+// defaultProviderOrder is the priority of the importers when none is configured.
+var defaultProviderOrder = []string{importer.ProviderMassive, importer.ProviderHTTP, importer.ProviderECB, importer.ProviderYahoo}
+
+// namedProvider is a configured importer client for one kind of data.
+type namedProvider[T any] struct {
+	name   string
+	client T
+}
+
+// initMarketDataProviders builds the market data clients. Prices, rates and ticker details come
+// from a chain over every importer offering them, ordered by cfg.Priority, so that a provider
+// that is down or lacks a symbol falls back to the next one. Fundamentals come from Massive only.
+func initMarketDataProviders(cfg MarketDataImportersCfg) (marketDataProviders, error) {
+	var p marketDataProviders
+	var prices []namedProvider[importer.Client]
+	var rates []namedProvider[importer.FXClient]
+	var reference []namedProvider[importer.ReferenceClient]
+
+	if len(cfg.Massive.ApiKeys) > 0 {
+		pool, err := importer.NewMassivePool(cfg.Massive.ApiKeys)
+		if err != nil {
+			return p, fmt.Errorf("market data importer pool (massive): %w", err)
+		}
+		prices = append(prices, namedProvider[importer.Client]{importer.ProviderMassive, pool})
+		fxPool, err := importer.NewMassiveFXPool(cfg.Massive.ApiKeys)
+		if err != nil {
+			return p, fmt.Errorf("FX importer pool (massive): %w", err)
+		}
+		rates = append(rates, namedProvider[importer.FXClient]{importer.ProviderMassive, fxPool})
+		fundamentalsPool, err := importer.NewMassiveFundamentalsPool(cfg.Massive.ApiKeys)
+		if err != nil {
+			return p, fmt.Errorf("fundamentals importer pool (massive): %w", err)
+		}
+		p.fundamentals = fundamentalsPool
+		refPool, err := importer.NewMassiveReferencePool(cfg.Massive.ApiKeys)
+		if err != nil {
+			return p, fmt.Errorf("reference importer pool (massive): %w", err)
+		}
+		reference = append(reference, namedProvider[importer.ReferenceClient]{importer.ProviderMassive, refPool})
+	}
+
+	if cfg.HTTP.configured() {
+		c, err := importer.NewHTTPTemplateClient(importer.HTTPTemplateConfig{
+			PricesURL:    cfg.HTTP.PricesURL,
+			RatesURL:     cfg.HTTP.RatesURL,
+			Format:       cfg.HTTP.Format,
+			DateLayout:   cfg.HTTP.DateLayout,
+			CsvSeparator: cfg.HTTP.CsvSeparator,
+			AuthHeader:   cfg.HTTP.AuthHeader,
+			AuthValue:    cfg.HTTP.AuthValue,
+			Fields: importer.HTTPFieldMapping{
+				Items:      cfg.HTTP.Fields.Items,
+				Date:       cfg.HTTP.Fields.Date,
+				DateFormat: cfg.HTTP.Fields.DateFormat,
+				Open:       cfg.HTTP.Fields.Open,
+				High:       cfg.HTTP.Fields.High,
+				Low:        cfg.HTTP.Fields.Low,
+				Close:      cfg.HTTP.Fields.Close,
+				Volume:     cfg.HTTP.Fields.Volume,
+				Rate:       cfg.HTTP.Fields.Rate,
+			},
+		})
+		if err != nil {
+			return p, fmt.Errorf("market data importer (http): %w", err)
+		}
+		if cfg.HTTP.PricesURL != "" {
+			prices = append(prices, namedProvider[importer.Client]{importer.ProviderHTTP, c})
+		}
+		if cfg.HTTP.RatesURL != "" {
+			rates = append(rates, namedProvider[importer.FXClient]{importer.ProviderHTTP, c})
+		}
+	}
+
+	if cfg.ECB.Enabled {
+		rates = append(rates, namedProvider[importer.FXClient]{importer.ProviderECB, importer.NewECBClient()})
+	}
+
+	if cfg.Yahoo.Enabled {
+		c := importer.NewYahooClient()
+		prices = append(prices, namedProvider[importer.Client]{importer.ProviderYahoo, c})
+		rates = append(rates, namedProvider[importer.FXClient]{importer.ProviderYahoo, c})
+		reference = append(reference, namedProvider[importer.ReferenceClient]{importer.ProviderYahoo, c})
+	}
+
+	var err error
+	if prices, err = prioritize("Prices", prices, cfg.Priority.Prices); err != nil {
+		return p, err
+	}
+	if rates, err = prioritize("Rates", rates, cfg.Priority.Rates); err != nil {
+		return p, err
+	}
+	if reference, err = prioritize("Reference", reference, cfg.Priority.Reference); err != nil {
+		return p, err
+	}
+
+	if len(prices) > 0 {
+		names, clients := splitProviders(prices)
+		if p.prices, err = importer.NewChainClient(names, clients); err != nil {
+			return p, fmt.Errorf("market data importer chain: %w", err)
+		}
+	}
+	if len(rates) > 0 {
+		names, clients := splitProviders(rates)
+		if p.fx, err = importer.NewFXChainClient(names, clients); err != nil {
+			return p, fmt.Errorf("FX importer chain: %w", err)
+		}
+	}
+	if len(reference) > 0 {
+		names, clients := splitProviders(reference)
+		if p.reference, err = importer.NewReferenceChainClient(names, clients); err != nil {
+			return p, fmt.Errorf("reference importer chain: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// prioritize sorts the providers by their position in priority; providers not listed keep the
+// default order after the listed ones. Listed importers that are not configured are ignored.
+func prioritize[T any](kind string, providers []namedProvider[T], priority []string) ([]namedProvider[T], error) {
+	rank := map[string]int{}
+	for i, name := range priority {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(defaultProviderOrder, name) {
+			return nil, fmt.Errorf("MarketDataImporters.Priority.%s: unknown importer %q, expected one of %v", kind, name, defaultProviderOrder)
+		}
+		if _, dup := rank[name]; !dup {
+			rank[name] = i
+		}
+	}
+	pos := func(name string) int {
+		if r, ok := rank[name]; ok {
+			return r
+		}
+		return len(priority) + slices.Index(defaultProviderOrder, name)
+	}
+	out := slices.Clone(providers)
+	slices.SortStableFunc(out, func(a, b namedProvider[T]) int { return pos(a.name) - pos(b.name) })
+	return out, nil
+}
+
+func splitProviders[T any](providers []namedProvider[T]) ([]string, []T) {
+	names := make([]string, len(providers))
+	clients := make([]T, len(providers))
+	for i, pr := range providers {
+		names[i], clients[i] = pr.name, pr.client
+	}
+	return names, clients
+}
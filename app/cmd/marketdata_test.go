@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andresbott/etna/internal/marketdata/importer"
+)
+
+// chainProviders returns the provider order of a chain client, or nil for any other client.
+func chainProviders(c any) []string {
+	if ch, ok := c.(interface{ Providers() []string }); ok {
+		return ch.Providers()
+	}
+	return nil
+}
+
+func TestInitMarketDataProviders(t *testing.T) {
+	t.Run("nothing configured", func(t *testing.T) {
+		p, err := initMarketDataProviders(MarketDataImportersCfg{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.prices != nil || p.fx != nil || p.fundamentals != nil || p.reference != nil {
+			t.Errorf("expected no clients, got %+v", p)
+		}
+	})
+
+	t.Run("http feed before yahoo", func(t *testing.T) {
+		p, err := initMarketDataProviders(MarketDataImportersCfg{
+			Yahoo: YahooImporterCfg{Enabled: true},
+			HTTP: HTTPImporterCfg{
+				PricesURL: "http://localhost/prices/{symbol}",
+				Fields:    HTTPImporterFields{Date: "date", Close: "close"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := chainProviders(p.prices); !slices.Equal(got, []string{importer.ProviderHTTP, importer.ProviderYahoo}) {
+			t.Errorf("expected prices from the HTTP feed then Yahoo, got %v", got)
+		}
+		// the feed has no rates URL, rates and details come from Yahoo
+		if got := chainProviders(p.fx); !slices.Equal(got, []string{importer.ProviderYahoo}) {
+			t.Errorf("expected rates from Yahoo, got %v", got)
+		}
+		if got := chainProviders(p.reference); !slices.Equal(got, []string{importer.ProviderYahoo}) {
+			t.Errorf("expected ticker details from Yahoo, got %v", got)
+		}
+		if p.fundamentals != nil {
+			t.Errorf("expected no fundamentals without Massive, got %T", p.fundamentals)
+		}
+	})
+
+	t.Run("ecb rates before yahoo", func(t *testing.T) {
+		p, err := initMarketDataProviders(MarketDataImportersCfg{
+			Yahoo: YahooImporterCfg{Enabled: true},
+			ECB:   ECBImporterCfg{Enabled: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := chainProviders(p.fx); !slices.Equal(got, []string{importer.ProviderECB, importer.ProviderYahoo}) {
+			t.Errorf("expected rates from the ECB then Yahoo, got %v", got)
+		}
+		// the ECB offers rates only
+		if got := chainProviders(p.prices); !slices.Equal(got, []string{importer.ProviderYahoo}) {
+			t.Errorf("expected prices from Yahoo, got %v", got)
+		}
+	})
+
+	t.Run("configured priority", func(t *testing.T) {
+		p, err := initMarketDataProviders(MarketDataImportersCfg{
+			Massive: MarketDataImporterConfig{ApiKeys: []string{"key"}},
+			Yahoo:   YahooImporterCfg{Enabled: true},
+			ECB:     ECBImporterCfg{Enabled: true},
+			Priority: ImporterPriorityCfg{
+				Prices: []string{"Yahoo"},
+				Rates:  []string{"ecb", "http", "yahoo"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := chainProviders(p.prices); !slices.Equal(got, []string{importer.ProviderYahoo, importer.ProviderMassive}) {
+			t.Errorf("expected Yahoo first and unlisted Massive after it, got %v", got)
+		}
+		if got := chainProviders(p.fx); !slices.Equal(got, []string{importer.ProviderECB, importer.ProviderYahoo, importer.ProviderMassive}) {
+			t.Errorf("unexpected rates order %v", got)
+		}
+		if got := chainProviders(p.reference); !slices.Equal(got, []string{importer.ProviderMassive, importer.ProviderYahoo}) {
+			t.Errorf("expected the default order for details, got %v", got)
+		}
+		if p.fundamentals == nil {
+			t.Error("expected fundamentals from Massive")
+		}
+	})
+
+	t.Run("unknown importer in priority", func(t *testing.T) {
+		_, err := initMarketDataProviders(MarketDataImportersCfg{
+			Yahoo:    YahooImporterCfg{Enabled: true},
+			Priority: ImporterPriorityCfg{Prices: []string{"bloomberg"}},
+		})
+		if err == nil {
+			t.Error("expected an error for an unknown importer")
+		}
+	})
+
+	t.Run("invalid http feed", func(t *testing.T) {
+		_, err := initMarketDataProviders(MarketDataImportersCfg{HTTP: HTTPImporterCfg{PricesURL: "http://localhost"}})
+		if err == nil {
+			t.Error("expected an error for a feed without field mapping")
+		}
+	})
+}
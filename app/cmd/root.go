@@ -32,6 +32,7 @@ func newRootCommand() *cobra.Command {
 		serverCmd(),
 		versionCmd(),
 		generateConfigCmd(),
+		attachmentsCmd(),
 	)
 
 	return cmd
@@ -16,12 +16,12 @@ import (
 	"github.com/andresbott/etna/app/metainfo"
 	"github.com/andresbott/etna/app/router"
 	handlers "github.com/andresbott/etna/app/router/handlers"
+	csvimportHandler "github.com/andresbott/etna/app/router/handlers/csvimport"
 	"github.com/andresbott/etna/app/tasks"
 	"github.com/andresbott/etna/internal/accounting"
 	"github.com/andresbott/etna/internal/csvimport"
 	"github.com/andresbott/etna/internal/filestore"
 	"github.com/andresbott/etna/internal/marketdata"
-	"github.com/andresbott/etna/internal/marketdata/importer"
 	"github.com/andresbott/etna/internal/taskrunner"
 	"github.com/andresbott/etna/internal/toolsdata"
 	"github.com/glebarez/sqlite"
@@ -38,6 +38,7 @@ const dbFile = "carbon.db"
 const sessionsDir = "sessions"
 const backupsDir = "backup"
 const attachmentsDir = "attachments"
+const inboxDir = "inbox"
 
 func serverCmd() *cobra.Command {
 	var configFile = "./config.yaml"
@@ -88,30 +89,10 @@ func runServer(configFile string) error {
 	l.Info("using data directory", slog.String("path", cfg.DataDir))
 
 	// ——— Database ———
-	gormLogger := gormlogger.NewSlogLogger(
-		l.With(slog.String("component", "gorm")),
-		gormlogger.Config{
-			IgnoreRecordNotFoundError: true,
-			LogLevel:                  gormlogger.Warn, // only warnings and errors, not every SQL
-		},
-	)
-	db, err := gorm.Open(sqlite.Open(filepath.Join(cfg.DataDir, dbFile)), &gorm.Config{
-		Logger: gormLogger,
-	})
+	db, err := openDatabase(cfg.DataDir, l)
 	if err != nil {
 		return err
 	}
-	// SQLite: limit to one open connection to prevent SQLITE_BUSY when concurrent
-	// goroutines (task runner, HTTP handlers) try to write at the same time.
-	// Also enable WAL mode for better read/write concurrency and set a busy timeout
-	// so any remaining contention blocks instead of failing immediately.
-	sqlDB, err := db.DB()
-	if err != nil {
-		return err
-	}
-	sqlDB.SetMaxOpenConns(10)
-	db.Exec("PRAGMA journal_mode=WAL")
-	db.Exec("PRAGMA busy_timeout=5000")
 
 	// ——— Application stores (shared by router and task runner) ———
 	marketStore, finStore, csvImportStore, attachmentStore, toolsDataStore, err := initStores(db, cfg)
@@ -125,9 +106,15 @@ func runServer(configFile string) error {
 		return err
 	}
 
+	// ——— Market data providers (shared by router and task runner) ———
+	providers, err := initMarketDataProviders(cfg.MarketDataImporters)
+	if err != nil {
+		return err
+	}
+
 	// ——— Task runner and cron scheduler (started inside GroupRunner task) ———
 	backupDest := filepath.Join(cfg.DataDir, backupsDir)
-	taskRunner, scheduleStore, scheduler, err := initTaskRunnerAndScheduler(cfg, db, l, marketStore, finStore, csvImportStore, attachmentStore, toolsDataStore, backupDest)
+	taskRunner, scheduleStore, scheduler, err := initTaskRunnerAndScheduler(cfg, db, l, marketStore, finStore, csvImportStore, attachmentStore, toolsDataStore, providers, backupDest)
 	if err != nil {
 		return err
 	}
@@ -139,14 +126,6 @@ func runServer(configFile string) error {
 	}
 
 	// ——— Router (API, SPA, handlers) ———
-	var referenceClient importer.ReferenceClient
-	if len(cfg.MarketDataImporters.Massive.ApiKeys) > 0 {
-		refPool, err := importer.NewMassiveReferencePool(cfg.MarketDataImporters.Massive.ApiKeys)
-		if err != nil {
-			return fmt.Errorf("reference importer pool (massive): %w", err)
-		}
-		referenceClient = refPool
-	}
 	routerCfg := router.Cfg{
 		Db:                db,
 		SessionAuth:       sessionAuth,
@@ -177,7 +156,7 @@ func runServer(configFile string) error {
 		CsvImportStore:  csvImportStore,
 		AttachmentStore: attachmentStore,
 		ToolsDataStore:  toolsDataStore,
-		ReferenceClient: referenceClient,
+		ReferenceClient: providers.reference,
 	}
 	mainAppHandler, err := router.New(routerCfg)
 	if err != nil {
@@ -224,6 +203,35 @@ func runServer(configFile string) error {
 	return g.Wait()
 }
 
+// openDatabase opens the SQLite database in the data directory.
+func openDatabase(dataDir string, l *slog.Logger) (*gorm.DB, error) {
+	gormLogger := gormlogger.NewSlogLogger(
+		l.With(slog.String("component", "gorm")),
+		gormlogger.Config{
+			IgnoreRecordNotFoundError: true,
+			LogLevel:                  gormlogger.Warn, // only warnings and errors, not every SQL
+		},
+	)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dataDir, dbFile)), &gorm.Config{
+		Logger: gormLogger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// SQLite: limit to one open connection to prevent SQLITE_BUSY when concurrent
+	// goroutines (task runner, HTTP handlers) try to write at the same time.
+	// Also enable WAL mode for better read/write concurrency and set a busy timeout
+	// so any remaining contention blocks instead of failing immediately.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(10)
+	db.Exec("PRAGMA journal_mode=WAL")
+	db.Exec("PRAGMA busy_timeout=5000")
+	return db, nil
+}
+
 // initStores creates the application-level data stores shared by router and task runner.
 func initStores(db *gorm.DB, cfg AppCfg) (*marketdata.Store, *accounting.Store, *csvimport.Store, *filestore.Store, *toolsdata.Store, error) {
 	marketStore, err := marketdata.NewStore(db)
@@ -238,11 +246,15 @@ func initStores(db *gorm.DB, cfg AppCfg) (*marketdata.Store, *accounting.Store,
 	if err != nil {
 		return nil, nil, nil, nil, nil, fmt.Errorf("csv import store: %w", err)
 	}
-	maxAttachmentBytes := int64(10 * 1024 * 1024) // default 10MB
-	if cfg.Settings.MaxAttachmentSizeMB > 0 {
-		maxAttachmentBytes = int64(cfg.Settings.MaxAttachmentSizeMB * 1024 * 1024)
+	backend, err := attachmentBackend(cfg, cfg.Attachments.Backend)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("attachment backend: %w", err)
+	}
+	opts, err := attachmentStoreOptions(cfg)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("attachment encryption: %w", err)
 	}
-	attachmentStore, err := filestore.New(db, filepath.Join(cfg.DataDir, attachmentsDir), maxAttachmentBytes)
+	attachmentStore, err := filestore.NewWithBackend(db, backend, maxAttachmentBytes(cfg), opts...)
 	if err != nil {
 		return nil, nil, nil, nil, nil, fmt.Errorf("attachment store: %w", err)
 	}
@@ -388,6 +400,7 @@ func initTaskRunnerAndScheduler(
 	csvImportStore *csvimport.Store,
 	attachmentStore *filestore.Store,
 	toolsDataStore *toolsdata.Store,
+	providers marketDataProviders,
 	backupDest string,
 ) (*taskrunner.Runner, *taskrunner.ScheduleStore, *taskrunner.Scheduler, error) {
 	runner, err := taskrunner.NewRunner(taskrunner.Cfg{
@@ -408,34 +421,18 @@ func initTaskRunnerAndScheduler(
 		return nil, nil, nil, fmt.Errorf("schedule store: %w", err)
 	}
 
-	var marketDataClient importer.Client
-	var fxClient importer.FXClient
-	var fundamentalsClient importer.FundamentalsClient
-	if len(cfg.MarketDataImporters.Massive.ApiKeys) > 0 {
-		pool, err := importer.NewMassivePool(cfg.MarketDataImporters.Massive.ApiKeys)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("market data importer pool (massive): %w", err)
-		}
-		marketDataClient = pool
-		fxPool, err := importer.NewMassiveFXPool(cfg.MarketDataImporters.Massive.ApiKeys)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("FX importer pool (massive): %w", err)
-		}
-		fxClient = fxPool
-		fundamentalsPool, err := importer.NewMassiveFundamentalsPool(cfg.MarketDataImporters.Massive.ApiKeys)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("fundamentals importer pool (massive): %w", err)
-		}
-		fundamentalsClient = fundamentalsPool
-	}
-
 	// Register tasks once; enqueue later via runner.AddRun(name) (scheduler and API).
 	runner.RegisterTask(tasks.NewBackupTaskFn(finStore, marketStore, csvImportStore, attachmentStore, toolsDataStore, scheduleStore, backupDest, l), tasks.BackupTaskName, 0)
-	runner.RegisterTask(tasks.NewFinancialImportTaskFn(marketStore, marketDataClient), tasks.FinancialImportTaskName, 0)
-	runner.RegisterTask(tasks.NewFinancialBackfillTaskFn(marketStore, l, marketDataClient), tasks.FinancialBackfillTaskName, 0)
-	runner.RegisterTask(tasks.NewFXImportTaskFn(marketStore, cfg.Settings.MainCurrency, cfg.Settings.AllCurrencies(), fxClient), tasks.FXImportTaskName, 0)
-	runner.RegisterTask(tasks.NewFXBackfillTaskFn(marketStore, l, cfg.Settings.MainCurrency, cfg.Settings.AllCurrencies(), fxClient), tasks.FXBackfillTaskName, 0)
-	runner.RegisterTask(tasks.NewEPSImportTaskFn(marketStore, fundamentalsClient), tasks.EPSImportTaskName, 0)
+	runner.RegisterTask(tasks.NewFinancialImportTaskFn(marketStore, providers.prices), tasks.FinancialImportTaskName, 0)
+	runner.RegisterTask(tasks.NewFinancialBackfillTaskFn(marketStore, l, providers.prices), tasks.FinancialBackfillTaskName, 0)
+	runner.RegisterTask(tasks.NewFXImportTaskFn(marketStore, cfg.Settings.MainCurrency, cfg.Settings.AllCurrencies(), providers.fx), tasks.FXImportTaskName, 0)
+	runner.RegisterTask(tasks.NewFXBackfillTaskFn(marketStore, l, cfg.Settings.MainCurrency, cfg.Settings.AllCurrencies(), providers.fx), tasks.FXBackfillTaskName, 0)
+	runner.RegisterTask(tasks.NewEPSImportTaskFn(marketStore, providers.fundamentals), tasks.EPSImportTaskName, 0)
+	runner.RegisterTask(tasks.NewPrepaidAmortizationTaskFn(finStore, l), tasks.PrepaidAmortizationTaskName, 1)
+	runner.RegisterTask(tasks.NewAttachmentCheckTaskFn(finStore, attachmentStore, toolsDataStore, false, l), tasks.AttachmentCheckTaskName, 1)
+	runner.RegisterTask(tasks.NewAttachmentCheckTaskFn(finStore, attachmentStore, toolsDataStore, true, l), tasks.AttachmentPurgeTaskName, 1)
+	inboxImporter := &csvimportHandler.ImportHandler{CsvStore: csvImportStore, FinStore: finStore, InstrumentStore: marketStore, Classifier: csvimport.NewClassifier(), MainCurrency: cfg.Settings.MainCurrency}
+	runner.RegisterTask(tasks.NewImportInboxTaskFn(csvImportStore, inboxImporter, filepath.Join(cfg.DataDir, inboxDir), l), tasks.ImportInboxTaskName, 1)
 	if !cfg.Env.Production {
 		runner.RegisterTask(tasks.NewLogOnlyTaskFn(l), tasks.LogOnlyTaskName, 4)
 		runner.RegisterTask(tasks.NewLogOnlyLongTaskFn(l), tasks.LogOnlyLongTaskName, 1)
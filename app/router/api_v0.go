@@ -14,6 +14,7 @@ import (
 	statsHandler "github.com/andresbott/etna/app/router/handlers/stats"
 	taskHandler "github.com/andresbott/etna/app/router/handlers/tasks"
 	toolsDataHandler "github.com/andresbott/etna/app/router/handlers/toolsdata"
+	"github.com/andresbott/etna/internal/csvimport"
 	"github.com/go-bumbu/userauth/authenticator"
 	"github.com/go-bumbu/userauth/handlers/sessionauth"
 	"github.com/gorilla/mux"
@@ -72,6 +73,11 @@ const finCategoryExpense = "/fin/category/expense"
 const finInstrumentPath = "/fin/instrument"
 const finPortfolio = "/fin/portfolio"
 const finReport = "/fin/report"
+const finAmortizationPath = "/fin/amortization"
+const finGoalPath = "/fin/goal"
+const finPersonPath = "/fin/person"
+const finSettleUpPath = "/fin/settle-up"
+const finDuplicatesPath = "/fin/duplicates"
 
 // this api surface is quite inconsistent, I know....
 // I haven't put too much thought into it for now and I will change it in the future
@@ -355,6 +361,22 @@ func (h *MainAppHandler) accountingAPI(r *mux.Router) {
 	// Attachments
 	// ==========================================================================
 
+	r.Path(fmt.Sprintf("%s/{id}/attachment", finEntries)).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		itemId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+
+		finHndlr.ListAttachments(itemId).ServeHTTP(w, r)
+	})
+
 	r.Path(fmt.Sprintf("%s/{id}/attachment", finEntries)).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := sessionauth.CtxGetUserData(r)
 		if err != nil {
@@ -371,7 +393,7 @@ func (h *MainAppHandler) accountingAPI(r *mux.Router) {
 		finHndlr.UploadAttachment(itemId).ServeHTTP(w, r)
 	})
 
-	r.Path(fmt.Sprintf("%s/{id}/attachment", finEntries)).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r.Path(fmt.Sprintf("%s/{id}/attachment/order", finEntries)).Methods(http.MethodPut).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := sessionauth.CtxGetUserData(r)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
@@ -384,10 +406,38 @@ func (h *MainAppHandler) accountingAPI(r *mux.Router) {
 			return
 		}
 
-		finHndlr.GetAttachment(itemId).ServeHTTP(w, r)
+		finHndlr.ReorderAttachments(itemId).ServeHTTP(w, r)
 	})
 
-	r.Path(fmt.Sprintf("%s/{id}/attachment", finEntries)).Methods(http.MethodDelete).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	attachmentHandlers := map[string]func(uint, uint) http.Handler{
+		http.MethodGet:    finHndlr.GetAttachment,
+		http.MethodPut:    finHndlr.UpdateAttachment,
+		http.MethodDelete: finHndlr.DeleteAttachment,
+	}
+	for method, hndlr := range attachmentHandlers {
+		r.Path(fmt.Sprintf("%s/{id}/attachment/{attachmentId:[0-9]+}", finEntries)).Methods(method).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := sessionauth.CtxGetUserData(r)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+
+			itemId, httpErr := getId(r)
+			if httpErr != nil {
+				http.Error(w, httpErr.Error, httpErr.Code)
+				return
+			}
+			attId, httpErr := getVarId(r, "attachmentId")
+			if httpErr != nil {
+				http.Error(w, httpErr.Error, httpErr.Code)
+				return
+			}
+
+			hndlr(itemId, attId).ServeHTTP(w, r)
+		})
+	}
+
+	r.Path(fmt.Sprintf("%s/{id}/attachment/{attachmentId:[0-9]+}/thumbnail", finEntries)).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := sessionauth.CtxGetUserData(r)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
@@ -399,8 +449,13 @@ func (h *MainAppHandler) accountingAPI(r *mux.Router) {
 			http.Error(w, httpErr.Error, httpErr.Code)
 			return
 		}
+		attId, httpErr := getVarId(r, "attachmentId")
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
 
-		finHndlr.DeleteAttachment(itemId).ServeHTTP(w, r)
+		finHndlr.GetAttachmentThumbnail(itemId, attId).ServeHTTP(w, r)
 	})
 
 	// ==========================================================================
@@ -511,6 +566,112 @@ func (h *MainAppHandler) accountingAPI(r *mux.Router) {
 		finHndlr.AccountBalance().ServeHTTP(w, r)
 	})
 
+	// ==========================================================================
+	// Prepaid expense amortization
+	// ==========================================================================
+
+	registerCrudRoutes(r, finAmortizationPath, crudHandlers{
+		list:   finHndlr.ListAmortizationPlans,
+		create: finHndlr.CreateAmortizationPlan,
+		update: finHndlr.UpdateAmortizationPlan,
+		delete: finHndlr.DeleteAmortizationPlan,
+	})
+
+	// ==========================================================================
+	// Savings goals
+	// ==========================================================================
+
+	registerCrudRoutes(r, finGoalPath, crudHandlers{
+		list:   finHndlr.ListSavingsGoals,
+		create: finHndlr.CreateSavingsGoal,
+		update: finHndlr.UpdateSavingsGoal,
+		delete: finHndlr.DeleteSavingsGoal,
+	})
+
+	r.Path(fmt.Sprintf("%s/{id}/progress", finGoalPath)).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		itemId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+		finHndlr.GoalProgress(itemId).ServeHTTP(w, r)
+	})
+
+	// ==========================================================================
+	// Shared expenses
+	// ==========================================================================
+
+	registerCrudRoutes(r, finPersonPath, crudHandlers{
+		list:   finHndlr.ListPeople,
+		create: finHndlr.CreatePerson,
+		update: finHndlr.UpdatePerson,
+		delete: finHndlr.DeletePerson,
+	})
+
+	splitHandlers := map[string]func(uint) http.Handler{
+		http.MethodGet:    finHndlr.GetExpenseSplit,
+		http.MethodPut:    finHndlr.SetExpenseSplit,
+		http.MethodDelete: finHndlr.ClearExpenseSplit,
+	}
+	for method, hndlr := range splitHandlers {
+		r.Path(fmt.Sprintf("%s/{id}/split", finEntries)).Methods(method).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := sessionauth.CtxGetUserData(r); err != nil {
+				http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			itemId, httpErr := getId(r)
+			if httpErr != nil {
+				http.Error(w, httpErr.Error, httpErr.Code)
+				return
+			}
+			hndlr(itemId).ServeHTTP(w, r)
+		})
+	}
+
+	r.Path(finSettleUpPath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		finHndlr.SettleUpBalances().ServeHTTP(w, r)
+	})
+
+	r.Path(fmt.Sprintf("%s/{id}", finSettleUpPath)).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		itemId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+		finHndlr.SettleUp(itemId).ServeHTTP(w, r)
+	})
+
+	// ==========================================================================
+	// Duplicate transactions
+	// ==========================================================================
+
+	r.Path(finDuplicatesPath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		finHndlr.ListDuplicates().ServeHTTP(w, r)
+	})
+
+	r.Path(fmt.Sprintf("%s/resolve", finDuplicatesPath)).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		finHndlr.ResolveDuplicates().ServeHTTP(w, r)
+	})
 }
 
 // ==========================================================================
@@ -678,11 +839,16 @@ const importSubmitPath = "/import/submit"
 const importPreviewPath = "/import/preview"
 const importCategoryRulesPreviewPath = "/import/category-rules-preview"
 const importCategoryRulesSubmitPath = "/import/category-rules-submit"
+const importExportQIFPath = "/import/export/qif"
+const importBatchesPath = "/import/batches"
+const importInboxesPath = "/import/inboxes"
+const importPendingPath = "/import/pending"
+const importBundlePath = "/import/bundle"
 
 func (h *MainAppHandler) csvImportAPI(r *mux.Router) {
 	profileHndlr := csvimportHandler.ProfileHandler{Store: h.csvImportStore}
 	ruleGroupHndlr := csvimportHandler.CategoryRuleGroupHandler{Store: h.csvImportStore}
-	importHndlr := csvimportHandler.ImportHandler{CsvStore: h.csvImportStore, FinStore: h.finStore}
+	importHndlr := csvimportHandler.ImportHandler{CsvStore: h.csvImportStore, FinStore: h.finStore, InstrumentStore: h.marketStore, Reference: h.referenceClient, Classifier: csvimport.NewClassifier(), FileStore: h.attachmentStore, MainCurrency: h.appSettings.MainCurrency}
 
 	registerCrudRoutes(r, importProfilePath, crudHandlers{
 		list:   profileHndlr.ListProfiles,
@@ -698,9 +864,17 @@ func (h *MainAppHandler) csvImportAPI(r *mux.Router) {
 		delete: ruleGroupHndlr.DeleteCategoryRuleGroup,
 	})
 
+	registerCrudRoutes(r, importInboxesPath, crudHandlers{
+		list:   importHndlr.ListInboxes,
+		create: importHndlr.CreateInbox,
+		update: importHndlr.UpdateInbox,
+		delete: importHndlr.DeleteInbox,
+	})
+
 	h.csvImportRulePatternRoutes(r, ruleGroupHndlr)
 	h.csvImportParseRoutes(r, importHndlr)
 	h.csvImportReapplyRoutes(r, importHndlr)
+	h.csvImportPendingRoutes(r, importHndlr)
 }
 
 type crudHandlers struct {
@@ -829,6 +1003,51 @@ func (h *MainAppHandler) csvImportParseRoutes(r *mux.Router, importHndlr csvimpo
 		}
 		importHndlr.PreviewCSV().ServeHTTP(w, r)
 	})
+
+	r.Path(importExportQIFPath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		importHndlr.ExportQIF().ServeHTTP(w, r)
+	})
+
+	r.Path(importBundlePath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		importHndlr.ExportBundle().ServeHTTP(w, r)
+	})
+
+	r.Path(importBundlePath).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		importHndlr.ImportBundle().ServeHTTP(w, r)
+	})
+
+	r.Path(importBatchesPath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		importHndlr.ListImportBatches().ServeHTTP(w, r)
+	})
+
+	r.Path(fmt.Sprintf("%s/{id}/rollback", importBatchesPath)).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		batchId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+		importHndlr.RollbackImportBatch(batchId).ServeHTTP(w, r)
+	})
 }
 
 func (h *MainAppHandler) csvImportReapplyRoutes(r *mux.Router, importHndlr csvimportHandler.ImportHandler) {
@@ -849,6 +1068,42 @@ func (h *MainAppHandler) csvImportReapplyRoutes(r *mux.Router, importHndlr csvim
 	})
 }
 
+func (h *MainAppHandler) csvImportPendingRoutes(r *mux.Router, importHndlr csvimportHandler.ImportHandler) {
+	r.Path(importPendingPath).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		importHndlr.ListPendingImports().ServeHTTP(w, r)
+	})
+
+	r.Path(fmt.Sprintf("%s/{id}/parse", importPendingPath)).Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		pendingId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+		importHndlr.ParsePendingImport(pendingId).ServeHTTP(w, r)
+	})
+
+	r.Path(fmt.Sprintf("%s/{id}", importPendingPath)).Methods(http.MethodDelete).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessionauth.CtxGetUserData(r); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read user data: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		pendingId, httpErr := getId(r)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error, httpErr.Code)
+			return
+		}
+		importHndlr.DeletePendingImport(pendingId).ServeHTTP(w, r)
+	})
+}
+
 const backupPath = "/backup"
 const restorePath = "/restore"
 
@@ -0,0 +1,136 @@
+package csvimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+type importBatchPayload struct {
+	ID           uint       `json:"id"`
+	ProfileID    uint       `json:"profileId,omitempty"`
+	AccountID    uint       `json:"accountId"`
+	FileName     string     `json:"fileName"`
+	FileHash     string     `json:"fileHash"`
+	Format       string     `json:"format"`
+	TotalRows    int        `json:"totalRows"`
+	ImportedRows int        `json:"importedRows"`
+	User         string     `json:"user"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	RolledBackAt *time.Time `json:"rolledBackAt,omitempty"`
+}
+
+func batchToPayload(b csvimport.ImportBatch) importBatchPayload {
+	return importBatchPayload{
+		ID:           b.ID,
+		ProfileID:    b.ProfileID,
+		AccountID:    b.AccountID,
+		FileName:     b.FileName,
+		FileHash:     b.FileHash,
+		Format:       b.Format,
+		TotalRows:    b.TotalRows,
+		ImportedRows: b.ImportedRows,
+		User:         b.User,
+		CreatedAt:    b.CreatedAt,
+		RolledBackAt: b.RolledBackAt,
+	}
+}
+
+// ListImportBatches returns the import history, optionally filtered by the accountId query parameter.
+func (h *ImportHandler) ListImportBatches() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var accountID uint
+		if v := r.URL.Query().Get("accountId"); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid accountId: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			accountID = uint(id)
+		}
+
+		batches, err := h.CsvStore.ListImportBatches(r.Context(), accountID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list import batches: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		items := make([]importBatchPayload, len(batches))
+		for i, b := range batches {
+			items[i] = batchToPayload(b)
+		}
+
+		respJSON, err := json.Marshal(items)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJSON)
+	})
+}
+
+// RollbackImportBatch deletes the transactions created by an import batch, newest first, together with
+// the attachment files added to them since. Transactions that were deleted since the import are skipped,
+// so a rollback interrupted by an error can be retried.
+func (h *ImportHandler) RollbackImportBatch(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch, err := h.CsvStore.GetImportBatch(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, csvimport.ErrImportBatchNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to get import batch: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if batch.RolledBackAt != nil {
+			http.Error(w, "import batch was already rolled back", http.StatusConflict)
+			return
+		}
+
+		deleted := 0
+		for i := len(batch.TransactionIDs) - 1; i >= 0; i-- {
+			attachments, _ := h.FinStore.ListTransactionAttachments(r.Context(), batch.TransactionIDs[i])
+			err := h.FinStore.DeleteTransaction(r.Context(), batch.TransactionIDs[i])
+			if errors.Is(err, accounting.ErrTransactionNotFound) {
+				continue
+			}
+			if err != nil {
+				status := http.StatusInternalServerError
+				var valErr accounting.ErrValidation
+				if errors.As(err, &valErr) {
+					status = http.StatusConflict
+				}
+				http.Error(w, fmt.Sprintf("unable to delete transaction %d: %s", batch.TransactionIDs[i], err.Error()), status)
+				return
+			}
+			if h.FileStore != nil {
+				for _, att := range attachments {
+					_ = h.FileStore.Delete(r.Context(), att.AttachmentID)
+				}
+			}
+			deleted++
+		}
+
+		if err := h.CsvStore.MarkImportBatchRolledBack(r.Context(), id, time.Now()); err != nil {
+			http.Error(w, fmt.Sprintf("unable to update import batch: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		respJSON, err := json.Marshal(map[string]int{"deleted": deleted})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJSON)
+	})
+}
@@ -0,0 +1,233 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/filestore"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestImportBatchRollback(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importBatchRollback?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := store.CreateAccount(ctx, accounting.Account{Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an entry of the account that was not imported, it must survive the rollback
+	if _, err := store.CreateTransaction(ctx, accounting.Income{Description: "manual", Amount: 10, AccountID: accID, Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+
+	const ofx = `OFXHEADER:100
+DATA:OFXSGML
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>CHF<BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260301<TRNAMT>1500.00<FITID>A1<NAME>Salary</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260302<TRNAMT>-45.30<FITID>A2<NAME>Groceries</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	fileStore, err := filestore.New(db, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store, FileStore: fileStore}
+	type parseResponse struct {
+		Rows            []csvimport.ParsedRow `json:"rows"`
+		Format          string                `json:"format"`
+		FileName        string                `json:"fileName"`
+		FileHash        string                `json:"fileHash"`
+		PreviousImports []importBatchPayload  `json:"previousImports"`
+	}
+	parse := func() parseResponse {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("accountId", strconv.Itoa(int(accID)))
+		fw, _ := mw.CreateFormFile("file", "march.ofx")
+		_, _ = fw.Write([]byte(ofx))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.ParseCSV().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("parse: unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp parseResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	submit := func(rows []csvimport.ParsedRow, parsed parseResponse) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, _ := json.Marshal(map[string]any{
+			"accountId": accID, "rows": rows,
+			"fileName": parsed.FileName, "fileHash": parsed.FileHash, "format": parsed.Format, "totalRows": len(parsed.Rows),
+		})
+		req := httptest.NewRequest(http.MethodPost, "/import/submit", bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(req.Context(), sessionauth.SessUserDataCtxKey, sessionauth.UserData{UserId: "demo"}))
+		rec := httptest.NewRecorder()
+		h.SubmitImport().ServeHTTP(rec, req)
+		return rec
+	}
+	countTxs := func() int {
+		t.Helper()
+		txs, _, err := store.ListTransactions(ctx, accounting.ListOpts{
+			StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+			AccountId: []int{int(accID)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(txs)
+	}
+	listBatches := func() []importBatchPayload {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ListImportBatches().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/import/batches?accountId="+strconv.Itoa(int(accID)), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list: unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var items []importBatchPayload
+		if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+	rollback := func(id uint) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.RollbackImportBatch(id).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/batches/x/rollback", nil))
+		return rec
+	}
+
+	parsed := parse()
+	if parsed.FileName != "march.ofx" || parsed.FileHash != csvimport.FileHash([]byte(ofx)) || len(parsed.PreviousImports) != 0 {
+		t.Fatalf("unexpected parse response %+v", parsed)
+	}
+	rec := submit(parsed.Rows, parsed)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var submitResp struct {
+		Created int  `json:"created"`
+		BatchID uint `json:"batchId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &submitResp); err != nil {
+		t.Fatal(err)
+	}
+	if submitResp.Created != 2 || submitResp.BatchID == 0 {
+		t.Fatalf("unexpected submit response %s", rec.Body.String())
+	}
+	if n := countTxs(); n != 3 {
+		t.Fatalf("expected 3 transactions after the import, got %d", n)
+	}
+
+	batches := listBatches()
+	if len(batches) != 1 {
+		t.Fatalf("expected one batch, got %+v", batches)
+	}
+	b := batches[0]
+	if b.ID != submitResp.BatchID || b.FileName != "march.ofx" || b.Format != csvimport.FormatOFX || b.User != "demo" ||
+		b.TotalRows != 2 || b.ImportedRows != 2 || b.RolledBackAt != nil {
+		t.Errorf("unexpected batch %+v", b)
+	}
+
+	// uploading the same file again warns about the earlier import
+	again := parse()
+	if len(again.PreviousImports) != 1 || again.PreviousImports[0].ID != submitResp.BatchID {
+		t.Errorf("expected the earlier import to be reported, got %+v", again.PreviousImports)
+	}
+
+	// a receipt attached after the import is released with its transaction
+	batch, err := csvStore.GetImportBatch(ctx, submitResp.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptID, err := fileStore.SaveRaw(ctx, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), []byte("%PDF-1.4 receipt"), "receipt.pdf", "application/pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddTransactionAttachment(ctx, batch.TransactionIDs[1], receiptID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := rollback(submitResp.BatchID); rec.Code != http.StatusOK || rec.Body.String() != `{"deleted":2}` {
+		t.Fatalf("rollback: unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := countTxs(); n != 1 {
+		t.Errorf("expected only the manual transaction to remain, got %d", n)
+	}
+	if _, err := fileStore.Get(ctx, receiptID); !errors.Is(err, filestore.ErrNotFound) {
+		t.Errorf("expected the receipt to be deleted with its transaction, got %v", err)
+	}
+	if batches := listBatches(); len(batches) != 1 || batches[0].RolledBackAt == nil {
+		t.Errorf("expected the batch to be marked as rolled back, got %+v", batches)
+	}
+	if again := parse(); len(again.PreviousImports) != 0 {
+		t.Errorf("rolled back imports should not be reported, got %+v", again.PreviousImports)
+	}
+	if rec := rollback(submitResp.BatchID); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 rolling back twice, got %d", rec.Code)
+	}
+	if rec := rollback(999); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown batch, got %d", rec.Code)
+	}
+
+	t.Run("failing row keeps the created ones in the batch", func(t *testing.T) {
+		rows := append([]csvimport.ParsedRow{}, parsed.Rows...)
+		rows[1].Date = "2026-13-40"
+		rec := submit(rows, parsed)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+		batches := listBatches()
+		if len(batches) != 2 || batches[0].ImportedRows != 1 {
+			t.Fatalf("expected a batch with the created row, got %+v", batches)
+		}
+		if rec := rollback(batches[0].ID); rec.Code != http.StatusOK {
+			t.Fatalf("rollback: unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if n := countTxs(); n != 1 {
+			t.Errorf("expected only the manual transaction to remain, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+const bundleFileName = "etna-import-rules.json"
+
+// uintListParam parses a comma-separated list of ids from a query parameter.
+func uintListParam(r *http.Request, key string) ([]uint, error) {
+	var ids []uint
+	for _, s := range strings.Split(r.URL.Query().Get(key), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// bundleNames returns the names the categories and accounts are exported as.
+func (h *ImportHandler) bundleNames(ctx context.Context) (csvimport.BundleNames, error) {
+	names := csvimport.BundleNames{Categories: map[uint]csvimport.CategoryRef{}, Accounts: map[uint]string{}}
+	for catType, typeName := range map[accounting.CategoryType]string{
+		accounting.IncomeCategory:  "income",
+		accounting.ExpenseCategory: "expense",
+	} {
+		paths, err := h.categoryNames(ctx, catType)
+		if err != nil {
+			return names, err
+		}
+		for id, path := range paths {
+			names.Categories[id] = csvimport.CategoryRef{Type: typeName, Path: path}
+		}
+	}
+	accounts, err := h.FinStore.ListAccounts(ctx)
+	if err != nil {
+		return names, err
+	}
+	for _, a := range accounts {
+		names.Accounts[a.ID] = a.Name
+	}
+	return names, nil
+}
+
+// ExportBundle downloads import profiles and category rule groups as a portable JSON bundle.
+// The profileIds and ruleGroupIds query parameters select them; without either, all are
+// exported.
+func (h *ImportHandler) ExportBundle() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		profileIDs, err := uintListParam(r, "profileIds")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		groupIDs, err := uintListParam(r, "ruleGroupIds")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(profileIDs) == 0 && len(groupIDs) == 0 {
+			profiles, err := h.CsvStore.ListProfiles(ctx)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, p := range profiles {
+				profileIDs = append(profileIDs, p.ID)
+			}
+			groups, err := h.CsvStore.ListCategoryRuleGroups(ctx)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, g := range groups {
+				groupIDs = append(groupIDs, g.ID)
+			}
+		}
+
+		names, err := h.bundleNames(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to load categories and accounts: %s", err), http.StatusInternalServerError)
+			return
+		}
+		bundle, err := h.CsvStore.ExportBundle(ctx, profileIDs, groupIDs, names)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bundleFileName))
+		writeJSON(w, bundle)
+	})
+}
+
+// ImportBundle imports a bundle written by ExportBundle. The strategy query parameter, skip
+// (default) or merge, handles profiles and rule groups named like existing ones; with dryRun=true
+// the result is reported without changing anything.
+func (h *ImportHandler) ImportBundle() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := r.URL.Query()
+		dryRun := q.Get("dryRun") == "true"
+
+		var bundle csvimport.Bundle
+		if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode bundle: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		targets := csvimport.BundleTargets{Accounts: map[string]uint{}}
+		var err error
+		targets.Categories, err = h.loadCategoryPaths(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to load categories: %s", err), http.StatusInternalServerError)
+			return
+		}
+		accounts, err := h.FinStore.ListAccounts(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to load accounts: %s", err), http.StatusInternalServerError)
+			return
+		}
+		for _, a := range accounts {
+			targets.Accounts[strings.ToLower(strings.TrimSpace(a.Name))] = a.ID
+		}
+
+		res, err := h.CsvStore.ImportBundle(ctx, bundle, targets, q.Get("strategy"), dryRun)
+		if err != nil {
+			var valErr csvimport.ErrValidation
+			if errors.As(err, &valErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, res)
+	})
+}
@@ -0,0 +1,166 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newBundleInstance(t *testing.T, name string) (*ImportHandler, *accounting.Store) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ImportHandler{CsvStore: csvStore, FinStore: store, InstrumentStore: mktStore}, store
+}
+
+func TestBundleExportImport(t *testing.T) {
+	ctx := context.Background()
+	src, srcStore := newBundleInstance(t, "bundleSource")
+	providerID, err := srcStore.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checking, err := srcStore.CreateAccount(ctx, accounting.Account{Name: "Checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, err := srcStore.CreateCategory(ctx, accounting.CategoryData{Name: "Food", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceries, err := srcStore.CreateCategory(ctx, accounting.CategoryData{Name: "Groceries", Type: accounting.ExpenseCategory}, food)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hobbies, err := srcStore.CreateCategory(ctx, accounting.CategoryData{Name: "Hobbies", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := src.CsvStore.CreateProfile(ctx, csvimport.ImportProfile{
+		Name: "bank", CsvSeparator: ";", DateColumn: "Date", DateFormat: "02.01.2006",
+		DescriptionColumn: "Description", AmountColumn: "Amount",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []csvimport.CategoryRuleGroup{
+		{Name: "Groceries", CategoryID: groceries, Priority: 1, AccountID: checking, ProfileID: profileID,
+			Patterns: []csvimport.CategoryRulePattern{{Pattern: "migros"}}},
+		{Name: "Hobbies", CategoryID: hobbies, Priority: 2, Patterns: []csvimport.CategoryRulePattern{{Pattern: "climbing"}}},
+	} {
+		if _, err := src.CsvStore.CreateCategoryRuleGroup(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	src.ExportBundle().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/import/bundle", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	exported := rec.Body.Bytes()
+	var bundle csvimport.Bundle
+	if err := json.Unmarshal(exported, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Profiles) != 1 || len(bundle.RuleGroups) != 2 {
+		t.Fatalf("expected all profiles and rule groups exported, got %+v", bundle)
+	}
+	if ref := bundle.RuleGroups[0].Category; ref == nil || !slices.Equal(ref.Path, []string{"Food", "Groceries"}) || ref.Type != "expense" {
+		t.Errorf("expected the category referenced by its path, got %+v", ref)
+	}
+
+	// the other instance has the groceries category and the account, created in another order
+	dst, dstStore := newBundleInstance(t, "bundleTarget")
+	if _, err := dstStore.CreateCategory(ctx, accounting.CategoryData{Name: "Salary", Type: accounting.IncomeCategory}, 0); err != nil {
+		t.Fatal(err)
+	}
+	dstFood, err := dstStore.CreateCategory(ctx, accounting.CategoryData{Name: "food", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstGroceries, err := dstStore.CreateCategory(ctx, accounting.CategoryData{Name: "Groceries", Type: accounting.ExpenseCategory}, dstFood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstProvider, err := dstStore.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dstStore.CreateAccount(ctx, accounting.Account{Name: "Savings", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: dstProvider}); err != nil {
+		t.Fatal(err)
+	}
+	dstChecking, err := dstStore.CreateAccount(ctx, accounting.Account{Name: "Checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: dstProvider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importBundle := func(query string) csvimport.BundleImportResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		dst.ImportBundle().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/bundle?"+query, bytes.NewReader(exported)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("import: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var res csvimport.BundleImportResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := importBundle("dryRun=true")
+	if !res.DryRun || !slices.Equal(res.UnmatchedCategories, []string{"expense Hobbies"}) {
+		t.Errorf("expected the dry run to report the hobbies category, got %+v", res)
+	}
+	if groups, _ := dst.CsvStore.ListCategoryRuleGroups(ctx); len(groups) != 0 {
+		t.Errorf("expected the dry run to create nothing, got %d groups", len(groups))
+	}
+
+	res = importBundle("strategy=skip")
+	if res.Profiles[0].Action != csvimport.BundleCreated || res.RuleGroups[0].Action != csvimport.BundleCreated ||
+		res.RuleGroups[1].Action != csvimport.BundleSkipped {
+		t.Errorf("unexpected import result: %+v", res)
+	}
+	groups, err := dst.CsvStore.ListCategoryRuleGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].CategoryID != dstGroceries || groups[0].AccountID != dstChecking {
+		t.Errorf("expected the groceries group with the ids of this instance, got %+v", groups)
+	}
+
+	rec = httptest.NewRecorder()
+	dst.ImportBundle().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/bundle?strategy=replace", bytes.NewReader(exported)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown strategy, got %d", rec.Code)
+	}
+}
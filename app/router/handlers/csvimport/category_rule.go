@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/andresbott/etna/internal/csvimport"
 )
@@ -19,6 +20,22 @@ type ruleGroupPayload struct {
 	CategoryID uint                 `json:"categoryId"`
 	Priority   int                  `json:"priority"`
 	Patterns   []rulePatternPayload `json:"patterns"`
+
+	// conditions
+	MatchAny  bool     `json:"matchAny,omitempty"`
+	AmountMin *float64 `json:"amountMin,omitempty"`
+	AmountMax *float64 `json:"amountMax,omitempty"`
+	Direction string   `json:"direction,omitempty"`
+	AccountID uint     `json:"accountId,omitempty"`
+	ProfileID uint     `json:"profileId,omitempty"`
+	DateFrom  string   `json:"dateFrom,omitempty"` // YYYY-MM-DD
+	DateTo    string   `json:"dateTo,omitempty"`
+	Weekdays  []int    `json:"weekdays,omitempty"` // 0 is Sunday
+
+	// actions besides setting the category
+	SetDescription    string `json:"setDescription,omitempty"`
+	AppendNotes       string `json:"appendNotes,omitempty"`
+	TransferAccountID uint   `json:"transferAccountId,omitempty"`
 }
 
 type rulePatternPayload struct {
@@ -27,6 +44,83 @@ type rulePatternPayload struct {
 	IsRegex bool   `json:"isRegex"`
 }
 
+func groupToPayload(g csvimport.CategoryRuleGroup) ruleGroupPayload {
+	payload := ruleGroupPayload{
+		ID:                g.ID,
+		Name:              g.Name,
+		CategoryID:        g.CategoryID,
+		Priority:          g.Priority,
+		Patterns:          make([]rulePatternPayload, len(g.Patterns)),
+		MatchAny:          g.MatchAny,
+		AmountMin:         g.AmountMin,
+		AmountMax:         g.AmountMax,
+		Direction:         g.Direction,
+		AccountID:         g.AccountID,
+		ProfileID:         g.ProfileID,
+		SetDescription:    g.SetDescription,
+		AppendNotes:       g.AppendNotes,
+		TransferAccountID: g.TransferAccountID,
+	}
+	for j, p := range g.Patterns {
+		payload.Patterns[j] = rulePatternPayload{
+			ID:      p.ID,
+			Pattern: p.Pattern,
+			IsRegex: p.IsRegex,
+		}
+	}
+	if g.DateFrom != nil {
+		payload.DateFrom = g.DateFrom.Format("2006-01-02")
+	}
+	if g.DateTo != nil {
+		payload.DateTo = g.DateTo.Format("2006-01-02")
+	}
+	for _, d := range g.Weekdays {
+		payload.Weekdays = append(payload.Weekdays, int(d))
+	}
+	return payload
+}
+
+// payloadToGroup converts a request payload, patterns are only used on create.
+func payloadToGroup(payload ruleGroupPayload) (csvimport.CategoryRuleGroup, error) {
+	group := csvimport.CategoryRuleGroup{
+		Name:              payload.Name,
+		CategoryID:        payload.CategoryID,
+		Priority:          payload.Priority,
+		MatchAny:          payload.MatchAny,
+		AmountMin:         payload.AmountMin,
+		AmountMax:         payload.AmountMax,
+		Direction:         payload.Direction,
+		AccountID:         payload.AccountID,
+		ProfileID:         payload.ProfileID,
+		SetDescription:    payload.SetDescription,
+		AppendNotes:       payload.AppendNotes,
+		TransferAccountID: payload.TransferAccountID,
+	}
+	for _, p := range payload.Patterns {
+		group.Patterns = append(group.Patterns, csvimport.CategoryRulePattern{
+			Pattern: p.Pattern,
+			IsRegex: p.IsRegex,
+		})
+	}
+	for _, d := range []struct {
+		value string
+		dst   **time.Time
+	}{{payload.DateFrom, &group.DateFrom}, {payload.DateTo, &group.DateTo}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return group, csvimport.ErrValidation(fmt.Sprintf("invalid date %q", d.value))
+		}
+		*d.dst = &t
+	}
+	for _, d := range payload.Weekdays {
+		group.Weekdays = append(group.Weekdays, time.Weekday(d))
+	}
+	return group, nil
+}
+
 func (h *CategoryRuleGroupHandler) ListCategoryRuleGroups() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		groups, err := h.Store.ListCategoryRuleGroups(r.Context())
@@ -37,21 +131,7 @@ func (h *CategoryRuleGroupHandler) ListCategoryRuleGroups() http.Handler {
 
 		items := make([]ruleGroupPayload, len(groups))
 		for i, g := range groups {
-			patterns := make([]rulePatternPayload, len(g.Patterns))
-			for j, p := range g.Patterns {
-				patterns[j] = rulePatternPayload{
-					ID:      p.ID,
-					Pattern: p.Pattern,
-					IsRegex: p.IsRegex,
-				}
-			}
-			items[i] = ruleGroupPayload{
-				ID:         g.ID,
-				Name:       g.Name,
-				CategoryID: g.CategoryID,
-				Priority:   g.Priority,
-				Patterns:   patterns,
-			}
+			items[i] = groupToPayload(g)
 		}
 
 		respJSON, err := json.Marshal(items)
@@ -78,16 +158,10 @@ func (h *CategoryRuleGroupHandler) CreateCategoryRuleGroup() http.Handler {
 			return
 		}
 
-		group := csvimport.CategoryRuleGroup{
-			Name:       payload.Name,
-			CategoryID: payload.CategoryID,
-			Priority:   payload.Priority,
-		}
-		for _, p := range payload.Patterns {
-			group.Patterns = append(group.Patterns, csvimport.CategoryRulePattern{
-				Pattern: p.Pattern,
-				IsRegex: p.IsRegex,
-			})
+		group, err := payloadToGroup(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		id, err := h.Store.CreateCategoryRuleGroup(r.Context(), group)
@@ -107,19 +181,7 @@ func (h *CategoryRuleGroupHandler) CreateCategoryRuleGroup() http.Handler {
 			return
 		}
 
-		respPayload := ruleGroupPayload{
-			ID:         created.ID,
-			Name:       created.Name,
-			CategoryID: created.CategoryID,
-			Priority:   created.Priority,
-		}
-		for _, p := range created.Patterns {
-			respPayload.Patterns = append(respPayload.Patterns, rulePatternPayload{
-				ID:      p.ID,
-				Pattern: p.Pattern,
-				IsRegex: p.IsRegex,
-			})
-		}
+		respPayload := groupToPayload(created)
 
 		respJSON, err := json.Marshal(respPayload)
 		if err != nil {
@@ -145,13 +207,13 @@ func (h *CategoryRuleGroupHandler) UpdateCategoryRuleGroup(id uint) http.Handler
 			return
 		}
 
-		group := csvimport.CategoryRuleGroup{
-			Name:       payload.Name,
-			CategoryID: payload.CategoryID,
-			Priority:   payload.Priority,
+		group, err := payloadToGroup(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		err := h.Store.UpdateCategoryRuleGroup(r.Context(), id, group)
+		err = h.Store.UpdateCategoryRuleGroup(r.Context(), id, group)
 		if err != nil {
 			if errors.As(err, &validationErr) {
 				http.Error(w, err.Error(), http.StatusBadRequest)
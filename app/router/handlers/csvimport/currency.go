@@ -0,0 +1,46 @@
+package csvimport
+
+import (
+	"context"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+// currencyConversion converts the rows of foreign currencies into the currency of the account.
+func (h *ImportHandler) currencyConversion(ctx context.Context, account accounting.Account) csvimport.ParseOption {
+	accountCurrency := account.Currency.String()
+	return csvimport.WithCurrencyConversion(accountCurrency, h.rateFunc(ctx, accountCurrency))
+}
+
+// rateFunc returns the exchange rates of the stored FX series into the account currency. The
+// series quote the other currencies against the main currency, e.g. "CHF/USD" is the USD per
+// CHF, so rates between two other currencies are crossed over the main currency.
+func (h *ImportHandler) rateFunc(ctx context.Context, accountCurrency string) csvimport.RateFunc {
+	return func(currency string, date time.Time) (float64, bool) {
+		if h.InstrumentStore == nil || h.MainCurrency == "" {
+			return 0, false
+		}
+		// rate of one unit of the main currency in the given currency
+		perMain := func(c string) (float64, bool) {
+			if c == h.MainCurrency {
+				return 1, true
+			}
+			rec, err := h.InstrumentStore.RateAt(ctx, h.MainCurrency, c, date.UTC())
+			if err != nil || rec == nil || rec.Rate <= 0 {
+				return 0, false
+			}
+			return rec.Rate, true
+		}
+		from, ok := perMain(currency)
+		if !ok {
+			return 0, false
+		}
+		to, ok := perMain(accountCurrency)
+		if !ok {
+			return 0, false
+		}
+		return to / from, true
+	}
+}
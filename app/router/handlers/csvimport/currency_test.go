@@ -0,0 +1,69 @@
+package csvimport
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRateFunc(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importRateFunc?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	// 1 CHF = 1.10 USD = 1.05 EUR
+	for secondary, rate := range map[string]float64{"USD": 1.10, "EUR": 1.05} {
+		if err := mktStore.RegisterPair(ctx, "CHF", secondary); err != nil {
+			t.Fatal(err)
+		}
+		if err := mktStore.IngestRate(ctx, "CHF", secondary, day, rate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &ImportHandler{InstrumentStore: mktStore, MainCurrency: "CHF"}
+	tests := []struct {
+		name     string
+		account  string
+		currency string
+		date     time.Time
+		want     float64
+		wantOK   bool
+	}{
+		{name: "into the main currency", account: "CHF", currency: "USD", date: day, want: 1 / 1.10, wantOK: true},
+		{name: "from the main currency", account: "USD", currency: "CHF", date: day, want: 1.10, wantOK: true},
+		{name: "crossed over the main currency", account: "EUR", currency: "USD", date: day, want: 1.05 / 1.10, wantOK: true},
+		{name: "later date uses the last rate", account: "CHF", currency: "USD", date: day.AddDate(0, 0, 3), want: 1 / 1.10, wantOK: true},
+		{name: "before the first rate", account: "CHF", currency: "USD", date: day.AddDate(0, 0, -1)},
+		{name: "unknown currency", account: "CHF", currency: "GBP", date: day},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := h.rateFunc(ctx, tc.account)(tc.currency, tc.date)
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tc.wantOK, ok)
+			}
+			if math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("expected rate %f, got %f", tc.want, got)
+			}
+		})
+	}
+
+	if _, ok := (&ImportHandler{}).rateFunc(ctx, "CHF")("USD", day); ok {
+		t.Error("expected no rate without an instrument store")
+	}
+}
@@ -0,0 +1,78 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+// rankProfiles ranks the import profiles by how well the uploaded file matches them, taking the
+// profiles linked to the accounts into account.
+func (h *ImportHandler) rankProfiles(ctx context.Context, data []byte, format string, account accounting.Account) ([]csvimport.ProfileMatch, error) {
+	profiles, err := h.CsvStore.ListProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := h.FinStore.ListAccountsMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	links := csvimport.ProfileLinks{AccountProfileID: account.ImportProfileID, LinkedAccounts: map[uint]int{}}
+	for _, acc := range accounts {
+		if acc.ImportProfileID != 0 {
+			links.LinkedAccounts[acc.ImportProfileID]++
+		}
+	}
+	return csvimport.RankProfiles(data, format, profiles, links), nil
+}
+
+// selectProfile returns the profile chosen by the user, or else the best match when it is
+// confident. It returns false when no profile fits the file.
+func (h *ImportHandler) selectProfile(ctx context.Context, profileID uint, matches []csvimport.ProfileMatch) (csvimport.ImportProfile, bool, error) {
+	if profileID == 0 {
+		if len(matches) == 0 || !matches[0].Confident {
+			return csvimport.ImportProfile{}, false, nil
+		}
+		profileID = matches[0].ProfileID
+	}
+	profile, err := h.CsvStore.GetProfile(ctx, profileID)
+	if err != nil {
+		return csvimport.ImportProfile{}, false, err
+	}
+	return profile, true, nil
+}
+
+// autoDetectPreview previews a file no profile fits, detecting its settings and columns.
+func autoDetectPreview(data []byte, format string) (csvimport.PreviewResult, error) {
+	if format == csvimport.FormatXLSX {
+		return csvimport.ParsePreviewXLSX(data, csvimport.ImportProfile{})
+	}
+	return csvimport.ParsePreviewWithAutoDetect(data, csvimport.ImportProfile{})
+}
+
+// learnHeaderRow stores the header of the file on a profile that has none yet, so the next
+// uploads are recognized by their header.
+func (h *ImportHandler) learnHeaderRow(ctx context.Context, data []byte, format string, profile csvimport.ImportProfile) error {
+	if len(profile.HeaderRow) > 0 {
+		return nil
+	}
+	headers, err := csvimport.ReadHeader(data, format, profile)
+	if err != nil {
+		return err
+	}
+	return h.CsvStore.SetProfileHeaderRow(ctx, profile.ID, headers)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	respJSON, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(respJSON)
+}
@@ -0,0 +1,143 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestProfileDetection(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:profileDetection?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := csvStore.CreateProfile(ctx, csvimport.ImportProfile{
+		Name: "bank", CsvSeparator: ";", DateColumn: "Date", DateFormat: "02.01.2006",
+		DescriptionColumn: "Description", AmountColumn: "Amount",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the account is not linked to the profile, the file has to be recognized
+	accID, err := store.CreateAccount(ctx, accounting.Account{Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const bankCSV = "Date;Description;Amount\n05.03.2026;Coffee;-4.50\n06.03.2026;Salary;3000.00\n"
+	const unknownCSV = "Booked,Merchant,Value\n2026-03-05,Coffee,-4.50\n2026-03-06,Salary,3000.00\n"
+
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store}
+	type parseResponse struct {
+		Rows           []csvimport.ParsedRow    `json:"rows"`
+		ProfileID      uint                     `json:"profileId"`
+		ProfileMatches []csvimport.ProfileMatch `json:"profileMatches"`
+		Preview        *csvimport.PreviewResult `json:"preview"`
+	}
+	parse := func(content, profileID string) (parseResponse, *httptest.ResponseRecorder) {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("accountId", strconv.Itoa(int(accID)))
+		if profileID != "" {
+			_ = mw.WriteField("profileId", profileID)
+		}
+		fw, _ := mw.CreateFormFile("file", "statement.csv")
+		_, _ = fw.Write([]byte(content))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.ParseCSV().ServeHTTP(rec, req)
+		var resp parseResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp, rec
+	}
+
+	t.Run("confident match is selected", func(t *testing.T) {
+		resp, rec := parse(bankCSV, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if resp.ProfileID != profileID || len(resp.Rows) != 2 || resp.Preview != nil {
+			t.Fatalf("expected the rows parsed with the bank profile, got %+v", resp)
+		}
+		if len(resp.ProfileMatches) != 1 || !resp.ProfileMatches[0].Confident {
+			t.Errorf("expected a confident match, got %+v", resp.ProfileMatches)
+		}
+		// the header row is learned from the first import
+		p, err := csvStore.GetProfile(ctx, profileID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(p.HeaderRow, []string{"Date", "Description", "Amount"}) {
+			t.Errorf("expected the header row to be stored, got %v", p.HeaderRow)
+		}
+	})
+
+	t.Run("unknown file falls back to auto detection", func(t *testing.T) {
+		resp, rec := parse(unknownCSV, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if resp.Rows != nil || resp.ProfileID != 0 {
+			t.Errorf("expected no rows without a profile, got %+v", resp)
+		}
+		if resp.Preview == nil || resp.Preview.DetectedColumns == nil || resp.Preview.DetectedColumns.DateColumn != "Booked" {
+			t.Fatalf("expected a preview with the detected columns, got %+v", resp.Preview)
+		}
+		if len(resp.ProfileMatches) != 1 || resp.ProfileMatches[0].Confident {
+			t.Errorf("expected the bank profile not to be a confident match, got %+v", resp.ProfileMatches)
+		}
+	})
+
+	t.Run("chosen profile", func(t *testing.T) {
+		resp, rec := parse(bankCSV, strconv.Itoa(int(profileID)))
+		if rec.Code != http.StatusOK || resp.ProfileID != profileID || len(resp.Rows) != 2 {
+			t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, rec := parse(bankCSV, "abc"); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an invalid profileId, got %d", rec.Code)
+		}
+		if _, rec := parse(bankCSV, "999"); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown profile, got %d", rec.Code)
+		}
+	})
+}
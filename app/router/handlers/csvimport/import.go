@@ -1,21 +1,34 @@
 package csvimport
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/andresbott/etna/internal/accounting"
 	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/filestore"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/andresbott/etna/internal/marketdata/importer"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
 )
 
 type ImportHandler struct {
-	CsvStore *csvimport.Store
-	FinStore *accounting.Store
+	CsvStore        *csvimport.Store
+	FinStore        *accounting.Store
+	InstrumentStore *marketdata.Store        // optional, needed to import trades and to convert foreign currency rows
+	Reference       importer.ReferenceClient // optional, creates the instruments of imported trades
+	Classifier      *csvimport.Classifier    // optional, suggests categories learned from the transactions
+	FileStore       *filestore.Store         // optional, releases the attachments of rolled back transactions
+	MainCurrency    string                   // currency the stored FX rates are quoted against
 }
 
 func (h *ImportHandler) ParseCSV() http.Handler {
@@ -37,67 +50,190 @@ func (h *ImportHandler) ParseCSV() http.Handler {
 			http.Error(w, fmt.Sprintf("invalid accountId: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
-		accountID := uint(accountID64)
+		var profileID uint64
+		if v := r.FormValue("profileId"); v != "" {
+			profileID, err = strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid profileId: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
 
-		file, _, err := r.FormFile("file")
+		file, header, err := r.FormFile("file")
 		if err != nil {
 			http.Error(w, fmt.Sprintf("unable to get uploaded file: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
 		defer func() { _ = file.Close() }()
 
-		// Look up account to get ImportProfileID
-		account, err := h.FinStore.GetAccount(r.Context(), accountID)
+		data, err := io.ReadAll(file)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to get account: %s", err.Error()), http.StatusNotFound)
-			return
-		}
-		if account.ImportProfileID == 0 {
-			http.Error(w, "account has no import profile", http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("unable to read uploaded file: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
 
-		// Look up profile
-		profile, err := h.CsvStore.GetProfile(r.Context(), account.ImportProfileID)
+		resp, err := h.parseFile(r.Context(), parseRequest{
+			AccountID: uint(accountID64),
+			ProfileID: uint(profileID),
+			FileName:  header.Filename,
+			Data:      data,
+		})
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to get import profile: %s", err.Error()), http.StatusInternalServerError)
+			http.Error(w, err.Error(), errorStatus(err))
 			return
 		}
+		writeJSON(w, resp)
+	})
+}
+
+// statusError is an error of the import pipeline that is answered with the given status
+// instead of 500.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e statusError) Error() string { return e.err.Error() }
+func (e statusError) Unwrap() error { return e.err }
+
+// errorStatus returns the HTTP status of an error returned by the import pipeline.
+func errorStatus(err error) int {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.status
+	}
+	return http.StatusInternalServerError
+}
 
-		// Load category rule groups
-		groups, err := h.CsvStore.ListCategoryRuleGroups(r.Context())
+// parseRequest is a file to parse for an import into an account.
+type parseRequest struct {
+	AccountID uint
+	ProfileID uint // import profile chosen by the user, detected from the file when 0
+	FileName  string
+	Data      []byte
+}
+
+// parseResult is the response of a parsed file. Files no import profile fits have no rows but
+// the ranked profiles and a preview of the detected settings instead.
+type parseResult struct {
+	Format          string                   `json:"format"`
+	FileName        string                   `json:"fileName"`
+	FileHash        string                   `json:"fileHash"`
+	PreviousImports []importBatchPayload     `json:"previousImports,omitempty"`
+	ProfileID       uint                     `json:"profileId,omitempty"`
+	ProfileMatches  []csvimport.ProfileMatch `json:"profileMatches,omitempty"`
+	Preview         *csvimport.PreviewResult `json:"preview,omitempty"`
+	ProfileType     string                   `json:"profileType,omitempty"`
+	CashAccountID   uint                     `json:"cashAccountId,omitempty"`
+	Rows            []csvimport.ParsedRow    `json:"rows"`
+	PendingImportID uint                     `json:"pendingImportId,omitempty"`
+}
+
+// parseFile parses a statement or trade history file: it selects the import profile of CSV and
+// XLSX files, applies the category rules, flags duplicates and suggests categories.
+func (h *ImportHandler) parseFile(ctx context.Context, req parseRequest) (parseResult, error) {
+	format := csvimport.DetectFormat(req.Data)
+
+	// Look up the account; structured formats do not need an import profile
+	account, err := h.FinStore.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		return parseResult{}, statusError{status: http.StatusNotFound, err: fmt.Errorf("unable to get account: %w", err)}
+	}
+	needsProfile := format == csvimport.FormatCSV || format == csvimport.FormatXLSX
+
+	// Warn about files that were imported before, the rows would be flagged as duplicates anyway
+	res := parseResult{Format: format, FileName: req.FileName, FileHash: csvimport.FileHash(req.Data)}
+	previous, err := h.CsvStore.FindImportBatchesByHash(ctx, res.FileHash)
+	if err != nil {
+		return parseResult{}, fmt.Errorf("unable to look up previous imports: %w", err)
+	}
+	for _, b := range previous {
+		res.PreviousImports = append(res.PreviousImports, batchToPayload(b))
+	}
+
+	var profile csvimport.ImportProfile
+	if needsProfile {
+		res.ProfileMatches, err = h.rankProfiles(ctx, req.Data, format, account)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to list category rule groups: %s", err.Error()), http.StatusInternalServerError)
-			return
+			return parseResult{}, fmt.Errorf("unable to rank import profiles: %w", err)
 		}
 
-		// Load existing transactions for duplicate detection
-		existing, err := h.loadExistingTransactions(r, accountID)
+		var found bool
+		profile, found, err = h.selectProfile(ctx, req.ProfileID, res.ProfileMatches)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to load existing transactions: %s", err.Error()), http.StatusInternalServerError)
-			return
+			if errors.Is(err, csvimport.ErrProfileNotFound) {
+				return parseResult{}, statusError{status: http.StatusBadRequest, err: fmt.Errorf("unable to get import profile: %w", err)}
+			}
+			return parseResult{}, fmt.Errorf("unable to get import profile: %w", err)
 		}
+		if !found {
+			// no profile fits the file: return what was detected so the user can pick or create one
+			preview, err := autoDetectPreview(req.Data, format)
+			if err != nil {
+				return parseResult{}, statusError{status: http.StatusBadRequest, err: fmt.Errorf("unable to parse %s: %w", strings.ToUpper(format), err)}
+			}
+			res.Preview = &preview
+			return res, nil
+		}
+		res.ProfileID = profile.ID
+	}
 
-		// Parse CSV
-		rows, err := csvimport.Parse(file, profile, groups, existing)
+	// Load category rule groups
+	groups, err := h.CsvStore.ListCategoryRuleGroups(ctx)
+	if err != nil {
+		return parseResult{}, fmt.Errorf("unable to list category rule groups: %w", err)
+	}
+	groups = csvimport.ScopeRules(groups, req.AccountID, profile.ID)
+
+	// Load existing transactions for duplicate detection
+	existing, err := h.loadExistingTransactions(ctx, req.AccountID)
+	if err != nil {
+		return parseResult{}, fmt.Errorf("unable to load existing transactions: %w", err)
+	}
+
+	data := req.Data
+	switch {
+	case format == csvimport.FormatOFX:
+		res.Rows, err = csvimport.ParseOFX(bytes.NewReader(data), groups, existing)
+	case format == csvimport.FormatCamt:
+		res.Rows, err = csvimport.ParseCamt(bytes.NewReader(data), groups, existing)
+	case format == csvimport.FormatMT940:
+		res.Rows, err = csvimport.ParseMT940(bytes.NewReader(data), groups, existing)
+	case format == csvimport.FormatQIF:
+		var categories csvimport.CategoryPaths
+		categories, err = h.loadCategoryPaths(ctx)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to parse CSV: %s", err.Error()), http.StatusBadRequest)
-			return
+			return parseResult{}, fmt.Errorf("unable to list categories: %w", err)
 		}
+		res.Rows, err = csvimport.ParseQIF(bytes.NewReader(data), groups, existing, categories)
+	case profile.Type == csvimport.ProfileTypeInvestment:
+		res.Rows, err = h.parseTrades(ctx, data, format, profile, groups, existing)
+		res.ProfileType = profile.Type
+		res.CashAccountID = profile.CashAccountID
+	case format == csvimport.FormatXLSX:
+		res.Rows, err = csvimport.ParseXLSX(data, profile, groups, existing, h.currencyConversion(ctx, account))
+	default:
+		res.Rows, err = csvimport.Parse(bytes.NewReader(data), profile, groups, existing, h.currencyConversion(ctx, account))
+	}
+	if err != nil {
+		return parseResult{}, statusError{status: http.StatusBadRequest, err: fmt.Errorf("unable to parse %s: %w", strings.ToUpper(format), err)}
+	}
 
-		resp := map[string]any{"rows": rows}
-		respJSON, err := json.Marshal(resp)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	classifier, err := h.trainClassifier(ctx)
+	if err != nil {
+		return parseResult{}, fmt.Errorf("unable to train category suggestions: %w", err)
+	}
+	classifier.SuggestCategories(res.Rows, req.AccountID)
+
+	if needsProfile {
+		if err := h.learnHeaderRow(ctx, data, format, profile); err != nil {
+			return parseResult{}, fmt.Errorf("unable to store the header row of the import profile: %w", err)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(respJSON)
-	})
+	}
+	return res, nil
 }
 
-func (h *ImportHandler) loadExistingTransactions(r *http.Request, accountID uint) ([]csvimport.ExistingTx, error) {
+func (h *ImportHandler) loadExistingTransactions(ctx context.Context, accountID uint) ([]csvimport.ExistingTx, error) {
 	if accountID > uint(math.MaxInt) {
 		return nil, fmt.Errorf("accountID %d overflows int", accountID)
 	}
@@ -114,7 +250,7 @@ func (h *ImportHandler) loadExistingTransactions(r *http.Request, accountID uint
 			Page:      page,
 		}
 
-		txs, _, err := h.FinStore.ListTransactions(r.Context(), opts)
+		txs, _, err := h.FinStore.ListTransactions(ctx, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -126,26 +262,48 @@ func (h *ImportHandler) loadExistingTransactions(r *http.Request, accountID uint
 			switch item := tx.(type) {
 			case accounting.Income:
 				existing = append(existing, csvimport.ExistingTx{
-					Date:   item.Date.Format("2006-01-02"),
-					Amount: item.Amount,
+					Date:       item.Date.Format("2006-01-02"),
+					Amount:     item.Amount,
+					ExternalID: item.ExternalID,
 				})
 			case accounting.Expense:
 				existing = append(existing, csvimport.ExistingTx{
-					Date:   item.Date.Format("2006-01-02"),
-					Amount: -item.Amount, // CSV parser uses negative for expenses
+					Date:       item.Date.Format("2006-01-02"),
+					Amount:     -item.Amount, // CSV parser uses negative for expenses
+					ExternalID: item.ExternalID,
+				})
+			case accounting.StockBuy:
+				existing = append(existing, csvimport.ExistingTx{
+					Date:       item.Date.Format("2006-01-02"),
+					Amount:     -item.TotalAmount,
+					ExternalID: item.ExternalID,
+				})
+			case accounting.StockSell:
+				existing = append(existing, csvimport.ExistingTx{
+					Date:       item.Date.Format("2006-01-02"),
+					Amount:     item.TotalAmount - item.Fees,
+					ExternalID: item.ExternalID,
+				})
+			case accounting.BalanceStatus:
+				existing = append(existing, csvimport.ExistingTx{
+					Date:    item.Date.Format("2006-01-02"),
+					Amount:  item.Amount,
+					Balance: true,
 				})
 			case accounting.Transfer:
 				// For transfers, include both legs if they match the account
 				if item.OriginAccountID == accountID {
 					existing = append(existing, csvimport.ExistingTx{
-						Date:   item.Date.Format("2006-01-02"),
-						Amount: -item.OriginAmount,
+						Date:       item.Date.Format("2006-01-02"),
+						Amount:     -item.OriginAmount,
+						ExternalID: item.ExternalID,
 					})
 				}
 				if item.TargetAccountID == accountID {
 					existing = append(existing, csvimport.ExistingTx{
-						Date:   item.Date.Format("2006-01-02"),
-						Amount: item.TargetAmount,
+						Date:       item.Date.Format("2006-01-02"),
+						Amount:     item.TargetAmount,
+						ExternalID: item.ExternalID,
 					})
 				}
 			}
@@ -155,16 +313,82 @@ func (h *ImportHandler) loadExistingTransactions(r *http.Request, accountID uint
 }
 
 type submitRequest struct {
-	AccountID uint        `json:"accountId"`
-	Rows      []submitRow `json:"rows"`
+	AccountID         uint        `json:"accountId"`
+	CashAccountID     uint        `json:"cashAccountId"`     // cash leg of imported trades
+	CreateInstruments bool        `json:"createInstruments"` // create unknown trade symbols from the reference provider
+	Rows              []submitRow `json:"rows"`
+
+	// source file, recorded on the import batch
+	FileName  string `json:"fileName"`
+	FileHash  string `json:"fileHash"`
+	Format    string `json:"format"`
+	TotalRows int    `json:"totalRows"`
+	ProfileID uint   `json:"profileId"` // profile the file was parsed with, defaults to the account's
+
+	PendingImportID uint `json:"pendingImportId"` // pending inbox file the rows come from, removed once submitted
 }
 
 type submitRow struct {
-	Date        string  `json:"date"`
-	Description string  `json:"description"`
-	Amount      float64 `json:"amount"`
-	Type        string  `json:"type"`
-	CategoryID  uint    `json:"categoryId"`
+	Date              string  `json:"date"`
+	Description       string  `json:"description"`
+	Notes             string  `json:"notes"`
+	Amount            float64 `json:"amount"`
+	Type              string  `json:"type"`
+	CategoryID        uint    `json:"categoryId"`
+	ExternalID        string  `json:"externalId"`
+	TransferAccountID uint    `json:"transferAccountId"` // counter account of transfer rows
+
+	// trades only
+	Symbol       string  `json:"symbol"`
+	Quantity     float64 `json:"quantity"`
+	Price        float64 `json:"price"`
+	Fees         float64 `json:"fees"`
+	Currency     string  `json:"currency"`
+	InstrumentID uint    `json:"instrumentId"`
+}
+
+func parseSubmitDate(v string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, accounting.ErrValidation(fmt.Sprintf("invalid date %q: %s", v, err.Error()))
+	}
+	return date, nil
+}
+
+// transferTransaction builds the transfer of a row a category rule marked as transfer: money
+// leaves the imported account for negative amounts and arrives on it for positive ones.
+// Both accounts must share the currency, the statement does not tell the amount on the other side.
+func (h *ImportHandler) transferTransaction(ctx context.Context, row submitRow, date time.Time, accountID uint) (accounting.Transaction, error) {
+	if row.TransferAccountID == 0 {
+		return nil, accounting.ErrValidation("transfer rows need a transferAccountId")
+	}
+	account, err := h.FinStore.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := h.FinStore.GetAccount(ctx, row.TransferAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Currency != other.Currency {
+		return nil, accounting.ErrValidation(fmt.Sprintf("transfer account %q is in %s, not %s: transfers between currencies cannot be imported",
+			other.Name, other.Currency, account.Currency))
+	}
+	amount := math.Abs(row.Amount)
+	tx := accounting.Transfer{
+		Description:     row.Description,
+		Notes:           row.Notes,
+		OriginAmount:    amount,
+		OriginAccountID: row.TransferAccountID,
+		TargetAmount:    amount,
+		TargetAccountID: accountID,
+		Date:            date,
+		ExternalID:      row.ExternalID,
+	}
+	if row.Amount < 0 {
+		tx.OriginAccountID, tx.TargetAccountID = accountID, row.TransferAccountID
+	}
+	return tx, nil
 }
 
 func (h *ImportHandler) SubmitImport() http.Handler {
@@ -184,58 +408,136 @@ func (h *ImportHandler) SubmitImport() http.Handler {
 			http.Error(w, "accountId is required", http.StatusBadRequest)
 			return
 		}
+		user := ""
+		if userData, err := sessionauth.CtxGetUserData(r); err == nil {
+			user = userData.UserId
+		}
+		res, err := h.submit(r.Context(), req, user)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		writeJSON(w, res)
+	})
+}
 
-		created := 0
-		for _, row := range req.Rows {
-			date, err := time.Parse("2006-01-02", row.Date)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("invalid date %q: %s", row.Date, err.Error()), http.StatusBadRequest)
-				return
-			}
+// submitResult is the response of a submitted import.
+type submitResult struct {
+	Created int  `json:"created"`
+	BatchID uint `json:"batchId"`
+}
 
-			var tx accounting.Transaction
-			switch row.Type {
-			case "income":
-				tx = accounting.Income{
-					Description: row.Description,
-					Amount:      row.Amount,
-					AccountID:   req.AccountID,
-					CategoryID:  row.CategoryID,
-					Date:        date,
-				}
-			case "expense":
-				tx = accounting.Expense{
-					Description: row.Description,
-					Amount:      math.Abs(row.Amount),
-					AccountID:   req.AccountID,
-					CategoryID:  row.CategoryID,
-					Date:        date,
-				}
-			default:
-				http.Error(w, fmt.Sprintf("unsupported transaction type: %s", row.Type), http.StatusBadRequest)
-				return
+// submit creates the transactions of the rows and records them as an import batch by the given
+// user. Rows created before a failing one are recorded as well, so they can be rolled back.
+func (h *ImportHandler) submit(ctx context.Context, req submitRequest, user string) (submitResult, error) {
+	account, err := h.FinStore.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		return submitResult{}, statusError{status: http.StatusNotFound, err: fmt.Errorf("unable to get account: %w", err)}
+	}
+
+	ids, createErr := h.createRows(ctx, req)
+
+	res := submitResult{Created: len(ids)}
+	if len(ids) > 0 {
+		batch := csvimport.ImportBatch{
+			AccountID:      req.AccountID,
+			FileName:       req.FileName,
+			FileHash:       req.FileHash,
+			Format:         req.Format,
+			TotalRows:      req.TotalRows,
+			User:           user,
+			TransactionIDs: ids,
+		}
+		if batch.Format == "" || batch.Format == csvimport.FormatCSV || batch.Format == csvimport.FormatXLSX {
+			batch.ProfileID = req.ProfileID
+			if batch.ProfileID == 0 {
+				batch.ProfileID = account.ImportProfileID
 			}
+		}
+		res.BatchID, err = h.CsvStore.CreateImportBatch(ctx, batch)
+		if err != nil {
+			return submitResult{}, fmt.Errorf("error recording import batch: %w", err)
+		}
+	}
+	if createErr != nil {
+		if submitErrorStatus(createErr) == http.StatusBadRequest {
+			return submitResult{}, statusError{status: http.StatusBadRequest, err: createErr}
+		}
+		return submitResult{}, fmt.Errorf("error creating transaction: %w", createErr)
+	}
+	if req.PendingImportID != 0 {
+		err = h.CsvStore.DeletePendingImport(ctx, req.PendingImportID)
+		if err != nil && !errors.Is(err, csvimport.ErrPendingImportNotFound) {
+			return submitResult{}, fmt.Errorf("unable to delete pending import: %w", err)
+		}
+	}
+	return res, nil
+}
 
-			if _, err := h.FinStore.CreateTransaction(r.Context(), tx); err != nil {
-				var valErr accounting.ErrValidation
-				if errors.As(err, &valErr) {
-					http.Error(w, err.Error(), http.StatusBadRequest)
-					return
-				}
-				http.Error(w, fmt.Sprintf("error creating transaction: %s", err.Error()), http.StatusInternalServerError)
-				return
+// createRows creates the transactions of the submitted rows in order and returns the ids of the
+// ones created, also when a later row fails.
+func (h *ImportHandler) createRows(ctx context.Context, req submitRequest) ([]uint, error) {
+	cashAccountID, err := h.cashBookingAccount(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get account: %w", err)
+	}
+	instruments := &instrumentResolver{h: h, create: req.CreateInstruments}
+
+	ids := make([]uint, 0, len(req.Rows))
+	for _, row := range req.Rows {
+		date, err := parseSubmitDate(row.Date)
+		if err != nil {
+			return ids, err
+		}
+
+		var tx accounting.Transaction
+		switch row.Type {
+		case "buy", "sell":
+			tx, err = h.tradeTransaction(ctx, req, row, date, instruments)
+			if err != nil {
+				return ids, rowError{row: fmt.Sprintf("%s %s", row.Date, row.Symbol), err: err}
+			}
+		case "income":
+			tx = accounting.Income{
+				Description: row.Description,
+				Notes:       row.Notes,
+				Amount:      row.Amount,
+				AccountID:   cashAccountID,
+				CategoryID:  row.CategoryID,
+				Date:        date,
+				ExternalID:  row.ExternalID,
+			}
+		case "expense":
+			tx = accounting.Expense{
+				Description: row.Description,
+				Notes:       row.Notes,
+				Amount:      math.Abs(row.Amount),
+				AccountID:   cashAccountID,
+				CategoryID:  row.CategoryID,
+				Date:        date,
+				ExternalID:  row.ExternalID,
 			}
-			created++
+		case "transfer":
+			tx, err = h.transferTransaction(ctx, row, date, cashAccountID)
+			if err != nil {
+				return ids, err
+			}
+		case "balance":
+			tx = accounting.BalanceStatus{
+				Description: row.Description,
+				Amount:      row.Amount,
+				AccountID:   req.AccountID,
+				Date:        date,
+			}
+		default:
+			return ids, accounting.ErrValidation(fmt.Sprintf("unsupported transaction type: %s", row.Type))
 		}
 
-		resp := map[string]int{"created": created}
-		respJSON, err := json.Marshal(resp)
+		id, err := h.FinStore.CreateTransaction(ctx, tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return ids, err
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(respJSON)
-	})
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
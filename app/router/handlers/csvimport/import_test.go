@@ -1,13 +1,19 @@
 package csvimport
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
 	"github.com/andresbott/etna/internal/marketdata"
 	"github.com/glebarez/sqlite"
 	"golang.org/x/text/currency"
@@ -77,8 +83,7 @@ func TestLoadExistingTransactions_PaginatesAll(t *testing.T) {
 	}
 
 	h := &ImportHandler{FinStore: store}
-	req, _ := http.NewRequest("GET", "/", nil)
-	existing, err := h.loadExistingTransactions(req, accID)
+	existing, err := h.loadExistingTransactions(ctx, accID)
 	if err != nil {
 		t.Fatalf("loadExistingTransactions: %v", err)
 	}
@@ -87,3 +92,113 @@ func TestLoadExistingTransactions_PaginatesAll(t *testing.T) {
 		t.Errorf("expected %d existing transactions, got %d", totalTx, len(existing))
 	}
 }
+
+func TestImportOFX(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importOFX?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no import profile: OFX files do not need one
+	accID, err := store.CreateAccount(ctx, accounting.Account{Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ofx = `OFXHEADER:100
+DATA:OFXSGML
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>CHF<BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260301<TRNAMT>1500.00<FITID>A1<NAME>Salary</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260302<TRNAMT>-45.30<FITID>A2<NAME>Groceries</STMTTRN>
+</BANKTRANLIST><LEDGERBAL><BALAMT>1454.70<DTASOF>20260310</LEDGERBAL></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store}
+	parse := func() []csvimport.ParsedRow {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("accountId", strconv.Itoa(int(accID)))
+		fw, _ := mw.CreateFormFile("file", "statement.ofx")
+		_, _ = fw.Write([]byte(ofx))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.ParseCSV().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("parse: unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Rows   []csvimport.ParsedRow `json:"rows"`
+			Format string                `json:"format"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Format != csvimport.FormatOFX {
+			t.Errorf("expected format %q, got %q", csvimport.FormatOFX, resp.Format)
+		}
+		return resp.Rows
+	}
+
+	rows := parse()
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rows)
+	}
+	payload, _ := json.Marshal(map[string]any{"accountId": accID, "rows": rows})
+	rec := httptest.NewRecorder()
+	h.SubmitImport().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/submit", bytes.NewReader(payload)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	txs, _, err := store.ListTransactions(ctx, accounting.ListOpts{
+		StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		AccountId: []int{int(accID)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var balances int
+	for _, tx := range txs {
+		switch item := tx.(type) {
+		case accounting.Income:
+			if item.ExternalID != "A1" {
+				t.Errorf("expected the FITID to be stored, got %q", item.ExternalID)
+			}
+		case accounting.BalanceStatus:
+			balances++
+			if item.Amount != 1454.70 {
+				t.Errorf("unexpected balance %v", item.Amount)
+			}
+		}
+	}
+	if len(txs) != 3 || balances != 1 {
+		t.Errorf("expected 2 transactions and a balance status, got %+v", txs)
+	}
+
+	// importing the same file again flags everything as duplicate
+	for _, row := range parse() {
+		if !row.IsDuplicate {
+			t.Errorf("expected row %d to be a duplicate", row.RowNumber)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+// InboxUser is the user recorded on the import batches submitted from an inbox.
+const InboxUser = "inbox"
+
+type inboxPayload struct {
+	ID         uint   `json:"id"`
+	AccountID  uint   `json:"accountId"`
+	ProfileID  uint   `json:"profileId"`
+	Directory  string `json:"directory"`
+	AutoSubmit bool   `json:"autoSubmit"`
+}
+
+func inboxToPayload(in csvimport.ImportInbox) inboxPayload {
+	return inboxPayload{
+		ID:         in.ID,
+		AccountID:  in.AccountID,
+		ProfileID:  in.ProfileID,
+		Directory:  in.Directory,
+		AutoSubmit: in.AutoSubmit,
+	}
+}
+
+func payloadToInbox(p inboxPayload) csvimport.ImportInbox {
+	return csvimport.ImportInbox{
+		AccountID:  p.AccountID,
+		ProfileID:  p.ProfileID,
+		Directory:  p.Directory,
+		AutoSubmit: p.AutoSubmit,
+	}
+}
+
+// accountIDParam returns the optional accountId query parameter, 0 when not set.
+func accountIDParam(r *http.Request) (uint, error) {
+	v := r.URL.Query().Get("accountId")
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid accountId: %w", err)
+	}
+	return uint(id), nil
+}
+
+// ListInboxes returns the import inboxes, optionally filtered by the accountId query parameter.
+func (h *ImportHandler) ListInboxes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountID, err := accountIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		inboxes, err := h.CsvStore.ListInboxes(r.Context(), accountID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list import inboxes: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		items := make([]inboxPayload, len(inboxes))
+		for i, in := range inboxes {
+			items[i] = inboxToPayload(in)
+		}
+		writeJSON(w, items)
+	})
+}
+
+// decodeInbox reads an inbox from the request body and checks that its account exists.
+func (h *ImportHandler) decodeInbox(r *http.Request) (inboxPayload, error) {
+	var payload inboxPayload
+	if r.Body == nil {
+		return payload, statusError{status: http.StatusBadRequest, err: errors.New("request had empty body")}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return payload, statusError{status: http.StatusBadRequest, err: fmt.Errorf("unable to decode json: %w", err)}
+	}
+	if payload.AccountID != 0 {
+		if _, err := h.FinStore.GetAccount(r.Context(), payload.AccountID); err != nil {
+			return payload, statusError{status: http.StatusBadRequest, err: fmt.Errorf("unable to get account: %w", err)}
+		}
+	}
+	return payload, nil
+}
+
+func (h *ImportHandler) CreateInbox() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := h.decodeInbox(r)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		id, err := h.CsvStore.CreateInbox(r.Context(), payloadToInbox(payload))
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to create import inbox: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		inbox, err := h.CsvStore.GetInbox(r.Context(), id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get import inbox: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, inboxToPayload(inbox))
+	})
+}
+
+func (h *ImportHandler) UpdateInbox(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := h.decodeInbox(r)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		err = h.CsvStore.UpdateInbox(r.Context(), id, payloadToInbox(payload))
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, csvimport.ErrInboxNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to update import inbox: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *ImportHandler) DeleteInbox(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.CsvStore.DeleteInbox(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, csvimport.ErrInboxNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to delete import inbox: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+type pendingImportPayload struct {
+	ID        uint      `json:"id"`
+	InboxID   uint      `json:"inboxId,omitempty"`
+	AccountID uint      `json:"accountId"`
+	FileName  string    `json:"fileName"`
+	Format    string    `json:"format"`
+	TotalRows int       `json:"totalRows"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListPendingImports returns the inbox files awaiting review, optionally filtered by the
+// accountId query parameter.
+func (h *ImportHandler) ListPendingImports() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountID, err := accountIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pending, err := h.CsvStore.ListPendingImports(r.Context(), accountID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list pending imports: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		items := make([]pendingImportPayload, len(pending))
+		for i, p := range pending {
+			items[i] = pendingImportPayload{
+				ID:        p.ID,
+				InboxID:   p.InboxID,
+				AccountID: p.AccountID,
+				FileName:  p.FileName,
+				Format:    p.Format,
+				TotalRows: p.TotalRows,
+				Error:     p.Error,
+				CreatedAt: p.CreatedAt,
+			}
+		}
+		writeJSON(w, items)
+	})
+}
+
+// ParsePendingImport parses the file of a pending import like an upload, optionally with the
+// import profile given in the profileId of the JSON body. Submitting the rows with the returned
+// pendingImportId removes the pending import.
+func (h *ImportHandler) ParsePendingImport(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ProfileID uint `json:"profileId"`
+		}
+		if r.Body != nil && r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+
+		pending, err := h.CsvStore.GetPendingImport(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, csvimport.ErrPendingImportNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to get pending import: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := h.parseFile(r.Context(), parseRequest{
+			AccountID: pending.AccountID,
+			ProfileID: req.ProfileID,
+			FileName:  pending.FileName,
+			Data:      pending.Data,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		res.PendingImportID = pending.ID
+		writeJSON(w, res)
+	})
+}
+
+// DeletePendingImport discards a pending import without importing it.
+func (h *ImportHandler) DeletePendingImport(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.CsvStore.DeletePendingImport(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, csvimport.ErrPendingImportNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to delete pending import: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// InboxResult is the outcome of importing a file found in an inbox: either the transactions were
+// created, or the file is kept as a pending import.
+type InboxResult struct {
+	Created         int
+	BatchID         uint
+	PendingImportID uint
+	Reason          string // why the file awaits review, empty when the inbox does not auto-submit
+}
+
+// ImportInboxFile imports a file found in an inbox. With auto-submit, the new rows of files that
+// parse without errors are submitted; all other files, including the ones no profile fits, are
+// kept as pending imports to review in the UI. Only unexpected errors are returned, the file
+// should then stay in the inbox to be retried.
+func (h *ImportHandler) ImportInboxFile(ctx context.Context, inbox csvimport.ImportInbox, fileName string, data []byte) (InboxResult, error) {
+	parsed, err := h.parseFile(ctx, parseRequest{AccountID: inbox.AccountID, ProfileID: inbox.ProfileID, FileName: fileName, Data: data})
+	var reason string
+	switch {
+	case err != nil && errorStatus(err) == http.StatusBadRequest:
+		reason = err.Error()
+	case err != nil:
+		return InboxResult{}, err
+	case parsed.Preview != nil:
+		reason = "no import profile matches the file"
+	case inbox.AutoSubmit:
+		reason = autoSubmitBlocker(parsed.Rows)
+		if reason != "" {
+			break
+		}
+		res, err := h.submit(ctx, inboxSubmitRequest(inbox, parsed), InboxUser)
+		if err == nil {
+			return InboxResult{Created: res.Created, BatchID: res.BatchID}, nil
+		}
+		if errorStatus(err) != http.StatusBadRequest {
+			return InboxResult{}, err
+		}
+		reason = fmt.Sprintf("submit failed: %s", err.Error())
+	}
+
+	id, err := h.CsvStore.CreatePendingImport(ctx, csvimport.PendingImport{
+		InboxID:   inbox.ID,
+		AccountID: inbox.AccountID,
+		FileName:  fileName,
+		FileHash:  csvimport.FileHash(data),
+		Format:    csvimport.DetectFormat(data),
+		TotalRows: len(parsed.Rows),
+		Error:     reason,
+		Data:      data,
+	})
+	if err != nil {
+		return InboxResult{}, fmt.Errorf("unable to store pending import: %w", err)
+	}
+	return InboxResult{PendingImportID: id, Reason: reason}, nil
+}
+
+// autoSubmitBlocker returns why the rows of a file need a review before they are imported,
+// empty when they can be submitted as parsed.
+func autoSubmitBlocker(rows []csvimport.ParsedRow) string {
+	failed, unknown := 0, 0
+	for _, row := range rows {
+		switch {
+		case row.Error != "":
+			failed++
+		case (row.Type == "buy" || row.Type == "sell") && row.InstrumentID == 0 && !row.IsDuplicate:
+			unknown++
+		}
+	}
+	switch {
+	case failed > 0:
+		return fmt.Sprintf("%d row(s) failed to parse", failed)
+	case unknown > 0:
+		return fmt.Sprintf("%d trade(s) of unknown instruments", unknown)
+	}
+	return ""
+}
+
+// inboxSubmitRequest returns the submit of the new rows of a parsed inbox file.
+func inboxSubmitRequest(inbox csvimport.ImportInbox, parsed parseResult) submitRequest {
+	req := submitRequest{
+		AccountID:     inbox.AccountID,
+		CashAccountID: parsed.CashAccountID,
+		FileName:      parsed.FileName,
+		FileHash:      parsed.FileHash,
+		Format:        parsed.Format,
+		TotalRows:     len(parsed.Rows),
+		ProfileID:     parsed.ProfileID,
+	}
+	for _, row := range parsed.Rows {
+		if row.IsDuplicate {
+			continue
+		}
+		req.Rows = append(req.Rows, submitRow{
+			Date:              row.Date,
+			Description:       row.Description,
+			Notes:             row.Notes,
+			Amount:            row.Amount,
+			Type:              row.Type,
+			CategoryID:        row.CategoryID,
+			ExternalID:        row.ExternalID,
+			TransferAccountID: row.TransferAccountID,
+			Symbol:            row.Symbol,
+			Quantity:          row.Quantity,
+			Price:             row.Price,
+			Fees:              row.Fees,
+			Currency:          row.Currency,
+			InstrumentID:      row.InstrumentID,
+		})
+	}
+	return req
+}
@@ -0,0 +1,173 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestImportInboxFile(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importInboxFile?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := csvStore.CreateProfile(ctx, csvimport.ImportProfile{
+		Name: "bank", CsvSeparator: ";", DateColumn: "Date", DateFormat: "02.01.2006",
+		DescriptionColumn: "Description", AmountColumn: "Amount",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := store.CreateAccount(ctx, accounting.Account{Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store}
+	auto := csvimport.ImportInbox{ID: 1, AccountID: accID, ProfileID: profileID, Directory: "bank", AutoSubmit: true}
+	review := csvimport.ImportInbox{ID: 2, AccountID: accID, Directory: "review"}
+
+	t.Run("auto submit creates the transactions", func(t *testing.T) {
+		const file = "Date;Description;Amount\n05.03.2026;Coffee;-4.50\n06.03.2026;Salary;3000.00\n"
+		res, err := h.ImportInboxFile(ctx, auto, "march.csv", []byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Created != 2 || res.BatchID == 0 || res.PendingImportID != 0 {
+			t.Fatalf("expected 2 transactions in a batch, got %+v", res)
+		}
+		batch, err := csvStore.GetImportBatch(ctx, res.BatchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batch.User != InboxUser || batch.FileName != "march.csv" || batch.ProfileID != profileID {
+			t.Errorf("unexpected batch %+v", batch)
+		}
+
+		// the same file again only has duplicates
+		res, err = h.ImportInboxFile(ctx, auto, "march-copy.csv", []byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Created != 0 || res.PendingImportID != 0 {
+			t.Errorf("expected the duplicates to be skipped, got %+v", res)
+		}
+	})
+
+	t.Run("rows with errors await review", func(t *testing.T) {
+		const file = "Date;Description;Amount\n07.03.2026;Lunch;-12.00\nyesterday;Dinner;-30.00\n"
+		res, err := h.ImportInboxFile(ctx, auto, "broken.csv", []byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Created != 0 || res.PendingImportID == 0 || !strings.Contains(res.Reason, "failed to parse") {
+			t.Fatalf("expected a pending import, got %+v", res)
+		}
+		if err := csvStore.DeletePendingImport(ctx, res.PendingImportID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("unknown file awaits review", func(t *testing.T) {
+		const file = "Booked,Merchant,Value\n2026-03-05,Coffee,-4.50\n"
+		res, err := h.ImportInboxFile(ctx, review, "unknown.csv", []byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.PendingImportID == 0 || !strings.Contains(res.Reason, "no import profile") {
+			t.Fatalf("expected a pending import, got %+v", res)
+		}
+		if err := csvStore.DeletePendingImport(ctx, res.PendingImportID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("review and submit a pending import", func(t *testing.T) {
+		const file = "Date;Description;Amount\n08.03.2026;Groceries;-55.20\n"
+		res, err := h.ImportInboxFile(ctx, review, "groceries.csv", []byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.PendingImportID == 0 || res.Reason != "" {
+			t.Fatalf("expected a pending import without error, got %+v", res)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ListPendingImports().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/import/pending?accountId=%d", accID), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var pending []pendingImportPayload
+		if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].FileName != "groceries.csv" || pending[0].TotalRows != 1 {
+			t.Fatalf("unexpected pending imports %+v", pending)
+		}
+
+		rec = httptest.NewRecorder()
+		h.ParsePendingImport(res.PendingImportID).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/pending/1/parse", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var parsed parseResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		if parsed.PendingImportID != res.PendingImportID || parsed.ProfileID != profileID || len(parsed.Rows) != 1 {
+			t.Fatalf("unexpected parse result %+v", parsed)
+		}
+
+		submit := inboxSubmitRequest(review, parsed)
+		submit.PendingImportID = parsed.PendingImportID
+		payload, _ := json.Marshal(submit)
+		rec = httptest.NewRecorder()
+		h.SubmitImport().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/submit", bytes.NewReader(payload)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, err := csvStore.GetPendingImport(ctx, res.PendingImportID); err != csvimport.ErrPendingImportNotFound {
+			t.Errorf("expected the pending import to be removed, got %v", err)
+		}
+	})
+
+	t.Run("missing pending import", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ParsePendingImport(999).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/pending/999/parse", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/andresbott/etna/internal/csvimport"
 )
@@ -40,20 +41,39 @@ func (h *ImportHandler) PreviewCSV() http.Handler {
 		}
 
 		profile := csvimport.ImportProfile{
-			CsvSeparator:      r.FormValue("csvSeparator"),
-			SkipRows:          skipRows,
-			DateColumn:        r.FormValue("dateColumn"),
-			DateFormat:        r.FormValue("dateFormat"),
-			DescriptionColumn: r.FormValue("descriptionColumn"),
-			AmountMode:        r.FormValue("amountMode"),
-			AmountColumn:      r.FormValue("amountColumn"),
-			CreditColumn:      r.FormValue("creditColumn"),
-			DebitColumn:       r.FormValue("debitColumn"),
+			CsvSeparator:       r.FormValue("csvSeparator"),
+			SheetName:          r.FormValue("sheetName"),
+			SkipRows:           skipRows,
+			DateColumn:         r.FormValue("dateColumn"),
+			DateFormat:         r.FormValue("dateFormat"),
+			DescriptionColumn:  r.FormValue("descriptionColumn"),
+			AmountMode:         r.FormValue("amountMode"),
+			AmountColumn:       r.FormValue("amountColumn"),
+			CreditColumn:       r.FormValue("creditColumn"),
+			DebitColumn:        r.FormValue("debitColumn"),
+			Type:               r.FormValue("type"),
+			SymbolColumn:       r.FormValue("symbolColumn"),
+			SideColumn:         r.FormValue("sideColumn"),
+			QuantityColumn:     r.FormValue("quantityColumn"),
+			PriceColumn:        r.FormValue("priceColumn"),
+			FeesColumn:         r.FormValue("feesColumn"),
+			CurrencyColumn:     r.FormValue("currencyColumn"),
+			OrderIDColumn:      r.FormValue("orderIdColumn"),
+			DecimalSeparator:   r.FormValue("decimalSeparator"),
+			ThousandsSeparator: r.FormValue("thousandsSeparator"),
+			InvertSign:         r.FormValue("invertSign") == "true",
 		}
 
-		result, err := csvimport.ParsePreviewWithAutoDetect(data, profile)
+		format := csvimport.FormatCSV
+		var result csvimport.PreviewResult
+		if csvimport.DetectFormat(data) == csvimport.FormatXLSX {
+			format = csvimport.FormatXLSX
+			result, err = csvimport.ParsePreviewXLSX(data, profile)
+		} else {
+			result, err = csvimport.ParsePreviewWithAutoDetect(data, profile)
+		}
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to preview CSV: %s", err.Error()), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("unable to preview %s: %s", strings.ToUpper(format), err.Error()), http.StatusBadRequest)
 			return
 		}
 
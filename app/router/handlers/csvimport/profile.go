@@ -14,17 +14,31 @@ type ProfileHandler struct {
 }
 
 type profilePayload struct {
-	ID                uint   `json:"id"`
-	Name              string `json:"name"`
-	CsvSeparator      string `json:"csvSeparator"`
-	SkipRows          int    `json:"skipRows"`
-	DateColumn        string `json:"dateColumn"`
-	DateFormat        string `json:"dateFormat"`
-	DescriptionColumn string `json:"descriptionColumn"`
-	AmountColumn      string `json:"amountColumn"`
-	AmountMode        string `json:"amountMode"`
-	CreditColumn      string `json:"creditColumn"`
-	DebitColumn       string `json:"debitColumn"`
+	ID                 uint     `json:"id"`
+	Name               string   `json:"name"`
+	CsvSeparator       string   `json:"csvSeparator"`
+	SheetName          string   `json:"sheetName"`
+	SkipRows           int      `json:"skipRows"`
+	DateColumn         string   `json:"dateColumn"`
+	DateFormat         string   `json:"dateFormat"`
+	DescriptionColumn  string   `json:"descriptionColumn"`
+	AmountColumn       string   `json:"amountColumn"`
+	AmountMode         string   `json:"amountMode"`
+	CreditColumn       string   `json:"creditColumn"`
+	DebitColumn        string   `json:"debitColumn"`
+	DecimalSeparator   string   `json:"decimalSeparator"`
+	ThousandsSeparator string   `json:"thousandsSeparator"`
+	InvertSign         bool     `json:"invertSign"`
+	Type               string   `json:"type"`
+	SymbolColumn       string   `json:"symbolColumn"`
+	SideColumn         string   `json:"sideColumn"`
+	QuantityColumn     string   `json:"quantityColumn"`
+	PriceColumn        string   `json:"priceColumn"`
+	FeesColumn         string   `json:"feesColumn"`
+	CurrencyColumn     string   `json:"currencyColumn"`
+	OrderIDColumn      string   `json:"orderIdColumn"`
+	CashAccountID      uint     `json:"cashAccountId"`
+	HeaderRow          []string `json:"headerRow,omitempty"`
 }
 
 var validationErr = csvimport.ErrValidation("")
@@ -40,17 +54,31 @@ func (h *ProfileHandler) ListProfiles() http.Handler {
 		items := make([]profilePayload, len(profiles))
 		for i, p := range profiles {
 			items[i] = profilePayload{
-				ID:                p.ID,
-				Name:              p.Name,
-				CsvSeparator:      p.CsvSeparator,
-				SkipRows:          p.SkipRows,
-				DateColumn:        p.DateColumn,
-				DateFormat:        p.DateFormat,
-				DescriptionColumn: p.DescriptionColumn,
-				AmountColumn:      p.AmountColumn,
-				AmountMode:        p.AmountMode,
-				CreditColumn:      p.CreditColumn,
-				DebitColumn:       p.DebitColumn,
+				ID:                 p.ID,
+				Name:               p.Name,
+				CsvSeparator:       p.CsvSeparator,
+				SheetName:          p.SheetName,
+				SkipRows:           p.SkipRows,
+				DateColumn:         p.DateColumn,
+				DateFormat:         p.DateFormat,
+				DescriptionColumn:  p.DescriptionColumn,
+				AmountColumn:       p.AmountColumn,
+				AmountMode:         p.AmountMode,
+				CreditColumn:       p.CreditColumn,
+				DebitColumn:        p.DebitColumn,
+				DecimalSeparator:   p.DecimalSeparator,
+				ThousandsSeparator: p.ThousandsSeparator,
+				InvertSign:         p.InvertSign,
+				Type:               p.Type,
+				SymbolColumn:       p.SymbolColumn,
+				SideColumn:         p.SideColumn,
+				QuantityColumn:     p.QuantityColumn,
+				PriceColumn:        p.PriceColumn,
+				FeesColumn:         p.FeesColumn,
+				CurrencyColumn:     p.CurrencyColumn,
+				OrderIDColumn:      p.OrderIDColumn,
+				CashAccountID:      p.CashAccountID,
+				HeaderRow:          p.HeaderRow,
 			}
 		}
 
@@ -79,16 +107,30 @@ func (h *ProfileHandler) CreateProfile() http.Handler {
 		}
 
 		profile := csvimport.ImportProfile{
-			Name:              payload.Name,
-			CsvSeparator:      payload.CsvSeparator,
-			SkipRows:          payload.SkipRows,
-			DateColumn:        payload.DateColumn,
-			DateFormat:        payload.DateFormat,
-			DescriptionColumn: payload.DescriptionColumn,
-			AmountColumn:      payload.AmountColumn,
-			AmountMode:        payload.AmountMode,
-			CreditColumn:      payload.CreditColumn,
-			DebitColumn:       payload.DebitColumn,
+			Name:               payload.Name,
+			CsvSeparator:       payload.CsvSeparator,
+			SheetName:          payload.SheetName,
+			SkipRows:           payload.SkipRows,
+			DateColumn:         payload.DateColumn,
+			DateFormat:         payload.DateFormat,
+			DescriptionColumn:  payload.DescriptionColumn,
+			AmountColumn:       payload.AmountColumn,
+			AmountMode:         payload.AmountMode,
+			CreditColumn:       payload.CreditColumn,
+			DebitColumn:        payload.DebitColumn,
+			DecimalSeparator:   payload.DecimalSeparator,
+			ThousandsSeparator: payload.ThousandsSeparator,
+			InvertSign:         payload.InvertSign,
+			Type:               payload.Type,
+			SymbolColumn:       payload.SymbolColumn,
+			SideColumn:         payload.SideColumn,
+			QuantityColumn:     payload.QuantityColumn,
+			PriceColumn:        payload.PriceColumn,
+			FeesColumn:         payload.FeesColumn,
+			CurrencyColumn:     payload.CurrencyColumn,
+			OrderIDColumn:      payload.OrderIDColumn,
+			CashAccountID:      payload.CashAccountID,
+			HeaderRow:          payload.HeaderRow,
 		}
 
 		id, err := h.Store.CreateProfile(r.Context(), profile)
@@ -127,16 +169,30 @@ func (h *ProfileHandler) UpdateProfile(id uint) http.Handler {
 		}
 
 		profile := csvimport.ImportProfile{
-			Name:              payload.Name,
-			CsvSeparator:      payload.CsvSeparator,
-			SkipRows:          payload.SkipRows,
-			DateColumn:        payload.DateColumn,
-			DateFormat:        payload.DateFormat,
-			DescriptionColumn: payload.DescriptionColumn,
-			AmountColumn:      payload.AmountColumn,
-			AmountMode:        payload.AmountMode,
-			CreditColumn:      payload.CreditColumn,
-			DebitColumn:       payload.DebitColumn,
+			Name:               payload.Name,
+			CsvSeparator:       payload.CsvSeparator,
+			SheetName:          payload.SheetName,
+			SkipRows:           payload.SkipRows,
+			DateColumn:         payload.DateColumn,
+			DateFormat:         payload.DateFormat,
+			DescriptionColumn:  payload.DescriptionColumn,
+			AmountColumn:       payload.AmountColumn,
+			AmountMode:         payload.AmountMode,
+			CreditColumn:       payload.CreditColumn,
+			DebitColumn:        payload.DebitColumn,
+			DecimalSeparator:   payload.DecimalSeparator,
+			ThousandsSeparator: payload.ThousandsSeparator,
+			InvertSign:         payload.InvertSign,
+			Type:               payload.Type,
+			SymbolColumn:       payload.SymbolColumn,
+			SideColumn:         payload.SideColumn,
+			QuantityColumn:     payload.QuantityColumn,
+			PriceColumn:        payload.PriceColumn,
+			FeesColumn:         payload.FeesColumn,
+			CurrencyColumn:     payload.CurrencyColumn,
+			OrderIDColumn:      payload.OrderIDColumn,
+			CashAccountID:      payload.CashAccountID,
+			HeaderRow:          payload.HeaderRow,
 		}
 
 		err := h.Store.UpdateProfile(r.Context(), id, profile)
@@ -0,0 +1,190 @@
+package csvimport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+// categoryNames returns, per category id, the names of the category and its ancestors from the root down.
+func (h *ImportHandler) categoryNames(ctx context.Context, catType accounting.CategoryType) (map[uint][]string, error) {
+	cats, err := h.FinStore.ListDescendantCategories(ctx, 0, -1, catType)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]accounting.Category, len(cats))
+	for _, c := range cats {
+		byID[c.Id] = c
+	}
+	out := make(map[uint][]string, len(cats))
+	for _, c := range cats {
+		var names []string
+		for cur, ok := c, true; ok; cur, ok = byID[cur.ParentId] {
+			names = append([]string{cur.Name}, names...)
+		}
+		out[c.Id] = names
+	}
+	return out, nil
+}
+
+// loadCategoryPaths returns the lookup of existing categories by name path used by the QIF import.
+func (h *ImportHandler) loadCategoryPaths(ctx context.Context) (csvimport.CategoryPaths, error) {
+	paths := csvimport.CategoryPaths{Income: map[string]uint{}, Expense: map[string]uint{}}
+	for catType, target := range map[accounting.CategoryType]map[string]uint{
+		accounting.IncomeCategory:  paths.Income,
+		accounting.ExpenseCategory: paths.Expense,
+	} {
+		names, err := h.categoryNames(ctx, catType)
+		if err != nil {
+			return csvimport.CategoryPaths{}, err
+		}
+		for id, n := range names {
+			target[csvimport.CategoryPath(n)] = id
+		}
+	}
+	return paths, nil
+}
+
+// ExportQIF writes the transactions of an account in a date range as a QIF file. Income and
+// expenses carry their category path, transfers the name of the other account. Balance
+// checkpoints have no QIF equivalent and are left out.
+func (h *ImportHandler) ExportQIF() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		accountID64, err := strconv.ParseUint(q.Get("accountId"), 10, 64)
+		if err != nil || accountID64 == 0 || accountID64 > math.MaxInt {
+			http.Error(w, "a valid accountId is required", http.StatusBadRequest)
+			return
+		}
+		accountID := uint(accountID64)
+
+		startDate, err := time.Parse(time.DateOnly, q.Get("startDate"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to parse start date: %s", err), http.StatusBadRequest)
+			return
+		}
+		endDate, err := time.Parse(time.DateOnly, q.Get("endDate"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to parse end date: %s", err), http.StatusBadRequest)
+			return
+		}
+		if endDate.Before(startDate) {
+			http.Error(w, "end date must not be before start date", http.StatusBadRequest)
+			return
+		}
+
+		account, err := h.FinStore.GetAccount(r.Context(), accountID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get account: %s", err.Error()), http.StatusNotFound)
+			return
+		}
+		sectionType := csvimport.QIFBank
+		switch account.Type {
+		case accounting.InvestmentAccountType, accounting.RestrictedStockAccountType:
+			http.Error(w, "QIF export is not available for investment accounts", http.StatusBadRequest)
+			return
+		case accounting.CashAccountType:
+			sectionType = csvimport.QIFCash
+		}
+
+		txs, err := h.qifTransactions(r.Context(), accountID, startDate, endDate)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to load transactions: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/qif")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", qifFileName(account.Name, startDate, endDate)))
+		w.WriteHeader(http.StatusOK)
+		_ = csvimport.WriteQIF(w, sectionType, txs)
+	})
+}
+
+// qifTransactions converts the transactions of an account into QIF transactions, oldest first.
+func (h *ImportHandler) qifTransactions(ctx context.Context, accountID uint, startDate, endDate time.Time) ([]csvimport.QIFTransaction, error) {
+	incomeNames, err := h.categoryNames(ctx, accounting.IncomeCategory)
+	if err != nil {
+		return nil, err
+	}
+	expenseNames, err := h.categoryNames(ctx, accounting.ExpenseCategory)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := h.FinStore.ListAccountsMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transferTo := func(id uint) string {
+		return "[" + accounts[id].Name + "]"
+	}
+
+	var out []csvimport.QIFTransaction
+	for page := 1; ; page++ {
+		txs, _, err := h.FinStore.ListTransactions(ctx, accounting.ListOpts{
+			StartDate: startDate,
+			EndDate:   endDate,
+			AccountId: []int{int(accountID)},
+			Limit:     accounting.MaxSearchResults,
+			Page:      page,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(txs) == 0 {
+			break
+		}
+		for _, tx := range txs {
+			switch item := tx.(type) {
+			case accounting.Income:
+				out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: item.Amount, Payee: item.Description,
+					Memo: item.Notes, Category: strings.Join(incomeNames[item.CategoryID], ":")})
+			case accounting.Expense:
+				out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: -item.Amount, Payee: item.Description,
+					Memo: item.Notes, Category: strings.Join(expenseNames[item.CategoryID], ":")})
+			case accounting.Transfer:
+				if item.OriginAccountID == accountID {
+					out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: -item.OriginAmount, Payee: item.Description,
+						Memo: item.Notes, Category: transferTo(item.TargetAccountID)})
+				}
+				if item.TargetAccountID == accountID {
+					out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: item.TargetAmount, Payee: item.Description,
+						Memo: item.Notes, Category: transferTo(item.OriginAccountID)})
+				}
+			case accounting.StockBuy:
+				if item.CashAccountID == accountID {
+					out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: -item.TotalAmount, Payee: item.Description,
+						Memo: item.Notes, Category: transferTo(item.InvestmentAccountID)})
+				}
+			case accounting.StockSell:
+				if item.CashAccountID == accountID {
+					out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: item.TotalAmount - item.Fees, Payee: item.Description,
+						Memo: item.Notes, Category: transferTo(item.InvestmentAccountID)})
+				}
+			case accounting.Revaluation:
+				out = append(out, csvimport.QIFTransaction{Date: item.Date, Amount: item.Amount, Payee: item.Description, Memo: item.Notes})
+			}
+		}
+	}
+	// transactions are listed newest first
+	slices.Reverse(out)
+	return out, nil
+}
+
+// qifFileName returns the download name of an export, e.g. "checking_2026-01-01_2026-03-31.qif".
+func qifFileName(account string, startDate, endDate time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(account))
+	return fmt.Sprintf("%s_%s_%s.qif", name, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+}
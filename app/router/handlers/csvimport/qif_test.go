@@ -0,0 +1,147 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestQIFExportImport(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:qifExportImport?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAccount := func(name string) uint {
+		id, err := store.CreateAccount(ctx, accounting.Account{Name: name, Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	checking, savings, other := newAccount("Main checking"), newAccount("Savings"), newAccount("Other bank")
+
+	salary, err := store.CreateCategory(ctx, accounting.CategoryData{Name: "Salary", Type: accounting.IncomeCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, err := store.CreateCategory(ctx, accounting.CategoryData{Name: "Food", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceries, err := store.CreateCategory(ctx, accounting.CategoryData{Name: "Groceries", Type: accounting.ExpenseCategory}, food)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	for _, tx := range []accounting.Transaction{
+		accounting.Income{Description: "ACME Corp", Amount: 1500, AccountID: checking, CategoryID: salary, Date: day(1)},
+		accounting.Expense{Description: "Supermarket", Notes: "weekly", Amount: 45.30, AccountID: checking, CategoryID: groceries, Date: day(2)},
+		accounting.Transfer{Description: "Saving", OriginAmount: 200, OriginAccountID: checking, TargetAmount: 200, TargetAccountID: savings, Date: day(3)},
+		accounting.BalanceStatus{Description: "Statement balance", Amount: 1254.70, AccountID: checking, Date: day(4)},
+		accounting.Expense{Description: "Out of range", Amount: 1, AccountID: checking, CategoryID: groceries, Date: day(20)},
+	} {
+		if _, err := store.CreateTransaction(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store}
+
+	// export
+	req := httptest.NewRequest(http.MethodGet, "/import/export/qif?accountId="+strconv.Itoa(int(checking))+"&startDate=2026-03-01&endDate=2026-03-10", nil)
+	rec := httptest.NewRecorder()
+	h.ExportQIF().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	want := "!Type:Bank\n" +
+		"D03/01/2026\nT1500.00\nPACME Corp\nLSalary\n^\n" +
+		"D03/02/2026\nT-45.30\nPSupermarket\nMweekly\nLFood:Groceries\n^\n" +
+		"D03/03/2026\nT-200.00\nPSaving\nL[Savings]\n^\n"
+	if rec.Body.String() != want {
+		t.Errorf("unexpected QIF:\n%s", rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="main_checking_2026-03-01_2026-03-10.qif"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	exported := rec.Body.Bytes()
+
+	// import the export into another account, categories are matched by name path
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("accountId", strconv.Itoa(int(other)))
+	fw, _ := mw.CreateFormFile("file", "export.qif")
+	_, _ = fw.Write(exported)
+	_ = mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	h.ParseCSV().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("parse: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Rows   []csvimport.ParsedRow `json:"rows"`
+		Format string                `json:"format"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Format != csvimport.FormatQIF {
+		t.Errorf("expected format %q, got %q", csvimport.FormatQIF, resp.Format)
+	}
+	if len(resp.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", resp.Rows)
+	}
+	if resp.Rows[0].CategoryID != salary || resp.Rows[1].CategoryID != groceries || resp.Rows[2].CategoryID != 0 {
+		t.Errorf("unexpected categories %+v", resp.Rows)
+	}
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, query := range []string{
+			"startDate=2026-03-01&endDate=2026-03-10",
+			"accountId=" + strconv.Itoa(int(checking)) + "&startDate=2026-03-10&endDate=2026-03-01",
+			"accountId=" + strconv.Itoa(int(checking)) + "&startDate=march",
+		} {
+			rec := httptest.NewRecorder()
+			h.ExportQIF().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/import/export/qif?"+query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d", query, rec.Code)
+			}
+		}
+	})
+}
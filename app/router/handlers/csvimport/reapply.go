@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"regexp"
 	"time"
@@ -28,6 +29,17 @@ type ReapplyRow struct {
 	NewCategoryID       uint    `json:"newCategoryId"`
 	NewCategoryName     string  `json:"newCategoryName"`
 	Changed             bool    `json:"changed"`
+
+	// Set when the matching rule rewrites the description, appends notes or books a transfer.
+	NewDescription      string `json:"newDescription,omitempty"`
+	NewNotes            string `json:"newNotes,omitempty"`
+	TransferAccountID   uint   `json:"transferAccountId,omitempty"`
+	TransferAccountName string `json:"transferAccountName,omitempty"`
+
+	// Set when no rule matches an uncategorized transaction and the category is learned from
+	// the transaction history instead.
+	Suggested  bool    `json:"suggested,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
 }
 
 type categoryRulesPreviewRequest struct {
@@ -94,8 +106,10 @@ func (h *ImportHandler) buildCategoryNamesMap(ctx context.Context) (map[uint]str
 }
 
 // collectPreviewRows paginates through all income and expense transactions and returns
-// rows where the given rule groups would assign a different category.
-func (h *ImportHandler) collectPreviewRows(ctx context.Context, groups []csvimport.CategoryRuleGroup, accountMap map[uint]accounting.Account, catNames map[uint]string) ([]ReapplyRow, error) {
+// rows where the given rule groups would change the category, description, notes or book a
+// transfer. The profile conditions match the import profile of the transaction's account.
+// Uncategorized transactions no rule matches get the category suggested by the classifier, if any.
+func (h *ImportHandler) collectPreviewRows(ctx context.Context, groups []csvimport.CategoryRuleGroup, classifier *csvimport.Classifier, accountMap map[uint]accounting.Account, catNames map[uint]string) ([]ReapplyRow, error) {
 	var rows []ReapplyRow
 	for page := 1; ; page++ {
 		opts := accounting.ListOpts{
@@ -113,35 +127,94 @@ func (h *ImportHandler) collectPreviewRows(ctx context.Context, groups []csvimpo
 			break
 		}
 		for _, tx := range txs {
+			var row ReapplyRow
+			var notes string
 			switch item := tx.(type) {
 			case accounting.Income:
-				newCatID := csvimport.MatchCategory(item.Description, groups)
-				if newCatID != 0 && newCatID != item.CategoryID {
-					rows = append(rows, ReapplyRow{
-						TransactionID: item.Id, TransactionType: "income",
-						Description: item.Description, Date: item.Date.Format("2006-01-02"),
-						Amount: item.Amount, AccountID: item.AccountID, AccountName: accountMap[item.AccountID].Name,
-						CurrentCategoryID: item.CategoryID, CurrentCategoryName: catNames[item.CategoryID],
-						NewCategoryID: newCatID, NewCategoryName: catNames[newCatID], Changed: true,
-					})
+				row = ReapplyRow{
+					TransactionID: item.Id, TransactionType: "income",
+					Description: item.Description, Date: item.Date.Format("2006-01-02"),
+					Amount: item.Amount, AccountID: item.AccountID, CurrentCategoryID: item.CategoryID,
 				}
+				notes = item.Notes
 			case accounting.Expense:
-				newCatID := csvimport.MatchCategory(item.Description, groups)
-				if newCatID != 0 && newCatID != item.CategoryID {
-					rows = append(rows, ReapplyRow{
-						TransactionID: item.Id, TransactionType: "expense",
-						Description: item.Description, Date: item.Date.Format("2006-01-02"),
-						Amount: item.Amount, AccountID: item.AccountID, AccountName: accountMap[item.AccountID].Name,
-						CurrentCategoryID: item.CategoryID, CurrentCategoryName: catNames[item.CategoryID],
-						NewCategoryID: newCatID, NewCategoryName: catNames[newCatID], Changed: true,
-					})
+				row = ReapplyRow{
+					TransactionID: item.Id, TransactionType: "expense",
+					Description: item.Description, Date: item.Date.Format("2006-01-02"),
+					Amount: item.Amount, AccountID: item.AccountID, CurrentCategoryID: item.CategoryID,
 				}
+				notes = item.Notes
+			default:
+				continue
+			}
+			if reapplyRule(&row, notes, groups, accountMap) || suggestCategory(&row, classifier) {
+				row.AccountName = accountMap[row.AccountID].Name
+				row.CurrentCategoryName = catNames[row.CurrentCategoryID]
+				row.NewCategoryName = catNames[row.NewCategoryID]
+				row.TransferAccountName = accountMap[row.TransferAccountID].Name
+				rows = append(rows, row)
 			}
 		}
 	}
 	return rows, nil
 }
 
+// reapplyRule fills in the changes the first matching rule makes to a transaction and reports
+// whether there are any.
+func reapplyRule(row *ReapplyRow, notes string, groups []csvimport.CategoryRuleGroup, accountMap map[uint]accounting.Account) bool {
+	in := csvimport.RuleInput{
+		Description: row.Description,
+		Amount:      row.Amount,
+		AccountID:   row.AccountID,
+		ProfileID:   accountMap[row.AccountID].ImportProfileID,
+	}
+	if row.TransactionType == "expense" {
+		in.Amount = -row.Amount
+	}
+	in.Date, _ = time.Parse("2006-01-02", row.Date)
+
+	rule, ok := csvimport.MatchRule(in, groups)
+	if !ok {
+		return false
+	}
+	row.NewCategoryID = row.CurrentCategoryID
+	if rule.CategoryID != 0 {
+		row.NewCategoryID = rule.CategoryID
+	}
+	if rule.SetDescription != "" && rule.SetDescription != row.Description {
+		row.NewDescription = rule.SetDescription
+	}
+	if newNotes := csvimport.AppendNote(notes, rule.AppendNotes); newNotes != notes {
+		row.NewNotes = newNotes
+	}
+	if rule.TransferAccountID != 0 && rule.TransferAccountID != row.AccountID {
+		row.TransferAccountID = rule.TransferAccountID
+	}
+	row.Changed = row.NewCategoryID != row.CurrentCategoryID || row.NewDescription != "" || row.NewNotes != "" || row.TransferAccountID != 0
+	return row.Changed
+}
+
+// suggestCategory sets the category the classifier suggests for an uncategorized transaction
+// and reports whether there is one.
+func suggestCategory(row *ReapplyRow, classifier *csvimport.Classifier) bool {
+	if classifier == nil || row.CurrentCategoryID != 0 {
+		return false
+	}
+	in := csvimport.RuleInput{Description: row.Description, Amount: row.Amount, AccountID: row.AccountID}
+	if row.TransactionType == "expense" {
+		in.Amount = -row.Amount
+	}
+	s, ok := classifier.Suggest(in)
+	if !ok {
+		return false
+	}
+	row.NewCategoryID = s.CategoryID
+	row.Suggested = true
+	row.Confidence = math.Round(s.Confidence*100) / 100
+	row.Changed = true
+	return true
+}
+
 // CategoryRulesPreview returns an http.Handler that previews the effect of re-applying
 // category matching rules to all existing income and expense transactions.
 func (h *ImportHandler) CategoryRulesPreview() http.Handler {
@@ -161,7 +234,16 @@ func (h *ImportHandler) CategoryRulesPreview() http.Handler {
 			http.Error(w, err.Error(), httpStatus)
 			return
 		}
-		if len(groups) == 0 {
+		// suggestions only complement the stored rules, an ad-hoc rule previews just its own matches
+		var classifier *csvimport.Classifier
+		if req.AdhocRule == nil {
+			classifier, err = h.trainClassifier(ctx)
+			if err != nil {
+				http.Error(w, "unable to train category suggestions: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if len(groups) == 0 && classifier == nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write([]byte("[]"))
@@ -180,7 +262,7 @@ func (h *ImportHandler) CategoryRulesPreview() http.Handler {
 			return
 		}
 
-		rows, err := h.collectPreviewRows(ctx, groups, accountMap, catNames)
+		rows, err := h.collectPreviewRows(ctx, groups, classifier, accountMap, catNames)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -200,11 +282,14 @@ func (h *ImportHandler) CategoryRulesPreview() http.Handler {
 	})
 }
 
-// reapplySubmitItem represents a single transaction category update request.
+// reapplySubmitItem represents a single transaction update request.
 type reapplySubmitItem struct {
-	TransactionID   uint   `json:"transactionId"`
-	TransactionType string `json:"transactionType"`
-	NewCategoryID   uint   `json:"newCategoryId"`
+	TransactionID     uint    `json:"transactionId"`
+	TransactionType   string  `json:"transactionType"`
+	NewCategoryID     uint    `json:"newCategoryId"`
+	NewDescription    *string `json:"newDescription"`
+	NewNotes          *string `json:"newNotes"`
+	TransferAccountID uint    `json:"transferAccountId"` // replaces the transaction with a transfer
 }
 
 // CategoryRulesSubmit returns an http.Handler that applies category changes to transactions.
@@ -219,13 +304,25 @@ func (h *ImportHandler) CategoryRulesSubmit() http.Handler {
 		}
 
 		for _, item := range items {
+			if item.TransferAccountID != 0 {
+				if err := h.replaceWithTransfer(ctx, item); err != nil {
+					status := submitErrorStatus(err)
+					if errors.Is(err, accounting.ErrTransactionNotFound) {
+						status = http.StatusNotFound
+					}
+					http.Error(w, err.Error(), status)
+					return
+				}
+				continue
+			}
+
 			catID := item.NewCategoryID
 			var update accounting.TransactionUpdate
 			switch item.TransactionType {
 			case "expense":
-				update = accounting.ExpenseUpdate{CategoryID: &catID}
+				update = accounting.ExpenseUpdate{CategoryID: &catID, Description: item.NewDescription, Notes: item.NewNotes}
 			case "income":
-				update = accounting.IncomeUpdate{CategoryID: &catID}
+				update = accounting.IncomeUpdate{CategoryID: &catID, Description: item.NewDescription, Notes: item.NewNotes}
 			default:
 				http.Error(w, fmt.Sprintf("unsupported transaction type: %s", item.TransactionType), http.StatusBadRequest)
 				return
@@ -248,3 +345,38 @@ func (h *ImportHandler) CategoryRulesSubmit() http.Handler {
 		_, _ = w.Write(resp)
 	})
 }
+
+// replaceWithTransfer books an income or expense as a transfer with another account: the
+// transfer takes over the attachments and import id of the original, which is deleted.
+func (h *ImportHandler) replaceWithTransfer(ctx context.Context, item reapplySubmitItem) error {
+	tx, err := h.FinStore.GetTransaction(ctx, item.TransactionID)
+	if err != nil {
+		return err
+	}
+	row := submitRow{TransferAccountID: item.TransferAccountID}
+	var accountID uint
+	var date time.Time
+	switch orig := tx.(type) {
+	case accounting.Income:
+		row.Description, row.Notes, row.Amount = orig.Description, orig.Notes, orig.Amount
+		accountID, date = orig.AccountID, orig.Date
+	case accounting.Expense:
+		row.Description, row.Notes, row.Amount = orig.Description, orig.Notes, -orig.Amount
+		accountID, date = orig.AccountID, orig.Date
+	default:
+		return accounting.ErrValidation(fmt.Sprintf("transaction %d is not an income or expense", item.TransactionID))
+	}
+	if item.NewDescription != nil {
+		row.Description = *item.NewDescription
+	}
+	if item.NewNotes != nil {
+		row.Notes = *item.NewNotes
+	}
+
+	transfer, err := h.transferTransaction(ctx, row, date, accountID)
+	if err != nil {
+		return err
+	}
+	_, err = h.FinStore.ReplaceWithTransfer(ctx, item.TransactionID, transfer.(accounting.Transfer))
+	return err
+}
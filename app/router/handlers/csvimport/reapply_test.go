@@ -363,3 +363,147 @@ func TestCategoryRulesSubmit(t *testing.T) {
 		}
 	})
 }
+
+func TestCategoryRulesActions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:reapplyActions?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	uDb, _ := db.DB()
+	defer func() { _ = uDb.Close() }()
+
+	mktStore, _ := marketdata.NewStore(db)
+	finStore, _ := accounting.NewStore(db, mktStore)
+	csvStore, _ := csvimport.NewStore(db)
+
+	ctx := context.Background()
+
+	providerID, err := finStore.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "test", Description: "test", Icon: "bank"})
+	if err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	newAccount := func(name string) uint {
+		t.Helper()
+		id, err := finStore.CreateAccount(ctx, accounting.Account{Name: name, Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+		if err != nil {
+			t.Fatalf("create account: %v", err)
+		}
+		return id
+	}
+	checkingID := newAccount("checking")
+	otherID := newAccount("other")
+	savingsID := newAccount("savings")
+
+	// only applies to the checking account
+	_, err = csvStore.CreateCategoryRuleGroup(ctx, csvimport.CategoryRuleGroup{
+		Name: "Savings order", Priority: 1, AccountID: checkingID,
+		Patterns:       []csvimport.CategoryRulePattern{{Pattern: "STANDING ORDER"}},
+		SetDescription: "To savings", TransferAccountID: savingsID,
+	})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	baseDate := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	txID, err := finStore.CreateTransaction(ctx, accounting.Expense{Description: "STANDING ORDER 42", Amount: 200, AccountID: checkingID, Date: baseDate, ExternalID: "FIT-42"})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	for i, caption := range []string{"order", "receipt"} {
+		if _, err := finStore.AddTransactionAttachment(ctx, txID, uint(10+i), caption); err != nil {
+			t.Fatalf("add attachment: %v", err)
+		}
+	}
+	if _, err := finStore.CreateTransaction(ctx, accounting.Expense{Description: "STANDING ORDER 43", Amount: 50, AccountID: otherID, Date: baseDate}); err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+
+	handler := &ImportHandler{CsvStore: csvStore, FinStore: finStore}
+
+	w := httptest.NewRecorder()
+	handler.CategoryRulesPreview().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import/category-rules-preview", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var rows []ReapplyRow
+	if err := json.Unmarshal(w.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("unable to unmarshal response: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected only the transaction of the checking account, got %+v", rows)
+	}
+	row := rows[0]
+	if row.TransactionID != txID || row.NewDescription != "To savings" || row.TransferAccountID != savingsID || row.TransferAccountName != "savings" {
+		t.Errorf("unexpected preview row %+v", row)
+	}
+
+	// the statement does not tell the amount in another currency
+	usdID, err := finStore.CreateAccount(ctx, accounting.Account{Name: "usd", Currency: currency.USD, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	body := fmt.Sprintf(`[{"transactionId":%d,"transactionType":"expense","transferAccountId":%d}]`, txID, usdID)
+	w = httptest.NewRecorder()
+	handler.CategoryRulesSubmit().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import/category-rules-submit", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a cross-currency transfer, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := finStore.GetTransaction(ctx, txID); err != nil {
+		t.Errorf("expected the expense to be kept: %v", err)
+	}
+
+	body = fmt.Sprintf(`[{"transactionId":%d,"transactionType":"expense","newDescription":"To savings","transferAccountId":%d}]`, txID, savingsID)
+	w = httptest.NewRecorder()
+	handler.CategoryRulesSubmit().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import/category-rules-submit", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := finStore.GetTransaction(ctx, txID); err == nil {
+		t.Error("expected the expense to be replaced")
+	}
+	txs, _, err := finStore.ListTransactions(ctx, accounting.ListOpts{
+		StartDate: baseDate.AddDate(0, 0, -1), EndDate: baseDate.AddDate(0, 0, 1),
+		AccountId: []int{int(savingsID)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expected one transfer into savings, got %d", len(txs))
+	}
+	tr, ok := txs[0].(accounting.Transfer)
+	if !ok || tr.OriginAccountID != checkingID || tr.TargetAccountID != savingsID || tr.OriginAmount != 200 || tr.Description != "To savings" {
+		t.Errorf("unexpected transfer %+v", txs[0])
+	}
+	if tr.ExternalID != "FIT-42" {
+		t.Errorf("expected the import id to be kept, got %q", tr.ExternalID)
+	}
+	if tr.AttachmentID == nil || *tr.AttachmentID != 10 {
+		t.Errorf("expected attachment 10 as primary, got %v", tr.AttachmentID)
+	}
+	atts, err := finStore.ListTransactionAttachments(ctx, tr.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []accounting.TransactionAttachment{
+		{TransactionID: tr.Id, AttachmentID: 10, Position: 0, Caption: "order"},
+		{TransactionID: tr.Id, AttachmentID: 11, Position: 1, Caption: "receipt"},
+	}
+	if len(atts) != len(want) {
+		t.Fatalf("expected both attachments moved to the transfer, got %+v", atts)
+	}
+	for i := range want {
+		if atts[i] != want[i] {
+			t.Errorf("attachment %d: expected %+v, got %+v", i, want[i], atts[i])
+		}
+	}
+	refs, err := finStore.AttachmentReferences(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs[10] != 1 || refs[11] != 1 {
+		t.Errorf("expected each attachment referenced once, got %v", refs)
+	}
+}
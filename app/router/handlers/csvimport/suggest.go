@@ -0,0 +1,41 @@
+package csvimport
+
+import (
+	"context"
+
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+// trainClassifier brings the category classifier up to date with the categorized transactions.
+// The transactions are only loaded when they changed since the last call, and then only the ones
+// that changed are learned or forgotten. It returns nil when the handler has no classifier.
+func (h *ImportHandler) trainClassifier(ctx context.Context) (*csvimport.Classifier, error) {
+	if h.Classifier == nil {
+		return nil, nil
+	}
+	version, err := h.FinStore.CategorizedEntriesVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = h.Classifier.Refresh(version, func() ([]csvimport.TrainingSample, error) {
+		entries, err := h.FinStore.ListCategorizedEntries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		samples := make([]csvimport.TrainingSample, len(entries))
+		for i, e := range entries {
+			samples[i] = csvimport.TrainingSample{
+				ID:          e.TransactionID,
+				Description: e.Description,
+				Amount:      e.Amount,
+				AccountID:   e.AccountID,
+				CategoryID:  e.CategoryID,
+			}
+		}
+		return samples, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Classifier, nil
+}
@@ -0,0 +1,156 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCategorySuggestions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:categorySuggestions?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, _ := marketdata.NewStore(db)
+	store, _ := accounting.NewStore(db, mktStore)
+	csvStore, _ := csvimport.NewStore(db)
+	ctx := context.Background()
+
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := store.CreateAccount(ctx, accounting.Account{Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceriesID, err := store.CreateCategory(ctx, accounting.CategoryData{Name: "Groceries", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transportID, err := store.CreateCategory(ctx, accounting.CategoryData{Name: "Transport", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	var migrosIDs []uint
+	for _, tx := range []accounting.Expense{
+		{Description: "MIGROS BASEL 1234", Amount: 54.20, CategoryID: groceriesID},
+		{Description: "Migros Zurich HB", Amount: 12.10, CategoryID: groceriesID},
+		{Description: "SBB CFF FFS ticket", Amount: 88, CategoryID: transportID},
+		{Description: "MIGROS LUZERN", Amount: 20}, // not categorized yet
+	} {
+		tx.AccountID, tx.Date = accID, date
+		id, err := store.CreateTransaction(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.CategoryID == groceriesID {
+			migrosIDs = append(migrosIDs, id)
+		}
+	}
+
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store, Classifier: csvimport.NewClassifier()}
+
+	t.Run("parse suggests categories", func(t *testing.T) {
+		const ofx = `OFXHEADER:100
+DATA:OFXSGML
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>CHF<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260302<TRNAMT>-45.30<FITID>A1<NAME>MIGROS GENEVA</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260303<TRNAMT>-9.90<FITID>A2<NAME>NETFLIX.COM</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("accountId", strconv.Itoa(int(accID)))
+		fw, _ := mw.CreateFormFile("file", "march.ofx")
+		_, _ = fw.Write([]byte(ofx))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.ParseCSV().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Rows []csvimport.ParsedRow `json:"rows"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Rows) != 2 {
+			t.Fatalf("expected 2 rows, got %+v", resp.Rows)
+		}
+		if resp.Rows[0].SuggestedCategoryID != groceriesID || resp.Rows[0].SuggestionConfidence == 0 || resp.Rows[0].CategoryID != 0 {
+			t.Errorf("expected groceries to be suggested, got %+v", resp.Rows[0])
+		}
+		if resp.Rows[1].SuggestedCategoryID != 0 {
+			t.Errorf("expected no suggestion for an unknown merchant, got %+v", resp.Rows[1])
+		}
+	})
+
+	preview := func() []ReapplyRow {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.CategoryRulesPreview().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/category-rules-preview", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var rows []ReapplyRow
+		if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	t.Run("reapply preview suggests categories", func(t *testing.T) {
+		rows := preview()
+		if len(rows) != 1 || rows[0].Description != "MIGROS LUZERN" || !rows[0].Suggested || rows[0].NewCategoryID != groceriesID || rows[0].NewCategoryName != "Groceries" {
+			t.Fatalf("expected a suggestion for the uncategorized transaction, got %+v", rows)
+		}
+	})
+
+	t.Run("recategorized transactions are learned", func(t *testing.T) {
+		for _, id := range migrosIDs {
+			catID := transportID
+			if err := store.UpdateTransaction(ctx, accounting.ExpenseUpdate{CategoryID: &catID}, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rows := preview()
+		if len(rows) != 1 || rows[0].NewCategoryID != transportID {
+			t.Fatalf("expected the new category to be suggested, got %+v", rows)
+		}
+	})
+
+	t.Run("deleted transactions are forgotten", func(t *testing.T) {
+		for _, id := range migrosIDs {
+			if err := store.DeleteTransaction(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if rows := preview(); len(rows) != 0 {
+			t.Fatalf("expected no suggestion without migros samples, got %+v", rows)
+		}
+		if h.Classifier.Len() != 1 {
+			t.Errorf("expected only the sbb sample to be left, got %d", h.Classifier.Len())
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/andresbott/etna/internal/marketdata/importer"
+	"golang.org/x/text/currency"
+)
+
+// loadTradeInstruments returns the existing instruments by upper-cased symbol.
+func (h *ImportHandler) loadTradeInstruments(ctx context.Context) (map[string]csvimport.TradeInstrument, error) {
+	out := map[string]csvimport.TradeInstrument{}
+	if h.InstrumentStore == nil {
+		return out, nil
+	}
+	items, err := h.InstrumentStore.ListInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		out[strings.ToUpper(item.Symbol)] = csvimport.TradeInstrument{ID: item.ID, Currency: item.Currency.String()}
+	}
+	return out, nil
+}
+
+// parseTrades parses a broker trade history with an investment profile. Order ids are compared
+// against the transactions of the investment account and of the profile's cash account, which
+// holds the imported dividends and fees.
+func (h *ImportHandler) parseTrades(ctx context.Context, data []byte, format string, profile csvimport.ImportProfile,
+	groups []csvimport.CategoryRuleGroup, existing []csvimport.ExistingTx) ([]csvimport.ParsedRow, error) {
+	instruments, err := h.loadTradeInstruments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list instruments: %w", err)
+	}
+	if profile.CashAccountID != 0 {
+		cashTxs, err := h.loadExistingTransactions(ctx, profile.CashAccountID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load existing transactions: %w", err)
+		}
+		existing = append(existing, cashTxs...)
+	}
+	if format == csvimport.FormatXLSX {
+		return csvimport.ParseTradesXLSX(data, profile, instruments, groups, existing)
+	}
+	return csvimport.ParseTrades(bytes.NewReader(data), profile, instruments, groups, existing)
+}
+
+// errUnknownInstrument is returned for symbols without an instrument that could not be created.
+type errUnknownInstrument string
+
+func (e errUnknownInstrument) Error() string { return string(e) }
+
+// instrumentResolver finds the instruments of submitted trades by symbol and, when asked to,
+// creates missing ones from the reference provider.
+type instrumentResolver struct {
+	h       *ImportHandler
+	create  bool
+	symbols map[string]uint // loaded on first use
+}
+
+func (res *instrumentResolver) resolve(ctx context.Context, symbol, ccy string) (uint, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return 0, errUnknownInstrument("trade without symbol")
+	}
+	if res.symbols == nil {
+		instruments, err := res.h.loadTradeInstruments(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list instruments: %w", err)
+		}
+		res.symbols = make(map[string]uint, len(instruments))
+		for s, inst := range instruments {
+			res.symbols[s] = inst.ID
+		}
+	}
+	if id, ok := res.symbols[symbol]; ok {
+		return id, nil
+	}
+	if !res.create {
+		return 0, errUnknownInstrument(fmt.Sprintf("no instrument with symbol %q", symbol))
+	}
+	if res.h.Reference == nil || res.h.InstrumentStore == nil {
+		return 0, errUnknownInstrument(fmt.Sprintf("no instrument with symbol %q and no reference provider to create it", symbol))
+	}
+
+	details, err := res.h.Reference.GetTickerDetails(ctx, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("unable to look up symbol %q: %w", symbol, err)
+	}
+	if !details.Found {
+		return 0, errUnknownInstrument(fmt.Sprintf("symbol %q is not known to the reference provider", symbol))
+	}
+	if details.Currency != "" {
+		ccy = details.Currency
+	}
+	unit, err := currency.ParseISO(ccy)
+	if err != nil {
+		return 0, errUnknownInstrument(fmt.Sprintf("no valid currency to create instrument %q", symbol))
+	}
+	id, err := res.h.InstrumentStore.CreateInstrument(ctx, marketdata.Instrument{
+		Symbol:   symbol,
+		Name:     details.Name,
+		Currency: unit,
+		Notes:    details.Notes,
+		Type:     importer.MassiveAppType(details.Type),
+		Exchange: importer.MassiveAppExchange(details.Exchange),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to create instrument %q: %w", symbol, err)
+	}
+	res.symbols[symbol] = id
+	return id, nil
+}
+
+// tradeTransaction builds the stock buy or sell of a submitted trade row.
+func (h *ImportHandler) tradeTransaction(ctx context.Context, req submitRequest, row submitRow, date time.Time, res *instrumentResolver) (accounting.Transaction, error) {
+	if req.CashAccountID == 0 {
+		return nil, accounting.ErrValidation("cashAccountId is required to import trades")
+	}
+	instrumentID := row.InstrumentID
+	if instrumentID == 0 {
+		var err error
+		instrumentID, err = res.resolve(ctx, row.Symbol, row.Currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	quantity := math.Abs(row.Quantity)
+	gross := math.Round(quantity*math.Abs(row.Price)*100) / 100
+
+	if row.Type == "buy" {
+		return accounting.StockBuy{
+			Description:         row.Description,
+			Date:                date,
+			InvestmentAccountID: req.AccountID,
+			CashAccountID:       req.CashAccountID,
+			InstrumentID:        instrumentID,
+			Quantity:            quantity,
+			TotalAmount:         math.Abs(row.Amount),
+			StockAmount:         gross,
+			ExternalID:          row.ExternalID,
+		}, nil
+	}
+	return accounting.StockSell{
+		Description:         row.Description,
+		Date:                date,
+		InvestmentAccountID: req.AccountID,
+		CashAccountID:       req.CashAccountID,
+		InstrumentID:        instrumentID,
+		Quantity:            quantity,
+		PricePerShare:       math.Abs(row.Price),
+		TotalAmount:         gross,
+		Fees:                math.Abs(row.Fees),
+		ExternalID:          row.ExternalID,
+	}, nil
+}
+
+// cashBookingAccount returns the account income and expense rows are booked on: the cash
+// account for imports into an investment account, which holds no cash, else the account itself.
+func (h *ImportHandler) cashBookingAccount(ctx context.Context, req submitRequest) (uint, error) {
+	if req.CashAccountID == 0 {
+		return req.AccountID, nil
+	}
+	account, err := h.FinStore.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	if account.Type == accounting.InvestmentAccountType {
+		return req.CashAccountID, nil
+	}
+	return req.AccountID, nil
+}
+
+// rowError ties the error of a submitted row to the row it happened on.
+type rowError struct {
+	row string
+	err error
+}
+
+func (e rowError) Error() string { return fmt.Sprintf("row %s: %s", e.row, e.err.Error()) }
+func (e rowError) Unwrap() error { return e.err }
+
+// submitErrorStatus returns the status of an error creating a submitted row.
+func submitErrorStatus(err error) int {
+	var valErr accounting.ErrValidation
+	var mdValErr marketdata.ErrValidation
+	var unknown errUnknownInstrument
+	if errors.As(err, &valErr) || errors.As(err, &mdValErr) || errors.As(err, &unknown) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
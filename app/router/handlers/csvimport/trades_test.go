@@ -0,0 +1,188 @@
+package csvimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/andresbott/etna/internal/marketdata/importer"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/text/currency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeReference map[string]importer.TickerDetails
+
+func (f fakeReference) GetTickerDetails(_ context.Context, symbol string) (importer.TickerDetails, error) {
+	return f[symbol], nil
+}
+
+func TestImportTrades(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importTrades?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open sqlite: %v", err)
+	}
+	mktStore, err := marketdata.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := accounting.NewStore(db, mktStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvStore, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	providerID, err := store.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "broker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cashID, err := store.CreateAccount(ctx, accounting.Account{Name: "broker cash", Currency: currency.USD, Type: accounting.CashAccountType, AccountProviderID: providerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := csvStore.CreateProfile(ctx, csvimport.ImportProfile{
+		Name:           "broker",
+		Type:           csvimport.ProfileTypeInvestment,
+		DateColumn:     "Date",
+		DateFormat:     "2006-01-02",
+		SymbolColumn:   "Symbol",
+		SideColumn:     "Action",
+		QuantityColumn: "Quantity",
+		PriceColumn:    "Price",
+		AmountColumn:   "Amount",
+		FeesColumn:     "Fees",
+		OrderIDColumn:  "Order",
+		CashAccountID:  cashID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invID, err := store.CreateAccount(ctx, accounting.Account{Name: "broker", Currency: currency.USD, Type: accounting.InvestmentAccountType, AccountProviderID: providerID, ImportProfileID: profileID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aapl, err := mktStore.CreateInstrument(ctx, marketdata.Instrument{Symbol: "AAPL", Name: "Apple", Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const trades = `Date,Order,Action,Symbol,Quantity,Price,Fees,Amount
+2026-03-02,1001,Buy,AAPL,10,150,1,
+2026-03-03,1002,Buy,NEWCO,5,20,0,
+2026-03-04,1003,Sell,AAPL,4,160,1,
+2026-03-05,1004,Dividend,AAPL,,,,3.20
+`
+	h := &ImportHandler{CsvStore: csvStore, FinStore: store, InstrumentStore: mktStore,
+		Reference: fakeReference{"NEWCO": {Name: "New Co", Currency: "USD", Type: "CS", Exchange: "XNAS", Found: true}}}
+	parse := func() []csvimport.ParsedRow {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("accountId", strconv.Itoa(int(invID)))
+		fw, _ := mw.CreateFormFile("file", "trades.csv")
+		_, _ = fw.Write([]byte(trades))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import/parse", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.ParseCSV().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("parse: unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Rows          []csvimport.ParsedRow `json:"rows"`
+			ProfileType   string                `json:"profileType"`
+			CashAccountID uint                  `json:"cashAccountId"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ProfileType != csvimport.ProfileTypeInvestment || resp.CashAccountID != cashID {
+			t.Errorf("unexpected response %+v", resp)
+		}
+		return resp.Rows
+	}
+	submit := func(rows []csvimport.ParsedRow, createInstruments bool) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]any{"accountId": invID, "cashAccountId": cashID, "createInstruments": createInstruments, "rows": rows})
+		rec := httptest.NewRecorder()
+		h.SubmitImport().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/import/submit", bytes.NewReader(payload)))
+		return rec
+	}
+
+	rows := parse()
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %+v", rows)
+	}
+	if rows[0].InstrumentID != aapl || rows[1].InstrumentID != 0 {
+		t.Errorf("unexpected instruments %+v", rows)
+	}
+
+	// unknown symbols are only created on request
+	if rec := submit(rows[1:2], false); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown symbol, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := submit(rows, true); rec.Code != http.StatusOK {
+		t.Fatalf("submit: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	newco, err := mktStore.ListInstruments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newco) != 2 || newco[1].Symbol != "NEWCO" || newco[1].Type != "Stock" || newco[1].Exchange != "NASDAQ" {
+		t.Errorf("unexpected instruments %+v", newco)
+	}
+
+	txs, _, err := store.ListTransactions(ctx, accounting.ListOpts{
+		StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		AccountId: []int{int(invID), int(cashID)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buys, sells, dividends int
+	for _, tx := range txs {
+		switch item := tx.(type) {
+		case accounting.StockBuy:
+			buys++
+			if item.ExternalID == "1001" && (item.TotalAmount != 1501 || item.StockAmount != 1500 || item.CashAccountID != cashID) {
+				t.Errorf("unexpected buy %+v", item)
+			}
+		case accounting.StockSell:
+			sells++
+			if item.ExternalID != "1003" || item.TotalAmount != 640 || item.Fees != 1 || item.PricePerShare != 160 {
+				t.Errorf("unexpected sell %+v", item)
+			}
+		case accounting.Income:
+			dividends++
+			if item.AccountID != cashID || item.Amount != 3.20 || item.ExternalID != "1004" {
+				t.Errorf("unexpected dividend %+v", item)
+			}
+		}
+	}
+	if buys != 2 || sells != 1 || dividends != 1 {
+		t.Errorf("unexpected transactions %+v", txs)
+	}
+
+	// importing the same file again flags everything as duplicate
+	for _, row := range parse() {
+		if !row.IsDuplicate {
+			t.Errorf("expected row %d to be a duplicate", row.RowNumber)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package finance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+type amortizationPlanPayload struct {
+	Id            uint         `json:"id"`
+	TransactionId uint         `json:"transactionId"`
+	CategoryId    uint         `json:"categoryId"`
+	StartDate     dateOnlyTime `json:"startDate"`
+	EndDate       dateOnlyTime `json:"endDate"`
+
+	// read only
+	AccountId     uint    `json:"accountId"`
+	Amount        float64 `json:"amount"`
+	Amortized     float64 `json:"amortized"`
+	Periods       int     `json:"periods"`
+	PostedPeriods int     `json:"postedPeriods"`
+}
+
+type amortizationPlanUpdatePayload struct {
+	CategoryId *uint            `json:"categoryId"`
+	StartDate  *dateOnlyTimePtr `json:"startDate"`
+	EndDate    *dateOnlyTimePtr `json:"endDate"`
+}
+
+type amortizationPlanListResponse struct {
+	Items []amortizationPlanPayload `json:"items"`
+}
+
+func amortizationPlanToPayload(in accounting.AmortizationPlan) amortizationPlanPayload {
+	return amortizationPlanPayload{
+		Id:            in.ID,
+		TransactionId: in.TransactionID,
+		CategoryId:    in.CategoryID,
+		StartDate:     dateOnlyTime{Time: in.StartDate},
+		EndDate:       dateOnlyTime{Time: in.EndDate},
+		AccountId:     in.AccountID,
+		Amount:        in.Amount,
+		Amortized:     in.Amortized,
+		Periods:       in.Periods,
+		PostedPeriods: in.PostedPeriods,
+	}
+}
+
+func (h *Handler) ListAmortizationPlans() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plans, err := h.Store.ListAmortizationPlans(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list amortization plans: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items := make([]amortizationPlanPayload, len(plans))
+		for i, p := range plans {
+			items[i] = amortizationPlanToPayload(p)
+		}
+
+		respJson, err := json.Marshal(amortizationPlanListResponse{Items: items})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) CreateAmortizationPlan() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := amortizationPlanPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		id, err := h.Store.CreateAmortizationPlan(r.Context(), accounting.AmortizationPlan{
+			TransactionID: payload.TransactionId,
+			CategoryID:    payload.CategoryId,
+			StartDate:     payload.StartDate.Time,
+			EndDate:       payload.EndDate.Time,
+		})
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrTransactionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to store amortization plan in DB: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		plan, err := h.Store.GetAmortizationPlan(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respJson, err := json.Marshal(amortizationPlanToPayload(plan))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) UpdateAmortizationPlan(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := amortizationPlanUpdatePayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err := h.Store.UpdateAmortizationPlan(r.Context(), accounting.AmortizationPlanUpdate{
+			CategoryID: payload.CategoryId,
+			StartDate:  dateOnlyPtrToTime(payload.StartDate),
+			EndDate:    dateOnlyPtrToTime(payload.EndDate),
+		}, id)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrAmortizationPlanNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, accounting.ErrNoChanges) {
+				http.Error(w, "no changes applied", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update amortization plan: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *Handler) DeleteAmortizationPlan(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.Store.DeleteAmortizationPlan(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, accounting.ErrAmortizationPlanNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to delete amortization plan: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -1,6 +1,7 @@
 package finance
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,9 +17,71 @@ type attachmentPayload struct {
 	OriginalName string `json:"originalName"`
 	MimeType     string `json:"mimeType"`
 	FileSize     int64  `json:"fileSize"`
+	Caption      string `json:"caption"`
+	Position     int    `json:"position"`
 }
 
-// UploadAttachment handles POST /fin/entries/{id}/attachment
+type attachmentListResponse struct {
+	Items []attachmentPayload `json:"items"`
+}
+
+type attachmentUpdatePayload struct {
+	Caption string `json:"caption"`
+}
+
+type attachmentOrderPayload struct {
+	Ids []uint `json:"ids"`
+}
+
+// ListAttachments handles GET /fin/entries/{id}/attachment
+func (h *Handler) ListAttachments(txId uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.FileStore == nil {
+			http.Error(w, "attachments not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		if _, err := h.Store.GetTransaction(r.Context(), txId); err != nil {
+			if errors.Is(err, accounting.ErrTransactionNotFound) {
+				http.Error(w, "transaction not found", http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to get transaction: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		links, err := h.Store.ListTransactionAttachments(r.Context(), txId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list attachments: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		items := make([]attachmentPayload, 0, len(links))
+		for _, link := range links {
+			att, err := h.FileStore.Get(r.Context(), link.AttachmentID)
+			if err != nil {
+				if errors.Is(err, filestore.ErrNotFound) {
+					continue // skip links to missing files
+				}
+				http.Error(w, fmt.Sprintf("unable to get attachment: %v", err), http.StatusInternalServerError)
+				return
+			}
+			items = append(items, attachmentToPayload(att, link))
+		}
+
+		respJSON, err := json.Marshal(attachmentListResponse{Items: items})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJSON)
+	})
+}
+
+// UploadAttachment handles POST /fin/entries/{id}/attachment, the file is appended to the
+// attachments of the transaction; an optional "caption" form field sets its caption.
 func (h *Handler) UploadAttachment(txId uint) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if h.FileStore == nil {
@@ -51,11 +114,6 @@ func (h *Handler) UploadAttachment(txId uint) http.Handler {
 		}
 		defer func() { _ = file.Close() }()
 
-		// If existing attachment, delete old one first
-		if attID := transactionAttachmentID(tx); attID != nil {
-			_ = h.FileStore.Delete(r.Context(), *attID)
-		}
-
 		date := transactionDate(tx)
 		attID, err := h.FileStore.Save(r.Context(), date, file, header)
 		if err != nil {
@@ -71,10 +129,15 @@ func (h *Handler) UploadAttachment(txId uint) http.Handler {
 			return
 		}
 
-		// Update the transaction's AttachmentID
-		if err := h.Store.SetAttachmentID(r.Context(), txId, &attID); err != nil {
-			// Cleanup: delete the just-saved file
+		link, err := h.Store.AddTransactionAttachment(r.Context(), txId, attID, r.FormValue("caption"))
+		if err != nil {
+			// Cleanup: release the just-saved file (identical content is stored once and reference counted)
 			_ = h.FileStore.Delete(r.Context(), attID)
+			var validationErr accounting.ErrValidation
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, fmt.Sprintf("failed to update transaction: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -85,13 +148,7 @@ func (h *Handler) UploadAttachment(txId uint) http.Handler {
 			return
 		}
 
-		payload := attachmentPayload{
-			Id:           att.Id,
-			OriginalName: att.OriginalName,
-			MimeType:     att.MimeType,
-			FileSize:     att.FileSize,
-		}
-		respJSON, err := json.Marshal(payload)
+		respJSON, err := json.Marshal(attachmentToPayload(att, link))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -102,31 +159,19 @@ func (h *Handler) UploadAttachment(txId uint) http.Handler {
 	})
 }
 
-// GetAttachment handles GET /fin/entries/{id}/attachment
-func (h *Handler) GetAttachment(txId uint) http.Handler {
+// GetAttachment handles GET /fin/entries/{id}/attachment/{attachmentId}
+func (h *Handler) GetAttachment(txId, attID uint) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if h.FileStore == nil {
 			http.Error(w, "attachments not configured", http.StatusServiceUnavailable)
 			return
 		}
 
-		tx, err := h.Store.GetTransaction(r.Context(), txId)
-		if err != nil {
-			if errors.Is(err, accounting.ErrTransactionNotFound) {
-				http.Error(w, "transaction not found", http.StatusNotFound)
-			} else {
-				http.Error(w, fmt.Sprintf("unable to get transaction: %v", err), http.StatusInternalServerError)
-			}
-			return
-		}
-
-		attID := transactionAttachmentID(tx)
-		if attID == nil {
-			http.Error(w, "no attachment", http.StatusNotFound)
+		if !h.attachmentLinked(w, r, txId, attID) {
 			return
 		}
 
-		att, err := h.FileStore.Get(r.Context(), *attID)
+		att, err := h.FileStore.Get(r.Context(), attID)
 		if err != nil {
 			if errors.Is(err, filestore.ErrNotFound) {
 				http.Error(w, "attachment file not found", http.StatusNotFound)
@@ -136,49 +181,116 @@ func (h *Handler) GetAttachment(txId uint) http.Handler {
 			return
 		}
 
-		filePath, err := h.FileStore.GetFilePath(r.Context(), *attID)
+		content, err := h.FileStore.ReadFile(r.Context(), attID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to resolve file path: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to read attachment: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", att.MimeType)
 		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", att.OriginalName))
-		http.ServeFile(w, r, filePath)
+		http.ServeContent(w, r, att.OriginalName, time.Time{}, bytes.NewReader(content))
 	})
 }
 
-// DeleteAttachment handles DELETE /fin/entries/{id}/attachment
-func (h *Handler) DeleteAttachment(txId uint) http.Handler {
+// GetAttachmentThumbnail handles GET /fin/entries/{id}/attachment/{attachmentId}/thumbnail, serving a JPEG preview.
+func (h *Handler) GetAttachmentThumbnail(txId, attID uint) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if h.FileStore == nil {
 			http.Error(w, "attachments not configured", http.StatusServiceUnavailable)
 			return
 		}
 
-		tx, err := h.Store.GetTransaction(r.Context(), txId)
+		if !h.attachmentLinked(w, r, txId, attID) {
+			return
+		}
+
+		thumb, err := h.FileStore.ReadThumbnail(r.Context(), attID)
 		if err != nil {
-			if errors.Is(err, accounting.ErrTransactionNotFound) {
-				http.Error(w, "transaction not found", http.StatusNotFound)
+			if errors.Is(err, filestore.ErrNotFound) {
+				http.Error(w, "attachment file not found", http.StatusNotFound)
+			} else if errors.Is(err, filestore.ErrNoThumbnail) {
+				http.Error(w, "thumbnail not available", http.StatusNotFound)
 			} else {
-				http.Error(w, fmt.Sprintf("unable to get transaction: %v", err), http.StatusInternalServerError)
+				http.Error(w, fmt.Sprintf("unable to get thumbnail: %v", err), http.StatusInternalServerError)
 			}
 			return
 		}
 
-		attID := transactionAttachmentID(tx)
-		if attID == nil {
-			http.Error(w, "no attachment", http.StatusNotFound)
+		w.Header().Set("Content-Type", "image/jpeg")
+		http.ServeContent(w, r, "thumbnail.jpg", time.Time{}, bytes.NewReader(thumb))
+	})
+}
+
+// UpdateAttachment handles PUT /fin/entries/{id}/attachment/{attachmentId}, only the caption can be changed.
+func (h *Handler) UpdateAttachment(txId, attID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := attachmentUpdatePayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
 
-		if err := h.FileStore.Delete(r.Context(), *attID); err != nil && !errors.Is(err, filestore.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("unable to delete attachment: %v", err), http.StatusInternalServerError)
+		if err := h.Store.UpdateTransactionAttachmentCaption(r.Context(), txId, attID, payload.Caption); err != nil {
+			if errors.Is(err, accounting.ErrTransactionAttachmentNotFound) {
+				http.Error(w, "attachment not found", http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update attachment: %v", err), http.StatusInternalServerError)
+			}
 			return
 		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
 
-		if err := h.Store.SetAttachmentID(r.Context(), txId, nil); err != nil {
-			http.Error(w, fmt.Sprintf("failed to update transaction: %v", err), http.StatusInternalServerError)
+// ReorderAttachments handles PUT /fin/entries/{id}/attachment/order with the attachment ids in the new order.
+func (h *Handler) ReorderAttachments(txId uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := attachmentOrderPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.Store.ReorderTransactionAttachments(r.Context(), txId, payload.Ids); err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to reorder attachments: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// DeleteAttachment handles DELETE /fin/entries/{id}/attachment/{attachmentId}
+func (h *Handler) DeleteAttachment(txId, attID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.FileStore == nil {
+			http.Error(w, "attachments not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := h.Store.RemoveTransactionAttachment(r.Context(), txId, attID); err != nil {
+			if errors.Is(err, accounting.ErrTransactionAttachmentNotFound) {
+				http.Error(w, "attachment not found", http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("failed to update transaction: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := h.FileStore.Delete(r.Context(), attID); err != nil && !errors.Is(err, filestore.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("unable to delete attachment: %v", err), http.StatusInternalServerError)
 			return
 		}
 
@@ -186,6 +298,33 @@ func (h *Handler) DeleteAttachment(txId uint) http.Handler {
 	})
 }
 
+// attachmentLinked verifies that the attachment belongs to the transaction, writing the error response if not.
+func (h *Handler) attachmentLinked(w http.ResponseWriter, r *http.Request, txId, attID uint) bool {
+	links, err := h.Store.ListTransactionAttachments(r.Context(), txId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to list attachments: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	for _, link := range links {
+		if link.AttachmentID == attID {
+			return true
+		}
+	}
+	http.Error(w, "attachment not found", http.StatusNotFound)
+	return false
+}
+
+func attachmentToPayload(att *filestore.Attachment, link accounting.TransactionAttachment) attachmentPayload {
+	return attachmentPayload{
+		Id:           att.Id,
+		OriginalName: att.OriginalName,
+		MimeType:     att.MimeType,
+		FileSize:     att.FileSize,
+		Caption:      link.Caption,
+		Position:     link.Position,
+	}
+}
+
 // transactionDate extracts the Date field from any transaction type.
 func transactionDate(tx accounting.Transaction) time.Time {
 	switch t := tx.(type) {
@@ -215,33 +354,3 @@ func transactionDate(tx accounting.Transaction) time.Time {
 		return time.Now()
 	}
 }
-
-// transactionAttachmentID extracts the AttachmentID field from any transaction type.
-func transactionAttachmentID(tx accounting.Transaction) *uint {
-	switch t := tx.(type) {
-	case accounting.Income:
-		return t.AttachmentID
-	case accounting.Expense:
-		return t.AttachmentID
-	case accounting.Transfer:
-		return t.AttachmentID
-	case accounting.StockBuy:
-		return t.AttachmentID
-	case accounting.StockSell:
-		return t.AttachmentID
-	case accounting.StockGrant:
-		return t.AttachmentID
-	case accounting.StockTransfer:
-		return t.AttachmentID
-	case accounting.StockVest:
-		return t.AttachmentID
-	case accounting.StockForfeit:
-		return t.AttachmentID
-	case accounting.BalanceStatus:
-		return t.AttachmentID
-	case accounting.Revaluation:
-		return t.AttachmentID
-	default:
-		return nil
-	}
-}
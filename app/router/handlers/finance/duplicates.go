@@ -0,0 +1,175 @@
+package finance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+const (
+	resolveDelete = "delete"
+	resolveMerge  = "merge"
+)
+
+type duplicateGroupPayload struct {
+	Confidence   float64              `json:"confidence"`
+	KeepId       uint                 `json:"keepId"`
+	Transactions []transactionPayload `json:"transactions"`
+}
+
+type duplicateListResponse struct {
+	Items []duplicateGroupPayload `json:"items"`
+}
+
+type resolveDuplicatesPayload struct {
+	Action string `json:"action"` // delete or merge
+	KeepId uint   `json:"keepId"`
+	Ids    []uint `json:"ids"` // the transactions of the group, keepId may be included
+}
+
+// ListDuplicates handles GET /fin/duplicates: likely duplicate transactions between startDate and
+// endDate (default: the last year), optionally limited to accountIds. windowDays and
+// minConfidence tune the matching.
+func (h *Handler) ListDuplicates() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		startDate, endDate, err := getDateRange(
+			r.URL.Query().Get("startDate"), r.URL.Query().Get("endDate"),
+			now.AddDate(-1, 0, 0), now,
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		accountIds, err := parseIntListParam(r, "accountIds")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		windowDays, err := parseIntQueryParam(r, "windowDays", accounting.DefaultDuplicateWindowDays)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minConfidence := accounting.DefaultDuplicateMinConfidence
+		if s := r.URL.Query().Get("minConfidence"); s != "" {
+			if _, err := fmt.Sscanf(s, "%g", &minConfidence); err != nil {
+				http.Error(w, "invalid minConfidence format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		groups, err := h.Store.FindDuplicates(r.Context(), accounting.DuplicateOpts{
+			StartDate:     startDate,
+			EndDate:       endDate,
+			AccountIds:    accountIds,
+			WindowDays:    windowDays,
+			MinConfidence: minConfidence,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to find duplicates: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		response := duplicateListResponse{Items: make([]duplicateGroupPayload, 0, len(groups))}
+		for _, g := range groups {
+			item := duplicateGroupPayload{Confidence: g.Confidence, KeepId: g.KeepID}
+			for _, tx := range g.Transactions {
+				item.Transactions = append(item.Transactions, transactionToPayload(tx))
+			}
+			response.Items = append(response.Items, item)
+		}
+
+		respJson, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+// ResolveDuplicates handles POST /fin/duplicates/resolve: keeps one transaction of a group and
+// either deletes the others or merges their notes, category and attachments into it first.
+func (h *Handler) ResolveDuplicates() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := resolveDuplicatesPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.KeepId == 0 {
+			http.Error(w, "keepId is required", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		switch payload.Action {
+		case resolveMerge:
+			err = h.mergeDuplicates(r, payload.KeepId, payload.Ids)
+		case resolveDelete:
+			err = h.deleteDuplicates(r, payload.KeepId, payload.Ids)
+		default:
+			http.Error(w, fmt.Sprintf("unknown action %q, expected delete or merge", payload.Action), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, accounting.ErrTransactionNotFound) {
+				http.Error(w, "entry not found", http.StatusNotFound)
+			} else if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to resolve duplicates: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// mergeDuplicates merges the given transactions into keepID and releases the attachments whose
+// link was dropped because keepID already had them.
+func (h *Handler) mergeDuplicates(r *http.Request, keepID uint, ids []uint) error {
+	dropped, err := h.Store.MergeTransactions(r.Context(), keepID, ids)
+	if err != nil {
+		return err
+	}
+	if h.FileStore != nil {
+		for _, id := range dropped {
+			_ = h.FileStore.Delete(r.Context(), id)
+		}
+	}
+	return nil
+}
+
+// deleteDuplicates deletes the given transactions except keepID, together with their attachment files.
+func (h *Handler) deleteDuplicates(r *http.Request, keepID uint, ids []uint) error {
+	if _, err := h.Store.GetTransaction(r.Context(), keepID); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id == keepID {
+			continue
+		}
+		attachments, _ := h.Store.ListTransactionAttachments(r.Context(), id)
+		if err := h.Store.DeleteTransaction(r.Context(), id); err != nil {
+			return err
+		}
+		if h.FileStore != nil {
+			for _, att := range attachments {
+				_ = h.FileStore.Delete(r.Context(), att.AttachmentID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package finance
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+func TestFinanceHandler_Duplicates(t *testing.T) {
+	h, end := SampleHandler(t)
+	defer end()
+	ctx := t.Context()
+
+	date := time.Date(2030, 5, 10, 0, 0, 0, 0, time.UTC)
+	first, err := h.Store.CreateExpense(ctx, accounting.Expense{Description: "Electricity May", Amount: 80, AccountID: 1, Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := h.Store.CreateExpense(ctx, accounting.Expense{Description: "electricity may", Amount: 80, AccountID: 1, Date: date.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/fin/duplicates?startDate=2030-01-01&endDate=2030-12-31", nil)
+	h.ListDuplicates().ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var got duplicateListResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != 1 || len(got.Items[0].Transactions) != 2 || got.Items[0].KeepId != first {
+		t.Fatalf("expected one group keeping the first entry, got %+v", got.Items)
+	}
+
+	resolve := func(body string) int {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/fin/duplicates/resolve", bytes.NewBufferString(body))
+		h.ResolveDuplicates().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	if code := resolve(`{"action":"replace","keepId":1,"ids":[2]}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown action, got %d", code)
+	}
+	body, _ := json.Marshal(resolveDuplicatesPayload{Action: resolveDelete, KeepId: first, Ids: []uint{first, second}})
+	if code := resolve(string(body)); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if _, err := h.Store.GetTransaction(ctx, second); err == nil {
+		t.Error("expected the duplicate to be deleted")
+	}
+	if _, err := h.Store.GetTransaction(ctx, first); err != nil {
+		t.Errorf("expected the kept entry to remain, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package finance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+type savingsGoalAccountPayload struct {
+	AccountId uint    `json:"accountId"`
+	Share     float64 `json:"share"`
+}
+
+type savingsGoalPayload struct {
+	Id           uint                        `json:"id"`
+	Name         string                      `json:"name"`
+	Notes        string                      `json:"notes,omitempty"`
+	Icon         string                      `json:"icon"`
+	TargetAmount float64                     `json:"targetAmount"`
+	TargetDate   dateOnlyTime                `json:"targetDate"`
+	Accounts     []savingsGoalAccountPayload `json:"accounts"`
+	Progress     *goalProgressPayload        `json:"progress,omitempty"`
+}
+
+type savingsGoalUpdatePayload struct {
+	Name         *string                      `json:"name"`
+	Notes        *string                      `json:"notes"`
+	Icon         *string                      `json:"icon"`
+	TargetAmount *float64                     `json:"targetAmount"`
+	TargetDate   *dateOnlyTimePtr             `json:"targetDate"`
+	Accounts     *[]savingsGoalAccountPayload `json:"accounts"`
+}
+
+type goalProgressPayload struct {
+	Date            dateOnlyTime  `json:"date"`
+	Current         float64       `json:"current"`
+	Remaining       float64       `json:"remaining"`
+	Progress        float64       `json:"progress"`
+	RequiredMonthly float64       `json:"requiredMonthly"`
+	MonthlyRate     float64       `json:"monthlyRate"`
+	ProjectedDate   *dateOnlyTime `json:"projectedDate,omitempty"`
+	OnTrack         bool          `json:"onTrack"`
+	Unconverted     bool          `json:"unconverted,omitempty"`
+}
+
+type savingsGoalListResponse struct {
+	Items []savingsGoalPayload `json:"items"`
+}
+
+func goalAccountsFromPayload(in []savingsGoalAccountPayload) []accounting.SavingsGoalAccount {
+	out := make([]accounting.SavingsGoalAccount, len(in))
+	for i, a := range in {
+		out[i] = accounting.SavingsGoalAccount{AccountID: a.AccountId, Share: a.Share}
+	}
+	return out
+}
+
+func savingsGoalToPayload(in accounting.SavingsGoal) savingsGoalPayload {
+	accounts := make([]savingsGoalAccountPayload, len(in.Accounts))
+	for i, a := range in.Accounts {
+		accounts[i] = savingsGoalAccountPayload{AccountId: a.AccountID, Share: a.Share}
+	}
+	return savingsGoalPayload{
+		Id:           in.ID,
+		Name:         in.Name,
+		Notes:        in.Notes,
+		Icon:         in.Icon,
+		TargetAmount: in.TargetAmount,
+		TargetDate:   dateOnlyTime{Time: in.TargetDate},
+		Accounts:     accounts,
+	}
+}
+
+func goalProgressToPayload(in accounting.SavingsGoalProgress) *goalProgressPayload {
+	out := goalProgressPayload{
+		Date:            dateOnlyTime{Time: in.Date},
+		Current:         in.Current,
+		Remaining:       in.Remaining,
+		Progress:        in.Progress,
+		RequiredMonthly: in.RequiredMonthly,
+		MonthlyRate:     in.MonthlyRate,
+		OnTrack:         in.OnTrack,
+		Unconverted:     in.Unconverted,
+	}
+	if in.ProjectedDate != nil {
+		out.ProjectedDate = &dateOnlyTime{Time: *in.ProjectedDate}
+	}
+	return &out
+}
+
+// ListSavingsGoals returns all goals together with their progress as of today.
+func (h *Handler) ListSavingsGoals() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		goals, err := h.Store.ListSavingsGoals(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list savings goals: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		items := make([]savingsGoalPayload, len(goals))
+		for i, g := range goals {
+			items[i] = savingsGoalToPayload(g)
+			progress, err := h.Store.GoalProgress(r.Context(), g.ID, now, 0)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to compute goal progress: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			items[i].Progress = goalProgressToPayload(progress)
+		}
+
+		respJson, err := json.Marshal(savingsGoalListResponse{Items: items})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) CreateSavingsGoal() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := savingsGoalPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		goal := accounting.SavingsGoal{
+			Name:         payload.Name,
+			Notes:        payload.Notes,
+			Icon:         payload.Icon,
+			TargetAmount: payload.TargetAmount,
+			TargetDate:   payload.TargetDate.Time,
+			Accounts:     goalAccountsFromPayload(payload.Accounts),
+		}
+		id, err := h.Store.CreateSavingsGoal(r.Context(), goal)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrAccountNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to store savings goal in DB: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		goal.ID = id
+		respJson, err := json.Marshal(savingsGoalToPayload(goal))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) UpdateSavingsGoal(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := savingsGoalUpdatePayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		item := accounting.SavingsGoalUpdate{
+			Name:         payload.Name,
+			Notes:        payload.Notes,
+			Icon:         payload.Icon,
+			TargetAmount: payload.TargetAmount,
+			TargetDate:   dateOnlyPtrToTime(payload.TargetDate),
+		}
+		if payload.Accounts != nil {
+			accounts := goalAccountsFromPayload(*payload.Accounts)
+			item.Accounts = &accounts
+		}
+
+		err := h.Store.UpdateSavingsGoal(r.Context(), item, id)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrSavingsGoalNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, accounting.ErrAccountNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrNoChanges) {
+				http.Error(w, "no changes applied", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update savings goal: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *Handler) DeleteSavingsGoal(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.Store.DeleteSavingsGoal(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, accounting.ErrSavingsGoalNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to delete savings goal: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// GoalProgress returns the progress of a single goal, optional query params:
+// date (YYYY-MM-DD, defaults to today) and lookback (months used for the contribution rate).
+func (h *Handler) GoalProgress(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		date := time.Now()
+		if v := r.URL.Query().Get("date"); v != "" {
+			d, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid date: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			date = d
+		}
+		lookback := 0
+		if v := r.URL.Query().Get("lookback"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid lookback: must be a positive number of months", http.StatusBadRequest)
+				return
+			}
+			lookback = n
+		}
+
+		progress, err := h.Store.GoalProgress(r.Context(), id, date, lookback)
+		if err != nil {
+			if errors.Is(err, accounting.ErrSavingsGoalNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("unable to compute goal progress: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		respJson, err := json.Marshal(goalProgressToPayload(progress))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
@@ -270,49 +270,12 @@ type instrumentLookupResponse struct {
 	Notes    string `json:"notes"`
 }
 
-// massiveTypeToAppType maps raw Massive ticker type codes to the app's Type values.
-// Anything not listed passes through unchanged (shown via the dialog's "Other" field).
-var massiveTypeToAppType = map[string]string{
-	"CS":   "Stock",
-	"ADRC": "Stock",
-	"ETF":  "ETF",
-	"BOND": "Bond",
-}
-
-// massiveMicToExchange maps Massive primary-exchange MIC codes to the app's exchange names.
-// Anything not listed passes through unchanged (shown via the dialog's "Other" field).
-var massiveMicToExchange = map[string]string{
-	"XNYS": "NYSE",
-	"XNAS": "NASDAQ",
-	"XLON": "LSE",
-	"XTSE": "TSX",
-	"XPAR": "Euronext",
-	"XAMS": "Euronext",
-	"XBRU": "Euronext",
-	"XETR": "XETRA",
-	"XSWX": "SIX",
-	"XTKS": "JPX (Tokyo)",
-	"XHKG": "HKEX",
-	"XASX": "ASX",
-	"XMAD": "BME (Madrid)",
-	"XMIL": "Borsa Italiana",
-	"XSTO": "Nasdaq Nordic",
-}
-
 func mapTickerDetails(d importer.TickerDetails) instrumentLookupResponse {
-	t := d.Type
-	if mapped, ok := massiveTypeToAppType[d.Type]; ok {
-		t = mapped
-	}
-	ex := d.Exchange
-	if mapped, ok := massiveMicToExchange[d.Exchange]; ok {
-		ex = mapped
-	}
 	return instrumentLookupResponse{
 		Name:     d.Name,
 		Currency: d.Currency,
-		Type:     t,
-		Exchange: ex,
+		Type:     importer.MassiveAppType(d.Type),
+		Exchange: importer.MassiveAppExchange(d.Exchange),
 		Notes:    d.Notes,
 	}
 }
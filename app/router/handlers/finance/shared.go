@@ -0,0 +1,319 @@
+package finance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+type personPayload struct {
+	Id        uint   `json:"id"`
+	Name      string `json:"name"`
+	AccountId uint   `json:"accountId"`
+}
+
+type personUpdatePayload struct {
+	Name *string `json:"name"`
+}
+
+type personListResponse struct {
+	Items []personPayload `json:"items"`
+}
+
+type expenseSharePayload struct {
+	PersonId     uint    `json:"personId"`
+	Value        float64 `json:"value,omitempty"`
+	Amount       float64 `json:"amount"`
+	ReceivableId uint    `json:"receivableId,omitempty"`
+}
+
+type expenseSplitPayload struct {
+	Rule        string                `json:"rule"`
+	IncludeSelf bool                  `json:"includeSelf"`
+	Shares      []expenseSharePayload `json:"shares"`
+}
+
+type settleUpBalancePayload struct {
+	Person  personPayload `json:"person"`
+	Balance float64       `json:"balance"`
+}
+
+type settleUpListResponse struct {
+	Date  dateOnlyTime             `json:"date"`
+	Items []settleUpBalancePayload `json:"items"`
+}
+
+type settleUpPayload struct {
+	AccountId uint          `json:"accountId"`
+	Date      *dateOnlyTime `json:"date"`
+}
+
+type settleUpResponse struct {
+	TransactionId uint `json:"transactionId"`
+}
+
+func personToPayload(in accounting.Person) personPayload {
+	return personPayload{Id: in.ID, Name: in.Name, AccountId: in.AccountID}
+}
+
+func (h *Handler) ListPeople() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		people, err := h.Store.ListPeople(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list people: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		items := make([]personPayload, len(people))
+		for i, p := range people {
+			items[i] = personToPayload(p)
+		}
+
+		respJson, err := json.Marshal(personListResponse{Items: items})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) CreatePerson() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := personPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		person := accounting.Person{Name: payload.Name, AccountID: payload.AccountId}
+		id, err := h.Store.CreatePerson(r.Context(), person)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrAccountNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to store person in DB: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		person.ID = id
+		respJson, err := json.Marshal(personToPayload(person))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+func (h *Handler) UpdatePerson(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := personUpdatePayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err := h.Store.UpdatePerson(r.Context(), accounting.PersonUpdate{Name: payload.Name}, id)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrPersonNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, accounting.ErrNoChanges) {
+				http.Error(w, "no changes applied", http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update person: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *Handler) DeletePerson(id uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.Store.DeletePerson(r.Context(), id)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrPersonNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to delete person: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *Handler) GetExpenseSplit(txID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		split, err := h.Store.GetExpenseSplit(r.Context(), txID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get expense split: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		shares := make([]expenseSharePayload, len(split.Shares))
+		for i, s := range split.Shares {
+			shares[i] = expenseSharePayload{PersonId: s.PersonID, Value: s.Value, Amount: s.Amount, ReceivableId: s.ReceivableID}
+		}
+
+		respJson, err := json.Marshal(expenseSplitPayload{Rule: string(split.Rule), IncludeSelf: split.IncludeSelf, Shares: shares})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+// SetExpenseSplit shares an expense with people, replacing any previous split and its receivables.
+func (h *Handler) SetExpenseSplit(txID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := expenseSplitPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		split := accounting.ExpenseSplit{
+			Rule:        accounting.SplitRule(payload.Rule),
+			IncludeSelf: payload.IncludeSelf,
+			Shares:      make([]accounting.ExpenseShare, len(payload.Shares)),
+		}
+		for i, s := range payload.Shares {
+			split.Shares[i] = accounting.ExpenseShare{PersonID: s.PersonId, Value: s.Value}
+		}
+
+		err := h.Store.SetExpenseSplit(r.Context(), txID, split)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrTransactionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, accounting.ErrPersonNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to share expense: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (h *Handler) ClearExpenseSplit(txID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.Store.ClearExpenseSplit(r.Context(), txID); err != nil {
+			http.Error(w, fmt.Sprintf("unable to remove expense split: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// SettleUpBalances returns the net balance with every person, optional query param date (YYYY-MM-DD, defaults to today).
+func (h *Handler) SettleUpBalances() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		date := time.Now()
+		if v := r.URL.Query().Get("date"); v != "" {
+			d, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid date: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			date = d
+		}
+
+		balances, err := h.Store.SettleUpBalances(r.Context(), date)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to compute balances: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		items := make([]settleUpBalancePayload, len(balances))
+		for i, b := range balances {
+			items[i] = settleUpBalancePayload{Person: personToPayload(b.Person), Balance: b.Balance}
+		}
+
+		respJson, err := json.Marshal(settleUpListResponse{Date: dateOnlyTime{Time: date}, Items: items})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
+
+// SettleUp creates the transfer that settles the balance with a person.
+func (h *Handler) SettleUp(personID uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+
+		payload := settleUpPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		date := time.Now()
+		if payload.Date != nil {
+			date = payload.Date.Time
+		}
+
+		id, err := h.Store.SettleUp(r.Context(), personID, payload.AccountId, date)
+		if err != nil {
+			if errors.As(err, &validationErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, accounting.ErrPersonNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, accounting.ErrAccountNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to settle up: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		respJson, err := json.Marshal(settleUpResponse{TransactionId: id})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(respJson)
+	})
+}
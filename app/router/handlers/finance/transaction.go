@@ -485,15 +485,10 @@ func (h *Handler) UpdateTx(Id uint) http.Handler {
 
 func (h *Handler) DeleteTx(Id uint) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Clean up attachment if exists
-		tx, err := h.Store.GetTransaction(r.Context(), Id)
-		if err == nil {
-			if attID := transactionAttachmentID(tx); attID != nil && h.FileStore != nil {
-				_ = h.FileStore.Delete(r.Context(), *attID)
-			}
-		}
+		// Remember the attachments to clean up their files once the transaction is gone
+		attachments, _ := h.Store.ListTransactionAttachments(r.Context(), Id)
 
-		err = h.Store.DeleteTransaction(r.Context(), Id)
+		err := h.Store.DeleteTransaction(r.Context(), Id)
 		if err != nil {
 			if errors.Is(err, accounting.ErrEntryNotFound) || errors.Is(err, accounting.ErrTransactionNotFound) {
 				http.Error(w, "entry not found", http.StatusNotFound)
@@ -505,6 +500,12 @@ func (h *Handler) DeleteTx(Id uint) http.Handler {
 			return
 		}
 
+		if h.FileStore != nil {
+			for _, att := range attachments {
+				_ = h.FileStore.Delete(r.Context(), att.AttachmentID)
+			}
+		}
+
 		w.WriteHeader(http.StatusOK)
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/andresbott/etna/internal/filestore"
 	"github.com/andresbott/etna/internal/marketdata"
@@ -28,6 +29,41 @@ type statsResponse struct {
 	FXSeries             int    `json:"fxSeries"`
 	FXPoints             int    `json:"fxPoints"`
 	LogLevel             string `json:"logLevel"`
+	// AttachmentCheck is the result of the last attachment integrity check, omitted if it never ran.
+	AttachmentCheck *attachmentCheckStats `json:"attachmentCheck,omitempty"`
+}
+
+type attachmentCheckStats struct {
+	CheckedAt          time.Time `json:"checkedAt"`
+	Attachments        int       `json:"attachments"`
+	Orphans            []uint    `json:"orphans"`
+	OrphanBytes        int64     `json:"orphanBytes"`
+	Missing            []uint    `json:"missing"`
+	SizeMismatches     []uint    `json:"sizeMismatches"`
+	RefCountMismatches []uint    `json:"refCountMismatches"`
+	Purged             int       `json:"purged"`
+}
+
+func toAttachmentCheckStats(r *filestore.IntegrityReport) *attachmentCheckStats {
+	if r == nil {
+		return nil
+	}
+	nonNil := func(ids []uint) []uint {
+		if ids == nil {
+			return []uint{}
+		}
+		return ids
+	}
+	return &attachmentCheckStats{
+		CheckedAt:          r.CheckedAt,
+		Attachments:        r.Attachments,
+		Orphans:            nonNil(r.Orphans),
+		OrphanBytes:        r.OrphanBytes,
+		Missing:            nonNil(r.Missing),
+		SizeMismatches:     nonNil(r.SizeMismatches),
+		RefCountMismatches: nonNil(r.RefCountMismatches),
+		Purged:             r.Purged,
+	}
 }
 
 // Stats returns storage statistics: database size and market data / FX volume.
@@ -44,12 +80,18 @@ func (h *Handler) Stats() http.Handler {
 			return
 		}
 		var attachmentsSize int64
+		var attachmentCheck *filestore.IntegrityReport
 		if h.FileStore != nil {
 			attachmentsSize, err = h.FileStore.TotalSize(r.Context())
 			if err != nil {
 				http.Error(w, fmt.Sprintf("unable to measure attachments size: %s", err.Error()), http.StatusInternalServerError)
 				return
 			}
+			attachmentCheck, err = h.FileStore.LastIntegrityReport(r.Context())
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read attachment check: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
 		}
 		resp := statsResponse{
 			DBSizeBytes:          size,
@@ -59,6 +101,7 @@ func (h *Handler) Stats() http.Handler {
 			FXSeries:             ds.FXSeries,
 			FXPoints:             ds.FXPoints,
 			LogLevel:             h.LogLevel,
+			AttachmentCheck:      toAttachmentCheckStats(attachmentCheck),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
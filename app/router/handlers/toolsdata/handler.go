@@ -1,6 +1,7 @@
 package toolsdata
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -313,15 +314,15 @@ func (h *Handler) GetAttachment(toolType string, id uint) http.Handler {
 			return
 		}
 
-		filePath, err := h.FileStore.GetFilePath(r.Context(), *cs.AttachmentID)
+		content, err := h.FileStore.ReadFile(r.Context(), *cs.AttachmentID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to resolve file path: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to read attachment: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", att.MimeType)
 		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", att.OriginalName))
-		http.ServeFile(w, r, filePath)
+		http.ServeContent(w, r, att.OriginalName, time.Time{}, bytes.NewReader(content))
 	})
 }
 
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/filestore"
+	"github.com/andresbott/etna/internal/toolsdata"
+)
+
+const (
+	AttachmentCheckTaskName = "attachment-check"
+	AttachmentPurgeTaskName = "attachment-purge"
+)
+
+// AttachmentCheckTaskDef is the task definition for the attachment integrity check.
+var AttachmentCheckTaskDef = TaskDef{
+	ID:          AttachmentCheckTaskName,
+	Name:        "Attachment integrity check",
+	Description: "Report attachments no longer used by any transaction or case study, and attachments whose file is missing or has an unexpected size. Files stored before deduplication are hashed along the way. Attachments saved in the last 24 hours are not considered unused yet.",
+}
+
+// AttachmentPurgeTaskDef is the task definition for the attachment garbage collection.
+var AttachmentPurgeTaskDef = TaskDef{
+	ID:          AttachmentPurgeTaskName,
+	Name:        "Attachment cleanup",
+	Description: "Run the attachment integrity check, delete unused attachments and correct reference counts.",
+}
+
+// NewAttachmentCheckTaskFn returns a task function that checks the attachment storage; with purge set,
+// orphaned attachments are deleted. Files not hashed yet are hashed first, so they take part in the
+// deduplication. The result is shown in the stats page.
+func NewAttachmentCheckTaskFn(store *accounting.Store, fileStore *filestore.Store, tdStore *toolsdata.Store, purge bool, l *slog.Logger) func(ctx context.Context) error {
+	taskName := AttachmentCheckTaskName
+	if purge {
+		taskName = AttachmentPurgeTaskName
+	}
+	return func(ctx context.Context) error {
+		if store == nil || fileStore == nil || tdStore == nil {
+			return fmt.Errorf("accounting, attachment and tools data stores are required")
+		}
+		taskLogInfo(ctx, l, taskName, "starting attachment integrity check")
+
+		hashed, err := fileStore.BackfillContentHashes(ctx)
+		if err != nil {
+			taskLogError(ctx, l, taskName, fmt.Sprintf("unable to hash existing attachments: %v", err))
+			return err
+		}
+		if hashed > 0 {
+			taskLogInfo(ctx, l, taskName, fmt.Sprintf("hashed %d attachment(s) stored before deduplication", hashed))
+		}
+
+		refs, err := attachmentReferences(ctx, store, tdStore)
+		if err != nil {
+			taskLogError(ctx, l, taskName, fmt.Sprintf("unable to collect attachment references: %v", err))
+			return err
+		}
+		report, err := fileStore.CheckIntegrity(ctx, refs, purge)
+		if err != nil {
+			taskLogError(ctx, l, taskName, fmt.Sprintf("integrity check failed: %v", err))
+			return err
+		}
+
+		if len(report.Missing) > 0 {
+			taskLogWarn(ctx, l, taskName, fmt.Sprintf("%d attachment(s) with missing file: %v", len(report.Missing), report.Missing))
+		}
+		if len(report.SizeMismatches) > 0 {
+			taskLogWarn(ctx, l, taskName, fmt.Sprintf("%d attachment(s) with unexpected size: %v", len(report.SizeMismatches), report.SizeMismatches))
+		}
+		if len(report.Orphans) > 0 {
+			taskLogInfo(ctx, l, taskName, fmt.Sprintf("%d unused attachment(s), %d bytes: %v", len(report.Orphans), report.OrphanBytes, report.Orphans))
+		}
+		taskLogInfo(ctx, l, taskName, fmt.Sprintf("attachment check completed, %d checked, %d purged, %d reference count(s) repaired",
+			report.Attachments, report.Purged, report.Repaired),
+			slog.Int("checked", report.Attachments), slog.Int("orphans", len(report.Orphans)), slog.Int("missing", len(report.Missing)))
+		return nil
+	}
+}
+
+// attachmentReferences counts the references to each attachment from transactions and case studies.
+func attachmentReferences(ctx context.Context, store *accounting.Store, tdStore *toolsdata.Store) (map[uint]int, error) {
+	refs, err := store.AttachmentReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	studies, err := tdStore.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cs := range studies {
+		if cs.AttachmentID != nil {
+			refs[*cs.AttachmentID]++
+		}
+	}
+	return refs, nil
+}
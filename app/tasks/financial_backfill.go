@@ -57,6 +57,7 @@ func NewFinancialBackfillTaskFn(store *marketdata.Store, l *slog.Logger, client
 			slog.String("start", start.Format(dateFmt)), slog.String("end", end.Format(dateFmt)), slog.Int("instruments", len(instruments)), slog.Int("batchSizeDays", BackfillBatchSize))
 
 		for _, inst := range instruments {
+			preferRecordedSource(client, inst.Symbol, func() (string, error) { return store.PriceSource(ctx, inst.Symbol) })
 			for batchStart := start; batchStart.Before(end); batchStart = batchStart.Add(BackfillBatchSize * 24 * time.Hour) {
 				batchEnd := batchStart.Add(BackfillBatchSize * 24 * time.Hour)
 				if batchEnd.After(end) {
@@ -110,6 +111,7 @@ func NewFinancialBackfillTaskFn(store *marketdata.Store, l *slog.Logger, client
 					taskLogError(ctx, l, FinancialBackfillTaskName, fmt.Sprintf("financial backfill: ingest %s: %v", inst.Symbol, err), slog.String("symbol", inst.Symbol), slog.String("error", err.Error()))
 					return fmt.Errorf("ingest %s: %w", inst.Symbol, err)
 				}
+				recordSource(ctx, client, inst.Symbol, func(src string) error { return store.SetPriceSource(ctx, inst.Symbol, src) })
 
 				first, last := newPoints[0].Time.Format(dateFmt), newPoints[len(newPoints)-1].Time.Format(dateFmt)
 				taskLogInfo(ctx, l, FinancialBackfillTaskName, fmt.Sprintf("financial backfill: %s — %d points (%s to %s)", inst.Symbol, len(newPoints), first, last),
@@ -116,6 +116,7 @@ func backfillInstrumentPrices(ctx context.Context, store *marketdata.Store, clie
 			tempo.Info(ctx, fmt.Sprintf("financial import: skip %s — already have data through %s (last trading day)", inst.Symbol, lastTrading.Format(dateFmt)))
 			continue
 		}
+		preferRecordedSource(client, inst.Symbol, func() (string, error) { return store.PriceSource(ctx, inst.Symbol) })
 		points, fetchErr := FetchWith429Retry(ctx, deadline, "financial import: "+inst.Symbol, func() ([]importer.PricePoint, error) {
 			return client.FetchDailyPrices(ctx, inst.Symbol, start, end)
 		})
@@ -138,6 +139,7 @@ func backfillInstrumentPrices(ctx context.Context, store *marketdata.Store, clie
 			tempo.Error(ctx, fmt.Sprintf("financial import: ingest %s: %v", inst.Symbol, err))
 			return fmt.Errorf("ingest %s: %w", inst.Symbol, err)
 		}
+		recordSource(ctx, client, inst.Symbol, func(src string) error { return store.SetPriceSource(ctx, inst.Symbol, src) })
 		first, last := newPoints[0].Time.Format(dateFmt), newPoints[len(newPoints)-1].Time.Format(dateFmt)
 		tempo.Info(ctx, fmt.Sprintf("financial import: imported %s — %d points (%s to %s)", inst.Symbol, len(newPoints), first, last))
 	}
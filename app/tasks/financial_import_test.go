@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andresbott/etna/internal/marketdata"
+	"github.com/andresbott/etna/internal/marketdata/importer"
+	"github.com/go-bumbu/testdbs"
+	"golang.org/x/text/currency"
+)
+
+type fakePriceClient struct {
+	bySymbol map[string][]importer.PricePoint
+	calls    map[string]int
+}
+
+func (f *fakePriceClient) FetchDailyPrices(_ context.Context, symbol string, _, _ time.Time) ([]importer.PricePoint, error) {
+	f.calls[symbol]++
+	return f.bySymbol[symbol], nil
+}
+
+func TestFinancialImportTask_recordsSource(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			ctx := t.Context()
+			store, err := marketdata.NewStore(db.ConnDbName("TestFinancialImportTaskSource"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, sym := range []string{"AAA", "BBB", "CCC"} {
+				if _, err := store.CreateInstrument(ctx, marketdata.Instrument{Symbol: sym, Name: sym, Currency: currency.USD, Type: "ETF"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// CCC was served by Yahoo in an earlier run
+			if err := store.SetPriceSource(ctx, "CCC", importer.ProviderYahoo); err != nil {
+				t.Fatal(err)
+			}
+
+			day := dayNormalize(time.Now().UTC()).AddDate(0, 0, -1)
+			points := []importer.PricePoint{{Time: day, Open: 1, High: 1, Low: 1, Close: 1}}
+			massive := &fakePriceClient{bySymbol: map[string][]importer.PricePoint{"AAA": points, "CCC": points}, calls: map[string]int{}}
+			yahoo := &fakePriceClient{bySymbol: map[string][]importer.PricePoint{"AAA": points, "BBB": points, "CCC": points}, calls: map[string]int{}}
+			client, err := importer.NewChainClient([]string{importer.ProviderMassive, importer.ProviderYahoo}, []importer.Client{massive, yahoo})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := NewFinancialImportTaskFn(store, client)(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"AAA": importer.ProviderMassive, "BBB": importer.ProviderYahoo, "CCC": importer.ProviderYahoo}
+			for sym, provider := range want {
+				src, err := store.PriceSource(ctx, sym)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if src != provider {
+					t.Errorf("%s: expected source %q, got %q", sym, provider, src)
+				}
+			}
+			if massive.calls["CCC"] != 0 {
+				t.Errorf("expected the recorded provider to be asked first for CCC, massive got %d calls", massive.calls["CCC"])
+			}
+		})
+	}
+}
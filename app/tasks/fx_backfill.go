@@ -41,7 +41,7 @@ func NewFXBackfillTaskFn(store *marketdata.Store, l *slog.Logger, mainCurrency s
 		taskLogInfo(ctx, l, FXBackfillTaskName, "starting currency exchange backfill (1 year)")
 
 		if client == nil {
-			return fmt.Errorf("no FX importer configured — set API key via ETNA_MARKETDATAIMPORTERS_MASSIVE_APIKEYS_0 or config file")
+			return fmt.Errorf("no FX importer configured — set API key via ETNA_MARKETDATAIMPORTERS_MASSIVE_APIKEYS_0 or enable the ECB or Yahoo importer in the config file")
 		}
 		if mainCurrency == "" || len(currencies) == 0 {
 			return fmt.Errorf("no currency pairs configured — set mainCurrency and currencies in config")
@@ -71,6 +71,8 @@ func NewFXBackfillTaskFn(store *marketdata.Store, l *slog.Logger, mainCurrency s
 				continue
 			}
 			pairLabel := mainCurrency + "/" + secondary
+			pairKey := importer.FXKey(mainCurrency, secondary)
+			preferRecordedSource(client, pairKey, func() (string, error) { return store.RateSource(ctx, mainCurrency, secondary) })
 			for batchStart := start; batchStart.Before(end); batchStart = batchStart.Add(FXBackfillBatchSize * 24 * time.Hour) {
 				batchEnd := batchStart.Add(FXBackfillBatchSize * 24 * time.Hour)
 				if batchEnd.After(end) {
@@ -116,6 +118,7 @@ func NewFXBackfillTaskFn(store *marketdata.Store, l *slog.Logger, mainCurrency s
 					taskLogError(ctx, l, FXBackfillTaskName, fmt.Sprintf("fx backfill: ingest %s/%s: %v", mainCurrency, secondary, err), slog.String("pair", pairLabel), slog.String("error", err.Error()))
 					return fmt.Errorf("ingest %s/%s: %w", mainCurrency, secondary, err)
 				}
+				recordSource(ctx, client, pairKey, func(src string) error { return store.SetRateSource(ctx, mainCurrency, secondary, src) })
 				first, last := newPoints[0].Time.Format(dateFmt), newPoints[len(newPoints)-1].Time.Format(dateFmt)
 				taskLogInfo(ctx, l, FXBackfillTaskName, fmt.Sprintf("fx backfill: %s/%s — %d points (%s to %s)", mainCurrency, secondary, len(newPoints), first, last),
 					slog.String("pair", pairLabel), slog.Int("points", len(newPoints)), slog.String("dateRange", first+" to "+last))
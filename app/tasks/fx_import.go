@@ -58,7 +58,7 @@ func NewFXImportTaskFn(store *marketdata.Store, mainCurrency string, currencies
 		tempo.Info(ctx, "starting currency exchange import")
 
 		if client == nil {
-			return fmt.Errorf("no FX importer configured — set API key via ETNA_MARKETDATAIMPORTERS_MASSIVE_APIKEYS_0 or config file")
+			return fmt.Errorf("no FX importer configured — set API key via ETNA_MARKETDATAIMPORTERS_MASSIVE_APIKEYS_0 or enable the ECB or Yahoo importer in the config file")
 		}
 
 		if mainCurrency != "" && len(currencies) > 0 {
@@ -107,6 +107,8 @@ func backfillFXRates(ctx context.Context, store *marketdata.Store, client import
 		existingTimes := daySetFromRecords(existing, func(r marketdata.RateRecord) time.Time { return r.Time })
 
 		pairLabel := mainCurrency + "/" + secondary
+		pairKey := importer.FXKey(mainCurrency, secondary)
+		preferRecordedSource(client, pairKey, func() (string, error) { return store.RateSource(ctx, mainCurrency, secondary) })
 		points, fetchErr := FetchWith429Retry(ctx, deadline, "fx import: "+pairLabel, func() ([]importer.RatePoint, error) {
 			return client.FetchDailyRates(ctx, mainCurrency, secondary, start, end)
 		})
@@ -134,6 +136,7 @@ func backfillFXRates(ctx context.Context, store *marketdata.Store, client import
 			tempo.Error(ctx, fmt.Sprintf("fx import: ingest %s/%s: %v", mainCurrency, secondary, err))
 			return fmt.Errorf("ingest %s/%s: %w", mainCurrency, secondary, err)
 		}
+		recordSource(ctx, client, pairKey, func(src string) error { return store.SetRateSource(ctx, mainCurrency, secondary, src) })
 		first, last := newPoints[0].Time.Format(dateFmt), newPoints[len(newPoints)-1].Time.Format(dateFmt)
 		tempo.Info(ctx, fmt.Sprintf("fx import: imported %s/%s — %d points (%s to %s)", mainCurrency, secondary, len(newPoints), first, last))
 	}
@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	csvimportHandler "github.com/andresbott/etna/app/router/handlers/csvimport"
+	"github.com/andresbott/etna/internal/csvimport"
+)
+
+const ImportInboxTaskName = "import-inbox"
+
+// ImportInboxTaskDef is the task definition for the inbox import.
+var ImportInboxTaskDef = TaskDef{
+	ID:          ImportInboxTaskName,
+	Name:        "Import inbox",
+	Description: "Import the statement files dropped into the inbox directories of the accounts, submitting them or keeping them for review, and move them to the archive folder.",
+}
+
+// InboxImporter imports a file found in an inbox, see csvimportHandler.ImportHandler.
+type InboxImporter interface {
+	ImportInboxFile(ctx context.Context, inbox csvimport.ImportInbox, fileName string, data []byte) (csvimportHandler.InboxResult, error)
+}
+
+// NewImportInboxTaskFn returns a task function that scans the inbox directories below root. Every
+// file is imported into the inbox's account and then moved to the archive folder of the inbox;
+// files that fail with an unexpected error stay in place to be retried on the next run.
+func NewImportInboxTaskFn(store *csvimport.Store, importer InboxImporter, root string, l *slog.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if store == nil || importer == nil {
+			return fmt.Errorf("import store and importer are required")
+		}
+		taskLogInfo(ctx, l, ImportInboxTaskName, "starting inbox import")
+
+		inboxes, err := store.ListInboxes(ctx, 0)
+		if err != nil {
+			taskLogError(ctx, l, ImportInboxTaskName, fmt.Sprintf("unable to list import inboxes: %v", err))
+			return err
+		}
+
+		var imported, pending, failed int
+		for _, inbox := range inboxes {
+			dir := filepath.Join(root, inbox.Directory)
+			files, err := inboxFiles(dir)
+			if err != nil {
+				taskLogError(ctx, l, ImportInboxTaskName, fmt.Sprintf("unable to read inbox %s: %v", inbox.Directory, err))
+				failed++
+				continue
+			}
+			for _, name := range files {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				res, err := importInboxFile(ctx, importer, inbox, dir, name)
+				if err != nil {
+					taskLogError(ctx, l, ImportInboxTaskName, fmt.Sprintf("unable to import %s/%s: %v", inbox.Directory, name, err))
+					failed++
+					continue
+				}
+				if res.PendingImportID != 0 {
+					pending++
+					msg := fmt.Sprintf("%s/%s awaits review", inbox.Directory, name)
+					if res.Reason != "" {
+						msg = fmt.Sprintf("%s: %s", msg, res.Reason)
+					}
+					taskLogWarn(ctx, l, ImportInboxTaskName, msg)
+					continue
+				}
+				imported++
+				taskLogInfo(ctx, l, ImportInboxTaskName, fmt.Sprintf("%s/%s imported, %d transaction(s) created", inbox.Directory, name, res.Created))
+			}
+		}
+
+		taskLogInfo(ctx, l, ImportInboxTaskName, fmt.Sprintf("inbox import completed, %d file(s) imported, %d pending review, %d failed", imported, pending, failed),
+			slog.Int("imported", imported), slog.Int("pending", pending), slog.Int("failed", failed))
+		if failed > 0 {
+			return fmt.Errorf("%d inbox file(s) or directories failed", failed)
+		}
+		return nil
+	}
+}
+
+// inboxFiles returns the names of the files waiting in an inbox directory, creating it when missing.
+// Hidden files, e.g. partial uploads of some sync tools, are skipped.
+func inboxFiles(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, e.Name())
+	}
+	return files, nil
+}
+
+// importInboxFile imports a file of an inbox and moves it to the archive folder once it is
+// submitted or stored for review.
+func importInboxFile(ctx context.Context, importer InboxImporter, inbox csvimport.ImportInbox, dir, name string) (csvimportHandler.InboxResult, error) {
+	path := filepath.Join(dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return csvimportHandler.InboxResult{}, err
+	}
+	res, err := importer.ImportInboxFile(ctx, inbox, name, data)
+	if err != nil {
+		return csvimportHandler.InboxResult{}, err
+	}
+	if err := archiveInboxFile(dir, name, time.Now()); err != nil {
+		return res, fmt.Errorf("imported but unable to archive: %w", err)
+	}
+	return res, nil
+}
+
+// archiveInboxFile moves a processed file to the archive folder of the inbox, adding a timestamp
+// to the name when a file with the same name was archived before.
+func archiveInboxFile(dir, name string, now time.Time) error {
+	archive := filepath.Join(dir, csvimport.InboxArchiveDir)
+	if err := os.MkdirAll(archive, 0o750); err != nil {
+		return err
+	}
+	target := filepath.Join(archive, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(archive, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), now.Format("20060102-150405.000"), ext))
+	}
+	return os.Rename(filepath.Join(dir, name), target)
+}
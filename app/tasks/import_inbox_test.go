@@ -0,0 +1,118 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	csvimportHandler "github.com/andresbott/etna/app/router/handlers/csvimport"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeInboxImporter struct {
+	files []string
+}
+
+func (f *fakeInboxImporter) ImportInboxFile(_ context.Context, _ csvimport.ImportInbox, fileName string, _ []byte) (csvimportHandler.InboxResult, error) {
+	f.files = append(f.files, fileName)
+	switch fileName {
+	case "fail.csv":
+		return csvimportHandler.InboxResult{}, errors.New("database is locked")
+	case "review.csv":
+		return csvimportHandler.InboxResult{PendingImportID: 1, Reason: "1 row(s) failed to parse"}, nil
+	}
+	return csvimportHandler.InboxResult{Created: 2, BatchID: 1}, nil
+}
+
+func TestImportInboxTask(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:importInboxTask?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := csvimport.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := store.CreateInbox(ctx, csvimport.ImportInbox{AccountID: 1, Directory: "bank"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateInbox(ctx, csvimport.ImportInbox{AccountID: 2, Directory: "empty"}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "bank")
+	for _, name := range []string{"march.csv", "review.csv", "fail.csv", ".partial.csv"} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a file of the same name was archived before
+	if err := os.MkdirAll(filepath.Join(dir, csvimport.InboxArchiveDir), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, csvimport.InboxArchiveDir, "march.csv"), []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	importer := &fakeInboxImporter{}
+	err = NewImportInboxTaskFn(store, importer, root, nil)(ctx)
+	if err == nil {
+		t.Error("expected an error for the failed file")
+	}
+	slices.Sort(importer.files)
+	if want := []string{"fail.csv", "march.csv", "review.csv"}; !slices.Equal(importer.files, want) {
+		t.Errorf("imported files = %v, want %v", importer.files, want)
+	}
+
+	remaining, err := inboxFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"fail.csv"}; !slices.Equal(remaining, want) {
+		t.Errorf("files left in the inbox = %v, want %v", remaining, want)
+	}
+	archived, err := inboxFiles(filepath.Join(dir, csvimport.InboxArchiveDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 3 || !slices.Contains(archived, "review.csv") {
+		t.Errorf("unexpected archived files %v", archived)
+	}
+	if _, err := os.Stat(filepath.Join(root, "empty")); err != nil {
+		t.Errorf("expected the inbox directory to be created: %v", err)
+	}
+}
+
+func TestArchiveInboxFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 5, 14, 23, 45, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "march.csv"), []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := archiveInboxFile(dir, "march.csv", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archived, err := inboxFiles(filepath.Join(dir, csvimport.InboxArchiveDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"march-20260305-142345.000.csv", "march.csv"}; !slices.Equal(archived, want) {
+		t.Errorf("archived files = %v, want %v", archived, want)
+	}
+}
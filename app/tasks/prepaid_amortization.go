@@ -0,0 +1,39 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/andresbott/etna/internal/accounting"
+)
+
+const PrepaidAmortizationTaskName = "prepaid-amortization"
+
+// PrepaidAmortizationTaskDef is the task definition for the prepaid expense amortization task.
+var PrepaidAmortizationTaskDef = TaskDef{
+	ID:          PrepaidAmortizationTaskName,
+	Name:        "Prepaid expense amortization",
+	Description: "Generate the periodic expenses of amortization plans that are due, drawing down prepaid expense accounts.",
+}
+
+// NewPrepaidAmortizationTaskFn returns a task function that creates all amortization expenses due up to today.
+func NewPrepaidAmortizationTaskFn(store *accounting.Store, l *slog.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if store == nil {
+			return fmt.Errorf("accounting store is required")
+		}
+		taskLogInfo(ctx, l, PrepaidAmortizationTaskName, "starting prepaid expense amortization")
+
+		created, err := store.GenerateAmortizationEntries(ctx, time.Now())
+		if err != nil {
+			taskLogError(ctx, l, PrepaidAmortizationTaskName, fmt.Sprintf("amortization failed after %d expense(s): %v", created, err))
+			return err
+		}
+
+		taskLogInfo(ctx, l, PrepaidAmortizationTaskName, fmt.Sprintf("amortization completed, %d expense(s) created", created),
+			slog.Int("created", created))
+		return nil
+	}
+}
@@ -19,7 +19,7 @@ type TaskDef struct {
 }
 
 // AvailableTasks is the full list of task definitions (including dev-only). Use AvailableTaskDefs(production) to filter.
-var AvailableTasks = []TaskDef{BackupTaskDef, FinancialImportTaskDef, FinancialBackfillTaskDef, FXImportTaskDef, FXBackfillTaskDef, EPSImportTaskDef, LogOnlyTaskDef, LogOnlyLongTaskDef, DebugFailTaskDef}
+var AvailableTasks = []TaskDef{BackupTaskDef, FinancialImportTaskDef, FinancialBackfillTaskDef, FXImportTaskDef, FXBackfillTaskDef, EPSImportTaskDef, PrepaidAmortizationTaskDef, AttachmentCheckTaskDef, AttachmentPurgeTaskDef, ImportInboxTaskDef, LogOnlyTaskDef, LogOnlyLongTaskDef, DebugFailTaskDef}
 
 // DevOnlyTaskIDs are task IDs hidden in production (non-prod only).
 var DevOnlyTaskIDs = map[string]bool{
@@ -161,6 +161,33 @@ func FetchWith429Retry[T any](ctx context.Context, deadline time.Time, logLabel
 	}
 }
 
+// preferRecordedSource makes a provider chain try the provider recorded on the series first, so the
+// provider that served a symbol or pair is remembered across restarts. Other clients are left as is.
+func preferRecordedSource(client any, key string, recorded func() (string, error)) {
+	tracker, ok := client.(importer.SourceTracker)
+	if !ok {
+		return
+	}
+	if src, err := recorded(); err == nil && src != "" {
+		tracker.Prefer(key, src)
+	}
+}
+
+// recordSource stores the provider of a chain that served key as the source label of its series.
+func recordSource(ctx context.Context, client any, key string, set func(source string) error) {
+	tracker, ok := client.(importer.SourceTracker)
+	if !ok {
+		return
+	}
+	src := tracker.Source(key)
+	if src == "" {
+		return
+	}
+	if err := set(src); err != nil {
+		tempo.Info(ctx, fmt.Sprintf("record data source of %s: %v", key, err))
+	}
+}
+
 // taskLogInfo writes the message to both tempo (task log) and optionally slog when l is non-nil.
 func taskLogInfo(ctx context.Context, l *slog.Logger, taskName, msg string, slogAttrs ...slog.Attr) {
 	tempo.Info(ctx, msg)
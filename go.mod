@@ -25,6 +25,7 @@ require (
 	github.com/reugn/go-quartz v0.15.2
 	github.com/samber/slog-formatter v1.3.0
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/image v0.25.0
 	golang.org/x/sync v0.21.0
 	golang.org/x/text v0.38.0
 	gorm.io/driver/sqlite v1.6.0
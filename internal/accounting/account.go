@@ -460,6 +460,21 @@ func (store *Store) DeleteAccount(ctx context.Context, Id uint) error {
 	if d.RowsAffected == 0 {
 		return ErrAccountNotFound
 	}
+
+	// unlink the account from savings goals
+	if err := db.Where("account_id = ?", Id).Delete(&dbSavingsGoalAccount{}).Error; err != nil {
+		return fmt.Errorf("failed to unlink account from savings goals: %w", err)
+	}
+
+	// people only exist through their lent account, their shares are left without receivables
+	// once the account holds no entries
+	if err := db.Where("person_id IN (?)", db.Model(&dbPerson{}).Select("id").Where("account_id = ?", Id)).
+		Delete(&dbExpenseShare{}).Error; err != nil {
+		return fmt.Errorf("failed to delete shares of the person of the account: %w", err)
+	}
+	if err := db.Where("account_id = ?", Id).Delete(&dbPerson{}).Error; err != nil {
+		return fmt.Errorf("failed to delete person of the account: %w", err)
+	}
 	return nil
 }
 
@@ -45,7 +45,7 @@ func NewStore(db *gorm.DB, marketStore *marketdata.Store, opts ...Option) (*Stor
 		return nil, fmt.Errorf("error parsing schema: %w", err)
 	}
 
-	err = db.AutoMigrate(&dbAccountProvider{}, &dbAccount{}, &dbTransaction{}, &dbEntry{}, &dbTrade{}, &dbLot{}, &dbLotDisposal{}, &dbPosition{})
+	err = db.AutoMigrate(&dbAccountProvider{}, &dbAccount{}, &dbTransaction{}, &dbEntry{}, &dbTrade{}, &dbLot{}, &dbLotDisposal{}, &dbPosition{}, &dbAmortizationPlan{}, &dbAmortizationPosting{}, &dbSavingsGoal{}, &dbSavingsGoalAccount{}, &dbPerson{}, &dbExpenseShare{}, &dbTransactionAttachment{})
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +73,11 @@ func NewStore(db *gorm.DB, marketStore *marketdata.Store, opts ...Option) (*Stor
 		return nil, fmt.Errorf("migrate stock sell expense sign: %w", err)
 	}
 
+	// Migration: transactions used to hold a single attachment, copy it into the attachment list.
+	if err := migrateTransactionAttachments(db); err != nil {
+		return nil, fmt.Errorf("migrate transaction attachments: %w", err)
+	}
+
 	categoryTree, err := closuretree.New(db, dbCategory{}) // init the closure tree, this includes gorm automigrate
 	if err != nil {
 		return nil, err
@@ -112,6 +117,13 @@ func (store *Store) WipeData(ctx context.Context) error {
 		"db_transactions",
 		"db_entries",
 		"db_categories",
+		"db_amortization_postings",
+		"db_amortization_plans",
+		"db_savings_goal_accounts",
+		"db_savings_goals",
+		"db_expense_shares",
+		"db_people",
+		"db_transaction_attachments",
 	}
 
 	for _, table := range tables {
@@ -0,0 +1,419 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAmortizationPlanNotFound = errors.New("amortization plan not found")
+
+// dbAmortizationPlan links a prepaid transfer (money moved into a PrepaidExpense account)
+// with the expense category and the period over which it should be expensed.
+type dbAmortizationPlan struct {
+	ID            uint      `gorm:"primaryKey"`
+	TransactionID uint      `gorm:"not null;uniqueIndex"` // the prepaid transfer
+	CategoryID    uint      `gorm:"not null"`             // target expense category
+	StartDate     time.Time `gorm:"not null"`
+	EndDate       time.Time `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// dbAmortizationPosting records an expense transaction generated by a plan for a given period.
+// When the expense is deleted the record is kept with TransactionID 0, so the period is not
+// generated again.
+type dbAmortizationPosting struct {
+	ID            uint `gorm:"primaryKey"`
+	PlanID        uint `gorm:"not null;index"`
+	TransactionID uint `gorm:"not null;index"` // the generated expense, 0 once deleted
+	Period        int  `gorm:"not null"`       // 0-based month index within the plan
+	CreatedAt     time.Time
+}
+
+// AmortizationPlan spreads the amount of a prepaid transfer over the months between
+// StartDate and EndDate (inclusive), one expense per month on the prepaid account.
+type AmortizationPlan struct {
+	ID            uint
+	TransactionID uint
+	CategoryID    uint
+	StartDate     time.Time
+	EndDate       time.Time
+
+	// read only, derived from the transfer and the generated expenses
+	AccountID     uint
+	Amount        float64
+	Amortized     float64
+	Periods       int
+	PostedPeriods int
+	Postings      []AmortizationPosting
+}
+
+// AmortizationPosting is an expense transaction generated by a plan for the given 0-based period.
+// TransactionID is 0 if the expense was deleted.
+type AmortizationPosting struct {
+	TransactionID uint
+	Period        int
+}
+
+type AmortizationPlanUpdate struct {
+	CategoryID *uint
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+// amortizationPeriods returns the number of calendar months covered by start and end, inclusive.
+func amortizationPeriods(start, end time.Time) int {
+	return (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month()) + 1
+}
+
+// amortizationPeriodDate returns the posting date of a period: the start date for the first
+// period and the first day of the month for the following ones.
+func amortizationPeriodDate(start time.Time, period int) time.Time {
+	if period == 0 {
+		return start
+	}
+	return time.Date(start.Year(), start.Month()+time.Month(period), 1, 0, 0, 0, 0, start.Location())
+}
+
+// prepaidTransfer returns the prepaid expense account and amount of the transfer a plan is built on.
+func (store *Store) prepaidTransfer(ctx context.Context, txID uint) (uint, float64, error) {
+	tx, err := store.GetTransaction(ctx, txID)
+	if err != nil {
+		return 0, 0, err
+	}
+	transfer, ok := tx.(Transfer)
+	if !ok {
+		return 0, 0, NewValidationErr("amortization plans can only be created on transfer transactions")
+	}
+	acc, err := store.GetAccount(ctx, transfer.TargetAccountID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if acc.Type != PrepaidExpenseAccountType {
+		return 0, 0, NewValidationErr("the transfer target account must be a prepaid expense account")
+	}
+	return transfer.TargetAccountID, transfer.TargetAmount, nil
+}
+
+func (store *Store) validateAmortizationPlan(ctx context.Context, categoryID uint, start, end time.Time) error {
+	if categoryID == 0 {
+		return ErrValidation("category id is required")
+	}
+	if err := store.validateCategory(ctx, categoryID, ExpenseCategory); err != nil {
+		return err
+	}
+	if start.IsZero() || end.IsZero() {
+		return ErrValidation("start and end date are required")
+	}
+	if end.Before(start) {
+		return ErrValidation("end date cannot be before start date")
+	}
+	return nil
+}
+
+func (store *Store) CreateAmortizationPlan(ctx context.Context, item AmortizationPlan) (uint, error) {
+	if item.TransactionID == 0 {
+		return 0, ErrValidation("transaction id is required")
+	}
+	accountID, _, err := store.prepaidTransfer(ctx, item.TransactionID)
+	if err != nil {
+		return 0, fmt.Errorf("error creating amortization plan: %w", err)
+	}
+	if err := store.validateAmortizationPlan(ctx, item.CategoryID, item.StartDate, item.EndDate); err != nil {
+		return 0, err
+	}
+	if err := store.validateExpenseTarget(ctx, accountID, item.CategoryID); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := store.db.WithContext(ctx).Model(&dbAmortizationPlan{}).
+		Where("transaction_id = ?", item.TransactionID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrValidation("the transaction already has an amortization plan")
+	}
+
+	payload := dbAmortizationPlan{
+		TransactionID: item.TransactionID,
+		CategoryID:    item.CategoryID,
+		StartDate:     item.StartDate,
+		EndDate:       item.EndDate,
+	}
+	if err := store.db.WithContext(ctx).Create(&payload).Error; err != nil {
+		return 0, err
+	}
+	return payload.ID, nil
+}
+
+func (store *Store) GetAmortizationPlan(ctx context.Context, id uint) (AmortizationPlan, error) {
+	var payload dbAmortizationPlan
+	d := store.db.WithContext(ctx).Where("id = ?", id).First(&payload)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return AmortizationPlan{}, ErrAmortizationPlanNotFound
+		}
+		return AmortizationPlan{}, d.Error
+	}
+	return store.amortizationPlanFromDb(ctx, payload)
+}
+
+func (store *Store) ListAmortizationPlans(ctx context.Context) ([]AmortizationPlan, error) {
+	var rows []dbAmortizationPlan
+	if err := store.db.WithContext(ctx).Order("start_date ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	plans := make([]AmortizationPlan, 0, len(rows))
+	for _, row := range rows {
+		p, err := store.amortizationPlanFromDb(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+func (store *Store) amortizationPlanFromDb(ctx context.Context, in dbAmortizationPlan) (AmortizationPlan, error) {
+	accountID, amount, err := store.prepaidTransfer(ctx, in.TransactionID)
+	if err != nil {
+		return AmortizationPlan{}, fmt.Errorf("unable to read transfer of amortization plan %d: %w", in.ID, err)
+	}
+	amortized, posted, err := store.amortizationProgress(ctx, store.db, in.ID)
+	if err != nil {
+		return AmortizationPlan{}, err
+	}
+	var postings []dbAmortizationPosting
+	if err := store.db.WithContext(ctx).Where("plan_id = ?", in.ID).Order("period ASC").Find(&postings).Error; err != nil {
+		return AmortizationPlan{}, err
+	}
+	out := make([]AmortizationPosting, 0, len(postings))
+	for _, p := range postings {
+		out = append(out, AmortizationPosting{TransactionID: p.TransactionID, Period: p.Period})
+	}
+	return AmortizationPlan{
+		ID:            in.ID,
+		TransactionID: in.TransactionID,
+		CategoryID:    in.CategoryID,
+		StartDate:     in.StartDate,
+		EndDate:       in.EndDate,
+		AccountID:     accountID,
+		Amount:        amount,
+		Amortized:     amortized,
+		Periods:       amortizationPeriods(in.StartDate, in.EndDate),
+		PostedPeriods: len(posted),
+		Postings:      out,
+	}, nil
+}
+
+// amortizationProgress returns the amount already expensed by a plan and the set of posted periods.
+func (store *Store) amortizationProgress(ctx context.Context, db *gorm.DB, planID uint) (float64, map[int]bool, error) {
+	var postings []dbAmortizationPosting
+	if err := db.WithContext(ctx).Where("plan_id = ?", planID).Find(&postings).Error; err != nil {
+		return 0, nil, err
+	}
+	posted := make(map[int]bool, len(postings))
+	txIDs := make([]uint, 0, len(postings))
+	for _, p := range postings {
+		posted[p.Period] = true
+		txIDs = append(txIDs, p.TransactionID)
+	}
+	if len(txIDs) == 0 {
+		return 0, posted, nil
+	}
+
+	var sum float64
+	err := db.WithContext(ctx).Model(&dbEntry{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("transaction_id IN ? AND entry_type = ?", txIDs, expenseEntry).
+		Scan(&sum).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return -sum, posted, nil
+}
+
+func (store *Store) UpdateAmortizationPlan(ctx context.Context, input AmortizationPlanUpdate, id uint) error {
+	var current dbAmortizationPlan
+	if err := store.db.WithContext(ctx).Where("id = ?", id).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAmortizationPlanNotFound
+		}
+		return err
+	}
+
+	var selectedFields []string
+	if input.CategoryID != nil {
+		current.CategoryID = *input.CategoryID
+		selectedFields = append(selectedFields, "CategoryID")
+	}
+	if input.StartDate != nil {
+		current.StartDate = *input.StartDate
+		selectedFields = append(selectedFields, "StartDate")
+	}
+	if input.EndDate != nil {
+		current.EndDate = *input.EndDate
+		selectedFields = append(selectedFields, "EndDate")
+	}
+	if len(selectedFields) == 0 {
+		return ErrNoChanges
+	}
+	if err := store.validateAmortizationPlan(ctx, current.CategoryID, current.StartDate, current.EndDate); err != nil {
+		return err
+	}
+
+	return store.db.WithContext(ctx).Model(&dbAmortizationPlan{}).Where("id = ?", id).
+		Select(selectedFields).Updates(current).Error
+}
+
+// DeleteAmortizationPlan removes the plan; expenses already generated by it are kept.
+func (store *Store) DeleteAmortizationPlan(ctx context.Context, id uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		d := tx.Where("id = ?", id).Delete(&dbAmortizationPlan{})
+		if d.Error != nil {
+			return d.Error
+		}
+		if d.RowsAffected == 0 {
+			return ErrAmortizationPlanNotFound
+		}
+		return tx.Where("plan_id = ?", id).Delete(&dbAmortizationPosting{}).Error
+	})
+}
+
+// AddAmortizationPosting links an existing expense transaction to a plan period, e.g. when restoring
+// a backup, so that the period is not generated again.
+func (store *Store) AddAmortizationPosting(ctx context.Context, planID uint, posting AmortizationPosting) error {
+	var count int64
+	if err := store.db.WithContext(ctx).Model(&dbAmortizationPlan{}).Where("id = ?", planID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAmortizationPlanNotFound
+	}
+	return store.db.WithContext(ctx).Create(&dbAmortizationPosting{
+		PlanID:        planID,
+		TransactionID: posting.TransactionID,
+		Period:        posting.Period,
+	}).Error
+}
+
+// deleteAmortizationRefs removes the amortization references of a transaction that is being deleted:
+// the plan built on it (if it is a prepaid transfer). The posting record of a generated expense is
+// kept without transaction, so that the period counts as posted and the expense is not generated again.
+func deleteAmortizationRefs(ctx context.Context, tx *gorm.DB, txID uint) error {
+	var planIDs []uint
+	if err := tx.WithContext(ctx).Model(&dbAmortizationPlan{}).
+		Where("transaction_id = ?", txID).Pluck("id", &planIDs).Error; err != nil {
+		return err
+	}
+	if len(planIDs) > 0 {
+		if err := tx.WithContext(ctx).Where("plan_id IN ?", planIDs).Delete(&dbAmortizationPosting{}).Error; err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Where("id IN ?", planIDs).Delete(&dbAmortizationPlan{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.WithContext(ctx).Model(&dbAmortizationPosting{}).Where("transaction_id = ?", txID).
+		Update("transaction_id", 0).Error
+}
+
+// GenerateAmortizationEntries creates the expense entries of every plan for all periods due up to asOf.
+// Each period expenses an equal share of what is left, the last period takes the remainder so that
+// the prepaid amount is drawn down to zero. A plan that fails, e.g. because its category no longer
+// exists, does not stop the others; the errors are returned together with the number of expenses created.
+func (store *Store) GenerateAmortizationEntries(ctx context.Context, asOf time.Time) (int, error) {
+	var plans []dbAmortizationPlan
+	if err := store.db.WithContext(ctx).Order("id ASC").Find(&plans).Error; err != nil {
+		return 0, err
+	}
+
+	created := 0
+	var errs []error
+	for _, plan := range plans {
+		n, err := store.generatePlanEntries(ctx, plan, asOf)
+		created += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("amortization plan %d: %w", plan.ID, err))
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+func (store *Store) generatePlanEntries(ctx context.Context, plan dbAmortizationPlan, asOf time.Time) (int, error) {
+	accountID, total, err := store.prepaidTransfer(ctx, plan.TransactionID)
+	if err != nil {
+		return 0, err
+	}
+	// the account or category may have changed since the plan was created
+	if err := store.validateExpenseTarget(ctx, accountID, plan.CategoryID); err != nil {
+		return 0, err
+	}
+	transfer, err := store.GetTransaction(ctx, plan.TransactionID)
+	if err != nil {
+		return 0, err
+	}
+	description := transfer.(Transfer).Description
+
+	periods := amortizationPeriods(plan.StartDate, plan.EndDate)
+	created := 0
+	for period := 0; period < periods; period++ {
+		date := amortizationPeriodDate(plan.StartDate, period)
+		if date.After(asOf) {
+			break
+		}
+
+		amortized, posted, err := store.amortizationProgress(ctx, store.db, plan.ID)
+		if err != nil {
+			return created, err
+		}
+		if posted[period] {
+			continue
+		}
+
+		remaining := roundMoney(total - amortized)
+		pending := periods - len(posted)
+		amount := remaining
+		if pending > 1 {
+			amount = roundMoney(remaining / float64(pending))
+		}
+		if math.Abs(amount) < 0.005 {
+			continue
+		}
+
+		err = store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			expense := dbTransaction{
+				Description: fmt.Sprintf("%s (%d/%d)", description, period+1, periods),
+				Date:        date,
+				Type:        ExpenseTransaction,
+				Entries: []dbEntry{
+					{
+						AccountID:  accountID,
+						CategoryID: plan.CategoryID,
+						Amount:     -amount,
+						EntryType:  expenseEntry,
+					},
+				},
+			}
+			if err := tx.Create(&expense).Error; err != nil {
+				return err
+			}
+			return tx.Create(&dbAmortizationPosting{
+				PlanID:        plan.ID,
+				TransactionID: expense.Id,
+				Period:        period,
+			}).Error
+		})
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
@@ -0,0 +1,254 @@
+package accounting
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/go-bumbu/testdbs"
+	"golang.org/x/text/currency"
+)
+
+func amortizationSampleData(t *testing.T, store *Store) (checkingID, prepaidID, catID, transferID uint) {
+	t.Helper()
+	ctx := t.Context()
+
+	providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkingID, err = store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepaidID, err = store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "insurance", Currency: currency.CHF, Type: PrepaidExpenseAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID, err = store.CreateCategory(ctx, CategoryData{Name: "insurance", Type: ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferID, err = store.CreateTransfer(ctx, Transfer{
+		Description:     "yearly insurance",
+		OriginAccountID: checkingID,
+		OriginAmount:    1000,
+		TargetAccountID: prepaidID,
+		TargetAmount:    1000,
+		Date:            getDate("2025-01-15"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkingID, prepaidID, catID, transferID
+}
+
+func TestCreateAmortizationPlan(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestCreateAmortizationPlan"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+			checkingID, _, catID, transferID := amortizationSampleData(t, store)
+
+			incomeCat, err := store.CreateCategory(ctx, CategoryData{Name: "salary", Type: IncomeCategory}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expenseID, err := store.CreateExpense(ctx, Expense{Description: "groceries", AccountID: checkingID, Amount: 10, Date: getDate("2025-01-01")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tcs := []struct {
+				name    string
+				input   AmortizationPlan
+				wantErr string
+			}{
+				{
+					name:    "not a transfer",
+					input:   AmortizationPlan{TransactionID: expenseID, CategoryID: catID, StartDate: getDate("2025-01-15"), EndDate: getDate("2025-12-31")},
+					wantErr: "error creating amortization plan: amortization plans can only be created on transfer transactions",
+				},
+				{
+					name:    "income category",
+					input:   AmortizationPlan{TransactionID: transferID, CategoryID: incomeCat, StartDate: getDate("2025-01-15"), EndDate: getDate("2025-12-31")},
+					wantErr: "incompatible category type for transaction",
+				},
+				{
+					name:    "end before start",
+					input:   AmortizationPlan{TransactionID: transferID, CategoryID: catID, StartDate: getDate("2025-12-31"), EndDate: getDate("2025-01-15")},
+					wantErr: "end date cannot be before start date",
+				},
+				{
+					name:  "valid plan",
+					input: AmortizationPlan{TransactionID: transferID, CategoryID: catID, StartDate: getDate("2025-01-15"), EndDate: getDate("2025-12-31")},
+				},
+				{
+					name:    "duplicate plan",
+					input:   AmortizationPlan{TransactionID: transferID, CategoryID: catID, StartDate: getDate("2025-01-15"), EndDate: getDate("2025-12-31")},
+					wantErr: "the transaction already has an amortization plan",
+				},
+			}
+
+			for _, tc := range tcs {
+				t.Run(tc.name, func(t *testing.T) {
+					_, err := store.CreateAmortizationPlan(ctx, tc.input)
+					if tc.wantErr != "" {
+						if err == nil {
+							t.Fatalf("expected error: %s, but got none", tc.wantErr)
+						}
+						if err.Error() != tc.wantErr {
+							t.Errorf("expected error: %s, but got %s", tc.wantErr, err.Error())
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestGenerateAmortizationEntries(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestGenerateAmortizationEntries"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+			_, prepaidID, catID, transferID := amortizationSampleData(t, store)
+
+			planID, err := store.CreateAmortizationPlan(ctx, AmortizationPlan{
+				TransactionID: transferID,
+				CategoryID:    catID,
+				StartDate:     getDate("2025-01-15"),
+				EndDate:       getDate("2025-03-31"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// only the first two periods are due
+			created, err := store.GenerateAmortizationEntries(ctx, getDate("2025-02-10"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != 2 {
+				t.Errorf("expected 2 expenses, got %d", created)
+			}
+
+			// running again does not duplicate entries
+			created, err = store.GenerateAmortizationEntries(ctx, getDate("2025-02-10"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != 0 {
+				t.Errorf("expected no new expenses, got %d", created)
+			}
+
+			created, err = store.GenerateAmortizationEntries(ctx, getDate("2025-12-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != 1 {
+				t.Errorf("expected 1 expense, got %d", created)
+			}
+
+			plan, err := store.GetAmortizationPlan(ctx, planID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Periods != 3 || plan.PostedPeriods != 3 {
+				t.Errorf("expected 3/3 posted periods, got %d/%d", plan.PostedPeriods, plan.Periods)
+			}
+			if plan.Amortized != 1000 {
+				t.Errorf("expected amortized amount 1000, got %v", plan.Amortized)
+			}
+
+			bal, err := store.AccountBalanceSingle(ctx, prepaidID, getDate("2025-12-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(bal.Sum) > 0.001 {
+				t.Errorf("expected prepaid balance to be drawn down to 0, got %v", bal.Sum)
+			}
+
+			// a deleted expense is not generated again
+			deletedTx := plan.Postings[1].TransactionID
+			if err := store.DeleteTransaction(ctx, deletedTx); err != nil {
+				t.Fatal(err)
+			}
+			created, err = store.GenerateAmortizationEntries(ctx, getDate("2025-12-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != 0 {
+				t.Errorf("expected the deleted expense not to be generated again, got %d new expenses", created)
+			}
+			plan, err = store.GetAmortizationPlan(ctx, planID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.PostedPeriods != 3 || plan.Postings[1].TransactionID != 0 {
+				t.Errorf("expected period 2 to stay posted without expense, got %+v", plan.Postings)
+			}
+
+			// deleting the prepaid transfer removes the plan
+			if err := store.DeleteTransaction(ctx, transferID); err != nil {
+				t.Fatal(err)
+			}
+			_, err = store.GetAmortizationPlan(ctx, planID)
+			if !errors.Is(err, ErrAmortizationPlanNotFound) {
+				t.Errorf("expected ErrAmortizationPlanNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateAmortizationEntries_invalidPlan(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestGenerateAmortizationEntriesInvalid"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+			checkingID, prepaidID, catID, transferID := amortizationSampleData(t, store)
+
+			goneCat, err := store.CreateCategory(ctx, CategoryData{Name: "subscriptions", Type: ExpenseCategory}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			otherTransfer, err := store.CreateTransfer(ctx, Transfer{Description: "streaming", OriginAccountID: checkingID, OriginAmount: 120,
+				TargetAccountID: prepaidID, TargetAmount: 120, Date: getDate("2025-01-01")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, plan := range []AmortizationPlan{
+				{TransactionID: otherTransfer, CategoryID: goneCat, StartDate: getDate("2025-01-01"), EndDate: getDate("2025-12-31")},
+				{TransactionID: transferID, CategoryID: catID, StartDate: getDate("2025-01-15"), EndDate: getDate("2025-03-31")},
+			} {
+				if _, err := store.CreateAmortizationPlan(ctx, plan); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.DeleteCategoryRecursive(ctx, goneCat); err != nil {
+				t.Fatal(err)
+			}
+
+			created, err := store.GenerateAmortizationEntries(ctx, getDate("2025-12-31"))
+			if err == nil {
+				t.Error("expected an error for the plan without category")
+			}
+			if created != 3 {
+				t.Errorf("expected the valid plan to be posted, got %d expenses", created)
+			}
+		})
+	}
+}
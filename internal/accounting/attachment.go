@@ -0,0 +1,228 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrTransactionAttachmentNotFound = errors.New("attachment not linked to transaction")
+
+// dbTransactionAttachment links a stored file (see filestore) to a transaction; a transaction can
+// hold several attachments, e.g. an invoice and its payment receipt or the pages of a scan.
+// The first attachment (by position) is mirrored on dbTransaction.AttachmentID so that listings
+// and the "has attachment" filter do not need to join this table.
+type dbTransactionAttachment struct {
+	ID            uint   `gorm:"primaryKey"`
+	TransactionID uint   `gorm:"not null;uniqueIndex:idx_tx_attachment"`
+	AttachmentID  uint   `gorm:"not null;uniqueIndex:idx_tx_attachment;index"`
+	Position      int    `gorm:"not null;default:0"`
+	Caption       string `gorm:"size:255"`
+	CreatedAt     time.Time
+}
+
+type TransactionAttachment struct {
+	TransactionID uint
+	AttachmentID  uint
+	Position      int
+	Caption       string
+}
+
+func dbToTransactionAttachments(rows []dbTransactionAttachment) []TransactionAttachment {
+	out := make([]TransactionAttachment, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, TransactionAttachment{TransactionID: r.TransactionID, AttachmentID: r.AttachmentID, Position: r.Position, Caption: r.Caption})
+	}
+	return out
+}
+
+// migrateTransactionAttachments moves the single attachment of transactions created before
+// attachments became a one-to-many relation into the link table.
+func migrateTransactionAttachments(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO db_transaction_attachments (transaction_id, attachment_id, position, caption, created_at)
+		SELECT id, attachment_id, 0, '', CURRENT_TIMESTAMP FROM db_transactions t
+		WHERE attachment_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM db_transaction_attachments a WHERE a.transaction_id = t.id)`).Error
+}
+
+// ListTransactionAttachments returns the attachments of a transaction in display order.
+func (store *Store) ListTransactionAttachments(ctx context.Context, txID uint) ([]TransactionAttachment, error) {
+	var rows []dbTransactionAttachment
+	if err := store.db.WithContext(ctx).Where("transaction_id = ?", txID).Order("position ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return dbToTransactionAttachments(rows), nil
+}
+
+// ListAllTransactionAttachments returns the attachments of all transactions, ordered by transaction and position.
+func (store *Store) ListAllTransactionAttachments(ctx context.Context) ([]TransactionAttachment, error) {
+	var rows []dbTransactionAttachment
+	if err := store.db.WithContext(ctx).Order("transaction_id ASC, position ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return dbToTransactionAttachments(rows), nil
+}
+
+// AddTransactionAttachment appends an attachment to the end of the transaction's attachment list.
+func (store *Store) AddTransactionAttachment(ctx context.Context, txID, attachmentID uint, caption string) (TransactionAttachment, error) {
+	if attachmentID == 0 {
+		return TransactionAttachment{}, ErrValidation("attachment id is required")
+	}
+	var out TransactionAttachment
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTransactionExists(tx, txID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&dbTransactionAttachment{}).Where("transaction_id = ? AND attachment_id = ?", txID, attachmentID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrValidation("attachment is already linked to the transaction")
+		}
+		var maxPos *int
+		if err := tx.Model(&dbTransactionAttachment{}).Where("transaction_id = ?", txID).Select("MAX(position)").Scan(&maxPos).Error; err != nil {
+			return err
+		}
+		pos := 0
+		if maxPos != nil {
+			pos = *maxPos + 1
+		}
+		row := dbTransactionAttachment{TransactionID: txID, AttachmentID: attachmentID, Position: pos, Caption: caption}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		out = TransactionAttachment{TransactionID: txID, AttachmentID: attachmentID, Position: pos, Caption: caption}
+		return syncPrimaryAttachment(tx, txID)
+	})
+	return out, err
+}
+
+// UpdateTransactionAttachmentCaption changes the caption of an attachment of a transaction.
+func (store *Store) UpdateTransactionAttachmentCaption(ctx context.Context, txID, attachmentID uint, caption string) error {
+	d := store.db.WithContext(ctx).Model(&dbTransactionAttachment{}).
+		Where("transaction_id = ? AND attachment_id = ?", txID, attachmentID).Update("caption", caption)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrTransactionAttachmentNotFound
+	}
+	return nil
+}
+
+// ReorderTransactionAttachments sets the display order of the attachments of a transaction;
+// order must contain every attachment id of the transaction exactly once.
+func (store *Store) ReorderTransactionAttachments(ctx context.Context, txID uint, order []uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Model(&dbTransactionAttachment{}).Where("transaction_id = ?", txID).Pluck("attachment_id", &current).Error; err != nil {
+			return err
+		}
+		if len(current) != len(order) {
+			return ErrValidation("the order must contain all attachments of the transaction")
+		}
+		known := map[uint]bool{}
+		for _, id := range current {
+			known[id] = true
+		}
+		for i, id := range order {
+			if !known[id] {
+				return ErrValidation("the order must contain all attachments of the transaction exactly once")
+			}
+			delete(known, id)
+			if err := tx.Model(&dbTransactionAttachment{}).
+				Where("transaction_id = ? AND attachment_id = ?", txID, id).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return syncPrimaryAttachment(tx, txID)
+	})
+}
+
+// RemoveTransactionAttachment unlinks an attachment from a transaction; deleting the file itself
+// is up to the caller.
+func (store *Store) RemoveTransactionAttachment(ctx context.Context, txID, attachmentID uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		d := tx.Where("transaction_id = ? AND attachment_id = ?", txID, attachmentID).Delete(&dbTransactionAttachment{})
+		if d.Error != nil {
+			return d.Error
+		}
+		if d.RowsAffected == 0 {
+			return ErrTransactionAttachmentNotFound
+		}
+		return syncPrimaryAttachment(tx, txID)
+	})
+}
+
+// SetAttachmentID replaces all attachments of a transaction with a single one.
+// Pass nil to detach (clear) all attachments.
+func (store *Store) SetAttachmentID(ctx context.Context, txId uint, attachmentID *uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTransactionExists(tx, txId); err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_id = ?", txId).Delete(&dbTransactionAttachment{}).Error; err != nil {
+			return err
+		}
+		if attachmentID != nil {
+			row := dbTransactionAttachment{TransactionID: txId, AttachmentID: *attachmentID}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		return syncPrimaryAttachment(tx, txId)
+	})
+}
+
+// AttachmentReferences returns how many times each attachment is referenced by transactions.
+// Transactions whose attachment was never moved to the link table are counted as well.
+func (store *Store) AttachmentReferences(ctx context.Context) (map[uint]int, error) {
+	type count struct {
+		AttachmentID uint
+		N            int
+	}
+	var linked []count
+	if err := store.db.WithContext(ctx).Model(&dbTransactionAttachment{}).
+		Select("attachment_id, COUNT(*) AS n").Group("attachment_id").Scan(&linked).Error; err != nil {
+		return nil, err
+	}
+	var legacy []count
+	if err := store.db.WithContext(ctx).Model(&dbTransaction{}).
+		Select("attachment_id, COUNT(*) AS n").
+		Where("attachment_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM db_transaction_attachments a WHERE a.transaction_id = db_transactions.id)").
+		Group("attachment_id").Scan(&legacy).Error; err != nil {
+		return nil, err
+	}
+	refs := map[uint]int{}
+	for _, c := range append(linked, legacy...) {
+		refs[c.AttachmentID] += c.N
+	}
+	return refs, nil
+}
+
+func checkTransactionExists(tx *gorm.DB, txID uint) error {
+	var count int64
+	if err := tx.Model(&dbTransaction{}).Where("id = ?", txID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// syncPrimaryAttachment mirrors the first attachment of a transaction on dbTransaction.AttachmentID.
+func syncPrimaryAttachment(tx *gorm.DB, txID uint) error {
+	var first []dbTransactionAttachment
+	if err := tx.Where("transaction_id = ?", txID).Order("position ASC, id ASC").Limit(1).Find(&first).Error; err != nil {
+		return err
+	}
+	var primary *uint
+	if len(first) > 0 {
+		primary = &first[0].AttachmentID
+	}
+	return tx.Model(&dbTransaction{}).Where("id = ?", txID).Update("attachment_id", primary).Error
+}
@@ -0,0 +1,186 @@
+package accounting
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/currency"
+)
+
+func TestTransactionAttachments(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestTransactionAttachments"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			accID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			txID, err := store.CreateExpense(ctx, Expense{Description: "laptop", AccountID: accID, Amount: 1200, Date: getDate("2025-02-01")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, caption := range []string{"invoice", "receipt", "warranty"} {
+				if _, err := store.AddTransactionAttachment(ctx, txID, uint(10+i), caption); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := store.AddTransactionAttachment(ctx, txID, 10, "again"); err == nil {
+				t.Error("expected error when linking the same attachment twice")
+			}
+			if _, err := store.AddTransactionAttachment(ctx, 999, 20, ""); !errors.Is(err, ErrTransactionNotFound) {
+				t.Errorf("expected ErrTransactionNotFound, got %v", err)
+			}
+
+			if err := store.UpdateTransactionAttachmentCaption(ctx, txID, 11, "payment receipt"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.ReorderTransactionAttachments(ctx, txID, []uint{12, 10}); err == nil {
+				t.Error("expected error for an incomplete order")
+			}
+			if err := store.ReorderTransactionAttachments(ctx, txID, []uint{11, 12, 10}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.RemoveTransactionAttachment(ctx, txID, 12); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.RemoveTransactionAttachment(ctx, txID, 12); !errors.Is(err, ErrTransactionAttachmentNotFound) {
+				t.Errorf("expected ErrTransactionAttachmentNotFound, got %v", err)
+			}
+
+			got, err := store.ListTransactionAttachments(ctx, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []TransactionAttachment{
+				{TransactionID: txID, AttachmentID: 11, Position: 0, Caption: "payment receipt"},
+				{TransactionID: txID, AttachmentID: 10, Position: 2, Caption: "invoice"},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected attachments (-want +got):\n%s", diff)
+			}
+
+			// the first attachment is mirrored on the transaction
+			tx, err := store.GetTransaction(ctx, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if att := tx.(Expense).AttachmentID; att == nil || *att != 11 {
+				t.Errorf("expected primary attachment 11, got %v", att)
+			}
+
+			if err := store.DeleteTransaction(ctx, txID); err != nil {
+				t.Fatal(err)
+			}
+			got, err = store.ListTransactionAttachments(ctx, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 0 {
+				t.Errorf("expected attachments to be unlinked, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestMigrateTransactionAttachments(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestMigrateTransactionAttachments"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			accID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			txID, err := store.CreateIncome(ctx, Income{Description: "salary", AccountID: accID, Amount: 100, Date: getDate("2025-02-01")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// simulate a transaction created before the attachment list existed
+			if err := store.db.Model(&dbTransaction{}).Where("id = ?", txID).Update("attachment_id", 7).Error; err != nil {
+				t.Fatal(err)
+			}
+			for range 2 { // the migration must be idempotent
+				if err := migrateTransactionAttachments(store.db); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.ListTransactionAttachments(ctx, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []TransactionAttachment{{TransactionID: txID, AttachmentID: 7}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected attachments (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAttachmentReferences(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestAttachmentReferences"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			accID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var txIDs []uint
+			for _, desc := range []string{"a", "b", "legacy"} {
+				id, err := store.CreateExpense(ctx, Expense{Description: desc, AccountID: accID, Amount: 10, Date: getDate("2025-02-01")})
+				if err != nil {
+					t.Fatal(err)
+				}
+				txIDs = append(txIDs, id)
+			}
+			for _, link := range []struct{ tx, att uint }{{txIDs[0], 1}, {txIDs[0], 2}, {txIDs[1], 1}} {
+				if _, err := store.AddTransactionAttachment(ctx, link.tx, link.att, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// a transaction only carrying the attachment column, as before the link table existed
+			if err := store.db.Model(&dbTransaction{}).Where("id = ?", txIDs[2]).Update("attachment_id", 3).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.AttachmentReferences(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[uint]int{1: 2, 2: 1, 3: 1}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected references (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
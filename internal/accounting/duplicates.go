@@ -0,0 +1,405 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// =======================================================================================
+// Duplicate transactions
+// =======================================================================================
+
+const (
+	DefaultDuplicateWindowDays    = 3
+	DefaultDuplicateMinConfidence = 0.5
+
+	// weights of the description and the date proximity in the confidence of a pair
+	duplicateDescWeight = 0.6
+	duplicateDateWeight = 0.4
+)
+
+// DuplicateOpts limits the scan for duplicate transactions.
+type DuplicateOpts struct {
+	StartDate     time.Time
+	EndDate       time.Time
+	AccountIds    []int
+	WindowDays    int     // maximum days between two duplicates, 0 uses DefaultDuplicateWindowDays
+	MinConfidence float64 // pairs below are ignored, 0 uses DefaultDuplicateMinConfidence
+}
+
+// DuplicateGroup is a set of transactions that likely record the same movement.
+type DuplicateGroup struct {
+	Transactions []Transaction // ordered by date
+	Confidence   float64       // 0..1, average of the pairs that form the group
+	KeepID       uint          // the transaction suggested to keep, the one holding the most information
+}
+
+// dupCandidate is a transaction that can have duplicates, reduced to what is compared.
+type dupCandidate struct {
+	tx          Transaction
+	kind        string
+	id          uint
+	date        time.Time
+	description string
+	notes       string
+	externalID  string
+	attachment  bool
+}
+
+func toDupCandidate(tx Transaction) (dupCandidate, string, bool) {
+	cents := func(v float64) int64 { return int64(math.Round(v * 100)) }
+	switch item := tx.(type) {
+	case Income:
+		return dupCandidate{tx: tx, kind: "income", id: item.Id, date: item.Date, description: item.Description, notes: item.Notes,
+				externalID: item.ExternalID, attachment: item.AttachmentID != nil},
+			dupKey("income", item.AccountID, 0, cents(item.Amount)), true
+	case Expense:
+		return dupCandidate{tx: tx, kind: "expense", id: item.Id, date: item.Date, description: item.Description, notes: item.Notes,
+				externalID: item.ExternalID, attachment: item.AttachmentID != nil},
+			dupKey("expense", item.AccountID, 0, cents(item.Amount)), true
+	case Transfer:
+		return dupCandidate{tx: tx, kind: "transfer", id: item.Id, date: item.Date, description: item.Description, notes: item.Notes,
+				externalID: item.ExternalID, attachment: item.AttachmentID != nil},
+			dupKey("transfer", item.OriginAccountID, item.TargetAccountID, cents(item.OriginAmount)), true
+	default:
+		return dupCandidate{}, "", false
+	}
+}
+
+func dupKey(kind string, account, target uint, cents int64) string {
+	return fmt.Sprintf("%s:%d:%d:%d", kind, account, target, cents)
+}
+
+// FindDuplicates scans the income, expense and transfer transactions for likely duplicates, e.g.
+// from a double import, an entry added by hand and imported later or a restored backup.
+// Transactions are candidates when they share type, account(s) and amount and are at most
+// WindowDays apart; their confidence weighs the similarity of the descriptions and the
+// distance in days. Groups are returned by confidence, highest first.
+func (store *Store) FindDuplicates(ctx context.Context, opts DuplicateOpts) ([]DuplicateGroup, error) {
+	if opts.WindowDays <= 0 {
+		opts.WindowDays = DefaultDuplicateWindowDays
+	}
+	if opts.MinConfidence <= 0 {
+		opts.MinConfidence = DefaultDuplicateMinConfidence
+	}
+
+	buckets := map[string][]dupCandidate{}
+	for page := 1; ; page++ {
+		txs, _, err := store.ListTransactions(ctx, ListOpts{
+			StartDate: opts.StartDate,
+			EndDate:   opts.EndDate,
+			AccountId: opts.AccountIds,
+			Types:     []TxType{IncomeTransaction, ExpenseTransaction, TransferTransaction},
+			Limit:     MaxSearchResults,
+			Page:      page,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(txs) == 0 {
+			break
+		}
+		for _, tx := range txs {
+			if c, key, ok := toDupCandidate(tx); ok {
+				buckets[key] = append(buckets[key], c)
+			}
+		}
+	}
+
+	// union-find over the candidates paired within their bucket
+	var all []dupCandidate
+	parent := []int{}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	type pair struct {
+		a          int
+		confidence float64
+	}
+	var pairs []pair
+
+	window := float64(opts.WindowDays)
+	for _, bucket := range buckets {
+		if len(bucket) < 2 {
+			continue
+		}
+		slices.SortFunc(bucket, func(a, b dupCandidate) int { return a.date.Compare(b.date) })
+		offset := len(all)
+		for range bucket {
+			parent = append(parent, len(parent))
+		}
+		all = append(all, bucket...)
+		for i := range bucket {
+			for j := i + 1; j < len(bucket); j++ {
+				days := bucket[j].date.Sub(bucket[i].date).Hours() / 24
+				if days > window {
+					break
+				}
+				confidence, ok := dupConfidence(bucket[i], bucket[j], days, window)
+				if !ok || confidence < opts.MinConfidence {
+					continue
+				}
+				pairs = append(pairs, pair{a: offset + i, confidence: confidence})
+				parent[find(offset+j)] = find(offset + i)
+			}
+		}
+	}
+
+	type acc struct {
+		members []int
+		sum     float64
+		n       int
+	}
+	groups := map[int]*acc{}
+	for _, p := range pairs {
+		root := find(p.a)
+		if groups[root] == nil {
+			groups[root] = &acc{}
+		}
+		groups[root].sum += p.confidence
+		groups[root].n++
+	}
+	for i := range all {
+		if g := groups[find(i)]; g != nil {
+			g.members = append(g.members, i)
+		}
+	}
+
+	out := make([]DuplicateGroup, 0, len(groups))
+	for _, g := range groups {
+		group := DuplicateGroup{Confidence: math.Round(g.sum/float64(g.n)*100) / 100}
+		var keep *dupCandidate
+		for _, i := range g.members {
+			c := all[i]
+			group.Transactions = append(group.Transactions, c.tx)
+			if keep == nil || dupRichness(c) > dupRichness(*keep) {
+				keep = &all[i]
+			}
+		}
+		group.KeepID = keep.id
+		out = append(out, group)
+	}
+	slices.SortFunc(out, func(a, b DuplicateGroup) int {
+		if a.Confidence != b.Confidence {
+			if a.Confidence > b.Confidence {
+				return -1
+			}
+			return 1
+		}
+		return int(a.KeepID) - int(b.KeepID) // #nosec G115 -- ids fit in int
+	})
+	return out, nil
+}
+
+// dupConfidence scores two candidates of the same bucket; false means they are known to differ.
+func dupConfidence(a, b dupCandidate, days, window float64) (float64, bool) {
+	if a.externalID != "" && b.externalID != "" {
+		// two lines of a bank statement are distinct, the same line imported twice is not
+		return 1, a.externalID == b.externalID
+	}
+	dateScore := 1 - days/(window+1)
+	return duplicateDescWeight*descriptionSimilarity(a.description, b.description) + duplicateDateWeight*dateScore, true
+}
+
+// dupRichness ranks which transaction of a group holds the most information; ties keep the oldest.
+func dupRichness(c dupCandidate) float64 {
+	score := float64(len(c.notes))/1000 - float64(c.id)/1e12
+	if c.externalID != "" {
+		score += 4
+	}
+	if c.attachment {
+		score += 2
+	}
+	if c.notes != "" {
+		score++
+	}
+	return score
+}
+
+// descriptionSimilarity is the Dice coefficient of the character bigrams of both descriptions,
+// ignoring case, punctuation and repeated spaces.
+func descriptionSimilarity(a, b string) float64 {
+	a, b = normalizeDescription(a), normalizeDescription(b)
+	if a == b {
+		return 1
+	}
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := map[string]int{}
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+func normalizeDescription(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		if len(runes) == 1 {
+			return []string{s}
+		}
+		return nil
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
+
+// MergeTransactions resolves a group of duplicates into keepID: the notes of the others are
+// appended, a missing category or import id is taken over and their attachments are moved,
+// then the others are deleted, all in one database transaction. All transactions must be of the
+// same type, income, expense or transfer. It returns the attachments whose link was dropped because
+// keepID already had them; the caller releases their reference in the file store.
+func (store *Store) MergeTransactions(ctx context.Context, keepID uint, ids []uint) ([]uint, error) {
+	keep, err := store.GetTransaction(ctx, keepID)
+	if err != nil {
+		return nil, err
+	}
+	kept, _, ok := toDupCandidate(keep)
+	if !ok {
+		return nil, ErrValidation("only income, expense and transfer transactions can be merged")
+	}
+
+	notes := kept.notes
+	externalID := kept.externalID
+	categoryID := txCategoryID(keep)
+	var others []uint
+	for _, id := range ids {
+		if id == keepID || slices.Contains(others, id) {
+			continue
+		}
+		other, err := store.GetTransaction(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		c, _, ok := toDupCandidate(other)
+		if !ok || c.kind != kept.kind {
+			return nil, ErrValidation("only transactions of the same type can be merged")
+		}
+		if n := strings.TrimSpace(c.notes); n != "" && !strings.Contains(notes, n) {
+			if notes != "" {
+				notes += "\n"
+			}
+			notes += n
+		}
+		if categoryID == 0 {
+			categoryID = txCategoryID(other)
+		}
+		if externalID == "" {
+			externalID = c.externalID
+		}
+		others = append(others, id)
+	}
+	if len(others) == 0 {
+		return nil, ErrValidation("no transactions to merge")
+	}
+
+	var dropped []uint
+	err = store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbTransaction{}).Where("id = ?", keepID).
+			Updates(map[string]any{"notes": notes, "external_id": externalID}).Error; err != nil {
+			return err
+		}
+		// the category comes from a transaction of the same type, so it is valid for keepID too
+		var entry entryType
+		switch keep.(type) {
+		case Income:
+			entry = incomeEntry
+		case Expense:
+			entry = expenseEntry
+		}
+		if entry != unknownentryType && categoryID != txCategoryID(keep) {
+			if err := tx.Model(&dbEntry{}).Where("transaction_id = ? AND entry_type = ?", keepID, entry).
+				Update("category_id", categoryID).Error; err != nil {
+				return err
+			}
+		}
+		moved, err := moveTransactionAttachments(tx, keepID, others)
+		if err != nil {
+			return err
+		}
+		dropped = moved
+		for _, id := range others {
+			if err := store.deleteTransaction(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+func txCategoryID(tx Transaction) uint {
+	switch item := tx.(type) {
+	case Income:
+		return item.CategoryID
+	case Expense:
+		return item.CategoryID
+	}
+	return 0
+}
+
+// moveTransactionAttachments appends the attachments of the given transactions to the ones of txID.
+// Links to an attachment txID already has are deleted; their attachment IDs are returned.
+func moveTransactionAttachments(tx *gorm.DB, txID uint, from []uint) ([]uint, error) {
+	var rows []dbTransactionAttachment
+	if err := tx.Where("transaction_id IN ?", from).Order("transaction_id ASC, position ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	var existing []uint
+	if err := tx.Model(&dbTransactionAttachment{}).Where("transaction_id = ?", txID).Pluck("attachment_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	var dropped []uint
+	pos := len(existing)
+	for _, row := range rows {
+		if slices.Contains(existing, row.AttachmentID) {
+			if err := tx.Delete(&row).Error; err != nil {
+				return nil, err
+			}
+			dropped = append(dropped, row.AttachmentID)
+			continue
+		}
+		existing = append(existing, row.AttachmentID)
+		if err := tx.Model(&row).Updates(map[string]any{"transaction_id": txID, "position": pos}).Error; err != nil {
+			return nil, err
+		}
+		pos++
+	}
+	return dropped, syncPrimaryAttachment(tx, txID)
+}
@@ -0,0 +1,157 @@
+package accounting
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/currency"
+)
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tcs := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{a: "Migros Zürich", b: "MIGROS  zürich!", min: 1, max: 1},
+		{a: "Migros Zurich 1234", b: "Migros Zurich", min: 0.75, max: 0.99},
+		{a: "Migros", b: "Netflix", min: 0, max: 0.2},
+		{a: "", b: "Netflix", min: 0, max: 0},
+	}
+	for _, tc := range tcs {
+		got := descriptionSimilarity(tc.a, tc.b)
+		if got < tc.min || got > tc.max {
+			t.Errorf("similarity of %q and %q: expected between %.2f and %.2f, got %.2f", tc.a, tc.b, tc.min, tc.max, got)
+		}
+	}
+}
+
+func TestFindAndMergeDuplicates(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestFindAndMergeDuplicates"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checking, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			savings, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "savings", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			groceries, err := store.CreateCategory(ctx, CategoryData{Name: "groceries", Type: ExpenseCategory}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			create := func(e Expense) uint {
+				t.Helper()
+				id, err := store.CreateExpense(ctx, e)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return id
+			}
+			// entered by hand, then imported from the statement
+			manual := create(Expense{Description: "Migros", Notes: "weekly shopping", Amount: 84.3, AccountID: checking, CategoryID: groceries, Date: getDate("2025-03-01")})
+			imported := create(Expense{Description: "MIGROS ZURICH", Amount: 84.3, AccountID: checking, Date: getDate("2025-03-02"), ExternalID: "fit-1"})
+			// two lines of the same statement are not duplicates
+			create(Expense{Description: "coffee", Amount: 4.5, AccountID: checking, Date: getDate("2025-03-05"), ExternalID: "fit-2"})
+			create(Expense{Description: "coffee", Amount: 4.5, AccountID: checking, Date: getDate("2025-03-05"), ExternalID: "fit-3"})
+			// same amount in another account, or too far apart
+			create(Expense{Description: "Migros", Amount: 84.3, AccountID: savings, Date: getDate("2025-03-01")})
+			create(Expense{Description: "Migros", Amount: 84.3, AccountID: checking, Date: getDate("2025-03-20")})
+			// a transfer restored twice from a backup
+			for range 2 {
+				if _, err := store.CreateTransfer(ctx, Transfer{Description: "savings", OriginAmount: 500, OriginAccountID: checking,
+					TargetAmount: 500, TargetAccountID: savings, Date: getDate("2025-03-10")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// two standing orders on the same statement are not duplicates either
+			for _, fitID := range []string{"fit-4", "fit-5"} {
+				if _, err := store.CreateTransfer(ctx, Transfer{Description: "rent reserve", OriginAmount: 200, OriginAccountID: checking,
+					TargetAmount: 200, TargetAccountID: savings, Date: getDate("2025-03-25"), ExternalID: fitID}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			groups, err := store.FindDuplicates(ctx, DuplicateOpts{StartDate: getDate("2025-01-01"), EndDate: getDate("2025-12-31")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(groups) != 2 {
+				t.Fatalf("expected 2 duplicate groups, got %d: %+v", len(groups), groups)
+			}
+			if _, ok := groups[0].Transactions[0].(Transfer); !ok || groups[0].Confidence != 1 {
+				t.Errorf("expected the identical transfers first with full confidence, got %+v", groups[0])
+			}
+			migros := groups[1]
+			if len(migros.Transactions) != 2 || migros.Confidence >= 1 || migros.Confidence < DefaultDuplicateMinConfidence {
+				t.Errorf("unexpected migros group: %+v", migros)
+			}
+			if migros.KeepID != imported {
+				t.Errorf("expected the imported transaction to be suggested, got %d", migros.KeepID)
+			}
+
+			// 43 is linked to both, as the same scanned statement deduplicated by the file store
+			if _, err := store.AddTransactionAttachment(ctx, imported, 43, "statement"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.AddTransactionAttachment(ctx, manual, 42, "receipt"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.AddTransactionAttachment(ctx, manual, 43, "statement"); err != nil {
+				t.Fatal(err)
+			}
+			dropped, err := store.MergeTransactions(ctx, imported, []uint{manual, imported})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]uint{43}, dropped); diff != "" {
+				t.Errorf("unexpected dropped attachments (-want +got):\n%s", diff)
+			}
+			if _, err := store.GetTransaction(ctx, manual); err == nil {
+				t.Error("expected the merged transaction to be deleted")
+			}
+			got, err := store.GetTransaction(ctx, imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept := got.(Expense)
+			if kept.Notes != "weekly shopping" || kept.CategoryID != groceries || kept.ExternalID != "fit-1" {
+				t.Errorf("expected notes and category taken over, got %+v", kept)
+			}
+			if kept.AttachmentID == nil || *kept.AttachmentID != 43 {
+				t.Errorf("expected the first attachment to stay primary, got %v", kept.AttachmentID)
+			}
+			links, err := store.ListTransactionAttachments(ctx, imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var linked []uint
+			for _, l := range links {
+				linked = append(linked, l.AttachmentID)
+			}
+			if diff := cmp.Diff([]uint{43, 42}, linked); diff != "" {
+				t.Errorf("expected the receipt moved after the statement (-want +got):\n%s", diff)
+			}
+
+			var valErr ErrValidation
+			transfer := groups[0].Transactions[0].(Transfer)
+			if _, err := store.MergeTransactions(ctx, imported, []uint{transfer.Id}); !errors.As(err, &valErr) {
+				t.Errorf("expected a validation error merging different types, got %v", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type entryType int
@@ -129,3 +131,55 @@ func (store *Store) sumBalanceEntries(ctx context.Context, opts sumEntriesOpts)
 	}
 	return target, nil
 }
+
+// CategorizedEntry is an income or expense with a category, as used to learn category suggestions.
+type CategorizedEntry struct {
+	TransactionID uint
+	Description   string
+	Amount        float64 // negative for expenses
+	AccountID     uint
+	CategoryID    uint
+}
+
+// categorizedEntries selects the entries of income and expense transactions that have a category.
+func (store *Store) categorizedEntries(ctx context.Context) *gorm.DB {
+	return store.db.WithContext(ctx).Table("db_entries").
+		Joins("JOIN db_transactions ON db_transactions.id = db_entries.transaction_id").
+		Where("db_entries.entry_type IN (?)", []entryType{incomeEntry, expenseEntry}).
+		Where("db_transactions.type IN (?)", []TxType{IncomeTransaction, ExpenseTransaction}).
+		Where("db_entries.category_id <> 0")
+}
+
+// ListCategorizedEntries returns all income and expense transactions that have a category,
+// ordered by transaction id.
+func (store *Store) ListCategorizedEntries(ctx context.Context) ([]CategorizedEntry, error) {
+	var rows []CategorizedEntry
+	q := store.categorizedEntries(ctx).
+		Select("db_entries.transaction_id, db_transactions.description, db_entries.amount, db_entries.account_id, db_entries.category_id").
+		Order("db_entries.transaction_id").
+		Scan(&rows)
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	return rows, nil
+}
+
+// CategorizedEntriesVersion returns a value that changes whenever the result of
+// ListCategorizedEntries changes, without loading the entries: the number of categorized entries
+// and the last update of them and of their transactions.
+func (store *Store) CategorizedEntriesVersion(ctx context.Context) (string, error) {
+	var row struct {
+		Count     int64
+		EntriesAt string
+		TxAt      string
+	}
+	q := store.categorizedEntries(ctx).
+		Select("COUNT(*) AS count, " +
+			"COALESCE(CAST(MAX(db_entries.updated_at) AS TEXT), '') AS entries_at, " +
+			"COALESCE(CAST(MAX(db_transactions.updated_at) AS TEXT), '') AS tx_at").
+		Scan(&row)
+	if q.Error != nil {
+		return "", q.Error
+	}
+	return fmt.Sprintf("%d/%s/%s", row.Count, row.EntriesAt, row.TxAt), nil
+}
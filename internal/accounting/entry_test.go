@@ -159,3 +159,85 @@ func TestSumEntries(t *testing.T) {
 		})
 	}
 }
+
+func TestListCategorizedEntries(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			dbCon := db.ConnDbName("TestListCategorizedEntries")
+			store, err := NewStore(dbCon, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			categorySampleData(t, store, sampleCategories)
+			transactionSampleData(t, store, map[int]Transaction{
+				1: Expense{Description: "groceries", Date: getDate("2022-01-01"), Amount: 100, AccountID: 1, CategoryID: 2},
+				2: Income{Description: "salary", Date: getDate("2022-01-02"), Amount: 1000, AccountID: 1, CategoryID: 1},
+				3: Expense{Description: "unknown", Date: getDate("2022-01-03"), Amount: 10, AccountID: 1},
+				4: Transfer{Description: "t1", Date: getDate("2022-01-04"), OriginAmount: 10, OriginAccountID: 1, TargetAmount: 10, TargetAccountID: 2},
+			})
+
+			got, err := store.ListCategorizedEntries(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("expected the two categorized entries, got %+v", got)
+			}
+			if got[0].Description != "groceries" || got[0].Amount != -100 || got[0].CategoryID != 2 || got[0].AccountID != 1 {
+				t.Errorf("unexpected expense %+v", got[0])
+			}
+			if got[1].Description != "salary" || got[1].Amount != 1000 || got[1].CategoryID != 1 {
+				t.Errorf("unexpected income %+v", got[1])
+			}
+		})
+	}
+}
+
+func TestCategorizedEntriesVersion(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			dbCon := db.ConnDbName("TestCategorizedEntriesVersion")
+			store, err := NewStore(dbCon, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			categorySampleData(t, store, sampleCategories)
+			transactionSampleData(t, store, map[int]Transaction{
+				1: Expense{Description: "groceries", Date: getDate("2022-01-01"), Amount: 100, AccountID: 1, CategoryID: 2},
+				2: Expense{Description: "bakery", Date: getDate("2022-01-02"), Amount: 10, AccountID: 1, CategoryID: 2},
+			})
+			ctx := t.Context()
+
+			version := func() string {
+				t.Helper()
+				v, err := store.CategorizedEntriesVersion(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return v
+			}
+			last := version()
+			if got := version(); got != last {
+				t.Fatalf("expected the same version without changes, got %q and %q", last, got)
+			}
+
+			desc := "supermarket"
+			cat := uint(6)
+			changes := map[string]func() error{
+				"description": func() error { return store.UpdateExpense(ctx, ExpenseUpdate{Description: &desc}, 1) },
+				"category":    func() error { return store.UpdateExpense(ctx, ExpenseUpdate{CategoryID: &cat}, 2) },
+				"delete":      func() error { return store.DeleteTransaction(ctx, 2) },
+			}
+			for _, name := range []string{"description", "category", "delete"} {
+				if err := changes[name](); err != nil {
+					t.Fatal(err)
+				}
+				got := version()
+				if got == last {
+					t.Errorf("expected the version to change after the %s change, still %q", name, got)
+				}
+				last = got
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSavingsGoalNotFound = errors.New("savings goal not found")
+
+// DefaultGoalLookbackMonths is the number of months used to compute the recent contribution rate of a goal.
+const DefaultGoalLookbackMonths = 3
+
+// avgDaysPerMonth is used to express durations in (fractional) months.
+const avgDaysPerMonth = 365.25 / 12
+
+type dbSavingsGoal struct {
+	ID           uint      `gorm:"primaryKey"`
+	Name         string    `gorm:"not null"`
+	Notes        string    `gorm:"size:1024"`
+	TargetAmount float64   `gorm:"not null"`
+	TargetDate   time.Time `gorm:"not null"`
+	Icon         string
+	Accounts     []dbSavingsGoalAccount `gorm:"foreignKey:GoalID"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type dbSavingsGoalAccount struct {
+	ID        uint    `gorm:"primaryKey"`
+	GoalID    uint    `gorm:"not null;index"`
+	AccountID uint    `gorm:"not null;index"`
+	Share     float64 `gorm:"not null;default:1"` // portion of the account balance that counts towards the goal
+}
+
+// SavingsGoal is a target amount (in main currency) to be reached by a date, funded by the
+// balance of one or more accounts, or a portion of them.
+type SavingsGoal struct {
+	ID           uint
+	Name         string
+	Notes        string
+	Icon         string
+	TargetAmount float64
+	TargetDate   time.Time
+	Accounts     []SavingsGoalAccount
+}
+
+// SavingsGoalAccount links an account to a goal, Share is the portion (0-1] of its balance assigned to the goal.
+type SavingsGoalAccount struct {
+	AccountID uint
+	Share     float64
+}
+
+type SavingsGoalUpdate struct {
+	Name         *string
+	Notes        *string
+	Icon         *string
+	TargetAmount *float64
+	TargetDate   *time.Time
+	Accounts     *[]SavingsGoalAccount
+}
+
+func dbToSavingsGoal(in dbSavingsGoal) SavingsGoal {
+	g := SavingsGoal{
+		ID:           in.ID,
+		Name:         in.Name,
+		Notes:        in.Notes,
+		Icon:         in.Icon,
+		TargetAmount: in.TargetAmount,
+		TargetDate:   in.TargetDate,
+		Accounts:     make([]SavingsGoalAccount, 0, len(in.Accounts)),
+	}
+	for _, a := range in.Accounts {
+		g.Accounts = append(g.Accounts, SavingsGoalAccount{AccountID: a.AccountID, Share: a.Share})
+	}
+	return g
+}
+
+func (store *Store) goalAccountsToDb(ctx context.Context, in []SavingsGoalAccount) ([]dbSavingsGoalAccount, error) {
+	if len(in) == 0 {
+		return nil, ErrValidation("at least one account is required")
+	}
+	seen := map[uint]bool{}
+	out := make([]dbSavingsGoalAccount, 0, len(in))
+	for _, a := range in {
+		if seen[a.AccountID] {
+			return nil, ErrValidation("an account can only be linked once to a goal")
+		}
+		seen[a.AccountID] = true
+		if _, err := store.GetAccount(ctx, a.AccountID); err != nil {
+			return nil, err
+		}
+		share := a.Share
+		if share == 0 {
+			share = 1
+		}
+		if share < 0 || share > 1 {
+			return nil, ErrValidation("account share must be between 0 and 1")
+		}
+		out = append(out, dbSavingsGoalAccount{AccountID: a.AccountID, Share: share})
+	}
+	return out, nil
+}
+
+func (store *Store) CreateSavingsGoal(ctx context.Context, item SavingsGoal) (uint, error) {
+	if item.Name == "" {
+		return 0, ErrValidation("name cannot be empty")
+	}
+	if item.TargetAmount <= 0 {
+		return 0, ErrValidation("target amount must be positive")
+	}
+	if item.TargetDate.IsZero() {
+		return 0, ErrValidation("target date is required")
+	}
+	accounts, err := store.goalAccountsToDb(ctx, item.Accounts)
+	if err != nil {
+		return 0, err
+	}
+
+	payload := dbSavingsGoal{
+		Name:         item.Name,
+		Notes:        item.Notes,
+		Icon:         item.Icon,
+		TargetAmount: item.TargetAmount,
+		TargetDate:   item.TargetDate,
+		Accounts:     accounts,
+	}
+	if err := store.db.WithContext(ctx).Create(&payload).Error; err != nil {
+		return 0, err
+	}
+	return payload.ID, nil
+}
+
+func (store *Store) GetSavingsGoal(ctx context.Context, id uint) (SavingsGoal, error) {
+	var payload dbSavingsGoal
+	d := store.db.WithContext(ctx).Preload("Accounts").Where("id = ?", id).First(&payload)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return SavingsGoal{}, ErrSavingsGoalNotFound
+		}
+		return SavingsGoal{}, d.Error
+	}
+	return dbToSavingsGoal(payload), nil
+}
+
+func (store *Store) ListSavingsGoals(ctx context.Context) ([]SavingsGoal, error) {
+	var rows []dbSavingsGoal
+	if err := store.db.WithContext(ctx).Preload("Accounts").Order("target_date ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	goals := make([]SavingsGoal, 0, len(rows))
+	for _, row := range rows {
+		goals = append(goals, dbToSavingsGoal(row))
+	}
+	return goals, nil
+}
+
+func (store *Store) UpdateSavingsGoal(ctx context.Context, input SavingsGoalUpdate, id uint) error {
+	var selectedFields []string
+	var payload dbSavingsGoal
+
+	if input.Name != nil {
+		if *input.Name == "" {
+			return ErrValidation("name cannot be empty")
+		}
+		payload.Name = *input.Name
+		selectedFields = append(selectedFields, "Name")
+	}
+	if input.Notes != nil {
+		payload.Notes = *input.Notes
+		selectedFields = append(selectedFields, "Notes")
+	}
+	if input.Icon != nil {
+		payload.Icon = *input.Icon
+		selectedFields = append(selectedFields, "Icon")
+	}
+	if input.TargetAmount != nil {
+		if *input.TargetAmount <= 0 {
+			return ErrValidation("target amount must be positive")
+		}
+		payload.TargetAmount = *input.TargetAmount
+		selectedFields = append(selectedFields, "TargetAmount")
+	}
+	if input.TargetDate != nil {
+		if input.TargetDate.IsZero() {
+			return ErrValidation("target date is required")
+		}
+		payload.TargetDate = *input.TargetDate
+		selectedFields = append(selectedFields, "TargetDate")
+	}
+	var accounts []dbSavingsGoalAccount
+	if input.Accounts != nil {
+		var err error
+		accounts, err = store.goalAccountsToDb(ctx, *input.Accounts)
+		if err != nil {
+			return err
+		}
+	}
+	if len(selectedFields) == 0 && input.Accounts == nil {
+		return ErrNoChanges
+	}
+
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&dbSavingsGoal{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrSavingsGoalNotFound
+		}
+		if len(selectedFields) > 0 {
+			if err := tx.Model(&dbSavingsGoal{}).Where("id = ?", id).Select(selectedFields).Updates(payload).Error; err != nil {
+				return err
+			}
+		}
+		if input.Accounts != nil {
+			if err := tx.Where("goal_id = ?", id).Delete(&dbSavingsGoalAccount{}).Error; err != nil {
+				return err
+			}
+			for i := range accounts {
+				accounts[i].GoalID = id
+			}
+			if err := tx.Create(&accounts).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (store *Store) DeleteSavingsGoal(ctx context.Context, id uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("goal_id = ?", id).Delete(&dbSavingsGoalAccount{}).Error; err != nil {
+			return err
+		}
+		d := tx.Where("id = ?", id).Delete(&dbSavingsGoal{})
+		if d.Error != nil {
+			return d.Error
+		}
+		if d.RowsAffected == 0 {
+			return ErrSavingsGoalNotFound
+		}
+		return nil
+	})
+}
+
+// SavingsGoalProgress describes how far a goal is and how it is expected to evolve.
+type SavingsGoalProgress struct {
+	GoalID          uint
+	Date            time.Time
+	Current         float64 // current amount assigned to the goal
+	Remaining       float64 // amount still missing to reach the target, 0 once reached
+	Progress        float64 // Current / TargetAmount, capped at 1
+	RequiredMonthly float64 // monthly contribution needed to reach the target by the target date
+	MonthlyRate     float64 // average monthly contribution over the lookback period
+	// ProjectedDate is the date the goal will be reached at the current rate;
+	// nil if the rate is not positive and the goal is not reached yet.
+	ProjectedDate *time.Time
+	OnTrack       bool // true if the projected date is on or before the target date
+	Unconverted   bool // true if any balance could not be converted to the main currency
+}
+
+// GoalProgress computes the progress of a goal at the given date; the recent contribution rate is
+// derived from the balance change over the last lookbackMonths months (DefaultGoalLookbackMonths if <= 0).
+func (store *Store) GoalProgress(ctx context.Context, id uint, date time.Time, lookbackMonths int) (SavingsGoalProgress, error) {
+	goal, err := store.GetSavingsGoal(ctx, id)
+	if err != nil {
+		return SavingsGoalProgress{}, err
+	}
+	if lookbackMonths <= 0 {
+		lookbackMonths = DefaultGoalLookbackMonths
+	}
+
+	out := SavingsGoalProgress{GoalID: goal.ID, Date: date}
+	pastDate := date.AddDate(0, -lookbackMonths, 0)
+	var past float64
+	for _, a := range goal.Accounts {
+		now, err := store.AccountBalanceSingle(ctx, a.AccountID, date)
+		if err != nil {
+			return SavingsGoalProgress{}, err
+		}
+		before, err := store.AccountBalanceSingle(ctx, a.AccountID, pastDate)
+		if err != nil {
+			return SavingsGoalProgress{}, err
+		}
+		out.Current += now.Sum * a.Share
+		past += before.Sum * a.Share
+		out.Unconverted = out.Unconverted || now.Unconverted || before.Unconverted
+	}
+	out.Current = roundMoney(out.Current)
+	out.MonthlyRate = roundMoney((out.Current - past) / float64(lookbackMonths))
+
+	out.Remaining = math.Max(0, roundMoney(goal.TargetAmount-out.Current))
+	out.Progress = math.Min(1, out.Current/goal.TargetAmount)
+	if out.Remaining == 0 {
+		out.ProjectedDate = &date
+		out.OnTrack = true
+		return out, nil
+	}
+
+	monthsLeft := goal.TargetDate.Sub(date).Hours() / 24 / avgDaysPerMonth
+	if monthsLeft < 1 {
+		// target date is (almost) due: everything missing has to be contributed now
+		out.RequiredMonthly = out.Remaining
+	} else {
+		out.RequiredMonthly = roundMoney(out.Remaining / monthsLeft)
+	}
+
+	if out.MonthlyRate > 0 {
+		months := out.Remaining / out.MonthlyRate
+		projected := date.Add(time.Duration(months * avgDaysPerMonth * 24 * float64(time.Hour)))
+		out.ProjectedDate = &projected
+		out.OnTrack = !projected.After(goal.TargetDate)
+	}
+	return out, nil
+}
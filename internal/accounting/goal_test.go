@@ -0,0 +1,202 @@
+package accounting
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/currency"
+)
+
+func goalSampleData(t *testing.T, store *Store) (savingsID, checkingID uint) {
+	t.Helper()
+	ctx := t.Context()
+
+	providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	savingsID, err = store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "savings", Currency: currency.CHF, Type: SavingsAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkingID, err = store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	incomes := []Income{
+		{Description: "initial", AccountID: savingsID, Amount: 1000, Date: getDate("2025-01-01")},
+		{Description: "saving", AccountID: savingsID, Amount: 300, Date: getDate("2025-02-01")},
+		{Description: "saving", AccountID: savingsID, Amount: 300, Date: getDate("2025-03-01")},
+		{Description: "saving", AccountID: savingsID, Amount: 300, Date: getDate("2025-04-01")},
+		{Description: "salary", AccountID: checkingID, Amount: 200, Date: getDate("2025-01-01")},
+	}
+	for _, in := range incomes {
+		if _, err := store.CreateIncome(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return savingsID, checkingID
+}
+
+func TestSavingsGoalCrud(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestSavingsGoalCrud"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+			savingsID, checkingID := goalSampleData(t, store)
+
+			_, err = store.CreateSavingsGoal(ctx, SavingsGoal{Name: "car", TargetAmount: 1000, TargetDate: getDate("2026-01-01")})
+			if err == nil || err.Error() != "at least one account is required" {
+				t.Errorf("expected missing accounts error, got %v", err)
+			}
+			_, err = store.CreateSavingsGoal(ctx, SavingsGoal{Name: "car", TargetAmount: 1000, TargetDate: getDate("2026-01-01"),
+				Accounts: []SavingsGoalAccount{{AccountID: savingsID, Share: 1.5}}})
+			if err == nil || err.Error() != "account share must be between 0 and 1" {
+				t.Errorf("expected share error, got %v", err)
+			}
+
+			id, err := store.CreateSavingsGoal(ctx, SavingsGoal{
+				Name:         "car",
+				TargetAmount: 5000,
+				TargetDate:   getDate("2026-01-01"),
+				Accounts:     []SavingsGoalAccount{{AccountID: savingsID}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = store.UpdateSavingsGoal(ctx, SavingsGoalUpdate{
+				Name:     ptr("new car"),
+				Accounts: &[]SavingsGoalAccount{{AccountID: savingsID, Share: 1}, {AccountID: checkingID, Share: 0.5}},
+			}, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.GetSavingsGoal(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := SavingsGoal{
+				ID:           id,
+				Name:         "new car",
+				TargetAmount: 5000,
+				TargetDate:   getDate("2026-01-01"),
+				Accounts:     []SavingsGoalAccount{{AccountID: savingsID, Share: 1}, {AccountID: checkingID, Share: 0.5}},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+
+			// deleting a linked account removes the link
+			tmpID, err := store.CreateAccount(ctx, Account{AccountProviderID: 1, Name: "tmp", Currency: currency.CHF, Type: CashAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.UpdateSavingsGoal(ctx, SavingsGoalUpdate{
+				Accounts: &[]SavingsGoalAccount{{AccountID: savingsID, Share: 1}, {AccountID: tmpID, Share: 1}},
+			}, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.DeleteAccount(ctx, tmpID); err != nil {
+				t.Fatal(err)
+			}
+			got, err = store.GetSavingsGoal(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Accounts) != 1 || got.Accounts[0].AccountID != savingsID {
+				t.Errorf("expected only the savings account to remain linked, got %+v", got.Accounts)
+			}
+
+			if err := store.DeleteSavingsGoal(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			_, err = store.GetSavingsGoal(ctx, id)
+			if !errors.Is(err, ErrSavingsGoalNotFound) {
+				t.Errorf("expected ErrSavingsGoalNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGoalProgress(t *testing.T) {
+	tcs := []struct {
+		name   string
+		target float64
+		date   time.Time
+		want   SavingsGoalProgress
+	}{
+		{
+			name:   "on track",
+			target: 3000,
+			date:   getDate("2025-04-30"),
+			want: SavingsGoalProgress{
+				Current:         2000, // 1900 savings + 50% of 200 checking
+				Remaining:       1000,
+				Progress:        2000.0 / 3000,
+				MonthlyRate:     300,
+				RequiredMonthly: 124.23, // 1000 over ~8.05 months
+				OnTrack:         true,
+			},
+		},
+		{
+			name:   "reached",
+			target: 1500,
+			date:   getDate("2025-04-30"),
+			want: SavingsGoalProgress{
+				Current:     2000,
+				Remaining:   0,
+				Progress:    1,
+				MonthlyRate: 300,
+				OnTrack:     true,
+			},
+		},
+	}
+
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestGoalProgress"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+			savingsID, checkingID := goalSampleData(t, store)
+
+			for _, tc := range tcs {
+				t.Run(tc.name, func(t *testing.T) {
+					id, err := store.CreateSavingsGoal(ctx, SavingsGoal{
+						Name:         tc.name,
+						TargetAmount: tc.target,
+						TargetDate:   getDate("2025-12-31"),
+						Accounts:     []SavingsGoalAccount{{AccountID: savingsID}, {AccountID: checkingID, Share: 0.5}},
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := store.GoalProgress(ctx, id, tc.date, 3)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got.ProjectedDate == nil {
+						t.Fatal("expected a projected date")
+					}
+					got.ProjectedDate = nil
+					tc.want.GoalID = id
+					tc.want.Date = tc.date
+					if diff := cmp.Diff(tc.want, got); diff != "" {
+						t.Errorf("unexpected result (-want +got):\n%s", diff)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrPersonNotFound = errors.New("person not found")
+
+// =======================================================================================
+// People
+// =======================================================================================
+
+// dbPerson is someone expenses are shared with; what they owe is tracked on their own Lent account.
+type dbPerson struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	AccountID uint   `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Person struct {
+	ID        uint
+	Name      string
+	AccountID uint // Lent account holding the receivables of this person
+}
+
+type PersonUpdate struct {
+	Name *string
+}
+
+func (store *Store) CreatePerson(ctx context.Context, item Person) (uint, error) {
+	if item.Name == "" {
+		return 0, ErrValidation("name cannot be empty")
+	}
+	if item.AccountID == 0 {
+		return 0, ErrValidation("account id is required")
+	}
+	acc, err := store.GetAccount(ctx, item.AccountID)
+	if err != nil {
+		return 0, fmt.Errorf("error creating person: %w", err)
+	}
+	if acc.Type != LentAccountType {
+		return 0, ErrValidation("the account of a person must be a lent account")
+	}
+	var count int64
+	if err := store.db.WithContext(ctx).Model(&dbPerson{}).Where("account_id = ?", item.AccountID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrValidation("the account is already assigned to another person")
+	}
+
+	payload := dbPerson{Name: item.Name, AccountID: item.AccountID}
+	if err := store.db.WithContext(ctx).Create(&payload).Error; err != nil {
+		return 0, err
+	}
+	return payload.ID, nil
+}
+
+func (store *Store) GetPerson(ctx context.Context, id uint) (Person, error) {
+	var payload dbPerson
+	d := store.db.WithContext(ctx).Where("id = ?", id).First(&payload)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return Person{}, ErrPersonNotFound
+		}
+		return Person{}, d.Error
+	}
+	return Person{ID: payload.ID, Name: payload.Name, AccountID: payload.AccountID}, nil
+}
+
+func (store *Store) ListPeople(ctx context.Context) ([]Person, error) {
+	var rows []dbPerson
+	if err := store.db.WithContext(ctx).Order("name ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	people := make([]Person, 0, len(rows))
+	for _, r := range rows {
+		people = append(people, Person{ID: r.ID, Name: r.Name, AccountID: r.AccountID})
+	}
+	return people, nil
+}
+
+func (store *Store) UpdatePerson(ctx context.Context, input PersonUpdate, id uint) error {
+	if input.Name == nil {
+		return ErrNoChanges
+	}
+	if *input.Name == "" {
+		return ErrValidation("name cannot be empty")
+	}
+	d := store.db.WithContext(ctx).Model(&dbPerson{}).Where("id = ?", id).Update("name", *input.Name)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrPersonNotFound
+	}
+	return nil
+}
+
+// DeletePerson removes a person; it is refused while expenses are still shared with them.
+func (store *Store) DeletePerson(ctx context.Context, id uint) error {
+	var count int64
+	if err := store.db.WithContext(ctx).Model(&dbExpenseShare{}).Where("person_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrValidation("the person still has shared expenses")
+	}
+	d := store.db.WithContext(ctx).Where("id = ?", id).Delete(&dbPerson{})
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrPersonNotFound
+	}
+	return nil
+}
+
+// =======================================================================================
+// Shared expenses
+// =======================================================================================
+
+type SplitRule string
+
+const (
+	SplitEqual      SplitRule = "equal"      // the expense is divided equally between the people (and self)
+	SplitPercentage SplitRule = "percentage" // each person owes a percentage of the expense
+	SplitExact      SplitRule = "exact"      // each person owes an exact amount
+)
+
+// dbExpenseShare is the portion of an expense owed by a person, ReceivableID is the transaction
+// that books the owed amount on the person's Lent account.
+type dbExpenseShare struct {
+	ID            uint      `gorm:"primaryKey"`
+	TransactionID uint      `gorm:"not null;index"` // the shared expense
+	PersonID      uint      `gorm:"not null;index"`
+	Rule          SplitRule `gorm:"not null"`
+	Value         float64   // percentage or exact amount, depending on the rule
+	IncludeSelf   bool      // for equal splits: count the payer as one of the parts
+	Amount        float64   `gorm:"not null"` // computed owed amount
+	ReceivableID  uint      `gorm:"index"`
+}
+
+// ExpenseSplit describes how an expense is shared.
+type ExpenseSplit struct {
+	Rule        SplitRule
+	IncludeSelf bool // only for SplitEqual
+	Shares      []ExpenseShare
+}
+
+type ExpenseShare struct {
+	PersonID     uint
+	Value        float64 // percentage (0-100] for SplitPercentage, amount for SplitExact, ignored for SplitEqual
+	Amount       float64 // read only: owed amount
+	ReceivableID uint    // read only: transaction booking the receivable
+}
+
+// computeShares returns the owed amount for each share of an expense of the given total.
+func computeShares(total float64, split ExpenseSplit) ([]float64, error) {
+	n := len(split.Shares)
+	if n == 0 {
+		return nil, ErrValidation("at least one person is required")
+	}
+	out := make([]float64, n)
+	switch split.Rule {
+	case SplitEqual:
+		parts := n
+		if split.IncludeSelf {
+			parts++
+		}
+		each := roundMoney(total / float64(parts))
+		for i := range out {
+			out[i] = each
+		}
+		if !split.IncludeSelf {
+			// assign the rounding remainder to the last person so that the full amount is owed
+			out[n-1] = roundMoney(total - each*float64(n-1))
+		}
+	case SplitPercentage:
+		var sum float64
+		for i, s := range split.Shares {
+			if s.Value <= 0 || s.Value > 100 {
+				return nil, ErrValidation("percentage must be between 0 and 100")
+			}
+			sum += s.Value
+			out[i] = roundMoney(total * s.Value / 100)
+		}
+		if sum > 100+1e-9 {
+			return nil, ErrValidation("percentages cannot add up to more than 100")
+		}
+	case SplitExact:
+		var sum float64
+		for i, s := range split.Shares {
+			if s.Value <= 0 {
+				return nil, ErrValidation("exact amounts must be positive")
+			}
+			sum += s.Value
+			out[i] = roundMoney(s.Value)
+		}
+		if roundMoney(sum) > roundMoney(total) {
+			return nil, ErrValidation("exact amounts cannot add up to more than the expense")
+		}
+	default:
+		return nil, ErrValidation(fmt.Sprintf("invalid split rule %q: must be equal, percentage or exact", split.Rule))
+	}
+	return out, nil
+}
+
+// GetExpenseSplit returns how an expense is shared; an empty split is returned if it is not shared.
+func (store *Store) GetExpenseSplit(ctx context.Context, txID uint) (ExpenseSplit, error) {
+	var rows []dbExpenseShare
+	if err := store.db.WithContext(ctx).Where("transaction_id = ?", txID).Order("id ASC").Find(&rows).Error; err != nil {
+		return ExpenseSplit{}, err
+	}
+	split := ExpenseSplit{Shares: []ExpenseShare{}}
+	for _, r := range rows {
+		split.Rule = r.Rule
+		split.IncludeSelf = r.IncludeSelf
+		split.Shares = append(split.Shares, ExpenseShare{PersonID: r.PersonID, Value: r.Value, Amount: r.Amount, ReceivableID: r.ReceivableID})
+	}
+	return split, nil
+}
+
+// ListExpenseSplits returns all shared expenses keyed by the id of the expense transaction.
+func (store *Store) ListExpenseSplits(ctx context.Context) (map[uint]ExpenseSplit, error) {
+	var rows []dbExpenseShare
+	if err := store.db.WithContext(ctx).Order("transaction_id ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := map[uint]ExpenseSplit{}
+	for _, r := range rows {
+		split := out[r.TransactionID]
+		split.Rule = r.Rule
+		split.IncludeSelf = r.IncludeSelf
+		split.Shares = append(split.Shares, ExpenseShare{PersonID: r.PersonID, Value: r.Value, Amount: r.Amount, ReceivableID: r.ReceivableID})
+		out[r.TransactionID] = split
+	}
+	return out, nil
+}
+
+// RestoreExpenseSplit stores a split as is, linking the already existing receivable transactions;
+// it is used when restoring a backup, where the receivables are imported as regular transactions.
+func (store *Store) RestoreExpenseSplit(ctx context.Context, txID uint, split ExpenseSplit) error {
+	if _, err := store.GetTransaction(ctx, txID); err != nil {
+		return err
+	}
+	rows := make([]dbExpenseShare, 0, len(split.Shares))
+	for _, s := range split.Shares {
+		if _, err := store.GetPerson(ctx, s.PersonID); err != nil {
+			return err
+		}
+		rows = append(rows, dbExpenseShare{
+			TransactionID: txID,
+			PersonID:      s.PersonID,
+			Rule:          split.Rule,
+			Value:         s.Value,
+			IncludeSelf:   split.IncludeSelf,
+			Amount:        s.Amount,
+			ReceivableID:  s.ReceivableID,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return store.db.WithContext(ctx).Create(&rows).Error
+}
+
+// SetExpenseSplit marks an expense as shared and creates the receivables for the owed portions:
+// a counter expense on each person's Lent account in the same category, so that reports only
+// show the own part of the expense and the Lent account reflects what the person owes.
+// Calling it again replaces the previous split and its receivables.
+func (store *Store) SetExpenseSplit(ctx context.Context, txID uint, split ExpenseSplit) error {
+	tx, err := store.GetTransaction(ctx, txID)
+	if err != nil {
+		return err
+	}
+	expense, ok := tx.(Expense)
+	if !ok {
+		return ErrValidation("only expenses can be shared")
+	}
+	write, err := store.prepareExpenseSplit(ctx, txID, expense, split)
+	if err != nil {
+		return err
+	}
+	return store.db.WithContext(ctx).Transaction(write)
+}
+
+// prepareExpenseSplit validates the split of an expense and returns the function writing it within
+// a database transaction, replacing the previous split and its receivables.
+func (store *Store) prepareExpenseSplit(ctx context.Context, txID uint, expense Expense, split ExpenseSplit) (func(dbTx *gorm.DB) error, error) {
+	if expense.Amount <= 0 {
+		return nil, ErrValidation("only expenses with a positive amount can be shared")
+	}
+	if isReceivable, err := store.isReceivable(ctx, txID); err != nil {
+		return nil, err
+	} else if isReceivable {
+		return nil, ErrValidation("a receivable cannot be shared")
+	}
+
+	amounts, err := computeShares(expense.Amount, split)
+	if err != nil {
+		return nil, err
+	}
+	expenseAcc, err := store.GetAccount(ctx, expense.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	people := make([]Person, len(split.Shares))
+	seen := map[uint]bool{}
+	for i, s := range split.Shares {
+		if seen[s.PersonID] {
+			return nil, ErrValidation("a person can only be added once to a shared expense")
+		}
+		seen[s.PersonID] = true
+		p, err := store.GetPerson(ctx, s.PersonID)
+		if err != nil {
+			return nil, err
+		}
+		if p.AccountID == expense.AccountID {
+			return nil, ErrValidation("the expense cannot be shared with the person that paid it")
+		}
+		personAcc, err := store.GetAccount(ctx, p.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		if personAcc.Currency != expenseAcc.Currency {
+			return nil, ErrValidation(fmt.Sprintf("the account of %s must have the same currency as the expense account", p.Name))
+		}
+		people[i] = p
+	}
+
+	return func(dbTx *gorm.DB) error {
+		if err := deleteExpenseShares(ctx, dbTx, txID); err != nil {
+			return err
+		}
+		for i, s := range split.Shares {
+			receivable := dbTransaction{
+				Description: fmt.Sprintf("%s (%s)", expense.Description, people[i].Name),
+				Date:        expense.Date,
+				Type:        ExpenseTransaction,
+				Entries: []dbEntry{
+					{
+						AccountID:  people[i].AccountID,
+						CategoryID: expense.CategoryID,
+						Amount:     amounts[i],
+						EntryType:  expenseEntry,
+					},
+				},
+			}
+			if err := dbTx.WithContext(ctx).Create(&receivable).Error; err != nil {
+				return err
+			}
+			share := dbExpenseShare{
+				TransactionID: txID,
+				PersonID:      s.PersonID,
+				Rule:          split.Rule,
+				Value:         s.Value,
+				IncludeSelf:   split.IncludeSelf,
+				Amount:        amounts[i],
+				ReceivableID:  receivable.Id,
+			}
+			if err := dbTx.WithContext(ctx).Create(&share).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// ClearExpenseSplit removes the sharing of an expense together with its receivables.
+func (store *Store) ClearExpenseSplit(ctx context.Context, txID uint) error {
+	return store.db.WithContext(ctx).Transaction(func(dbTx *gorm.DB) error {
+		return deleteExpenseShares(ctx, dbTx, txID)
+	})
+}
+
+// prepareSplitRefresh recomputes the receivables of a shared expense for an update of it and
+// returns the function writing them within the database transaction of the update; it returns nil
+// if the expense is not shared.
+func (store *Store) prepareSplitRefresh(ctx context.Context, txID uint, params updateIncomeExpenseParams) (func(dbTx *gorm.DB) error, error) {
+	split, err := store.GetExpenseSplit(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if len(split.Shares) == 0 {
+		return nil, nil
+	}
+	tx, err := store.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	expense, ok := tx.(Expense)
+	if !ok {
+		return nil, ErrValidation("only expenses can be shared")
+	}
+	if params.description != nil {
+		expense.Description = *params.description
+	}
+	if params.date != nil {
+		expense.Date = *params.date
+	}
+	if params.amount != nil {
+		expense.Amount = *params.amount
+	}
+	if params.accountID != nil {
+		expense.AccountID = *params.accountID
+	}
+	if params.categoryID != nil {
+		expense.CategoryID = *params.categoryID
+	}
+	return store.prepareExpenseSplit(ctx, txID, expense, split)
+}
+
+func (store *Store) isReceivable(ctx context.Context, txID uint) (bool, error) {
+	var count int64
+	if err := store.db.WithContext(ctx).Model(&dbExpenseShare{}).Where("receivable_id = ?", txID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// deleteExpenseShares deletes the shares of an expense and the receivable transactions they created.
+func deleteExpenseShares(ctx context.Context, dbTx *gorm.DB, txID uint) error {
+	var receivableIDs []uint
+	if err := dbTx.WithContext(ctx).Model(&dbExpenseShare{}).
+		Where("transaction_id = ? AND receivable_id <> 0", txID).Pluck("receivable_id", &receivableIDs).Error; err != nil {
+		return err
+	}
+	if len(receivableIDs) > 0 {
+		if err := dbTx.WithContext(ctx).Where("transaction_id IN ?", receivableIDs).Delete(&dbEntry{}).Error; err != nil {
+			return err
+		}
+		if err := dbTx.WithContext(ctx).Where("id IN ?", receivableIDs).Delete(&dbTransaction{}).Error; err != nil {
+			return err
+		}
+	}
+	return dbTx.WithContext(ctx).Where("transaction_id = ?", txID).Delete(&dbExpenseShare{}).Error
+}
+
+// deleteShareRefs removes the sharing data of a transaction that is being deleted: if it is a shared
+// expense its receivables are removed, if it is a receivable the link on the share is cleared.
+func deleteShareRefs(ctx context.Context, dbTx *gorm.DB, txID uint) error {
+	if err := deleteExpenseShares(ctx, dbTx, txID); err != nil {
+		return err
+	}
+	return dbTx.WithContext(ctx).Model(&dbExpenseShare{}).
+		Where("receivable_id = ?", txID).Update("receivable_id", 0).Error
+}
+
+// =======================================================================================
+// Settle up
+// =======================================================================================
+
+// PersonBalance is the net balance with a person: positive if they owe money, negative if it is owed to them.
+type PersonBalance struct {
+	Person  Person
+	Balance float64
+}
+
+// SettleUpBalances returns the net balance with every person at the given date.
+func (store *Store) SettleUpBalances(ctx context.Context, date time.Time) ([]PersonBalance, error) {
+	people, err := store.ListPeople(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PersonBalance, 0, len(people))
+	for _, p := range people {
+		bal, err := store.personBalance(ctx, p.AccountID, date)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, PersonBalance{Person: p, Balance: bal})
+	}
+	return out, nil
+}
+
+// personBalance returns the balance of a person's lent account at date in the currency of the
+// account, unlike AccountBalance which converts it into the main currency.
+func (store *Store) personBalance(ctx context.Context, accountID uint, date time.Time) (float64, error) {
+	sum, err := store.sumBalanceEntries(ctx, sumEntriesOpts{
+		endDate:    date,
+		accountIds: []uint{accountID},
+		entryTypes: balanceEntryTypes,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return roundMoney(sum.Sum), nil
+}
+
+// SettleUp creates the transfer that brings the balance with a person back to zero, using accountID
+// as the account the money is received on (or paid from). Returns the id of the created transfer.
+func (store *Store) SettleUp(ctx context.Context, personID, accountID uint, date time.Time) (uint, error) {
+	p, err := store.GetPerson(ctx, personID)
+	if err != nil {
+		return 0, err
+	}
+	acc, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("error settling up: %w", err)
+	}
+	if !slices.Contains(allowedTransferAccountTypes, acc.Type) || acc.Type == LentAccountType {
+		return 0, ErrValidation(fmt.Sprintf("incompatible account type %s to settle up", acc.Type.String()))
+	}
+	personAcc, err := store.GetAccount(ctx, p.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	if personAcc.Currency != acc.Currency {
+		return 0, ErrValidation("the settle up account must have the same currency as the person account")
+	}
+
+	balance, err := store.personBalance(ctx, p.AccountID, date)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(balance) < 0.005 {
+		return 0, ErrValidation("nothing to settle")
+	}
+
+	transfer := Transfer{
+		Description: fmt.Sprintf("Settle up with %s", p.Name),
+		Date:        date,
+	}
+	if balance > 0 {
+		// the person pays back
+		transfer.OriginAccountID, transfer.TargetAccountID = p.AccountID, accountID
+	} else {
+		// money is paid to the person
+		transfer.OriginAccountID, transfer.TargetAccountID = accountID, p.AccountID
+	}
+	transfer.OriginAmount = math.Abs(balance)
+	transfer.TargetAmount = math.Abs(balance)
+	return store.CreateTransfer(ctx, transfer)
+}
@@ -0,0 +1,346 @@
+package accounting
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/currency"
+)
+
+func TestComputeShares(t *testing.T) {
+	tcs := []struct {
+		name    string
+		total   float64
+		split   ExpenseSplit
+		want    []float64
+		wantErr string
+	}{
+		{
+			name:  "equal including self",
+			total: 90,
+			split: ExpenseSplit{Rule: SplitEqual, IncludeSelf: true, Shares: []ExpenseShare{{PersonID: 1}, {PersonID: 2}}},
+			want:  []float64{30, 30},
+		},
+		{
+			name:  "equal without self takes the remainder",
+			total: 100,
+			split: ExpenseSplit{Rule: SplitEqual, Shares: []ExpenseShare{{PersonID: 1}, {PersonID: 2}, {PersonID: 3}}},
+			want:  []float64{33.33, 33.33, 33.34},
+		},
+		{
+			name:  "percentage",
+			total: 200,
+			split: ExpenseSplit{Rule: SplitPercentage, Shares: []ExpenseShare{{PersonID: 1, Value: 25}, {PersonID: 2, Value: 10}}},
+			want:  []float64{50, 20},
+		},
+		{
+			name:    "percentage above 100",
+			total:   200,
+			split:   ExpenseSplit{Rule: SplitPercentage, Shares: []ExpenseShare{{PersonID: 1, Value: 60}, {PersonID: 2, Value: 50}}},
+			wantErr: "percentages cannot add up to more than 100",
+		},
+		{
+			name:  "exact",
+			total: 80,
+			split: ExpenseSplit{Rule: SplitExact, Shares: []ExpenseShare{{PersonID: 1, Value: 12.5}, {PersonID: 2, Value: 40}}},
+			want:  []float64{12.5, 40},
+		},
+		{
+			name:    "exact above total",
+			total:   80,
+			split:   ExpenseSplit{Rule: SplitExact, Shares: []ExpenseShare{{PersonID: 1, Value: 50}, {PersonID: 2, Value: 40}}},
+			wantErr: "exact amounts cannot add up to more than the expense",
+		},
+		{
+			name:    "invalid rule",
+			total:   80,
+			split:   ExpenseSplit{Rule: "half", Shares: []ExpenseShare{{PersonID: 1}}},
+			wantErr: `invalid split rule "half": must be equal, percentage or exact`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := computeShares(tc.total, tc.split)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSharedExpenses(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, err := NewStore(db.ConnDbName("TestSharedExpenses"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkingID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			aliceAccID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "alice", Currency: currency.CHF, Type: LentAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobAccID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "bob", Currency: currency.CHF, Type: LentAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			catID, err := store.CreateCategory(ctx, CategoryData{Name: "groceries", Type: ExpenseCategory}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = store.CreatePerson(ctx, Person{Name: "carl", AccountID: checkingID})
+			if err == nil || err.Error() != "the account of a person must be a lent account" {
+				t.Errorf("expected lent account error, got %v", err)
+			}
+			aliceID, err := store.CreatePerson(ctx, Person{Name: "alice", AccountID: aliceAccID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobID, err := store.CreatePerson(ctx, Person{Name: "bob", AccountID: bobAccID})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expenseID, err := store.CreateExpense(ctx, Expense{Description: "dinner", AccountID: checkingID, CategoryID: catID, Amount: 90, Date: getDate("2025-03-10")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.SetExpenseSplit(ctx, expenseID, ExpenseSplit{Rule: SplitEqual, IncludeSelf: true,
+				Shares: []ExpenseShare{{PersonID: aliceID}, {PersonID: bobID}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			balances, err := store.SettleUpBalances(ctx, getDate("2025-03-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(balances) != 2 || balances[0].Balance != 30 || balances[1].Balance != 30 {
+				t.Fatalf("unexpected balances %+v", balances)
+			}
+
+			// changing the expense updates the receivables
+			err = store.UpdateExpense(ctx, ExpenseUpdate{Amount: ptr(120.0)}, expenseID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			split, err := store.GetExpenseSplit(ctx, expenseID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(split.Shares) != 2 || split.Shares[0].Amount != 40 || split.Shares[1].Amount != 40 {
+				t.Fatalf("unexpected split %+v", split)
+			}
+
+			// an update the receivables cannot follow is rolled back as a whole
+			eurID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "eur", Currency: currency.EUR, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.UpdateExpense(ctx, ExpenseUpdate{Amount: ptr(150.0), AccountID: &eurID}, expenseID)
+			if err == nil {
+				t.Fatal("expected an error moving a shared expense to an account in another currency")
+			}
+			got, err := store.GetTransaction(ctx, expenseID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := got.(Expense); exp.AccountID != checkingID || exp.Amount != 120 {
+				t.Errorf("expected the expense to be unchanged, got %+v", exp)
+			}
+
+			// the receivable cannot be shared again
+			err = store.SetExpenseSplit(ctx, split.Shares[0].ReceivableID, ExpenseSplit{Rule: SplitEqual, Shares: []ExpenseShare{{PersonID: bobID}}})
+			if err == nil {
+				t.Error("expected error when sharing a receivable")
+			}
+
+			// alice pays back
+			transferID, err := store.SettleUp(ctx, aliceID, checkingID, getDate("2025-04-01"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = store.GetTransaction(ctx, transferID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := got.(Transfer)
+			if tr.OriginAccountID != aliceAccID || tr.TargetAccountID != checkingID || tr.OriginAmount != 40 {
+				t.Errorf("unexpected settle up transfer %+v", tr)
+			}
+			_, err = store.SettleUp(ctx, aliceID, checkingID, getDate("2025-04-02"))
+			if err == nil || err.Error() != "nothing to settle" {
+				t.Errorf("expected nothing to settle, got %v", err)
+			}
+
+			// a person with shared expenses cannot be deleted
+			if err := store.DeletePerson(ctx, bobID); err == nil {
+				t.Error("expected error deleting a person with shared expenses")
+			}
+
+			// deleting the expense removes its receivables
+			if err := store.DeleteTransaction(ctx, expenseID); err != nil {
+				t.Fatal(err)
+			}
+			bobBalance, err := store.AccountBalanceSingle(ctx, bobAccID, getDate("2025-04-30"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bobBalance.Sum != 0 {
+				t.Errorf("expected bob balance 0 after deleting the expense, got %v", bobBalance.Sum)
+			}
+			if err := store.DeletePerson(ctx, bobID); err != nil {
+				t.Fatal(err)
+			}
+			_, err = store.GetPerson(ctx, bobID)
+			if !errors.Is(err, ErrPersonNotFound) {
+				t.Errorf("expected ErrPersonNotFound, got %v", err)
+			}
+
+			// receivables are booked in the currency of the expense
+			eurAccID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "dora", Currency: currency.EUR, Type: LentAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			doraID, err := store.CreatePerson(ctx, Person{Name: "dora", AccountID: eurAccID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lunchID, err := store.CreateExpense(ctx, Expense{Description: "lunch", AccountID: checkingID, CategoryID: catID, Amount: 50, Date: getDate("2025-05-10")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.SetExpenseSplit(ctx, lunchID, ExpenseSplit{Rule: SplitEqual, Shares: []ExpenseShare{{PersonID: doraID}}})
+			if err == nil || err.Error() != "the account of dora must have the same currency as the expense account" {
+				t.Errorf("expected currency error, got %v", err)
+			}
+
+			// deleting the lent account of a person removes their remaining shares
+			erinAccID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "erin", Currency: currency.CHF, Type: LentAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			erinID, err := store.CreatePerson(ctx, Person{Name: "erin", AccountID: erinAccID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.SetExpenseSplit(ctx, lunchID, ExpenseSplit{Rule: SplitEqual, IncludeSelf: true, Shares: []ExpenseShare{{PersonID: aliceID}, {PersonID: erinID}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			split, err = store.GetExpenseSplit(ctx, lunchID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.DeleteTransaction(ctx, split.Shares[1].ReceivableID); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.DeleteAccount(ctx, erinAccID); err != nil {
+				t.Fatal(err)
+			}
+			split, err = store.GetExpenseSplit(ctx, lunchID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(split.Shares) != 1 || split.Shares[0].PersonID != aliceID {
+				t.Errorf("expected only the share of alice to be left, got %+v", split.Shares)
+			}
+		})
+	}
+}
+
+func TestSettleUpForeignCurrency(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			store, mktStore := newAccountingStoreWithMarketData(t, db.ConnDbName("TestSettleUpForeignCurrency"))
+			store.mainCurrency = "CHF"
+			ctx := t.Context()
+
+			if err := mktStore.RegisterPair(ctx, "CHF", "EUR"); err != nil {
+				t.Fatal(err)
+			}
+			if err := mktStore.IngestRate(ctx, "CHF", "EUR", getDate("2025-01-01"), 0.9); err != nil {
+				t.Fatal(err)
+			}
+
+			providerID, err := store.CreateAccountProvider(ctx, AccountProvider{Name: "bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkingID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "checking", Currency: currency.EUR, Type: CheckinAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lentID, err := store.CreateAccount(ctx, Account{AccountProviderID: providerID, Name: "alice", Currency: currency.EUR, Type: LentAccountType})
+			if err != nil {
+				t.Fatal(err)
+			}
+			catID, err := store.CreateCategory(ctx, CategoryData{Name: "groceries", Type: ExpenseCategory}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			aliceID, err := store.CreatePerson(ctx, Person{Name: "alice", AccountID: lentID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expenseID, err := store.CreateExpense(ctx, Expense{Description: "dinner", AccountID: checkingID, CategoryID: catID, Amount: 90, Date: getDate("2025-03-10")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.SetExpenseSplit(ctx, expenseID, ExpenseSplit{Rule: SplitEqual, IncludeSelf: true, Shares: []ExpenseShare{{PersonID: aliceID}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the account balance is converted into CHF, the debt stays in EUR
+			bal, err := store.AccountBalanceSingle(ctx, lentID, getDate("2025-03-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bal.Sum == 45 {
+				t.Fatalf("expected the account balance to be converted, got %v", bal.Sum)
+			}
+			balances, err := store.SettleUpBalances(ctx, getDate("2025-03-31"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(balances) != 1 || balances[0].Balance != 45 {
+				t.Errorf("expected a balance of 45 EUR, got %+v", balances)
+			}
+
+			transferID, err := store.SettleUp(ctx, aliceID, checkingID, getDate("2025-04-01"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.GetTransaction(ctx, transferID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr := got.(Transfer); tr.OriginAmount != 45 || tr.TargetAmount != 45 {
+				t.Errorf("expected the transfer to settle 45 EUR, got %+v", tr)
+			}
+		})
+	}
+}
@@ -41,6 +41,7 @@ type dbTransaction struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	AttachmentID *uint
+	ExternalID   string    `gorm:"size:255;index"` // id of the imported statement line, e.g. an OFX FITID
 	Entries      []dbEntry `gorm:"foreignKey:TransactionID"` // One-to-many relationship
 	Trades       []dbTrade `gorm:"foreignKey:TransactionID"` // One-to-many for stock operations
 }
@@ -66,6 +67,7 @@ type Income struct {
 	CategoryID   uint
 	Date         time.Time
 	AttachmentID *uint
+	ExternalID   string // id of the imported statement line, used to detect duplicate imports
 
 	baseTx
 }
@@ -79,6 +81,7 @@ type Expense struct {
 	CategoryID   uint
 	Date         time.Time
 	AttachmentID *uint
+	ExternalID   string // id of the imported statement line, used to detect duplicate imports
 
 	baseTx
 }
@@ -93,6 +96,7 @@ type Transfer struct {
 	TargetAccountID uint
 	Date            time.Time
 	AttachmentID    *uint
+	ExternalID      string // id of the imported statement line, used to detect duplicate imports
 
 	baseTx
 }
@@ -112,6 +116,7 @@ type StockBuy struct {
 	TotalAmount         float64 // total cash spent (positive), in cash account currency
 	StockAmount         float64 // monetary value of shares (positive), in investment account / instrument currency
 	AttachmentID        *uint
+	ExternalID          string // broker order id of an imported trade, used to detect duplicate imports
 	baseTx
 }
 
@@ -134,6 +139,7 @@ type StockSell struct {
 	RealizedGainLoss    float64  // P&L = totalAmount - costBasis - fees (computed)
 	LotSelections       []LotSelection // nil/empty → FIFO; non-nil → manual allocation
 	AttachmentID        *uint
+	ExternalID          string // broker order id of an imported trade, used to detect duplicate imports
 	baseTx
 }
 
@@ -212,6 +218,7 @@ type BalanceStatus struct {
 	AccountID    uint
 	Date         time.Time
 	AttachmentID *uint
+	ExternalID   string
 	baseTx
 }
 
@@ -291,6 +298,7 @@ func (store *Store) CreateIncome(ctx context.Context, item Income) (uint, error)
 		Notes:       item.Notes,
 		Date:        item.Date,
 		Type:        IncomeTransaction,
+		ExternalID:  item.ExternalID,
 		Entries: []dbEntry{
 			{
 				AccountID:  item.AccountID,
@@ -311,33 +319,42 @@ func (store *Store) CreateIncome(ctx context.Context, item Income) (uint, error)
 	return tx.Id, nil
 }
 
-func (store *Store) CreateExpense(ctx context.Context, item Expense) (uint, error) {
-	if item.AccountID == 0 {
-		return 0, ErrValidation("account id is required")
-	}
-	acc, err := store.GetAccount(ctx, item.AccountID)
+// validateExpenseTarget checks that an expense can be booked on the account and, if set, the category.
+func (store *Store) validateExpenseTarget(ctx context.Context, accountID, categoryID uint) error {
+	acc, err := store.GetAccount(ctx, accountID)
 	if err != nil {
-		return 0, fmt.Errorf("error creating expense: %w", err)
+		return fmt.Errorf("error creating expense: %w", err)
 	}
 	if !slices.Contains(allowedExpenseAccountTypes, acc.Type) {
-		return 0, NewValidationErr(fmt.Sprintf("incompatible account type %s for expense transaction", acc.Type.String()))
+		return NewValidationErr(fmt.Sprintf("incompatible account type %s for expense transaction", acc.Type.String()))
 	}
 
-	if item.CategoryID != 0 {
-		cat, err := store.GetCategory(ctx, item.CategoryID)
+	if categoryID != 0 {
+		cat, err := store.GetCategory(ctx, categoryID)
 		if err != nil {
-			return 0, fmt.Errorf("error creating expense: %w", err)
+			return fmt.Errorf("error creating expense: %w", err)
 		}
 		if cat.Type != ExpenseCategory {
-			return 0, NewValidationErr("incompatible category type for Expense transaction")
+			return NewValidationErr("incompatible category type for Expense transaction")
 		}
 	}
+	return nil
+}
+
+func (store *Store) CreateExpense(ctx context.Context, item Expense) (uint, error) {
+	if item.AccountID == 0 {
+		return 0, ErrValidation("account id is required")
+	}
+	if err := store.validateExpenseTarget(ctx, item.AccountID, item.CategoryID); err != nil {
+		return 0, err
+	}
 
 	tx := dbTransaction{
 		Description: item.Description,
 		Notes:       item.Notes,
 		Date:        item.Date,
 		Type:        ExpenseTransaction,
+		ExternalID:  item.ExternalID,
 		Entries: []dbEntry{
 			{
 				AccountID:  item.AccountID,
@@ -376,6 +393,7 @@ func (store *Store) CreateBalanceStatus(ctx context.Context, item BalanceStatus)
 		Notes:       item.Notes,
 		Date:        item.Date,
 		Type:        BalanceStatusTransaction,
+		ExternalID:  item.ExternalID,
 		Entries: []dbEntry{
 			{
 				AccountID: item.AccountID,
@@ -434,29 +452,41 @@ func (store *Store) CreateRevaluation(ctx context.Context, item Revaluation) (ui
 }
 
 func (store *Store) CreateTransfer(ctx context.Context, item Transfer) (uint, error) {
+	tx, err := store.newTransferTx(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.db.WithContext(ctx).Create(&tx).Error; err != nil {
+		return 0, err
+	}
+	return tx.Id, nil
+}
+
+// newTransferTx validates a transfer and builds its db transaction.
+func (store *Store) newTransferTx(ctx context.Context, item Transfer) (dbTransaction, error) {
 
 	if item.OriginAccountID == 0 || item.TargetAccountID == 0 {
-		return 0, ErrValidation("origin and target account IDs are required")
+		return dbTransaction{}, ErrValidation("origin and target account IDs are required")
 	}
 
 	if item.OriginAccountID == item.TargetAccountID {
-		return 0, ErrValidation("origin and target account must be different")
+		return dbTransaction{}, ErrValidation("origin and target account must be different")
 	}
 
 	originAcc, err := store.GetAccount(ctx, item.OriginAccountID)
 	if err != nil {
-		return 0, fmt.Errorf("error creating transfer: %w", err)
+		return dbTransaction{}, fmt.Errorf("error creating transfer: %w", err)
 	}
 
 	if !slices.Contains(allowedTransferAccountTypes, originAcc.Type) {
-		return 0, NewValidationErr(fmt.Sprintf("incompatible account type %s for transfer transaction", originAcc.Type.String()))
+		return dbTransaction{}, NewValidationErr(fmt.Sprintf("incompatible account type %s for transfer transaction", originAcc.Type.String()))
 	}
 	targetAcc, err := store.GetAccount(ctx, item.TargetAccountID)
 	if err != nil {
-		return 0, fmt.Errorf("error creating transfer: %w", err)
+		return dbTransaction{}, fmt.Errorf("error creating transfer: %w", err)
 	}
 	if !slices.Contains(allowedTransferAccountTypes, targetAcc.Type) {
-		return 0, NewValidationErr(fmt.Sprintf("incompatible account type %s for transfer transaction", targetAcc.Type.String()))
+		return dbTransaction{}, NewValidationErr(fmt.Sprintf("incompatible account type %s for transfer transaction", targetAcc.Type.String()))
 	}
 
 	tx := dbTransaction{
@@ -464,6 +494,7 @@ func (store *Store) CreateTransfer(ctx context.Context, item Transfer) (uint, er
 		Notes:       item.Notes,
 		Date:        item.Date,
 		Type:        TransferTransaction,
+		ExternalID:  item.ExternalID,
 		Entries: []dbEntry{
 			{
 				AccountID: item.OriginAccountID,
@@ -479,13 +510,9 @@ func (store *Store) CreateTransfer(ctx context.Context, item Transfer) (uint, er
 	}
 
 	if err := validateTransaction(tx); err != nil {
-		return 0, err
-	}
-
-	if err := store.db.WithContext(ctx).Create(&tx).Error; err != nil {
-		return 0, err
+		return dbTransaction{}, err
 	}
-	return tx.Id, nil
+	return tx, nil
 }
 
 // allowedIncomeAccountTypes lists account types that can receive income transactions.
@@ -661,6 +688,7 @@ func (store *Store) CreateStockBuy(ctx context.Context, item StockBuy) (uint, er
 		Notes:       item.Notes,
 		Date:        item.Date,
 		Type:        StockBuyTransaction,
+		ExternalID:  item.ExternalID,
 		Entries: []dbEntry{
 			{
 				AccountID: item.CashAccountID,
@@ -856,6 +884,7 @@ func (store *Store) CreateStockSell(ctx context.Context, item StockSell) (uint,
 			Notes:       item.Notes,
 			Date:        item.Date,
 			Type:        StockSellTransaction,
+			ExternalID:  item.ExternalID,
 			Entries:     entries,
 		}
 		if err := validateTransaction(tx); err != nil {
@@ -1360,6 +1389,7 @@ func incomeFromDb(in dbTransaction) (Transaction, error) {
 		AccountID:    in.Entries[0].AccountID,
 		CategoryID:   in.Entries[0].CategoryID,
 		AttachmentID: in.AttachmentID,
+		ExternalID:   in.ExternalID,
 	}, nil
 }
 
@@ -1372,6 +1402,7 @@ func balanceStatusFromDb(in dbTransaction) (Transaction, error) {
 		Amount:       in.Entries[0].Amount,
 		AccountID:    in.Entries[0].AccountID,
 		AttachmentID: in.AttachmentID,
+		ExternalID:   in.ExternalID,
 	}, nil
 }
 
@@ -1397,6 +1428,7 @@ func expenseFromDb(in dbTransaction) (Transaction, error) {
 		AccountID:    in.Entries[0].AccountID,
 		CategoryID:   in.Entries[0].CategoryID,
 		AttachmentID: in.AttachmentID,
+		ExternalID:   in.ExternalID,
 	}, nil
 }
 
@@ -1421,6 +1453,7 @@ func transferFromDb(in dbTransaction) (Transaction, error) {
 		TargetAccountID: inEntity.AccountID,
 		Date:            in.Date,
 		AttachmentID:    in.AttachmentID,
+		ExternalID:      in.ExternalID,
 	}, nil
 }
 
@@ -1458,6 +1491,7 @@ func stockBuyFromDb(in dbTransaction) (Transaction, error) {
 		TotalAmount:         -cashEntry.Amount,
 		StockAmount:         trade.TotalAmount,
 		AttachmentID:        in.AttachmentID,
+		ExternalID:          in.ExternalID,
 	}, nil
 }
 
@@ -1536,6 +1570,7 @@ func stockSellFromDb(in dbTransaction) (Transaction, error) {
 		RealizedGainLoss:    realizedGainLoss,
 		Fees:                fees,
 		AttachmentID:        in.AttachmentID,
+		ExternalID:          in.ExternalID,
 	}, nil
 }
 
@@ -1889,68 +1924,111 @@ func (store *Store) guardBeforeDelete(ctx context.Context, tx *gorm.DB, txType T
 
 func (store *Store) DeleteTransaction(ctx context.Context, Id uint) error {
 	return store.db.Transaction(func(tx *gorm.DB) error {
-		var dbTx dbTransaction
-		if err := tx.WithContext(ctx).Where("id = ?", Id).First(&dbTx).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrTransactionNotFound
-			}
-			return err
+		return store.deleteTransaction(ctx, tx, Id)
+	})
+}
+
+// deleteTransaction deletes a transaction and everything referencing it within the db transaction tx.
+func (store *Store) deleteTransaction(ctx context.Context, tx *gorm.DB, Id uint) error {
+	var dbTx dbTransaction
+	if err := tx.WithContext(ctx).Where("id = ?", Id).First(&dbTx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTransactionNotFound
 		}
+		return err
+	}
+
+	// Guard: block deletion if downstream operations depend on this transaction's lots.
+	if err := store.guardBeforeDelete(ctx, tx, dbTx.Type, Id); err != nil {
+		return err
+	}
 
-		// Guard: block deletion if downstream operations depend on this transaction's lots.
-		if err := store.guardBeforeDelete(ctx, tx, dbTx.Type, Id); err != nil {
+	// For vest and transfer transactions, restore source lots before deleting trades.
+	// The generic deleteTrade does not handle TransferOut/In lot restoration,
+	// so we must do it explicitly here.
+	if dbTx.Type == StockVestTransaction || dbTx.Type == StockTransferTransaction {
+		if err := store.restoreTransferSourceLots(ctx, tx, Id); err != nil {
 			return err
 		}
+	}
 
-		// For vest and transfer transactions, restore source lots before deleting trades.
-		// The generic deleteTrade does not handle TransferOut/In lot restoration,
-		// so we must do it explicitly here.
-		if dbTx.Type == StockVestTransaction || dbTx.Type == StockTransferTransaction {
-			if err := store.restoreTransferSourceLots(ctx, tx, Id); err != nil {
-				return err
-			}
-		}
+	// Delete trades (and cascading lots/disposals/positions) for stock transactions
+	if err := store.deleteTradesByTransactionID(ctx, tx, Id); err != nil {
+		return err
+	}
+
+	// Delete entries
+	if err := tx.WithContext(ctx).
+		Where("transaction_id = ?", Id).
+		Delete(&dbEntry{}).Error; err != nil {
+		return err
+	}
+
+	// Delete amortization plans built on a prepaid transfer, or the posting of a generated expense
+	if err := deleteAmortizationRefs(ctx, tx, Id); err != nil {
+		return err
+	}
+
+	// Unlink attachments, deleting the files is up to the caller
+	if err := tx.WithContext(ctx).
+		Where("transaction_id = ?", Id).
+		Delete(&dbTransactionAttachment{}).Error; err != nil {
+		return err
+	}
+
+	// Delete the receivables of a shared expense, or unlink a deleted receivable from its share
+	if err := deleteShareRefs(ctx, tx, Id); err != nil {
+		return err
+	}
+
+	// Delete transaction
+	d := tx.WithContext(ctx).
+		Where("id = ?", Id).
+		Delete(&dbTransaction{})
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
 
-		// Delete trades (and cascading lots/disposals/positions) for stock transactions
-		if err := store.deleteTradesByTransactionID(ctx, tx, Id); err != nil {
+// ReplaceWithTransfer books the transaction id as the transfer item: the transfer takes over the
+// import id and every attachment of the original (keeping position and caption), then the original
+// is deleted. Everything happens in one db transaction.
+func (store *Store) ReplaceWithTransfer(ctx context.Context, id uint, item Transfer) (uint, error) {
+	newTx, err := store.newTransferTx(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+	err = store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var orig dbTransaction
+		if err := tx.Where("id = ?", id).First(&orig).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
 			return err
 		}
-
-		// Delete entries
-		if err := tx.WithContext(ctx).
-			Where("transaction_id = ?", Id).
-			Delete(&dbEntry{}).Error; err != nil {
+		if newTx.ExternalID == "" {
+			newTx.ExternalID = orig.ExternalID
+		}
+		if err := tx.Create(&newTx).Error; err != nil {
 			return err
 		}
-
-		// Delete transaction
-		d := tx.WithContext(ctx).
-			Where("id = ?", Id).
-			Delete(&dbTransaction{})
-		if d.Error != nil {
-			return d.Error
+		if err := tx.Model(&dbTransactionAttachment{}).Where("transaction_id = ?", id).
+			Update("transaction_id", newTx.Id).Error; err != nil {
+			return err
 		}
-		if d.RowsAffected == 0 {
-			return ErrTransactionNotFound
+		if err := syncPrimaryAttachment(tx, newTx.Id); err != nil {
+			return err
 		}
-		return nil
+		return store.deleteTransaction(ctx, tx, id)
 	})
-}
-
-// SetAttachmentID sets or clears the attachment ID on a transaction.
-// Pass nil to detach (clear) the attachment.
-func (store *Store) SetAttachmentID(ctx context.Context, txId uint, attachmentID *uint) error {
-	result := store.db.WithContext(ctx).
-		Model(&dbTransaction{}).
-		Where("id = ?", txId).
-		Update("attachment_id", attachmentID)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrTransactionNotFound
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return newTx.Id, nil
 }
 
 type TransactionUpdate interface {
@@ -2160,6 +2238,7 @@ func (store *Store) UpdateExpense(ctx context.Context, input ExpenseUpdate, id u
 		txType:               ExpenseTransaction,
 		entryType:            expenseEntry,
 		allowedAccountTypes:  allowedExpenseAccountTypes,
+		refreshSplit:         true,
 	}
 	return store.updateIncomeExpense(ctx, params, id)
 }
@@ -2208,6 +2287,7 @@ type updateIncomeExpenseParams struct {
 	txType               TxType
 	entryType            entryType
 	allowedAccountTypes  []AccountType // if set, overrides the default for account validation
+	refreshSplit         bool          // keep the owed portions of a shared expense in sync with the update
 }
 
 func (store *Store) validateCategory(ctx context.Context, categoryID uint, expectedType CategoryType) error {
@@ -2312,7 +2392,25 @@ func (store *Store) updateIncomeExpense(ctx context.Context, params updateIncome
 		entryType1:       params.entryType,
 	}
 
-	if err := store.writeTxUpdate(wParams, id); err != nil {
+	var writeSplit func(dbTx *gorm.DB) error
+	if params.refreshSplit {
+		var err error
+		writeSplit, err = store.prepareSplitRefresh(ctx, id, params)
+		if err != nil {
+			return fmt.Errorf("error updating transaction: %w", err)
+		}
+	}
+
+	err := store.db.WithContext(ctx).Transaction(func(dbTx *gorm.DB) error {
+		if err := store.writeTxUpdate(dbTx, wParams, id); err != nil {
+			return err
+		}
+		if writeSplit != nil {
+			return writeSplit(dbTx)
+		}
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("error updating transaction: %w", err)
 	}
 	return nil
@@ -2333,52 +2431,51 @@ type writeTxUpdateParams struct {
 	entryType2       entryType
 }
 
-func (store *Store) writeTxUpdate(params writeTxUpdateParams, id uint) error {
-	return store.db.Transaction(func(tx *gorm.DB) error {
-		// Update the main transaction
-		if len(params.selectedFields) > 0 {
-			q := tx.Model(&dbTransaction{}).
-				Where("id = ? AND type = ?", id, params.txType).
-				Select(params.selectedFields).
-				Updates(params.updateStruct)
-
-			if q.Error != nil {
-				return q.Error
-			}
-			if q.RowsAffected == 0 {
-				return ErrTransactionNotFound
-			}
+// writeTxUpdate writes the update of a transaction and its entries within the database transaction tx.
+func (store *Store) writeTxUpdate(tx *gorm.DB, params writeTxUpdateParams, id uint) error {
+	// Update the main transaction
+	if len(params.selectedFields) > 0 {
+		q := tx.Model(&dbTransaction{}).
+			Where("id = ? AND type = ?", id, params.txType).
+			Select(params.selectedFields).
+			Updates(params.updateStruct)
+
+		if q.Error != nil {
+			return q.Error
+		}
+		if q.RowsAffected == 0 {
+			return ErrTransactionNotFound
 		}
+	}
 
-		// Update fields of the first related entries
-		if len(params.entryType1Fields) > 0 {
-			q := tx.Model(&dbEntry{}).
-				Where("transaction_id = ? AND entry_type = ?", id, params.entryType1).
-				Select(params.entryType1Fields).
-				Updates(params.entryType1Values)
-			if q.Error != nil {
-				return q.Error
-			}
-			if q.RowsAffected == 0 {
-				return ErrEntryNotFound
-			}
+	// Update fields of the first related entries
+	if len(params.entryType1Fields) > 0 {
+		q := tx.Model(&dbEntry{}).
+			Where("transaction_id = ? AND entry_type = ?", id, params.entryType1).
+			Select(params.entryType1Fields).
+			Updates(params.entryType1Values)
+		if q.Error != nil {
+			return q.Error
 		}
-		// Update fields of the second related entries
-		if len(params.entryType2Fields) > 0 {
-			q := tx.Model(&dbEntry{}).
-				Where("transaction_id = ? AND entry_type = ?", id, params.entryType2).
-				Select(params.entryType2Fields).
-				Updates(params.entryType2Values)
-			if q.Error != nil {
-				return q.Error
-			}
-			if q.RowsAffected == 0 {
-				return ErrEntryNotFound
-			}
+		if q.RowsAffected == 0 {
+			return ErrEntryNotFound
 		}
+	}
+	// Update fields of the second related entries
+	if len(params.entryType2Fields) > 0 {
+		q := tx.Model(&dbEntry{}).
+			Where("transaction_id = ? AND entry_type = ?", id, params.entryType2).
+			Select(params.entryType2Fields).
+			Updates(params.entryType2Values)
+		if q.Error != nil {
+			return q.Error
+		}
+		if q.RowsAffected == 0 {
+			return ErrEntryNotFound
+		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 //nolint:gocyclo// the linter flags it but the code is simply different input validations and payload generation
@@ -2483,7 +2580,10 @@ func (store *Store) UpdateTransfer(ctx context.Context, input TransferUpdate, Id
 		entryType2Values: originEntry,
 		entryType2:       transferOutEntry,
 	}
-	if err := store.writeTxUpdate(wParams, Id); err != nil {
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return store.writeTxUpdate(tx, wParams, Id)
+	})
+	if err != nil {
 		return fmt.Errorf("error updating transaction: %w", err)
 	}
 	return nil
@@ -3575,6 +3675,7 @@ type intermediate struct {
 	Type          TxType
 	TransactionId uint
 	AttachmentID  *uint
+	ExternalID    string
 
 	CategoryId       uint
 	AccountId        uint
@@ -3639,6 +3740,7 @@ func (store *Store) ListTransactions(ctx context.Context, opts ListOpts) ([]Tran
         db_transactions.notes,
         db_transactions.type,
         db_transactions.attachment_id,
+        db_transactions.external_id,
 		COALESCE(MAX(db_entries.category_id), 0) AS category_id,
 		COALESCE(MAX(db_entries.account_id), 0) AS account_id,
 
@@ -3787,19 +3889,21 @@ func intermediateToTransaction(item intermediate) Transaction {
 			Id: item.TransactionId, Description: item.Description, Notes: item.Notes,
 			Amount: item.IncomeAmount, AccountID: item.IncomeAccountId,
 			CategoryID: item.CategoryId, Date: item.Date, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}
 	case ExpenseTransaction:
 		return Expense{
 			Id: item.TransactionId, Description: item.Description, Notes: item.Notes,
 			Amount: -item.ExpenseAmount, AccountID: item.ExpenseAccountId,
 			CategoryID: item.CategoryId, Date: item.Date, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}
 	case TransferTransaction:
 		return Transfer{
 			Id: item.TransactionId, Description: item.Description, Notes: item.Notes,
 			Date: item.Date, OriginAmount: -item.OriginAmount, OriginAccountID: item.OriginAccountId,
 			TargetAmount: item.TargetAmount, TargetAccountID: item.TargetAccountId,
-			AttachmentID: item.AttachmentID,
+			AttachmentID: item.AttachmentID, ExternalID: item.ExternalID,
 		}
 	case StockBuyTransaction:
 		totalAmount := item.StockCashAmount
@@ -3812,6 +3916,7 @@ func intermediateToTransaction(item intermediate) Transaction {
 			CashAccountID: item.StockCashAccountId, InstrumentID: item.TradeBuyInstrumentId,
 			Quantity: item.TradeBuyQuantity, TotalAmount: totalAmount,
 			StockAmount: item.TradeBuyAmount, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}
 	case StockSellTransaction:
 		// StockCashAmount is the net cash-in entry (totalAmount - fees).
@@ -3831,7 +3936,7 @@ func intermediateToTransaction(item intermediate) Transaction {
 			Quantity: item.TradeSellQuantity, PricePerShare: item.TradeSellPricePerShare,
 			TotalAmount: item.TradeSellAmount, Fees: fees,
 			CostBasis: costBasis, RealizedGainLoss: realizedGainLoss,
-			AttachmentID: item.AttachmentID,
+			AttachmentID: item.AttachmentID, ExternalID: item.ExternalID,
 		}
 	case StockGrantTransaction:
 		return StockGrant{
@@ -3871,6 +3976,7 @@ func intermediateToTransaction(item intermediate) Transaction {
 			Id: item.TransactionId, Description: item.Description, Notes: item.Notes,
 			Date: item.Date, Amount: item.BalanceStatusAmount,
 			AccountID: item.AccountId, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}
 	case RevaluationTransaction:
 		return Revaluation{
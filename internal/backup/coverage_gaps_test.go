@@ -3,8 +3,12 @@ package backup
 import (
 	"bytes"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/andresbott/etna/internal/accounting"
+	"github.com/andresbott/etna/internal/csvimport"
+	"github.com/andresbott/etna/internal/filestore"
 	"github.com/andresbott/etna/internal/marketdata"
 	"github.com/andresbott/etna/internal/toolsdata"
 	"golang.org/x/text/currency"
@@ -91,3 +95,462 @@ func TestCaseStudyAttachmentRoundTrip(t *testing.T) {
 		t.Errorf("expected original name %q, got %q", "study.pdf", att.OriginalName)
 	}
 }
+
+// TestAmortizationPlanRoundTrip verifies an amortization plan and its generated postings survive
+// export -> import, so restored plans do not generate the already posted periods again.
+func TestAmortizationPlanRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:amortSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkingID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepaidID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "insurance", Currency: currency.CHF, Type: accounting.PrepaidExpenseAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID, err := src.accounting.CreateCategory(ctx, accounting.CategoryData{Name: "insurance", Type: accounting.ExpenseCategory}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferID, err := src.accounting.CreateTransfer(ctx, accounting.Transfer{
+		Description: "yearly insurance", OriginAccountID: checkingID, OriginAmount: 1200,
+		TargetAccountID: prepaidID, TargetAmount: 1200, Date: getDate("2024-01-01"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.accounting.CreateAmortizationPlan(ctx, accounting.AmortizationPlan{
+		TransactionID: transferID, CategoryID: catID, StartDate: getDate("2024-01-01"), EndDate: getDate("2024-12-31"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.accounting.GenerateAmortizationEntries(ctx, getDate("2024-03-15")); err != nil {
+		t.Fatal(err)
+	}
+	// the expense of February was deleted by hand and must stay deleted
+	srcPlans, err := src.accounting.ListAmortizationPlans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.accounting.DeleteTransaction(ctx, srcPlans[0].Postings[1].TransactionID); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "amort.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:amortDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	plans, err := dst.accounting.ListAmortizationPlans(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("expected 1 amortization plan, got %d", len(plans))
+	}
+	if plans[0].PostedPeriods != 3 || plans[0].Amortized != 200 {
+		t.Errorf("expected 3 posted periods amortizing 200, got %d / %v", plans[0].PostedPeriods, plans[0].Amortized)
+	}
+
+	created, err := dst.accounting.GenerateAmortizationEntries(ctx, getDate("2024-03-15"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 0 {
+		t.Errorf("expected no new expenses after restore, got %d", created)
+	}
+}
+
+// TestSavingsGoalRoundTrip verifies savings goals and their account links survive export -> import.
+func TestSavingsGoalRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:goalSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	savingsID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "savings", Currency: currency.CHF, Type: accounting.SavingsAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.accounting.CreateSavingsGoal(ctx, accounting.SavingsGoal{
+		Name: "holidays", TargetAmount: 2500, TargetDate: getDate("2026-06-01"),
+		Accounts: []accounting.SavingsGoalAccount{{AccountID: savingsID, Share: 0.4}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "goals.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:goalDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	goals, err := dst.accounting.ListSavingsGoals(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goals) != 1 {
+		t.Fatalf("expected 1 savings goal, got %d", len(goals))
+	}
+	g := goals[0]
+	if g.Name != "holidays" || g.TargetAmount != 2500 || !g.TargetDate.Equal(getDate("2026-06-01")) {
+		t.Errorf("unexpected goal after restore: %+v", g)
+	}
+	if len(g.Accounts) != 1 || g.Accounts[0].Share != 0.4 {
+		t.Errorf("expected one linked account with share 0.4, got %+v", g.Accounts)
+	}
+}
+
+// TestSharedExpensesRoundTrip verifies people and expense splits survive export -> import
+// and stay linked to the restored receivables instead of creating new ones.
+func TestSharedExpensesRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:sharedSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkingID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lentID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "alice", Currency: currency.CHF, Type: accounting.LentAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	personID, err := src.accounting.CreatePerson(ctx, accounting.Person{Name: "alice", AccountID: lentID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseID, err := src.accounting.CreateExpense(ctx, accounting.Expense{Description: "rent", AccountID: checkingID, Amount: 1000, Date: getDate("2025-05-01")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.accounting.SetExpenseSplit(ctx, expenseID, accounting.ExpenseSplit{Rule: accounting.SplitPercentage,
+		Shares: []accounting.ExpenseShare{{PersonID: personID, Value: 40}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "shared.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:sharedDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	balances, err := dst.accounting.SettleUpBalances(ctx, getDate("2025-05-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 1 || balances[0].Person.Name != "alice" || balances[0].Balance != 400 {
+		t.Fatalf("unexpected balances after restore: %+v", balances)
+	}
+	splits, err := dst.accounting.ListExpenseSplits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(splits) != 1 {
+		t.Fatalf("expected 1 shared expense, got %d", len(splits))
+	}
+	for _, split := range splits {
+		if len(split.Shares) != 1 || split.Shares[0].ReceivableID == 0 || split.Shares[0].Amount != 400 {
+			t.Errorf("unexpected split after restore: %+v", split)
+		}
+	}
+}
+
+// TestTransactionAttachmentsRoundTrip verifies that all attachments of a transaction, with their
+// order and captions, survive export -> import.
+func TestTransactionAttachmentsRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:txAttSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txID, err := src.accounting.CreateExpense(ctx, accounting.Expense{Description: "laptop", AccountID: accID, Amount: 999, Date: getDate("2024-03-01")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"invoice.pdf", "receipt.pdf"} {
+		pdfContent := append([]byte("%PDF-1.4 "+name), bytes.Repeat([]byte{0x00}, 20)...)
+		attID, err := src.filestore.SaveRaw(ctx, getDate("2024-03-01"), pdfContent, name, "application/pdf")
+		if err != nil {
+			t.Fatalf("save attachment: %v", err)
+		}
+		if _, err := src.accounting.AddTransactionAttachment(ctx, txID, attID, strings.TrimSuffix(name, ".pdf")); err != nil {
+			t.Fatalf("link attachment: %v", err)
+		}
+	}
+
+	target := filepath.Join(t.TempDir(), "txatt.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:txAttDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	links, err := dst.accounting.ListAllTransactionAttachments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("expected 2 transaction attachments, got %d", len(links))
+	}
+	for i, want := range []string{"invoice", "receipt"} {
+		if links[i].Caption != want {
+			t.Errorf("attachment %d: expected caption %q, got %q", i, want, links[i].Caption)
+		}
+		att, err := dst.filestore.Get(ctx, links[i].AttachmentID)
+		if err != nil {
+			t.Fatalf("attachment %d not restored: %v", i, err)
+		}
+		if att.OriginalName != want+".pdf" {
+			t.Errorf("attachment %d: expected file %q, got %q", i, want+".pdf", att.OriginalName)
+		}
+	}
+}
+
+// TestSharedAttachmentRoundTrip verifies that a file uploaded for several transactions, and stored
+// once by the attachment deduplication, is restored once with all its references.
+func TestSharedAttachmentRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:sharedAttSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdfContent := append([]byte("%PDF-1.4"), bytes.Repeat([]byte{0x00}, 20)...)
+	var srcAttID uint
+	for _, name := range []string{"deposit", "rent"} {
+		txID, err := src.accounting.CreateExpense(ctx, accounting.Expense{Description: name, AccountID: accID, Amount: 500, Date: getDate("2024-03-01")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcAttID, err = src.filestore.SaveRaw(ctx, getDate("2024-03-01"), pdfContent, "contract.pdf", "application/pdf")
+		if err != nil {
+			t.Fatalf("save attachment: %v", err)
+		}
+		if _, err := src.accounting.AddTransactionAttachment(ctx, txID, srcAttID, name); err != nil {
+			t.Fatalf("link attachment: %v", err)
+		}
+	}
+	if att, err := src.filestore.Get(ctx, srcAttID); err != nil || att.RefCount != 2 {
+		t.Fatalf("expected one shared attachment with 2 references, got %+v, %v", att, err)
+	}
+
+	target := filepath.Join(t.TempDir(), "sharedatt.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:sharedAttDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	links, err := dst.accounting.ListAllTransactionAttachments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0].AttachmentID != links[1].AttachmentID || links[0].TransactionID == links[1].TransactionID {
+		t.Fatalf("expected two transactions sharing one attachment, got %+v", links)
+	}
+	for i, want := range []string{"deposit", "rent"} {
+		if links[i].Caption != want {
+			t.Errorf("link %d: expected caption %q, got %q", i, want, links[i].Caption)
+		}
+	}
+	att, err := dst.filestore.Get(ctx, links[0].AttachmentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att.RefCount != 2 {
+		t.Errorf("expected the restored attachment to hold 2 references, got %d", att.RefCount)
+	}
+
+	// releasing the reference of one transaction keeps the file of the other
+	if err := dst.filestore.Delete(ctx, att.Id); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := dst.filestore.ReadFile(ctx, att.Id); err != nil || !bytes.Equal(content, pdfContent) {
+		t.Errorf("expected the shared file to be kept, got %v", err)
+	}
+}
+
+// TestEncryptedAttachmentsRoundTrip verifies that attachments kept encrypted inside a backup
+// are restored with the same secret and rejected without it.
+func TestEncryptedAttachmentsRoundTrip(t *testing.T) {
+	const secret = "correct horse battery staple"
+	newCipher := func() *filestore.Cipher {
+		c, err := filestore.NewCipher(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	src := newScheduleTestStores(t, "file:encAttSource?mode=memory&cache=shared",
+		filestore.WithEncryption(newCipher()), filestore.WithEncryptedBackups(true))
+	ctx := t.Context()
+
+	cs, err := src.toolsdata.Create(ctx, toolsdata.CaseStudy{ToolType: "buy_vs_rent", Name: "confidential"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdfContent := append([]byte("%PDF-1.4 confidential"), bytes.Repeat([]byte{0x00}, 20)...)
+	attID, err := src.filestore.SaveRaw(ctx, getDate("2024-01-01"), pdfContent, "study.pdf", "application/pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.toolsdata.SetAttachmentID(ctx, cs.ToolType, cs.ID, &attID); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "encrypted.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	plain := newScheduleTestStores(t, "file:encAttPlain?mode=memory&cache=shared")
+	if err := Import(ctx, plain.accounting, plain.marketdata, plain.csvimport, plain.filestore, plain.toolsdata, plain.schedules, target); err == nil {
+		t.Error("expected import without the secret to fail")
+	}
+
+	dst := newScheduleTestStores(t, "file:encAttDest?mode=memory&cache=shared", filestore.WithEncryption(newCipher()))
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	studies, err := dst.toolsdata.ListAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(studies) != 1 || studies[0].AttachmentID == nil {
+		t.Fatalf("expected the case study with its attachment, got %+v", studies)
+	}
+	got, err := dst.filestore.ReadFile(ctx, *studies[0].AttachmentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pdfContent) {
+		t.Errorf("unexpected restored content %q", got)
+	}
+}
+
+// TestImportInboxRoundTrip verifies import inboxes and their pending imports survive export -> import,
+// linked to the restored account and profile.
+func TestImportInboxRoundTrip(t *testing.T) {
+	src := newScheduleTestStores(t, "file:inboxSource?mode=memory&cache=shared")
+	ctx := t.Context()
+
+	providerID, err := src.accounting.CreateAccountProvider(ctx, accounting.AccountProvider{Name: "bank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := src.accounting.CreateAccount(ctx, accounting.Account{AccountProviderID: providerID, Name: "checking", Currency: currency.CHF, Type: accounting.CheckinAccountType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := src.csvimport.CreateProfile(ctx, csvimport.ImportProfile{
+		Name: "bank-csv", CsvSeparator: ",", DateColumn: "Date",
+		DateFormat: "2006-01-02", DescriptionColumn: "Description",
+		AmountColumn: "Amount", AmountMode: "single",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inboxID, err := src.csvimport.CreateInbox(ctx, csvimport.ImportInbox{AccountID: accID, ProfileID: profileID, Directory: "bank/checking", AutoSubmit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement := []byte("Date,Description,Amount\n2025-03-01,coffee,-4.50\n")
+	if _, err := src.csvimport.CreatePendingImport(ctx, csvimport.PendingImport{
+		InboxID: inboxID, AccountID: accID, FileName: "march.csv", FileHash: csvimport.FileHash(statement),
+		Format: csvimport.FormatCSV, TotalRows: 1, Error: "row 1: unknown category", Data: statement,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "inbox.zip")
+	if err := export(ctx, src.accounting, src.marketdata, src.csvimport, src.filestore, src.toolsdata, src.schedules, target); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	dst := newScheduleTestStores(t, "file:inboxDest?mode=memory&cache=shared")
+	if err := Import(ctx, dst.accounting, dst.marketdata, dst.csvimport, dst.filestore, dst.toolsdata, dst.schedules, target); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	accounts, err := dst.accounting.ListAccounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := dst.csvimport.ListProfiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || len(profiles) != 1 {
+		t.Fatalf("expected one account and one profile, got %d and %d", len(accounts), len(profiles))
+	}
+	inboxes, err := dst.csvimport.ListInboxes(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inboxes) != 1 {
+		t.Fatalf("expected 1 inbox, got %d", len(inboxes))
+	}
+	in := inboxes[0]
+	if in.AccountID != accounts[0].ID || in.ProfileID != profiles[0].ID || in.Directory != "bank/checking" || !in.AutoSubmit {
+		t.Errorf("unexpected restored inbox %+v", in)
+	}
+
+	pending, err := dst.csvimport.ListPendingImports(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 pending import, got %d", len(pending))
+	}
+	got, err := dst.csvimport.GetPendingImport(ctx, pending[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.InboxID != in.ID || got.AccountID != accounts[0].ID || got.FileName != "march.csv" ||
+		got.Error != "row 1: unknown category" || !bytes.Equal(got.Data, statement) {
+		t.Errorf("unexpected restored pending import %+v", got)
+	}
+}
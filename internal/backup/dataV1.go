@@ -102,7 +102,10 @@ type TransactionV1 struct {
 	// for revaluation (informative target balance)
 	Balance float64 `json:"balance,omitempty"`
 
-	AttachmentID *uint `json:"attachmentId,omitempty"`
+	AttachmentID *uint `json:"attachmentId,omitempty"` // first attachment, the full list is in the attachments manifest
+
+	// for income/expense/balance status created by a statement import
+	ExternalID string `json:"externalId,omitempty"`
 
 	Date time.Time `json:"date"`
 	Type string    `json:"type"`
@@ -152,17 +155,31 @@ type fxRateRecordV1 struct {
 }
 
 type importProfileV1 struct {
-	ID                uint   `json:"id"`
-	Name              string `json:"name"`
-	CsvSeparator      string `json:"csvSeparator"`
-	SkipRows          int    `json:"skipRows"`
-	DateColumn        string `json:"dateColumn"`
-	DateFormat        string `json:"dateFormat"`
-	DescriptionColumn string `json:"descriptionColumn"`
-	AmountColumn      string `json:"amountColumn"`
-	AmountMode        string `json:"amountMode"`
-	CreditColumn      string `json:"creditColumn"`
-	DebitColumn       string `json:"debitColumn"`
+	ID                 uint     `json:"id"`
+	Name               string   `json:"name"`
+	CsvSeparator       string   `json:"csvSeparator"`
+	SheetName          string   `json:"sheetName,omitempty"`
+	SkipRows           int      `json:"skipRows"`
+	DateColumn         string   `json:"dateColumn"`
+	DateFormat         string   `json:"dateFormat"`
+	DescriptionColumn  string   `json:"descriptionColumn"`
+	AmountColumn       string   `json:"amountColumn"`
+	AmountMode         string   `json:"amountMode"`
+	CreditColumn       string   `json:"creditColumn"`
+	DebitColumn        string   `json:"debitColumn"`
+	DecimalSeparator   string   `json:"decimalSeparator,omitempty"`
+	ThousandsSeparator string   `json:"thousandsSeparator,omitempty"`
+	InvertSign         bool     `json:"invertSign,omitempty"`
+	Type               string   `json:"type,omitempty"`
+	SymbolColumn       string   `json:"symbolColumn,omitempty"`
+	SideColumn         string   `json:"sideColumn,omitempty"`
+	QuantityColumn     string   `json:"quantityColumn,omitempty"`
+	PriceColumn        string   `json:"priceColumn,omitempty"`
+	FeesColumn         string   `json:"feesColumn,omitempty"`
+	CurrencyColumn     string   `json:"currencyColumn,omitempty"`
+	OrderIDColumn      string   `json:"orderIdColumn,omitempty"`
+	CashAccountID      uint     `json:"cashAccountId,omitempty"`
+	HeaderRow          []string `json:"headerRow,omitempty"`
 }
 
 type categoryRuleGroupV1 struct {
@@ -171,6 +188,19 @@ type categoryRuleGroupV1 struct {
 	CategoryID uint                    `json:"categoryId"`
 	Priority   int                     `json:"position"`
 	Patterns   []categoryRulePatternV1 `json:"patterns"`
+
+	MatchAny          bool           `json:"matchAny,omitempty"`
+	AmountMin         *float64       `json:"amountMin,omitempty"`
+	AmountMax         *float64       `json:"amountMax,omitempty"`
+	Direction         string         `json:"direction,omitempty"`
+	AccountID         uint           `json:"accountId,omitempty"`
+	ProfileID         uint           `json:"profileId,omitempty"`
+	DateFrom          *time.Time     `json:"dateFrom,omitempty"`
+	DateTo            *time.Time     `json:"dateTo,omitempty"`
+	Weekdays          []time.Weekday `json:"weekdays,omitempty"`
+	SetDescription    string         `json:"setDescription,omitempty"`
+	AppendNotes       string         `json:"appendNotes,omitempty"`
+	TransferAccountID uint           `json:"transferAccountId,omitempty"`
 }
 
 type categoryRulePatternV1 struct {
@@ -179,6 +209,30 @@ type categoryRulePatternV1 struct {
 	IsRegex bool   `json:"isRegex"`
 }
 
+const importInboxesFile = "import_inboxes.json"
+
+type importInboxV1 struct {
+	ID         uint   `json:"id"`
+	AccountID  uint   `json:"accountId"`
+	ProfileID  uint   `json:"profileId,omitempty"`
+	Directory  string `json:"directory"`
+	AutoSubmit bool   `json:"autoSubmit,omitempty"`
+}
+
+const pendingImportsFile = "pending_imports.json"
+
+type pendingImportV1 struct {
+	InboxID   uint      `json:"inboxId,omitempty"`
+	AccountID uint      `json:"accountId"`
+	FileName  string    `json:"fileName"`
+	FileHash  string    `json:"fileHash"`
+	Format    string    `json:"format"`
+	TotalRows int       `json:"totalRows"`
+	Error     string    `json:"error,omitempty"`
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 const caseStudiesFile = "case_studies.json"
 
 type caseStudyV1 struct {
@@ -191,6 +245,68 @@ type caseStudyV1 struct {
 	AttachmentID         *uint           `json:"attachmentId,omitempty"`
 }
 
+const amortizationPlansFile = "amortization_plans.json"
+
+type amortizationPlanV1 struct {
+	ID            uint                    `json:"id"`
+	TransactionID uint                    `json:"transactionId"`
+	CategoryID    uint                    `json:"categoryId"`
+	StartDate     time.Time               `json:"startDate"`
+	EndDate       time.Time               `json:"endDate"`
+	Postings      []amortizationPostingV1 `json:"postings"`
+}
+
+type amortizationPostingV1 struct {
+	TransactionID uint `json:"transactionId"`
+	Period        int  `json:"period"`
+}
+
+const savingsGoalsFile = "savings_goals.json"
+
+type savingsGoalV1 struct {
+	ID           uint                   `json:"id"`
+	Name         string                 `json:"name"`
+	Notes        string                 `json:"notes,omitempty"`
+	Icon         string                 `json:"icon"`
+	TargetAmount float64                `json:"targetAmount"`
+	TargetDate   time.Time              `json:"targetDate"`
+	Accounts     []savingsGoalAccountV1 `json:"accounts"`
+}
+
+type savingsGoalAccountV1 struct {
+	AccountID uint    `json:"accountId"`
+	Share     float64 `json:"share"`
+}
+
+const sharedExpensesFile = "shared_expenses.json"
+
+// sharedExpensesV1 holds the people expenses are shared with and the splits of the shared expenses;
+// the receivable transactions themselves are part of the regular transactions.
+type sharedExpensesV1 struct {
+	People []personV1       `json:"people"`
+	Splits []expenseSplitV1 `json:"splits"`
+}
+
+type personV1 struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	AccountID uint   `json:"accountId"`
+}
+
+type expenseSplitV1 struct {
+	TransactionID uint             `json:"transactionId"`
+	Rule          string           `json:"rule"`
+	IncludeSelf   bool             `json:"includeSelf"`
+	Shares        []expenseShareV1 `json:"shares"`
+}
+
+type expenseShareV1 struct {
+	PersonID     uint    `json:"personId"`
+	Value        float64 `json:"value"`
+	Amount       float64 `json:"amount"`
+	ReceivableID uint    `json:"receivableId"`
+}
+
 const schedulesFile = "task_schedules.json"
 
 type scheduleV1 struct {
@@ -203,9 +319,20 @@ const attachmentsFile = "attachments.json"
 const attachmentsDir = "attachments/"
 
 type attachmentV1 struct {
-	ID           uint   `json:"id"`
-	OriginalName string `json:"originalName"`
-	MimeType     string `json:"mimeType"`
-	FileSize     int64  `json:"fileSize"`
-	ZipPath      string `json:"zipPath"`
+	ID           uint               `json:"id"`
+	OriginalName string             `json:"originalName"`
+	MimeType     string             `json:"mimeType"`
+	FileSize     int64              `json:"fileSize"`
+	ZipPath      string             `json:"zipPath"`
+	RefCount     int                `json:"refCount,omitempty"`
+	Encrypted    bool               `json:"encrypted,omitempty"` // file in the zip is encrypted with the attachment key
+	Transactions []attachmentLinkV1 `json:"transactions,omitempty"`
+}
+
+// attachmentLinkV1 links an attachment to a transaction; backups made before transactions could hold
+// several attachments only carry TransactionV1.AttachmentID.
+type attachmentLinkV1 struct {
+	TransactionID uint   `json:"transactionId"`
+	Position      int    `json:"position"`
+	Caption       string `json:"caption,omitempty"`
 }
@@ -1,11 +1,13 @@
 package backup
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -82,7 +84,27 @@ func export(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 		return err
 	}
 
-	attachmentIDs, err := writeTransactions(ctx, zw, store)
+	attachmentLinks, err := writeTransactions(ctx, zw, store)
+	if err != nil {
+		return err
+	}
+	attachmentIDs := make([]uint, 0, len(attachmentLinks))
+	for id := range attachmentLinks {
+		attachmentIDs = append(attachmentIDs, id)
+	}
+	slices.Sort(attachmentIDs)
+
+	err = writeAmortizationPlans(ctx, zw, store)
+	if err != nil {
+		return err
+	}
+
+	err = writeSavingsGoals(ctx, zw, store)
+	if err != nil {
+		return err
+	}
+
+	err = writeSharedExpenses(ctx, zw, store)
 	if err != nil {
 		return err
 	}
@@ -93,7 +115,7 @@ func export(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 	}
 	attachmentIDs = append(attachmentIDs, caseStudyAttIDs...)
 
-	err = writeAttachments(ctx, zw, fileStore, attachmentIDs)
+	err = writeAttachments(ctx, zw, fileStore, attachmentIDs, attachmentLinks)
 	if err != nil {
 		return err
 	}
@@ -123,6 +145,11 @@ func export(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 		return err
 	}
 
+	err = writeImportInboxes(ctx, zw, csvStore)
+	if err != nil {
+		return err
+	}
+
 	err = writeSchedules(ctx, zw, schStore)
 	if err != nil {
 		return err
@@ -265,6 +292,7 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 			Id: item.Id, Description: item.Description, Notes: item.Notes,
 			Amount: item.Amount, AccountID: item.AccountID, CategoryID: item.CategoryID,
 			Date: item.Date, Type: txTypeIncome, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}, true
 	case accounting.Expense:
 		if item.AccountID == 0 {
@@ -274,6 +302,7 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 			Id: item.Id, Description: item.Description, Notes: item.Notes,
 			Amount: item.Amount, AccountID: item.AccountID, CategoryID: item.CategoryID,
 			Date: item.Date, Type: txTypeExpense, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}, true
 	case accounting.StockBuy:
 		return TransactionV1{
@@ -282,6 +311,7 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 			TotalAmount: item.TotalAmount, StockAmount: item.StockAmount,
 			InvestmentAccountID: item.InvestmentAccountID, CashAccountID: item.CashAccountID,
 			Date: item.Date, Type: txTypeStockBuy, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}, true
 	case accounting.StockSell:
 		return TransactionV1{
@@ -290,6 +320,7 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 			PricePerShare: item.PricePerShare, TotalAmount: item.TotalAmount, Fees: item.Fees,
 			InvestmentAccountID: item.InvestmentAccountID, CashAccountID: item.CashAccountID,
 			Date: item.Date, Type: txTypeStockSell, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}, true
 	case accounting.StockGrant:
 		return TransactionV1{
@@ -324,6 +355,7 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 			Id: item.Id, Description: item.Description, Notes: item.Notes,
 			Amount: item.Amount, AccountID: item.AccountID,
 			Date: item.Date, Type: txTypeBalanceStatus, AttachmentID: item.AttachmentID,
+			ExternalID: item.ExternalID,
 		}, true
 	case accounting.Revaluation:
 		return TransactionV1{
@@ -336,7 +368,8 @@ func txToV1(tx accounting.Transaction) (TransactionV1, bool) {
 	}
 }
 
-func writeTransactions(ctx context.Context, zw *zipWriter, store *accounting.Store) ([]uint, error) {
+// writeTransactions writes the transactions file and returns the links of the attachments of the transactions.
+func writeTransactions(ctx context.Context, zw *zipWriter, store *accounting.Store) (map[uint][]attachmentLinkV1, error) {
 	jsonData := []TransactionV1{}
 	opts := accounting.ListOpts{
 		EndDate: dataFuture(),
@@ -378,19 +411,27 @@ func writeTransactions(ctx context.Context, zw *zipWriter, store *accounting.Sto
 			break
 		}
 	}
-	seen := map[uint]bool{}
-	var attachmentIDs []uint
-	for _, tx := range jsonData {
-		if tx.AttachmentID != nil && !seen[*tx.AttachmentID] {
-			seen[*tx.AttachmentID] = true
-			attachmentIDs = append(attachmentIDs, *tx.AttachmentID)
-		}
+	if err := zw.writeJsonFile(transactionsFile, jsonData); err != nil {
+		return nil, err
 	}
+	return transactionAttachmentLinks(ctx, store)
+}
 
-	if err := zw.writeJsonFile(transactionsFile, jsonData); err != nil {
+// transactionAttachmentLinks returns the attachments linked to transactions, with their links.
+func transactionAttachmentLinks(ctx context.Context, store *accounting.Store) (map[uint][]attachmentLinkV1, error) {
+	all, err := store.ListAllTransactionAttachments(ctx)
+	if err != nil {
 		return nil, err
 	}
-	return attachmentIDs, nil
+	links := map[uint][]attachmentLinkV1{}
+	for _, a := range all {
+		links[a.AttachmentID] = append(links[a.AttachmentID], attachmentLinkV1{
+			TransactionID: a.TransactionID,
+			Position:      a.Position,
+			Caption:       a.Caption,
+		})
+	}
+	return links, nil
 }
 
 func writeInstruments(ctx context.Context, zw *zipWriter, mdStore *marketdata.Store) error {
@@ -475,22 +516,82 @@ func writeImportProfiles(ctx context.Context, zw *zipWriter, csvStore *csvimport
 	jsonData := make([]importProfileV1, len(profiles))
 	for i, p := range profiles {
 		jsonData[i] = importProfileV1{
-			ID:                p.ID,
-			Name:              p.Name,
-			CsvSeparator:      p.CsvSeparator,
-			SkipRows:          p.SkipRows,
-			DateColumn:        p.DateColumn,
-			DateFormat:        p.DateFormat,
-			DescriptionColumn: p.DescriptionColumn,
-			AmountColumn:      p.AmountColumn,
-			AmountMode:        p.AmountMode,
-			CreditColumn:      p.CreditColumn,
-			DebitColumn:       p.DebitColumn,
+			ID:                 p.ID,
+			Name:               p.Name,
+			CsvSeparator:       p.CsvSeparator,
+			SheetName:          p.SheetName,
+			SkipRows:           p.SkipRows,
+			DateColumn:         p.DateColumn,
+			DateFormat:         p.DateFormat,
+			DescriptionColumn:  p.DescriptionColumn,
+			AmountColumn:       p.AmountColumn,
+			AmountMode:         p.AmountMode,
+			CreditColumn:       p.CreditColumn,
+			DebitColumn:        p.DebitColumn,
+			DecimalSeparator:   p.DecimalSeparator,
+			ThousandsSeparator: p.ThousandsSeparator,
+			InvertSign:         p.InvertSign,
+			Type:               p.Type,
+			SymbolColumn:       p.SymbolColumn,
+			SideColumn:         p.SideColumn,
+			QuantityColumn:     p.QuantityColumn,
+			PriceColumn:        p.PriceColumn,
+			FeesColumn:         p.FeesColumn,
+			CurrencyColumn:     p.CurrencyColumn,
+			OrderIDColumn:      p.OrderIDColumn,
+			CashAccountID:      p.CashAccountID,
+			HeaderRow:          p.HeaderRow,
 		}
 	}
 	return zw.writeJsonFile(importProfilesFile, jsonData)
 }
 
+// writeImportInboxes writes the inbox configs and the files of their pending imports.
+func writeImportInboxes(ctx context.Context, zw *zipWriter, csvStore *csvimport.Store) error {
+	inboxes, err := csvStore.ListInboxes(ctx, 0)
+	if err != nil {
+		return err
+	}
+	inboxData := make([]importInboxV1, len(inboxes))
+	for i, in := range inboxes {
+		inboxData[i] = importInboxV1{
+			ID:         in.ID,
+			AccountID:  in.AccountID,
+			ProfileID:  in.ProfileID,
+			Directory:  in.Directory,
+			AutoSubmit: in.AutoSubmit,
+		}
+	}
+	if err := zw.writeJsonFile(importInboxesFile, inboxData); err != nil {
+		return err
+	}
+
+	pending, err := csvStore.ListPendingImports(ctx, 0)
+	if err != nil {
+		return err
+	}
+	pendingData := make([]pendingImportV1, len(pending))
+	for i, item := range pending {
+		// the list is returned without file content
+		p, err := csvStore.GetPendingImport(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+		pendingData[i] = pendingImportV1{
+			InboxID:   p.InboxID,
+			AccountID: p.AccountID,
+			FileName:  p.FileName,
+			FileHash:  p.FileHash,
+			Format:    p.Format,
+			TotalRows: p.TotalRows,
+			Error:     p.Error,
+			Data:      p.Data,
+			CreatedAt: p.CreatedAt,
+		}
+	}
+	return zw.writeJsonFile(pendingImportsFile, pendingData)
+}
+
 func writeCategoryRules(ctx context.Context, zw *zipWriter, csvStore *csvimport.Store) error {
 	groups, err := csvStore.ListCategoryRuleGroups(ctx)
 	if err != nil {
@@ -512,11 +613,106 @@ func writeCategoryRules(ctx context.Context, zw *zipWriter, csvStore *csvimport.
 			CategoryID: g.CategoryID,
 			Priority:   g.Priority,
 			Patterns:   patterns,
+
+			MatchAny:          g.MatchAny,
+			AmountMin:         g.AmountMin,
+			AmountMax:         g.AmountMax,
+			Direction:         g.Direction,
+			AccountID:         g.AccountID,
+			ProfileID:         g.ProfileID,
+			DateFrom:          g.DateFrom,
+			DateTo:            g.DateTo,
+			Weekdays:          g.Weekdays,
+			SetDescription:    g.SetDescription,
+			AppendNotes:       g.AppendNotes,
+			TransferAccountID: g.TransferAccountID,
 		}
 	}
 	return zw.writeJsonFile(categoryRulesFile, jsonData)
 }
 
+func writeAmortizationPlans(ctx context.Context, zw *zipWriter, store *accounting.Store) error {
+	plans, err := store.ListAmortizationPlans(ctx)
+	if err != nil {
+		return err
+	}
+	jsonData := make([]amortizationPlanV1, len(plans))
+	for i, p := range plans {
+		postings := make([]amortizationPostingV1, len(p.Postings))
+		for j, posting := range p.Postings {
+			postings[j] = amortizationPostingV1{
+				TransactionID: posting.TransactionID,
+				Period:        posting.Period,
+			}
+		}
+		jsonData[i] = amortizationPlanV1{
+			ID:            p.ID,
+			TransactionID: p.TransactionID,
+			CategoryID:    p.CategoryID,
+			StartDate:     p.StartDate,
+			EndDate:       p.EndDate,
+			Postings:      postings,
+		}
+	}
+	return zw.writeJsonFile(amortizationPlansFile, jsonData)
+}
+
+func writeSavingsGoals(ctx context.Context, zw *zipWriter, store *accounting.Store) error {
+	goals, err := store.ListSavingsGoals(ctx)
+	if err != nil {
+		return err
+	}
+	jsonData := make([]savingsGoalV1, len(goals))
+	for i, g := range goals {
+		accounts := make([]savingsGoalAccountV1, len(g.Accounts))
+		for j, a := range g.Accounts {
+			accounts[j] = savingsGoalAccountV1{AccountID: a.AccountID, Share: a.Share}
+		}
+		jsonData[i] = savingsGoalV1{
+			ID:           g.ID,
+			Name:         g.Name,
+			Notes:        g.Notes,
+			Icon:         g.Icon,
+			TargetAmount: g.TargetAmount,
+			TargetDate:   g.TargetDate,
+			Accounts:     accounts,
+		}
+	}
+	return zw.writeJsonFile(savingsGoalsFile, jsonData)
+}
+
+func writeSharedExpenses(ctx context.Context, zw *zipWriter, store *accounting.Store) error {
+	people, err := store.ListPeople(ctx)
+	if err != nil {
+		return err
+	}
+	splits, err := store.ListExpenseSplits(ctx)
+	if err != nil {
+		return err
+	}
+	jsonData := sharedExpensesV1{
+		People: make([]personV1, len(people)),
+		Splits: make([]expenseSplitV1, 0, len(splits)),
+	}
+	for i, p := range people {
+		jsonData.People[i] = personV1{ID: p.ID, Name: p.Name, AccountID: p.AccountID}
+	}
+	for txID, split := range splits {
+		shares := make([]expenseShareV1, len(split.Shares))
+		for j, sh := range split.Shares {
+			shares[j] = expenseShareV1{PersonID: sh.PersonID, Value: sh.Value, Amount: sh.Amount, ReceivableID: sh.ReceivableID}
+		}
+		jsonData.Splits = append(jsonData.Splits, expenseSplitV1{
+			TransactionID: txID,
+			Rule:          string(split.Rule),
+			IncludeSelf:   split.IncludeSelf,
+			Shares:        shares,
+		})
+	}
+	slices.SortFunc(jsonData.Splits, func(a, b expenseSplitV1) int { return cmp.Compare(a.TransactionID, b.TransactionID) })
+	return zw.writeJsonFile(sharedExpensesFile, jsonData)
+}
+
 // writeCaseStudies writes the case studies file and returns the IDs of any
 // attachments referenced by case studies so their binaries get exported too.
 func writeCaseStudies(ctx context.Context, zw *zipWriter, tdStore *toolsdata.Store) ([]uint, error) {
@@ -546,7 +742,7 @@ func writeCaseStudies(ctx context.Context, zw *zipWriter, tdStore *toolsdata.Sto
 	return attachmentIDs, zw.writeJsonFile(caseStudiesFile, jsonData)
 }
 
-func writeAttachments(ctx context.Context, zw *zipWriter, fileStore *filestore.Store, attachmentIDs []uint) error {
+func writeAttachments(ctx context.Context, zw *zipWriter, fileStore *filestore.Store, attachmentIDs []uint, links map[uint][]attachmentLinkV1) error {
 	if fileStore == nil || len(attachmentIDs) == 0 {
 		return zw.writeJsonFile(attachmentsFile, []attachmentV1{})
 	}
@@ -558,12 +754,7 @@ func writeAttachments(ctx context.Context, zw *zipWriter, fileStore *filestore.S
 			continue // skip missing attachments
 		}
 
-		filePath, err := fileStore.GetFilePath(ctx, id)
-		if err != nil {
-			continue
-		}
-
-		content, err := os.ReadFile(filePath) //nolint:gosec // path from trusted filestore
+		content, encrypted, err := fileStore.ExportContent(ctx, id)
 		if err != nil {
 			continue
 		}
@@ -581,6 +772,9 @@ func writeAttachments(ctx context.Context, zw *zipWriter, fileStore *filestore.S
 			MimeType:     att.MimeType,
 			FileSize:     att.FileSize,
 			ZipPath:      zipPath,
+			RefCount:     att.RefCount,
+			Encrypted:    encrypted,
+			Transactions: links[id],
 		})
 	}
 
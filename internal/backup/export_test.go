@@ -117,18 +117,18 @@ func TestExport(t *testing.T) {
 			{Main: "USD", Secondary: "EUR", Time: getDate("2024-01-15"), Rate: 0.92},
 		},
 		ImportProfiles: []importProfileV1{
-			{ID: 1, Name: "bank-csv", CsvSeparator: ",", DateColumn: "Date", DateFormat: "2006-01-02", DescriptionColumn: "Description", AmountColumn: "Amount", AmountMode: "single"},
+			{ID: 1, Name: "bank-csv", CsvSeparator: ",", DateColumn: "Date", DateFormat: "2006-01-02", DescriptionColumn: "Description", AmountColumn: "Amount", AmountMode: "single", Type: "statement", HeaderRow: []string{"Date", "Description", "Amount"}},
 		},
 		CategoryRules: []categoryRuleGroupV1{
 			{ID: 1, Name: "grocery", CategoryID: 3, Priority: 0, Patterns: []categoryRulePatternV1{
 				{ID: 1, Pattern: "grocery"},
-			}},
+			}, Direction: "expense", AppendNotes: "food"},
 		},
 		CaseStudies: []caseStudyV1{
 			{ID: 1, ToolType: "buy_vs_rent", Name: "test-case", Description: "test desc", ExpectedAnnualReturn: 7.5, Params: json.RawMessage(`{"key":"value"}`)},
 		},
 		Attachments: []attachmentV1{
-			{ID: 1, OriginalName: "receipt.jpg", MimeType: "image/jpeg", FileSize: 54, ZipPath: "attachments/1.jpg"},
+			{ID: 1, OriginalName: "receipt.jpg", MimeType: "image/jpeg", FileSize: 54, ZipPath: "attachments/1.jpg", RefCount: 1, Transactions: []attachmentLinkV1{{TransactionID: 1}}},
 		},
 	}
 
@@ -457,6 +457,7 @@ func sampleExtraData(t *testing.T, store *accounting.Store, mdStore *marketdata.
 		Name: "bank-csv", CsvSeparator: ",", DateColumn: "Date",
 		DateFormat: "2006-01-02", DescriptionColumn: "Description",
 		AmountColumn: "Amount", AmountMode: "single",
+		HeaderRow: []string{"Date", "Description", "Amount"},
 	})
 	if err != nil {
 		t.Fatalf("error creating import profile: %v", err)
@@ -464,7 +465,8 @@ func sampleExtraData(t *testing.T, store *accounting.Store, mdStore *marketdata.
 
 	_, err = csvStore.CreateCategoryRuleGroup(t.Context(), csvimport.CategoryRuleGroup{
 		Name: "grocery", CategoryID: expenseCategoryID, Priority: 0,
-		Patterns: []csvimport.CategoryRulePattern{{Pattern: "grocery"}},
+		Patterns:  []csvimport.CategoryRulePattern{{Pattern: "grocery"}},
+		Direction: csvimport.RuleDirectionExpense, AppendNotes: "food",
 	})
 	if err != nil {
 		t.Fatalf("error creating category rule group: %v", err)
@@ -604,6 +606,7 @@ func TestRoundTrip(t *testing.T) {
 		cmpopts.IgnoreFields(caseStudyV1{}, "ID"),
 		cmpopts.SortSlices(func(a, b caseStudyV1) bool { return a.Name < b.Name }),
 		cmpopts.IgnoreFields(attachmentV1{}, "ID", "ZipPath"),
+		cmpopts.IgnoreFields(attachmentLinkV1{}, "TransactionID"),
 		cmpopts.SortSlices(func(a, b attachmentV1) bool { return a.OriginalName < b.OriginalName }),
 	); diff != "" {
 		t.Errorf("round-trip mismatch (-first +second):\n%s", diff)
@@ -86,6 +86,9 @@ func Import(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 	if err != nil {
 		return err
 	}
+	if err := linkProfileCashAccounts(ctx, csvStore, r, profilesMap, accountsMap); err != nil {
+		return err
+	}
 	inMap, exMap, err := importCategories(ctx, store, r)
 	if err != nil {
 		return err
@@ -101,7 +104,27 @@ func Import(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 		return err
 	}
 
-	err = importTransactions(ctx, store, r, accountsMap, inMap, exMap, instrumentsMap, attachmentsMap)
+	txMap, err := importTransactions(ctx, store, r, accountsMap, inMap, exMap, instrumentsMap, attachmentsMap)
+	if err != nil {
+		return err
+	}
+
+	err = importTransactionAttachments(ctx, store, r, txMap, attachmentsMap)
+	if err != nil {
+		return err
+	}
+
+	err = importAmortizationPlans(ctx, store, r, txMap, exMap)
+	if err != nil {
+		return err
+	}
+
+	err = importSavingsGoals(ctx, store, r, accountsMap)
+	if err != nil {
+		return err
+	}
+
+	err = importSharedExpenses(ctx, store, r, accountsMap, txMap)
 	if err != nil {
 		return err
 	}
@@ -116,7 +139,12 @@ func Import(ctx context.Context, store *accounting.Store, mdStore *marketdata.St
 		return err
 	}
 
-	err = importCategoryRules(ctx, csvStore, r, inMap, exMap)
+	err = importCategoryRules(ctx, csvStore, r, inMap, exMap, accountsMap, profilesMap)
+	if err != nil {
+		return err
+	}
+
+	err = importImportInboxes(ctx, csvStore, r, accountsMap, profilesMap)
 	if err != nil {
 		return err
 	}
@@ -292,7 +320,7 @@ func v1ToBasicTx(tx TransactionV1, m importMaps, attID *uint) (accounting.Transa
 		return accounting.Income{
 			Description: tx.Description, Notes: tx.Notes, Amount: tx.Amount,
 			AccountID: m.accounts[tx.AccountID], CategoryID: m.income[tx.CategoryID],
-			Date: tx.Date, AttachmentID: attID,
+			Date: tx.Date, AttachmentID: attID, ExternalID: tx.ExternalID,
 		}, true
 	case txTypeExpense:
 		if tx.AccountID == 0 {
@@ -301,7 +329,7 @@ func v1ToBasicTx(tx TransactionV1, m importMaps, attID *uint) (accounting.Transa
 		return accounting.Expense{
 			Description: tx.Description, Notes: tx.Notes, Amount: tx.Amount,
 			AccountID: m.accounts[tx.AccountID], CategoryID: m.expense[tx.CategoryID],
-			Date: tx.Date, AttachmentID: attID,
+			Date: tx.Date, AttachmentID: attID, ExternalID: tx.ExternalID,
 		}, true
 	case txTypeTransfer:
 		if tx.OriginAccountID == 0 || tx.TargetAccountID == 0 {
@@ -319,6 +347,7 @@ func v1ToBasicTx(tx TransactionV1, m importMaps, attID *uint) (accounting.Transa
 			InvestmentAccountID: m.accounts[tx.InvestmentAccountID], CashAccountID: m.accounts[tx.CashAccountID],
 			InstrumentID: m.instruments[tx.InstrumentID], Quantity: tx.Quantity,
 			TotalAmount: tx.TotalAmount, StockAmount: tx.StockAmount, AttachmentID: attID,
+			ExternalID: tx.ExternalID,
 		}, true
 	case txTypeStockSell:
 		return accounting.StockSell{
@@ -326,6 +355,7 @@ func v1ToBasicTx(tx TransactionV1, m importMaps, attID *uint) (accounting.Transa
 			InvestmentAccountID: m.accounts[tx.InvestmentAccountID], CashAccountID: m.accounts[tx.CashAccountID],
 			InstrumentID: m.instruments[tx.InstrumentID], Quantity: tx.Quantity,
 			PricePerShare: tx.PricePerShare, TotalAmount: tx.TotalAmount, Fees: tx.Fees, AttachmentID: attID,
+			ExternalID: tx.ExternalID,
 		}, true
 	case txTypeStockGrant:
 		return accounting.StockGrant{
@@ -343,6 +373,7 @@ func v1ToBasicTx(tx TransactionV1, m importMaps, attID *uint) (accounting.Transa
 		return accounting.BalanceStatus{
 			Description: tx.Description, Notes: tx.Notes, Date: tx.Date,
 			Amount: tx.Amount, AccountID: m.accounts[tx.AccountID], AttachmentID: attID,
+			ExternalID: tx.ExternalID,
 		}, true
 	case txTypeRevaluation:
 		return accounting.Revaluation{
@@ -385,10 +416,11 @@ func v1ToLotTx(ctx context.Context, store *accounting.Store, tx TransactionV1, m
 	}
 }
 
-func importTransactions(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, accountsMap, incomeMap, expenseMap, instrumentsMap, attachmentsMap map[uint]uint) error {
+// importTransactions creates all transactions from the backup and returns a map of old to new transaction IDs.
+func importTransactions(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, accountsMap, incomeMap, expenseMap, instrumentsMap, attachmentsMap map[uint]uint) (map[uint]uint, error) {
 	txs, err := loadV1Json[[]TransactionV1](r, transactionsFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Sort transactions by date ASC (then by original ID) so that buys/grants
@@ -400,6 +432,7 @@ func importTransactions(ctx context.Context, store *accounting.Store, r *zip.Rea
 		return txs[i].Date.Before(txs[j].Date)
 	})
 
+	txMap := make(map[uint]uint, len(txs))
 	m := importMaps{accounts: accountsMap, income: incomeMap, expense: expenseMap, instruments: instrumentsMap, attachments: attachmentsMap}
 
 	for _, tx := range txs {
@@ -415,7 +448,7 @@ func importTransactions(ctx context.Context, store *accounting.Store, r *zip.Rea
 		case txTypeStockVest, txTypeStockForfeit:
 			item, err = v1ToLotTx(ctx, store, tx, m, remappedAttID)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		default:
 			var ok bool
@@ -430,17 +463,185 @@ func importTransactions(ctx context.Context, store *accounting.Store, r *zip.Rea
 		}
 		newTxID, err := store.CreateTransaction(ctx, item)
 		if err != nil {
-			return fmt.Errorf("failed to create transaction: %w", err)
+			return nil, fmt.Errorf("failed to create transaction: %w", err)
 		}
+		txMap[tx.Id] = newTxID
 		if remappedAttID != nil {
 			if err := store.SetAttachmentID(ctx, newTxID, remappedAttID); err != nil {
-				return fmt.Errorf("failed to set attachment ID on transaction %d: %w", newTxID, err)
+				return nil, fmt.Errorf("failed to set attachment ID on transaction %d: %w", newTxID, err)
+			}
+		}
+	}
+	return txMap, nil
+}
+
+// importTransactionAttachments restores the attachment lists of transactions from the manifest links;
+// transactions of backups without links keep the single attachment set by importTransactions.
+func importTransactionAttachments(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, txMap, attachmentsMap map[uint]uint) error {
+	if len(attachmentsMap) == 0 {
+		return nil
+	}
+	manifest, err := loadAttachmentManifest(r)
+	if err != nil {
+		return nil // old backup without attachments
+	}
+
+	type link struct {
+		attachmentID uint
+		position     int
+		caption      string
+	}
+	byTx := map[uint][]link{}
+	for _, att := range manifest {
+		newAttID, ok := attachmentsMap[att.ID]
+		if !ok {
+			continue
+		}
+		for _, l := range att.Transactions {
+			newTxID, ok := txMap[l.TransactionID]
+			if !ok {
+				continue
+			}
+			byTx[newTxID] = append(byTx[newTxID], link{attachmentID: newAttID, position: l.Position, caption: l.Caption})
+		}
+	}
+
+	txIDs := make([]uint, 0, len(byTx))
+	for id := range byTx {
+		txIDs = append(txIDs, id)
+	}
+	sort.Slice(txIDs, func(i, j int) bool { return txIDs[i] < txIDs[j] })
+	for _, txID := range txIDs {
+		links := byTx[txID]
+		sort.SliceStable(links, func(i, j int) bool { return links[i].position < links[j].position })
+		// replace the attachment set from TransactionV1.AttachmentID with the full list
+		if err := store.SetAttachmentID(ctx, txID, nil); err != nil {
+			return fmt.Errorf("failed to reset attachments of transaction %d: %w", txID, err)
+		}
+		for _, l := range links {
+			if _, err := store.AddTransactionAttachment(ctx, txID, l.attachmentID, l.caption); err != nil {
+				return fmt.Errorf("failed to add attachment to transaction %d: %w", txID, err)
 			}
 		}
 	}
 	return nil
 }
 
+func importAmortizationPlans(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, txMap, expenseMap map[uint]uint) error {
+	plans, err := loadV1Json[[]amortizationPlanV1](r, amortizationPlansFile)
+	if err != nil {
+		// Old backups may not have this file; skip gracefully.
+		if strings.Contains(err.Error(), "not found in zip") {
+			return nil
+		}
+		return err
+	}
+	for _, p := range plans {
+		txID, ok := txMap[p.TransactionID]
+		if !ok {
+			continue
+		}
+		planID, err := store.CreateAmortizationPlan(ctx, accounting.AmortizationPlan{
+			TransactionID: txID,
+			CategoryID:    expenseMap[p.CategoryID],
+			StartDate:     p.StartDate,
+			EndDate:       p.EndDate,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create amortization plan: %w", err)
+		}
+		for _, posting := range p.Postings {
+			// 0 keeps a period whose expense was deleted from being generated again
+			postingTxID, ok := txMap[posting.TransactionID]
+			if !ok && posting.TransactionID != 0 {
+				continue
+			}
+			err := store.AddAmortizationPosting(ctx, planID, accounting.AmortizationPosting{TransactionID: postingTxID, Period: posting.Period})
+			if err != nil {
+				return fmt.Errorf("failed to restore amortization posting: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func importSavingsGoals(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, accountsMap map[uint]uint) error {
+	goals, err := loadV1Json[[]savingsGoalV1](r, savingsGoalsFile)
+	if err != nil {
+		// Old backups may not have this file; skip gracefully.
+		if strings.Contains(err.Error(), "not found in zip") {
+			return nil
+		}
+		return err
+	}
+	for _, g := range goals {
+		accounts := make([]accounting.SavingsGoalAccount, 0, len(g.Accounts))
+		for _, a := range g.Accounts {
+			if newID, ok := accountsMap[a.AccountID]; ok {
+				accounts = append(accounts, accounting.SavingsGoalAccount{AccountID: newID, Share: a.Share})
+			}
+		}
+		_, err := store.CreateSavingsGoal(ctx, accounting.SavingsGoal{
+			Name:         g.Name,
+			Notes:        g.Notes,
+			Icon:         g.Icon,
+			TargetAmount: g.TargetAmount,
+			TargetDate:   g.TargetDate,
+			Accounts:     accounts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create savings goal %q: %w", g.Name, err)
+		}
+	}
+	return nil
+}
+
+func importSharedExpenses(ctx context.Context, store *accounting.Store, r *zip.ReadCloser, accountsMap, txMap map[uint]uint) error {
+	data, err := loadV1Json[sharedExpensesV1](r, sharedExpensesFile)
+	if err != nil {
+		// Old backups may not have this file; skip gracefully.
+		if strings.Contains(err.Error(), "not found in zip") {
+			return nil
+		}
+		return err
+	}
+	peopleMap := map[uint]uint{}
+	for _, p := range data.People {
+		accID, ok := accountsMap[p.AccountID]
+		if !ok {
+			continue
+		}
+		newID, err := store.CreatePerson(ctx, accounting.Person{Name: p.Name, AccountID: accID})
+		if err != nil {
+			return fmt.Errorf("failed to create person %q: %w", p.Name, err)
+		}
+		peopleMap[p.ID] = newID
+	}
+	for _, sp := range data.Splits {
+		txID, ok := txMap[sp.TransactionID]
+		if !ok {
+			continue
+		}
+		split := accounting.ExpenseSplit{Rule: accounting.SplitRule(sp.Rule), IncludeSelf: sp.IncludeSelf}
+		for _, sh := range sp.Shares {
+			personID, ok := peopleMap[sh.PersonID]
+			if !ok {
+				continue
+			}
+			split.Shares = append(split.Shares, accounting.ExpenseShare{
+				PersonID:     personID,
+				Value:        sh.Value,
+				Amount:       sh.Amount,
+				ReceivableID: txMap[sh.ReceivableID],
+			})
+		}
+		if err := store.RestoreExpenseSplit(ctx, txID, split); err != nil {
+			return fmt.Errorf("failed to restore split of transaction %d: %w", sp.TransactionID, err)
+		}
+	}
+	return nil
+}
+
 // fifoLotSelections builds lot selections by picking open lots in FIFO order
 // until the requested quantity is fulfilled. Only lots opened on or before txDate are considered.
 func fifoLotSelections(ctx context.Context, store *accounting.Store, accountID, instrumentID uint, quantity float64, txDate time.Time) ([]accounting.LotSelection, error) {
@@ -471,7 +672,7 @@ func fifoLotSelections(ctx context.Context, store *accounting.Store, accountID,
 }
 
 // Load V1 data from json files
-func loadV1Json[T metaInfoV1 | []accountProviderV1 | []accountV1 | []categoryV1 | []TransactionV1 | []instrumentV1 | []priceRecordV1 | []fxRateRecordV1 | []importProfileV1 | []categoryRuleGroupV1 | []caseStudyV1 | []scheduleV1](r *zip.ReadCloser, fileName string) (T, error) {
+func loadV1Json[T metaInfoV1 | []accountProviderV1 | []accountV1 | []categoryV1 | []TransactionV1 | []instrumentV1 | []priceRecordV1 | []fxRateRecordV1 | []importProfileV1 | []categoryRuleGroupV1 | []caseStudyV1 | []scheduleV1 | []amortizationPlanV1 | []savingsGoalV1 | sharedExpensesV1 | []importInboxV1 | []pendingImportV1](r *zip.ReadCloser, fileName string) (T, error) {
 	var result T
 
 	for _, f := range r.File {
@@ -636,16 +837,30 @@ func importProfiles(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadC
 	profilesMap := map[uint]uint{}
 	for _, p := range profiles {
 		item := csvimport.ImportProfile{
-			Name:              p.Name,
-			CsvSeparator:      p.CsvSeparator,
-			SkipRows:          p.SkipRows,
-			DateColumn:        p.DateColumn,
-			DateFormat:        p.DateFormat,
-			DescriptionColumn: p.DescriptionColumn,
-			AmountColumn:      p.AmountColumn,
-			AmountMode:        p.AmountMode,
-			CreditColumn:      p.CreditColumn,
-			DebitColumn:       p.DebitColumn,
+			Name:               p.Name,
+			CsvSeparator:       p.CsvSeparator,
+			SheetName:          p.SheetName,
+			SkipRows:           p.SkipRows,
+			DateColumn:         p.DateColumn,
+			DateFormat:         p.DateFormat,
+			DescriptionColumn:  p.DescriptionColumn,
+			AmountColumn:       p.AmountColumn,
+			AmountMode:         p.AmountMode,
+			CreditColumn:       p.CreditColumn,
+			DebitColumn:        p.DebitColumn,
+			DecimalSeparator:   p.DecimalSeparator,
+			ThousandsSeparator: p.ThousandsSeparator,
+			InvertSign:         p.InvertSign,
+			Type:               p.Type,
+			SymbolColumn:       p.SymbolColumn,
+			SideColumn:         p.SideColumn,
+			QuantityColumn:     p.QuantityColumn,
+			PriceColumn:        p.PriceColumn,
+			FeesColumn:         p.FeesColumn,
+			CurrencyColumn:     p.CurrencyColumn,
+			OrderIDColumn:      p.OrderIDColumn,
+			HeaderRow:          p.HeaderRow,
+			// the cash account is linked once the accounts exist, see linkProfileCashAccounts
 		}
 		newID, err := csvStore.CreateProfile(ctx, item)
 		if err != nil {
@@ -656,6 +871,29 @@ func importProfiles(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadC
 	return profilesMap, nil
 }
 
+// linkProfileCashAccounts sets the cash account of the imported investment profiles. Profiles
+// are imported before the accounts, which reference them.
+func linkProfileCashAccounts(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadCloser, profilesMap, accountsMap map[uint]uint) error {
+	profiles, err := loadV1Json[[]importProfileV1](r, importProfilesFile)
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if p.CashAccountID == 0 {
+			continue
+		}
+		item, err := csvStore.GetProfile(ctx, profilesMap[p.ID])
+		if err != nil {
+			return fmt.Errorf("failed to get import profile: %w", err)
+		}
+		item.CashAccountID = accountsMap[p.CashAccountID]
+		if err := csvStore.UpdateProfile(ctx, item.ID, item); err != nil {
+			return fmt.Errorf("failed to link the cash account of import profile %q: %w", item.Name, err)
+		}
+	}
+	return nil
+}
+
 func importCaseStudies(ctx context.Context, tdStore *toolsdata.Store, r *zip.ReadCloser, attachmentsMap map[uint]uint) error {
 	if tdStore == nil {
 		return nil
@@ -692,7 +930,7 @@ func importCaseStudies(ctx context.Context, tdStore *toolsdata.Store, r *zip.Rea
 	return nil
 }
 
-func importCategoryRules(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadCloser, incomeMap, expenseMap map[uint]uint) error {
+func importCategoryRules(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadCloser, incomeMap, expenseMap, accountsMap, profilesMap map[uint]uint) error {
 	groups, err := loadV1Json[[]categoryRuleGroupV1](r, categoryRulesFile)
 	if err != nil {
 		return err
@@ -706,6 +944,19 @@ func importCategoryRules(ctx context.Context, csvStore *csvimport.Store, r *zip.
 			Name:       g.Name,
 			CategoryID: catID,
 			Priority:   g.Priority,
+
+			MatchAny:          g.MatchAny,
+			AmountMin:         g.AmountMin,
+			AmountMax:         g.AmountMax,
+			Direction:         g.Direction,
+			AccountID:         accountsMap[g.AccountID],
+			ProfileID:         profilesMap[g.ProfileID],
+			DateFrom:          g.DateFrom,
+			DateTo:            g.DateTo,
+			Weekdays:          g.Weekdays,
+			SetDescription:    g.SetDescription,
+			AppendNotes:       g.AppendNotes,
+			TransferAccountID: accountsMap[g.TransferAccountID],
 		}
 		for _, p := range g.Patterns {
 			item.Patterns = append(item.Patterns, csvimport.CategoryRulePattern{
@@ -721,6 +972,61 @@ func importCategoryRules(ctx context.Context, csvStore *csvimport.Store, r *zip.
 	return nil
 }
 
+// importImportInboxes restores the inbox configs and their pending imports.
+func importImportInboxes(ctx context.Context, csvStore *csvimport.Store, r *zip.ReadCloser, accountsMap, profilesMap map[uint]uint) error {
+	inboxes, err := loadV1Json[[]importInboxV1](r, importInboxesFile)
+	if err != nil {
+		// Old backups may not have this file; skip gracefully.
+		if strings.Contains(err.Error(), "not found in zip") {
+			return nil
+		}
+		return err
+	}
+	inboxMap := map[uint]uint{}
+	for _, in := range inboxes {
+		accountID, ok := accountsMap[in.AccountID]
+		if !ok {
+			continue
+		}
+		newID, err := csvStore.CreateInbox(ctx, csvimport.ImportInbox{
+			AccountID:  accountID,
+			ProfileID:  profilesMap[in.ProfileID],
+			Directory:  in.Directory,
+			AutoSubmit: in.AutoSubmit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create import inbox %q: %w", in.Directory, err)
+		}
+		inboxMap[in.ID] = newID
+	}
+
+	pending, err := loadV1Json[[]pendingImportV1](r, pendingImportsFile)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		accountID, ok := accountsMap[p.AccountID]
+		if !ok {
+			continue
+		}
+		_, err := csvStore.CreatePendingImport(ctx, csvimport.PendingImport{
+			InboxID:   inboxMap[p.InboxID],
+			AccountID: accountID,
+			FileName:  p.FileName,
+			FileHash:  p.FileHash,
+			Format:    p.Format,
+			TotalRows: p.TotalRows,
+			Error:     p.Error,
+			Data:      p.Data,
+			CreatedAt: p.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create pending import %q: %w", p.FileName, err)
+		}
+	}
+	return nil
+}
+
 func importAttachments(ctx context.Context, fileStore *filestore.Store, r *zip.ReadCloser) (map[uint]uint, error) {
 	attachmentsMap := map[uint]uint{}
 	if fileStore == nil {
@@ -737,12 +1043,24 @@ func importAttachments(ctx context.Context, fileStore *filestore.Store, r *zip.R
 		if err != nil {
 			return nil, fmt.Errorf("failed to read attachment %s from zip: %w", att.ZipPath, err)
 		}
+		if att.Encrypted {
+			content, err = fileStore.DecryptExported(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt attachment %d: %w", att.ID, err)
+			}
+		}
 
 		date := time.Now()
 		newID, err := fileStore.SaveRaw(ctx, date, content, att.OriginalName, att.MimeType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to save attachment %d: %w", att.ID, err)
 		}
+		// restore the references held by the transactions and case studies sharing the file
+		if refs := max(att.RefCount, len(att.Transactions)); refs > 1 {
+			if err := fileStore.AddRefs(ctx, newID, refs-1); err != nil {
+				return nil, fmt.Errorf("failed to restore references of attachment %d: %w", att.ID, err)
+			}
+		}
 		attachmentsMap[att.ID] = newID
 	}
 
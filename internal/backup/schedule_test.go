@@ -27,7 +27,7 @@ type scheduleTestStores struct {
 	schedules  *taskrunner.ScheduleStore
 }
 
-func newScheduleTestStores(t *testing.T, dsn string) scheduleTestStores {
+func newScheduleTestStores(t *testing.T, dsn string, fileOpts ...filestore.Option) scheduleTestStores {
 	t.Helper()
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
 	if err != nil {
@@ -49,7 +49,7 @@ func newScheduleTestStores(t *testing.T, dsn string) scheduleTestStores {
 	if err != nil {
 		t.Fatalf("unable to create toolsdata store: %v", err)
 	}
-	fileStore, err := filestore.New(db, filepath.Join(t.TempDir(), "attachments"), 10*1024*1024)
+	fileStore, err := filestore.New(db, filepath.Join(t.TempDir(), "attachments"), 10*1024*1024, fileOpts...)
 	if err != nil {
 		t.Fatalf("unable to create filestore: %v", err)
 	}
@@ -0,0 +1,168 @@
+package csvimport
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numberFormat holds the separators of the numbers of a profile's files. Without a decimal
+// separator the format is guessed for each value, see parseDecimal.
+type numberFormat struct {
+	decimal   string
+	thousands string
+}
+
+func profileNumberFormat(p ImportProfile) numberFormat {
+	return numberFormat{decimal: p.DecimalSeparator, thousands: p.ThousandsSeparator}
+}
+
+// parseAmount parses an amount like parse and rounds it to 2 decimal places.
+func (nf numberFormat) parseAmount(s string) (float64, error) {
+	val, err := nf.parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(val*100) / 100, nil
+}
+
+// parse parses a number written with the separators of the format. Besides a leading sign, a
+// trailing minus as written by some banks, e.g. "45,60-", marks a negative number.
+func (nf numberFormat) parse(s string) (float64, error) {
+	if nf.decimal == "" {
+		return parseDecimal(s)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+
+	negative := false
+	v := s
+	switch {
+	case strings.HasPrefix(v, "-"):
+		negative = true
+		v = v[1:]
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	case strings.HasSuffix(v, "-"):
+		negative = true
+		v = strings.TrimSpace(v[:len(v)-1])
+	}
+
+	if nf.thousands != "" {
+		v = strings.ReplaceAll(v, nf.thousands, "")
+		if nf.thousands == " " {
+			v = strings.ReplaceAll(v, "\u00a0", "") // non-breaking space of spreadsheet exports
+		}
+	}
+	other := "."
+	if nf.decimal == "." {
+		other = ","
+	}
+	if strings.Contains(v, other) || strings.Count(v, nf.decimal) > 1 {
+		return 0, fmt.Errorf("cannot parse amount %q with decimal separator %q", s, nf.decimal)
+	}
+	v = strings.Replace(v, nf.decimal, ".", 1)
+
+	val, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsInf(val, 0) || math.IsNaN(val) {
+		return 0, fmt.Errorf("cannot parse amount %q", s)
+	}
+	if negative {
+		val = -val
+	}
+	return val, nil
+}
+
+// checkStatementColumns checks that the optional fees and currency columns of a statement
+// profile are present.
+func checkStatementColumns(colIndex map[string]int, profile ImportProfile) error {
+	for _, c := range []string{profile.FeesColumn, profile.CurrencyColumn} {
+		if c == "" {
+			continue
+		}
+		if _, ok := colIndex[c]; !ok {
+			return fmt.Errorf("required column %q not found in headers", c)
+		}
+	}
+	return nil
+}
+
+// statementFees reads the currency and the fees of a statement row. The fees are deducted from
+// the amount, so the imported transaction matches the change of the account balance.
+// It returns an error string like resolveAmount.
+func statementFees(parsed *ParsedRow, row []string, colIndex map[string]int, profile ImportProfile) string {
+	field := func(column string) string {
+		idx, ok := colIndex[column]
+		if column == "" || !ok || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+
+	parsed.Currency = strings.ToUpper(field(profile.CurrencyColumn))
+	raw := field(profile.FeesColumn)
+	if raw == "" {
+		return ""
+	}
+	fees, err := profileNumberFormat(profile).parseAmount(raw)
+	if err != nil {
+		return fmt.Sprintf("invalid fees %q: %v", raw, err)
+	}
+	if fees == 0 {
+		return ""
+	}
+	parsed.Fees = math.Abs(fees)
+	parsed.Amount = math.Round((parsed.Amount-parsed.Fees)*100) / 100
+	if parsed.Amount < 0 {
+		parsed.Type = "expense"
+	} else {
+		parsed.Type = "income"
+	}
+	return ""
+}
+
+// RateFunc returns the exchange rate that converts an amount in currency into the account
+// currency on the given date, false when no rate is known.
+type RateFunc func(currency string, date time.Time) (float64, bool)
+
+type parseOptions struct {
+	accountCurrency string
+	rate            RateFunc
+}
+
+// ParseOption configures Parse and ParseXLSX.
+type ParseOption func(*parseOptions)
+
+// WithCurrencyConversion converts the rows of profiles with a currency column whose currency
+// differs from the account currency, before the category rules and the duplicate detection
+// see them. Rows without an exchange rate, or all of them with a nil rate, are flagged with
+// an error.
+func WithCurrencyConversion(accountCurrency string, rate RateFunc) ParseOption {
+	return func(o *parseOptions) {
+		o.accountCurrency = strings.ToUpper(accountCurrency)
+		o.rate = rate
+	}
+}
+
+// convert converts the amount of a statement row into the account currency and returns an error
+// string when it cannot be converted.
+func (o parseOptions) convert(parsed *ParsedRow, date time.Time) string {
+	if o.accountCurrency == "" || parsed.Currency == "" || parsed.Currency == o.accountCurrency {
+		return ""
+	}
+	if o.rate == nil {
+		return fmt.Sprintf("currency %s differs from the account currency %s", parsed.Currency, o.accountCurrency)
+	}
+	rate, ok := o.rate(parsed.Currency, date)
+	if !ok || rate <= 0 {
+		return fmt.Sprintf("no exchange rate from %s to %s on %s", parsed.Currency, o.accountCurrency, parsed.Date)
+	}
+	parsed.OriginalAmount = parsed.Amount
+	parsed.Amount = math.Round(parsed.Amount*rate*100) / 100
+	parsed.Fees = math.Round(parsed.Fees*rate*100) / 100
+	return ""
+}
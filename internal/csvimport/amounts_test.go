@@ -0,0 +1,171 @@
+package csvimport
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNumberFormatParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		decimal   string
+		thousands string
+		input     string
+		want      float64
+		wantErr   bool
+	}{
+		{name: "comma decimal thousands dot", decimal: ",", thousands: ".", input: "1.234", want: 1234},
+		{name: "dot decimal", decimal: ".", input: "1.234", want: 1.234},
+		{name: "dot decimal thousands comma", decimal: ".", thousands: ",", input: "1,234.56", want: 1234.56},
+		{name: "swiss apostrophe", decimal: ".", thousands: "'", input: "12'345.60", want: 12345.60},
+		{name: "space thousands", decimal: ",", thousands: " ", input: "1 234,50", want: 1234.50},
+		{name: "non-breaking space thousands", decimal: ",", thousands: " ", input: "1\u00a0234,50", want: 1234.50},
+		{name: "trailing minus", decimal: ",", thousands: ".", input: "45,60-", want: -45.60},
+		{name: "leading plus", decimal: ".", input: "+12.5", want: 12.5},
+		{name: "guessed without decimal", input: "1.234,56", want: 1234.56},
+		{name: "other separator", decimal: ",", input: "1.234,56", wantErr: true},
+		{name: "two decimal separators", decimal: ".", input: "1.234.56", wantErr: true},
+		{name: "empty", decimal: ",", input: " ", wantErr: true},
+		{name: "not a number", decimal: ",", input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nf := numberFormat{decimal: tt.decimal, thousands: tt.thousands}
+			got, err := nf.parse(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil (value=%f)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("expected %f, got %f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_NumberFormat(t *testing.T) {
+	csv := `Date;Description;Amount
+01/03/2026;Rent;1.234
+02/03/2026;Refund;12,50-
+`
+	profile := defaultProfile()
+	profile.CsvSeparator = ";"
+	profile.DecimalSeparator = ","
+	profile.ThousandsSeparator = "."
+
+	rows, err := Parse(strings.NewReader(csv), profile, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows[0].Amount != 1234 {
+		t.Errorf("expected Amount=1234, got %f", rows[0].Amount)
+	}
+	if rows[1].Amount != -12.50 || rows[1].Type != "expense" {
+		t.Errorf("expected expense of -12.50, got %s %f", rows[1].Type, rows[1].Amount)
+	}
+}
+
+func TestParse_InvertSign(t *testing.T) {
+	csv := `Date,Description,Amount
+01/03/2026,Card payment,45.30
+02/03/2026,Payment received,-100.00
+`
+	profile := defaultProfile()
+	profile.InvertSign = true
+
+	rows, err := Parse(strings.NewReader(csv), profile, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows[0].Amount != -45.30 || rows[0].Type != "expense" {
+		t.Errorf("row 0: expected expense of -45.30, got %s %f", rows[0].Type, rows[0].Amount)
+	}
+	if rows[1].Amount != 100 || rows[1].Type != "income" {
+		t.Errorf("row 1: expected income of 100, got %s %f", rows[1].Type, rows[1].Amount)
+	}
+}
+
+func TestParse_FeesAndCurrency(t *testing.T) {
+	csv := `Date,Description,Amount,Fee,Currency
+01/03/2026,Transfer,100.00,1.50,chf
+02/03/2026,Card payment,-20.00,0.40,CHF
+03/03/2026,Bad fee,-20.00,x,CHF
+`
+	profile := defaultProfile()
+	profile.FeesColumn = "Fee"
+	profile.CurrencyColumn = "Currency"
+
+	rows, err := Parse(strings.NewReader(csv), profile, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows[0].Amount != 98.50 || rows[0].Fees != 1.50 || rows[0].Currency != "CHF" {
+		t.Errorf("row 0: expected 98.50 CHF with fees 1.50, got %f %s with fees %f", rows[0].Amount, rows[0].Currency, rows[0].Fees)
+	}
+	if rows[1].Amount != -20.40 || rows[1].Type != "expense" {
+		t.Errorf("row 1: expected expense of -20.40, got %s %f", rows[1].Type, rows[1].Amount)
+	}
+	if rows[2].Error == "" {
+		t.Error("row 2: expected an error for the invalid fee")
+	}
+
+	profile.FeesColumn = "Missing"
+	if _, err := Parse(strings.NewReader(csv), profile, nil, nil); err == nil {
+		t.Error("expected error for missing fee column, got nil")
+	}
+}
+
+func TestParse_CurrencyConversion(t *testing.T) {
+	csv := `Date,Description,Amount,Fee,Currency
+01/03/2026,Hotel,-100.00,2.00,EUR
+02/03/2026,Coffee,-4.00,,CHF
+03/03/2026,Dinner,-50.00,,USD
+`
+	profile := defaultProfile()
+	profile.FeesColumn = "Fee"
+	profile.CurrencyColumn = "Currency"
+
+	rate := func(currency string, date time.Time) (float64, bool) {
+		if currency == "EUR" && date.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			return 0.95, true
+		}
+		return 0, false
+	}
+	existing := []ExistingTx{{Date: "2026-03-01", Amount: -96.90}}
+
+	rows, err := Parse(strings.NewReader(csv), profile, nil, existing, WithCurrencyConversion("chf", rate))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hotel := rows[0]
+	if hotel.OriginalAmount != -102 || hotel.Amount != -96.90 || hotel.Fees != 1.90 {
+		t.Errorf("row 0: expected -102 converted to -96.90 with fees 1.90, got %f to %f with fees %f",
+			hotel.OriginalAmount, hotel.Amount, hotel.Fees)
+	}
+	if !hotel.IsDuplicate {
+		t.Error("row 0: expected the converted amount to match the existing transaction")
+	}
+	if rows[1].Amount != -4 || rows[1].OriginalAmount != 0 || rows[1].Error != "" {
+		t.Errorf("row 1: expected the account currency to be kept, got %+v", rows[1])
+	}
+	if !strings.Contains(rows[2].Error, "no exchange rate") {
+		t.Errorf("row 2: expected a missing rate error, got %q", rows[2].Error)
+	}
+
+	rows, err = Parse(strings.NewReader(csv), profile, nil, nil, WithCurrencyConversion("CHF", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows[0].Error == "" || rows[1].Error != "" {
+		t.Errorf("expected only the foreign currency row to be flagged, got %q and %q", rows[0].Error, rows[1].Error)
+	}
+}
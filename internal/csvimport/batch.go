@@ -0,0 +1,164 @@
+package csvimport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrImportBatchNotFound = errors.New("import batch not found")
+
+// dbImportBatch records one submit of an import, the transactions it created are linked through
+// dbImportBatchTransaction.
+type dbImportBatch struct {
+	ID           uint `gorm:"primarykey"`
+	ProfileID    uint
+	AccountID    uint `gorm:"not null;index"`
+	FileName     string
+	FileHash     string `gorm:"index"`
+	Format       string
+	TotalRows    int
+	ImportedRows int
+	User         string
+	RolledBackAt *time.Time
+	Transactions []dbImportBatchTransaction `gorm:"foreignKey:BatchID"`
+	CreatedAt    time.Time
+}
+
+type dbImportBatchTransaction struct {
+	ID            uint `gorm:"primarykey"`
+	BatchID       uint `gorm:"not null;index"`
+	TransactionID uint `gorm:"not null;index"`
+}
+
+// ImportBatch is the record of a submitted import file.
+type ImportBatch struct {
+	ID             uint
+	ProfileID      uint // 0 for structured formats that need no profile
+	AccountID      uint
+	FileName       string
+	FileHash       string // see FileHash
+	Format         string
+	TotalRows      int // rows parsed from the file
+	ImportedRows   int // transactions created
+	User           string
+	TransactionIDs []uint     // in creation order
+	RolledBackAt   *time.Time // nil while the transactions are in place
+	CreatedAt      time.Time
+}
+
+// FileHash returns the hash identifying the content of an uploaded file.
+func FileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func dbToBatch(in dbImportBatch) ImportBatch {
+	b := ImportBatch{
+		ID:           in.ID,
+		ProfileID:    in.ProfileID,
+		AccountID:    in.AccountID,
+		FileName:     in.FileName,
+		FileHash:     in.FileHash,
+		Format:       in.Format,
+		TotalRows:    in.TotalRows,
+		ImportedRows: in.ImportedRows,
+		User:         in.User,
+		RolledBackAt: in.RolledBackAt,
+		CreatedAt:    in.CreatedAt,
+	}
+	for _, t := range in.Transactions {
+		b.TransactionIDs = append(b.TransactionIDs, t.TransactionID)
+	}
+	return b
+}
+
+// CreateImportBatch stores an import batch together with the links to its transactions.
+func (s *Store) CreateImportBatch(ctx context.Context, b ImportBatch) (uint, error) {
+	if b.AccountID == 0 {
+		return 0, ErrValidation("account_id cannot be zero")
+	}
+	row := dbImportBatch{
+		ProfileID:    b.ProfileID,
+		AccountID:    b.AccountID,
+		FileName:     b.FileName,
+		FileHash:     b.FileHash,
+		Format:       b.Format,
+		TotalRows:    b.TotalRows,
+		ImportedRows: len(b.TransactionIDs),
+		User:         b.User,
+	}
+	for _, id := range b.TransactionIDs {
+		row.Transactions = append(row.Transactions, dbImportBatchTransaction{TransactionID: id})
+	}
+
+	d := s.db.WithContext(ctx).Create(&row)
+	if d.Error != nil {
+		return 0, d.Error
+	}
+	return row.ID, nil
+}
+
+func (s *Store) GetImportBatch(ctx context.Context, id uint) (ImportBatch, error) {
+	var row dbImportBatch
+	d := s.db.WithContext(ctx).Preload("Transactions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("id = ?", id).First(&row)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return ImportBatch{}, ErrImportBatchNotFound
+		}
+		return ImportBatch{}, d.Error
+	}
+	return dbToBatch(row), nil
+}
+
+// ListImportBatches returns the import batches of an account, all of them for accountID 0,
+// newest first. TransactionIDs are not loaded.
+func (s *Store) ListImportBatches(ctx context.Context, accountID uint) ([]ImportBatch, error) {
+	q := s.db.WithContext(ctx).Order("created_at DESC, id DESC")
+	if accountID != 0 {
+		q = q.Where("account_id = ?", accountID)
+	}
+	var rows []dbImportBatch
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	batches := make([]ImportBatch, 0, len(rows))
+	for _, row := range rows {
+		batches = append(batches, dbToBatch(row))
+	}
+	return batches, nil
+}
+
+// FindImportBatchesByHash returns the batches that imported a file with the given hash and
+// were not rolled back, newest first.
+func (s *Store) FindImportBatchesByHash(ctx context.Context, hash string) ([]ImportBatch, error) {
+	var rows []dbImportBatch
+	d := s.db.WithContext(ctx).Where("file_hash = ? AND rolled_back_at IS NULL", hash).
+		Order("created_at DESC, id DESC").Find(&rows)
+	if d.Error != nil {
+		return nil, d.Error
+	}
+	batches := make([]ImportBatch, 0, len(rows))
+	for _, row := range rows {
+		batches = append(batches, dbToBatch(row))
+	}
+	return batches, nil
+}
+
+// MarkImportBatchRolledBack records that the transactions of a batch were deleted.
+func (s *Store) MarkImportBatchRolledBack(ctx context.Context, id uint, at time.Time) error {
+	d := s.db.WithContext(ctx).Model(&dbImportBatch{}).Where("id = ?", id).Update("rolled_back_at", at)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrImportBatchNotFound
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestImportBatches(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	hash := FileHash([]byte("Date,Description,Amount\n"))
+	if len(hash) != 64 {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+
+	first, err := store.CreateImportBatch(ctx, ImportBatch{
+		ProfileID: 1, AccountID: 10, FileName: "march.csv", FileHash: hash, Format: FormatCSV,
+		TotalRows: 3, User: "demo", TransactionIDs: []uint{7, 5, 6},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.CreateImportBatch(ctx, ImportBatch{AccountID: 11, FileName: "april.ofx", FileHash: "other", Format: FormatOFX})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetImportBatch(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FileName != "march.csv" || got.User != "demo" || got.TotalRows != 3 || got.ImportedRows != 3 || got.RolledBackAt != nil {
+		t.Errorf("unexpected batch %+v", got)
+	}
+	if len(got.TransactionIDs) != 3 || got.TransactionIDs[0] != 7 || got.TransactionIDs[2] != 6 {
+		t.Errorf("expected the transactions in creation order, got %v", got.TransactionIDs)
+	}
+
+	all, err := store.ListImportBatches(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != second {
+		t.Errorf("expected both batches newest first, got %+v", all)
+	}
+	byAccount, err := store.ListImportBatches(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 1 || byAccount[0].ID != first {
+		t.Errorf("unexpected batches of account 10: %+v", byAccount)
+	}
+
+	same, err := store.FindImportBatchesByHash(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same) != 1 || same[0].ID != first {
+		t.Errorf("expected the batch with the same hash, got %+v", same)
+	}
+
+	if err := store.MarkImportBatchRolledBack(ctx, first, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetImportBatch(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RolledBackAt == nil {
+		t.Error("expected the batch to be marked as rolled back")
+	}
+	same, err = store.FindImportBatchesByHash(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same) != 0 {
+		t.Errorf("rolled back batches should not be reported as previous imports, got %+v", same)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		var valErr ErrValidation
+		if _, err := store.CreateImportBatch(ctx, ImportBatch{FileName: "x.csv"}); !errors.As(err, &valErr) {
+			t.Errorf("expected a validation error without account, got %v", err)
+		}
+		if _, err := store.GetImportBatch(ctx, 999); !errors.Is(err, ErrImportBatchNotFound) {
+			t.Errorf("expected ErrImportBatchNotFound, got %v", err)
+		}
+		if err := store.MarkImportBatchRolledBack(ctx, 999, time.Now()); !errors.Is(err, ErrImportBatchNotFound) {
+			t.Errorf("expected ErrImportBatchNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,496 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BundleVersion is the version of the bundle format written by ExportBundle.
+const BundleVersion = 1
+
+// Bundle is a portable set of import profiles and category rule groups, shared as JSON between
+// instances without a backup restore. Categories, accounts and profiles are referenced by name
+// instead of id, as the ids differ between instances.
+type Bundle struct {
+	Version    int               `json:"version"`
+	Profiles   []BundleProfile   `json:"profiles"`
+	RuleGroups []BundleRuleGroup `json:"ruleGroups"`
+}
+
+// CategoryRef references a category by its names from the root down, e.g. ["Food", "Groceries"].
+type CategoryRef struct {
+	Type string   `json:"type"` // "income" or "expense"
+	Path []string `json:"path"`
+}
+
+func (c CategoryRef) String() string {
+	return c.Type + " " + strings.Join(c.Path, " > ")
+}
+
+type BundleProfile struct {
+	Name               string   `json:"name"`
+	CsvSeparator       string   `json:"csvSeparator"`
+	SheetName          string   `json:"sheetName,omitempty"`
+	SkipRows           int      `json:"skipRows"`
+	DateColumn         string   `json:"dateColumn"`
+	DateFormat         string   `json:"dateFormat"`
+	DescriptionColumn  string   `json:"descriptionColumn"`
+	AmountColumn       string   `json:"amountColumn"`
+	AmountMode         string   `json:"amountMode"`
+	CreditColumn       string   `json:"creditColumn,omitempty"`
+	DebitColumn        string   `json:"debitColumn,omitempty"`
+	DecimalSeparator   string   `json:"decimalSeparator,omitempty"`
+	ThousandsSeparator string   `json:"thousandsSeparator,omitempty"`
+	InvertSign         bool     `json:"invertSign,omitempty"`
+	Type               string   `json:"type,omitempty"`
+	SymbolColumn       string   `json:"symbolColumn,omitempty"`
+	SideColumn         string   `json:"sideColumn,omitempty"`
+	QuantityColumn     string   `json:"quantityColumn,omitempty"`
+	PriceColumn        string   `json:"priceColumn,omitempty"`
+	FeesColumn         string   `json:"feesColumn,omitempty"`
+	CurrencyColumn     string   `json:"currencyColumn,omitempty"`
+	OrderIDColumn      string   `json:"orderIdColumn,omitempty"`
+	CashAccount        string   `json:"cashAccount,omitempty"` // account name
+	HeaderRow          []string `json:"headerRow,omitempty"`
+}
+
+type BundleRuleGroup struct {
+	Name     string          `json:"name"`
+	Category *CategoryRef    `json:"category,omitempty"` // nil keeps the category
+	Patterns []BundlePattern `json:"patterns"`
+
+	MatchAny        bool     `json:"matchAny,omitempty"`
+	AmountMin       *float64 `json:"amountMin,omitempty"`
+	AmountMax       *float64 `json:"amountMax,omitempty"`
+	Direction       string   `json:"direction,omitempty"`
+	Account         string   `json:"account,omitempty"`  // account name
+	Profile         string   `json:"profile,omitempty"`  // profile name
+	DateFrom        string   `json:"dateFrom,omitempty"` // YYYY-MM-DD
+	DateTo          string   `json:"dateTo,omitempty"`
+	Weekdays        []int    `json:"weekdays,omitempty"` // 0 is Sunday
+	SetDescription  string   `json:"setDescription,omitempty"`
+	AppendNotes     string   `json:"appendNotes,omitempty"`
+	TransferAccount string   `json:"transferAccount,omitempty"` // account name
+}
+
+type BundlePattern struct {
+	Pattern string `json:"pattern"`
+	IsRegex bool   `json:"isRegex,omitempty"`
+}
+
+// BundleNames holds the names the ids referenced by exported profiles and rules are written as.
+type BundleNames struct {
+	Categories map[uint]CategoryRef
+	Accounts   map[uint]string
+}
+
+// ExportBundle returns the given profiles and rule groups as a bundle; the rule groups keep
+// their order. Ids that do not exist are ignored.
+func (s *Store) ExportBundle(ctx context.Context, profileIDs, groupIDs []uint, names BundleNames) (Bundle, error) {
+	b := Bundle{Version: BundleVersion, Profiles: []BundleProfile{}, RuleGroups: []BundleRuleGroup{}}
+
+	profiles, err := s.ListProfiles(ctx)
+	if err != nil {
+		return Bundle{}, err
+	}
+	profileNames := make(map[uint]string, len(profiles))
+	for _, p := range profiles {
+		profileNames[p.ID] = p.Name
+		if slices.Contains(profileIDs, p.ID) {
+			b.Profiles = append(b.Profiles, profileToBundle(p, names))
+		}
+	}
+
+	groups, err := s.ListCategoryRuleGroups(ctx)
+	if err != nil {
+		return Bundle{}, err
+	}
+	for _, g := range groups {
+		if !slices.Contains(groupIDs, g.ID) {
+			continue
+		}
+		out := BundleRuleGroup{
+			Name:            g.Name,
+			Patterns:        make([]BundlePattern, 0, len(g.Patterns)),
+			MatchAny:        g.MatchAny,
+			AmountMin:       g.AmountMin,
+			AmountMax:       g.AmountMax,
+			Direction:       g.Direction,
+			Account:         names.Accounts[g.AccountID],
+			Profile:         profileNames[g.ProfileID],
+			SetDescription:  g.SetDescription,
+			AppendNotes:     g.AppendNotes,
+			TransferAccount: names.Accounts[g.TransferAccountID],
+		}
+		if g.CategoryID != 0 {
+			ref, ok := names.Categories[g.CategoryID]
+			if !ok {
+				return Bundle{}, fmt.Errorf("rule group %q: category %d not found", g.Name, g.CategoryID)
+			}
+			out.Category = &ref
+		}
+		for _, p := range g.Patterns {
+			out.Patterns = append(out.Patterns, BundlePattern{Pattern: p.Pattern, IsRegex: p.IsRegex})
+		}
+		if g.DateFrom != nil {
+			out.DateFrom = g.DateFrom.Format(time.DateOnly)
+		}
+		if g.DateTo != nil {
+			out.DateTo = g.DateTo.Format(time.DateOnly)
+		}
+		for _, d := range g.Weekdays {
+			out.Weekdays = append(out.Weekdays, int(d))
+		}
+		b.RuleGroups = append(b.RuleGroups, out)
+	}
+	return b, nil
+}
+
+func profileToBundle(p ImportProfile, names BundleNames) BundleProfile {
+	return BundleProfile{
+		Name:               p.Name,
+		CsvSeparator:       p.CsvSeparator,
+		SheetName:          p.SheetName,
+		SkipRows:           p.SkipRows,
+		DateColumn:         p.DateColumn,
+		DateFormat:         p.DateFormat,
+		DescriptionColumn:  p.DescriptionColumn,
+		AmountColumn:       p.AmountColumn,
+		AmountMode:         p.AmountMode,
+		CreditColumn:       p.CreditColumn,
+		DebitColumn:        p.DebitColumn,
+		DecimalSeparator:   p.DecimalSeparator,
+		ThousandsSeparator: p.ThousandsSeparator,
+		InvertSign:         p.InvertSign,
+		Type:               p.Type,
+		SymbolColumn:       p.SymbolColumn,
+		SideColumn:         p.SideColumn,
+		QuantityColumn:     p.QuantityColumn,
+		PriceColumn:        p.PriceColumn,
+		FeesColumn:         p.FeesColumn,
+		CurrencyColumn:     p.CurrencyColumn,
+		OrderIDColumn:      p.OrderIDColumn,
+		CashAccount:        names.Accounts[p.CashAccountID],
+		HeaderRow:          p.HeaderRow,
+	}
+}
+
+// Strategies for the profiles and rule groups of a bundle named like existing ones.
+const (
+	ConflictSkip  = "skip"  // keep the existing one
+	ConflictMerge = "merge" // update the existing one, rule groups get the missing patterns added
+)
+
+// Outcomes of the items of an imported bundle.
+const (
+	BundleCreated = "created"
+	BundleMerged  = "merged"
+	BundleSkipped = "skipped"
+)
+
+// BundleTargets resolves the names of a bundle to the ids of this instance.
+type BundleTargets struct {
+	Categories CategoryPaths
+	Accounts   map[string]uint // by lower-cased name
+}
+
+// BundleItem is the outcome of a profile or rule group of an imported bundle.
+type BundleItem struct {
+	Name   string `json:"name"`
+	Action string `json:"action"` // BundleCreated, BundleMerged or BundleSkipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// BundleImportResult reports what importing a bundle did, or would do on a dry run.
+type BundleImportResult struct {
+	DryRun              bool         `json:"dryRun"`
+	Profiles            []BundleItem `json:"profiles"`
+	RuleGroups          []BundleItem `json:"ruleGroups"`
+	UnmatchedCategories []string     `json:"unmatchedCategories"`
+	UnmatchedAccounts   []string     `json:"unmatchedAccounts"`
+}
+
+var errDryRun = errors.New("dry run")
+
+// ImportBundle creates the profiles and rule groups of a bundle. Those named like an existing
+// one, ignoring case, are skipped or merged according to strategy. Rule groups whose category,
+// account or profile does not exist here are skipped, a profile whose cash account does not
+// exist is imported without one; the names are listed in the result. New rule groups are
+// placed after the existing ones in bundle order. A dry run reports the same result without
+// changing anything.
+func (s *Store) ImportBundle(ctx context.Context, b Bundle, targets BundleTargets, strategy string, dryRun bool) (BundleImportResult, error) {
+	if b.Version != BundleVersion {
+		return BundleImportResult{}, ErrValidation(fmt.Sprintf("unsupported bundle version %d", b.Version))
+	}
+	if strategy == "" {
+		strategy = ConflictSkip
+	}
+	if strategy != ConflictSkip && strategy != ConflictMerge {
+		return BundleImportResult{}, ErrValidation("strategy must be skip or merge")
+	}
+
+	var res BundleImportResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// a store on the transaction, so a dry run and failures leave nothing behind
+		txStore := &Store{db: tx}
+		var err error
+		res, err = txStore.importBundle(ctx, b, targets, strategy)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return BundleImportResult{}, err
+	}
+	res.DryRun = dryRun
+	return res, nil
+}
+
+// bundleResolver resolves the names of an imported bundle and records those not found.
+type bundleResolver struct {
+	targets  BundleTargets
+	profiles map[string]uint // by lower-cased name
+	res      *BundleImportResult
+}
+
+func (r *bundleResolver) account(name string) (uint, bool) {
+	if name == "" {
+		return 0, true
+	}
+	id, ok := r.targets.Accounts[strings.ToLower(strings.TrimSpace(name))]
+	if !ok && !slices.Contains(r.res.UnmatchedAccounts, name) {
+		r.res.UnmatchedAccounts = append(r.res.UnmatchedAccounts, name)
+	}
+	return id, ok
+}
+
+func (r *bundleResolver) category(ref CategoryRef) (uint, bool) {
+	var id uint
+	var ok bool
+	switch ref.Type {
+	case "income":
+		id, ok = r.targets.Categories.Income[CategoryPath(ref.Path)]
+	case "expense":
+		id, ok = r.targets.Categories.Expense[CategoryPath(ref.Path)]
+	}
+	if !ok && !slices.Contains(r.res.UnmatchedCategories, ref.String()) {
+		r.res.UnmatchedCategories = append(r.res.UnmatchedCategories, ref.String())
+	}
+	return id, ok
+}
+
+func (s *Store) importBundle(ctx context.Context, b Bundle, targets BundleTargets, strategy string) (BundleImportResult, error) {
+	res := BundleImportResult{
+		Profiles:            []BundleItem{},
+		RuleGroups:          []BundleItem{},
+		UnmatchedCategories: []string{},
+		UnmatchedAccounts:   []string{},
+	}
+	r := &bundleResolver{targets: targets, profiles: map[string]uint{}, res: &res}
+
+	profiles, err := s.ListProfiles(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, p := range profiles {
+		r.profiles[strings.ToLower(p.Name)] = p.ID
+	}
+
+	for _, bp := range b.Profiles {
+		item := BundleItem{Name: bp.Name}
+		p := bundleToProfile(bp)
+		p.CashAccountID, _ = r.account(bp.CashAccount)
+
+		key := strings.ToLower(bp.Name)
+		existingID, exists := r.profiles[key]
+		switch {
+		case exists && strategy == ConflictSkip:
+			item.Action, item.Reason = BundleSkipped, "a profile with this name exists"
+		case exists:
+			if p.CashAccountID == 0 {
+				existing, err := s.GetProfile(ctx, existingID)
+				if err != nil {
+					return res, err
+				}
+				p.CashAccountID = existing.CashAccountID
+			}
+			err = s.UpdateProfile(ctx, existingID, p)
+			item.Action = BundleMerged
+		default:
+			var id uint
+			id, err = s.CreateProfile(ctx, p)
+			r.profiles[key] = id
+			item.Action = BundleCreated
+		}
+		if err != nil {
+			var valErr ErrValidation
+			if !errors.As(err, &valErr) {
+				return res, err
+			}
+			item.Action, item.Reason = BundleSkipped, valErr.Error()
+			if !exists {
+				delete(r.profiles, key)
+			}
+		}
+		res.Profiles = append(res.Profiles, item)
+	}
+
+	groups, err := s.ListCategoryRuleGroups(ctx)
+	if err != nil {
+		return res, err
+	}
+	existingGroups := make(map[string]CategoryRuleGroup, len(groups))
+	priority := 0
+	for _, g := range groups {
+		existingGroups[strings.ToLower(g.Name)] = g
+		priority = max(priority, g.Priority)
+	}
+
+	for _, bg := range b.RuleGroups {
+		item := BundleItem{Name: bg.Name}
+		g, reason := r.group(bg)
+		if reason != "" {
+			item.Action, item.Reason = BundleSkipped, reason
+			res.RuleGroups = append(res.RuleGroups, item)
+			continue
+		}
+
+		key := strings.ToLower(bg.Name)
+		existing, exists := existingGroups[key]
+		switch {
+		case exists && strategy == ConflictSkip:
+			item.Action, item.Reason = BundleSkipped, "a rule group with this name exists"
+		case exists:
+			g.Priority = existing.Priority
+			err = s.mergeRuleGroup(ctx, existing, g)
+			item.Action = BundleMerged
+		default:
+			priority++
+			g.Priority = priority
+			g.ID, err = s.CreateCategoryRuleGroup(ctx, g)
+			existingGroups[key] = g
+			item.Action = BundleCreated
+		}
+		if err != nil {
+			var valErr ErrValidation
+			if !errors.As(err, &valErr) {
+				return res, err
+			}
+			item.Action, item.Reason = BundleSkipped, valErr.Error()
+			if !exists {
+				delete(existingGroups, key)
+			}
+		}
+		res.RuleGroups = append(res.RuleGroups, item)
+	}
+	return res, nil
+}
+
+// mergeRuleGroup updates an existing group with an imported one and adds the patterns it lacks.
+func (s *Store) mergeRuleGroup(ctx context.Context, existing, g CategoryRuleGroup) error {
+	if err := s.UpdateCategoryRuleGroup(ctx, existing.ID, g); err != nil {
+		return err
+	}
+	for _, p := range g.Patterns {
+		found := slices.ContainsFunc(existing.Patterns, func(e CategoryRulePattern) bool {
+			return e.Pattern == p.Pattern && e.IsRegex == p.IsRegex
+		})
+		if found {
+			continue
+		}
+		if _, err := s.CreateCategoryRulePattern(ctx, existing.ID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bundleToProfile(bp BundleProfile) ImportProfile {
+	return ImportProfile{
+		Name:               bp.Name,
+		CsvSeparator:       bp.CsvSeparator,
+		SheetName:          bp.SheetName,
+		SkipRows:           bp.SkipRows,
+		DateColumn:         bp.DateColumn,
+		DateFormat:         bp.DateFormat,
+		DescriptionColumn:  bp.DescriptionColumn,
+		AmountColumn:       bp.AmountColumn,
+		AmountMode:         bp.AmountMode,
+		CreditColumn:       bp.CreditColumn,
+		DebitColumn:        bp.DebitColumn,
+		DecimalSeparator:   bp.DecimalSeparator,
+		ThousandsSeparator: bp.ThousandsSeparator,
+		InvertSign:         bp.InvertSign,
+		Type:               bp.Type,
+		SymbolColumn:       bp.SymbolColumn,
+		SideColumn:         bp.SideColumn,
+		QuantityColumn:     bp.QuantityColumn,
+		PriceColumn:        bp.PriceColumn,
+		FeesColumn:         bp.FeesColumn,
+		CurrencyColumn:     bp.CurrencyColumn,
+		OrderIDColumn:      bp.OrderIDColumn,
+		HeaderRow:          bp.HeaderRow,
+	}
+}
+
+// group resolves the names of an imported rule group. It returns the reason the group cannot be
+// imported when a referenced category, account or profile does not exist.
+func (r *bundleResolver) group(bg BundleRuleGroup) (CategoryRuleGroup, string) {
+	g := CategoryRuleGroup{
+		Name:           bg.Name,
+		MatchAny:       bg.MatchAny,
+		AmountMin:      bg.AmountMin,
+		AmountMax:      bg.AmountMax,
+		Direction:      bg.Direction,
+		SetDescription: bg.SetDescription,
+		AppendNotes:    bg.AppendNotes,
+	}
+	var ok bool
+	if bg.Category != nil {
+		if g.CategoryID, ok = r.category(*bg.Category); !ok {
+			return g, fmt.Sprintf("category %s not found", bg.Category)
+		}
+	}
+	if g.AccountID, ok = r.account(bg.Account); !ok {
+		return g, fmt.Sprintf("account %q not found", bg.Account)
+	}
+	if g.TransferAccountID, ok = r.account(bg.TransferAccount); !ok {
+		return g, fmt.Sprintf("account %q not found", bg.TransferAccount)
+	}
+	if bg.Profile != "" {
+		if g.ProfileID, ok = r.profiles[strings.ToLower(bg.Profile)]; !ok {
+			return g, fmt.Sprintf("profile %q not found", bg.Profile)
+		}
+	}
+
+	for _, p := range bg.Patterns {
+		g.Patterns = append(g.Patterns, CategoryRulePattern{Pattern: p.Pattern, IsRegex: p.IsRegex})
+	}
+	for _, d := range []struct {
+		value string
+		dst   **time.Time
+	}{{bg.DateFrom, &g.DateFrom}, {bg.DateTo, &g.DateTo}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d.value)
+		if err != nil {
+			return g, fmt.Sprintf("invalid date %q", d.value)
+		}
+		*d.dst = &t
+	}
+	for _, d := range bg.Weekdays {
+		g.Weekdays = append(g.Weekdays, time.Weekday(d))
+	}
+	return g, ""
+}
@@ -0,0 +1,173 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	// source instance
+	src := newTestStore(t)
+	profile := validProfile()
+	profile.DecimalSeparator = ","
+	profileID, err := src.CreateProfile(ctx, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groceriesID, err := src.CreateCategoryRuleGroup(ctx, CategoryRuleGroup{
+		Name: "Groceries", CategoryID: 7, Priority: 1, ProfileID: profileID, AccountID: 3,
+		Patterns: []CategoryRulePattern{{Pattern: "migros"}, {Pattern: "^coop", IsRegex: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	salaryID, err := src.CreateCategoryRuleGroup(ctx, CategoryRuleGroup{
+		Name: "Salary", CategoryID: 9, Priority: 2, Direction: RuleDirectionIncome,
+		Patterns: []CategoryRulePattern{{Pattern: "acme"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rentID, err := src.CreateCategoryRuleGroup(ctx, CategoryRuleGroup{
+		Name: "Rent", CategoryID: 11, Priority: 3,
+		Patterns: []CategoryRulePattern{{Pattern: "landlord"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := BundleNames{
+		Categories: map[uint]CategoryRef{
+			7:  {Type: "expense", Path: []string{"Food", "Groceries"}},
+			9:  {Type: "income", Path: []string{"Salary"}},
+			11: {Type: "expense", Path: []string{"Housing", "Rent"}},
+		},
+		Accounts: map[uint]string{3: "Checking"},
+	}
+	b, err := src.ExportBundle(ctx, []uint{profileID}, []uint{groceriesID, salaryID, rentID}, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shared Bundle
+	if err := json.Unmarshal(data, &shared); err != nil {
+		t.Fatal(err)
+	}
+	if len(shared.Profiles) != 1 || len(shared.RuleGroups) != 3 {
+		t.Fatalf("expected 1 profile and 3 rule groups, got %d and %d", len(shared.Profiles), len(shared.RuleGroups))
+	}
+	if g := shared.RuleGroups[0]; g.Profile != "My Bank" || g.Account != "Checking" || g.Category.String() != "expense Food > Groceries" {
+		t.Errorf("unexpected references of the exported group: %+v", g)
+	}
+
+	// target instance, with its own ids, an existing salary group and no housing category
+	dst := newTestStore(t)
+	existingSalaryID, err := dst.CreateCategoryRuleGroup(ctx, CategoryRuleGroup{
+		Name: "salary", CategoryID: 2, Priority: 5,
+		Patterns: []CategoryRulePattern{{Pattern: "acme"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := BundleTargets{
+		Categories: CategoryPaths{
+			Income:  map[string]uint{"salary": 2},
+			Expense: map[string]uint{"food:groceries": 4},
+		},
+		Accounts: map[string]uint{"checking": 8},
+	}
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		res, err := dst.ImportBundle(ctx, shared, targets, ConflictMerge, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.DryRun || !slices.Equal(res.UnmatchedCategories, []string{"expense Housing > Rent"}) {
+			t.Errorf("expected a dry run reporting the housing category, got %+v", res)
+		}
+		wantActions := []string{BundleCreated, BundleMerged, BundleSkipped}
+		for i, item := range res.RuleGroups {
+			if item.Action != wantActions[i] {
+				t.Errorf("group %s: expected %s, got %s", item.Name, wantActions[i], item.Action)
+			}
+		}
+		profiles, _ := dst.ListProfiles(ctx)
+		groups, _ := dst.ListCategoryRuleGroups(ctx)
+		if len(profiles) != 0 || len(groups) != 1 {
+			t.Errorf("expected no changes, got %d profiles and %d groups", len(profiles), len(groups))
+		}
+	})
+
+	t.Run("skip keeps existing groups", func(t *testing.T) {
+		res, err := dst.ImportBundle(ctx, shared, targets, ConflictSkip, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Profiles[0].Action != BundleCreated || res.RuleGroups[1].Action != BundleSkipped {
+			t.Errorf("unexpected result: %+v", res)
+		}
+		salary, err := dst.GetCategoryRuleGroup(ctx, existingSalaryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if salary.Direction != "" {
+			t.Errorf("expected the existing group to be unchanged, got direction %q", salary.Direction)
+		}
+
+		profiles, _ := dst.ListProfiles(ctx)
+		groups, _ := dst.ListCategoryRuleGroups(ctx)
+		if len(profiles) != 1 || len(groups) != 2 {
+			t.Fatalf("expected 1 profile and 2 groups, got %d and %d", len(profiles), len(groups))
+		}
+		groceries := groups[1]
+		if groceries.Name != "Groceries" || groceries.CategoryID != 4 || groceries.AccountID != 8 || groceries.ProfileID != profiles[0].ID {
+			t.Errorf("expected the references to be resolved, got %+v", groceries)
+		}
+		if groceries.Priority <= salary.Priority {
+			t.Errorf("expected the new group after the existing ones, got priority %d", groceries.Priority)
+		}
+		if profiles[0].DecimalSeparator != "," {
+			t.Errorf("expected the profile settings to be imported, got %+v", profiles[0])
+		}
+	})
+
+	t.Run("merge updates existing groups", func(t *testing.T) {
+		shared.RuleGroups[1].Patterns = append(shared.RuleGroups[1].Patterns, BundlePattern{Pattern: "bonus"})
+		res, err := dst.ImportBundle(ctx, shared, targets, ConflictMerge, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Profiles[0].Action != BundleMerged || res.RuleGroups[0].Action != BundleMerged {
+			t.Errorf("unexpected result: %+v", res)
+		}
+		salary, err := dst.GetCategoryRuleGroup(ctx, existingSalaryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if salary.Direction != RuleDirectionIncome || salary.Priority != 5 || len(salary.Patterns) != 2 {
+			t.Errorf("expected the group merged with the new pattern added once, got %+v", salary)
+		}
+		groups, _ := dst.ListCategoryRuleGroups(ctx)
+		if len(groups) != 2 {
+			t.Errorf("expected no new groups, got %d", len(groups))
+		}
+	})
+
+	t.Run("invalid bundle", func(t *testing.T) {
+		var valErr ErrValidation
+		if _, err := dst.ImportBundle(ctx, Bundle{Version: 2}, targets, ConflictSkip, false); !errors.As(err, &valErr) {
+			t.Errorf("expected a validation error for the version, got %v", err)
+		}
+		if _, err := dst.ImportBundle(ctx, shared, targets, "replace", false); !errors.As(err, &valErr) {
+			t.Errorf("expected a validation error for the strategy, got %v", err)
+		}
+	})
+}
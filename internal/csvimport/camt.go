@@ -0,0 +1,313 @@
+package csvimport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// camtDocument is the subset of an ISO 20022 camt.053 (account statement) or camt.054
+// (debit/credit notification) document needed for the import. Element names are matched
+// without namespace, so all message versions are accepted.
+type camtDocument struct {
+	Statements    []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Notifications []camtStatement `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	OtherID  string        `xml:"Acct>Id>Othr>Id"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+// camtStatus is <Sts>BOOK</Sts> up to version 05 and <Sts><Cd>BOOK</Cd></Sts> afterwards.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Ref            string          `xml:"NtryRef"`
+	Amount         camtAmount      `xml:"Amt"`
+	Indicator      string          `xml:"CdtDbtInd"`
+	Status         camtStatus      `xml:"Sts"`
+	BookingDate    camtDate        `xml:"BookgDt"`
+	ValueDate      camtDate        `xml:"ValDt"`
+	ServicerRef    string          `xml:"AcctSvcrRef"`
+	Details        []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string          `xml:"AddtlNtryInf"`
+}
+
+type camtTxDetails struct {
+	ServicerRef    string     `xml:"Refs>AcctSvcrRef"`
+	Amount         camtAmount `xml:"Amt"`
+	TxAmount       camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Indicator      string     `xml:"CdtDbtInd"`
+	Debtor         camtParty  `xml:"RltdPties>Dbtr"`
+	Creditor       camtParty  `xml:"RltdPties>Cdtr"`
+	Unstructured   []string   `xml:"RmtInf>Ustrd"`
+	StructuredRefs []string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AdditionalInfo string     `xml:"AddtlTxInf"`
+}
+
+// camtParty holds the name of a related party; version 08 and later wrap it in <Pty>.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// isCamt reports whether data looks like an ISO 20022 camt.053 or camt.054 document.
+func isCamt(data []byte) bool {
+	head := data[:min(len(data), 2048)]
+	return bytes.Contains(head, []byte("BkToCstmrStmt")) || bytes.Contains(head, []byte("BkToCstmrDbtCdtNtfctn"))
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	v := d.Date
+	if v == "" {
+		v = d.DateTime
+	}
+	if len(v) < 10 {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	t, err := time.Parse("2006-01-02", v[:10])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	return t, nil
+}
+
+// signedAmount returns the amount, negative for debits (DBIT).
+func signedAmount(a camtAmount, indicator string) (float64, error) {
+	amount, err := parseAmount(a.Value)
+	if err != nil {
+		return 0, err
+	}
+	if indicator == "DBIT" {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// readCamt extracts the booked entries and balances of all statements in a camt file.
+// Files with several statements are accepted as long as they belong to the same account,
+// e.g. a month of daily camt.053 statements.
+func readCamt(data []byte) (statement, error) {
+	var doc camtDocument
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		// files declaring a legacy encoding are ISO-8859-1 or Windows-1252
+		raw, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(toUTF8(raw)), nil
+	}
+	if err := dec.Decode(&doc); err != nil {
+		return statement{}, fmt.Errorf("error reading camt: %w", err)
+	}
+	stmts := append(doc.Statements, doc.Notifications...)
+	if len(stmts) == 0 {
+		return statement{}, ErrValidation("the file contains no camt.053 statement or camt.054 notification")
+	}
+
+	var st statement
+	account := ""
+	for _, stmt := range stmts {
+		id := stmt.IBAN
+		if id == "" {
+			id = stmt.OtherID
+		}
+		if account != "" && id != account {
+			return statement{}, ErrValidation("the file contains statements of several accounts, export one account per file")
+		}
+		account = id
+
+		for _, entry := range stmt.Entries {
+			lines, err := camtLines(entry)
+			if err != nil {
+				return statement{}, err
+			}
+			st.lines = append(st.lines, lines...)
+		}
+		balances, err := camtBalances(stmt.Balances)
+		if err != nil {
+			return statement{}, err
+		}
+		st.balances = append(st.balances, balances...)
+	}
+	return st, nil
+}
+
+// camtBalances returns the opening and closing booked balances. The opening balance is the
+// balance at the start of its date, it is recorded at the end of the previous day.
+func camtBalances(in []camtBalance) ([]statementBalance, error) {
+	var out []statementBalance
+	for _, bal := range in {
+		if bal.Code != "OPBD" && bal.Code != "CLBD" {
+			continue
+		}
+		amount, err := signedAmount(bal.Amount, bal.Indicator)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s balance: %w", bal.Code, err)
+		}
+		date, err := bal.Date.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s balance: %w", bal.Code, err)
+		}
+		if bal.Code == "OPBD" {
+			date = date.AddDate(0, 0, -1)
+		}
+		out = append(out, statementBalance{Date: date, Amount: amount})
+	}
+	return out, nil
+}
+
+// camtLines converts a booked entry into statement lines. A batch entry whose transaction
+// details carry individual amounts is split into one line per transaction.
+func camtLines(entry camtEntry) ([]statementLine, error) {
+	status := strings.TrimSpace(entry.Status.Text)
+	if entry.Status.Code != "" {
+		status = entry.Status.Code
+	}
+	if status != "" && status != "BOOK" {
+		return nil, nil // pending or informational entries are not part of the balance yet
+	}
+
+	ref := entry.ServicerRef
+	if ref == "" {
+		ref = entry.Ref
+	}
+	date, err := entry.BookingDate.parse()
+	if err != nil {
+		if date, err = entry.ValueDate.parse(); err != nil {
+			return nil, fmt.Errorf("entry %q: %w", ref, err)
+		}
+	}
+
+	if len(entry.Details) > 1 && camtDetailsHaveAmounts(entry.Details) {
+		lines := make([]statementLine, 0, len(entry.Details))
+		for i, tx := range entry.Details {
+			amt := tx.Amount
+			if amt.Value == "" {
+				amt = tx.TxAmount
+			}
+			indicator := tx.Indicator
+			if indicator == "" {
+				indicator = entry.Indicator
+			}
+			amount, err := signedAmount(amt, indicator)
+			if err != nil {
+				return nil, fmt.Errorf("entry %q: %w", ref, err)
+			}
+			id := tx.ServicerRef
+			if id == "" && ref != "" {
+				id = fmt.Sprintf("%s/%d", ref, i+1)
+			}
+			lines = append(lines, statementLine{
+				ExternalID:  id,
+				Date:        date,
+				Description: camtDescription(entry, &tx, indicator),
+				Amount:      amount,
+			})
+		}
+		return lines, nil
+	}
+
+	amount, err := signedAmount(entry.Amount, entry.Indicator)
+	if err != nil {
+		return nil, fmt.Errorf("entry %q: %w", ref, err)
+	}
+	var tx *camtTxDetails
+	if len(entry.Details) > 0 {
+		tx = &entry.Details[0]
+	}
+	return []statementLine{{
+		ExternalID:  ref,
+		Date:        date,
+		Description: camtDescription(entry, tx, entry.Indicator),
+		Amount:      amount,
+	}}, nil
+}
+
+func camtDetailsHaveAmounts(details []camtTxDetails) bool {
+	for _, tx := range details {
+		if tx.Amount.Value == "" && tx.TxAmount.Value == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// camtDescription combines the counterparty, the creditor for debits and the debtor for
+// credits, with the remittance information. Without them the additional information is used.
+func camtDescription(entry camtEntry, tx *camtTxDetails, indicator string) string {
+	var parts []string
+	if tx != nil {
+		party := tx.Debtor.name()
+		if indicator == "DBIT" {
+			party = tx.Creditor.name()
+		}
+		if party != "" {
+			parts = append(parts, strings.TrimSpace(party))
+		}
+		remittance := strings.TrimSpace(strings.Join(tx.Unstructured, " "))
+		if remittance == "" {
+			remittance = strings.TrimSpace(strings.Join(tx.StructuredRefs, " "))
+		}
+		if remittance != "" {
+			parts = append(parts, remittance)
+		}
+		if len(parts) == 0 && tx.AdditionalInfo != "" {
+			parts = append(parts, strings.TrimSpace(tx.AdditionalInfo))
+		}
+	}
+	if len(parts) == 0 {
+		return strings.TrimSpace(entry.AdditionalInfo)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// ParseCamt reads an ISO 20022 camt.053 or camt.054 file from r, applies category matching
+// rules and detects duplicates by the bank reference of each entry. Opening and closing
+// balances are returned as the last rows, with type "balance". It is a pure function with no
+// DB access.
+func ParseCamt(r io.Reader, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading camt: %w", err)
+	}
+	st, err := readCamt(data)
+	if err != nil {
+		return nil, err
+	}
+	return statementRows(st, groups, existing), nil
+}
@@ -0,0 +1,204 @@
+package csvimport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2026-03-31T18:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-1</Id>
+      <Acct><Id><IBAN>CH9300762011623852957</IBAN></Id><Ccy>CHF</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="CHF">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2026-03-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="CHF">2354.70</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2026-03-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="CHF">1500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-03-01</Dt></BookgDt>
+        <ValDt><Dt>2026-03-01</Dt></ValDt>
+        <AcctSvcrRef>REF-001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Dbtr><Nm>ACME Corp</Nm></Dbtr></RltdPties>
+          <RmtInf><Ustrd>Salary March</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CHF">145.30</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-03-02</Dt></BookgDt>
+        <AcctSvcrRef>REF-002</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>REF-002-A</AcctSvcrRef></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="CHF">100.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Swisscom</Nm></Cdtr></RltdPties>
+            <RmtInf><Strd><CdtrRefInf><Ref>210000000003139471430009017</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="CHF">45.30</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Migros</Nm></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CHF">20.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2026-03-31</Dt></BookgDt>
+        <AddtlNtryInf>Card payment pending</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+const camt054 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.08">
+  <BkToCstmrDbtCdtNtfctn>
+    <Ntfctn>
+      <Acct><Id><IBAN>CH9300762011623852957</IBAN></Id></Acct>
+      <Ntry>
+        <NtryRef>N-1</NtryRef>
+        <Amt Ccy="CHF">80.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2026-04-02T10:15:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <RltdPties><Dbtr><Pty><Nm>Jane Doe</Nm></Pty></Dbtr></RltdPties>
+        </TxDtls></NtryDtls>
+        <AddtlNtryInf>Credit transfer</AddtlNtryInf>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>
+`
+
+func TestParseCamt(t *testing.T) {
+	if got := DetectFormat([]byte(camt053)); got != FormatCamt {
+		t.Errorf("expected camt.053 to be detected, got %q", got)
+	}
+
+	t.Run("camt.053", func(t *testing.T) {
+		groups := []CategoryRuleGroup{{CategoryID: 4, Patterns: []CategoryRulePattern{{Pattern: "swisscom"}}}}
+		rows, err := ParseCamt(strings.NewReader(camt053), groups, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-03-01", Description: "ACME Corp - Salary March", Amount: 1500, Type: "income", ExternalID: "REF-001"},
+			{RowNumber: 2, Date: "2026-03-02", Description: "Swisscom - 210000000003139471430009017", Amount: -100, Type: "expense", CategoryID: 4, ExternalID: "REF-002-A"},
+			{RowNumber: 3, Date: "2026-03-02", Description: "Migros", Amount: -45.30, Type: "expense", ExternalID: "REF-002/2"},
+			{RowNumber: 4, Date: "2026-02-28", Description: balanceRowDescription, Amount: 1000, Type: "balance"},
+			{RowNumber: 5, Date: "2026-03-31", Description: balanceRowDescription, Amount: 2354.70, Type: "balance"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("camt.054", func(t *testing.T) {
+		rows, err := ParseCamt(strings.NewReader(camt054), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := ParsedRow{RowNumber: 1, Date: "2026-04-02", Description: "Jane Doe", Amount: 80, Type: "income", ExternalID: "N-1"}
+		if len(rows) != 1 || rows[0] != want {
+			t.Errorf("unexpected rows %+v", rows)
+		}
+	})
+
+	t.Run("duplicates by bank reference", func(t *testing.T) {
+		existing := []ExistingTx{
+			{Date: "2026-03-01", Amount: 1500, ExternalID: "REF-001"},
+			{Date: "2026-03-31", Amount: 2354.70, Balance: true},
+		}
+		rows, err := ParseCamt(strings.NewReader(camt053), nil, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dups []int
+		for _, r := range rows {
+			if r.IsDuplicate {
+				dups = append(dups, r.RowNumber)
+			}
+		}
+		if len(dups) != 2 || dups[0] != 1 || dups[1] != 5 {
+			t.Errorf("expected rows 1 and 5 to be duplicates, got %v", dups)
+		}
+	})
+
+	t.Run("daily statements", func(t *testing.T) {
+		// the closing balance of one statement is the opening balance of the next
+		day := func(date, open, close string) string {
+			return `<Stmt><Acct><Id><IBAN>CH93</IBAN></Id></Acct>
+<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="CHF">` + open + `</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>` + date + `</Dt></Dt></Bal>
+<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="CHF">` + close + `</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>` + date + `</Dt></Dt></Bal></Stmt>`
+		}
+		doc := `<Document><BkToCstmrStmt>` + day("2026-03-02", "10.00", "20.00") + day("2026-03-03", "20.00", "30.00") + `</BkToCstmrStmt></Document>`
+		rows, err := ParseCamt(strings.NewReader(doc), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 4 || rows[1].IsDuplicate || !rows[2].IsDuplicate {
+			t.Errorf("expected the repeated balance to be flagged, got %+v", rows)
+		}
+	})
+
+	t.Run("latin-1", func(t *testing.T) {
+		doc := strings.Replace(camt054, `encoding="UTF-8"`, `encoding="ISO-8859-1"`, 1)
+		doc = strings.Replace(doc, "Jane Doe", "Z\xfcrich AG", 1)
+		rows, err := ParseCamt(strings.NewReader(doc), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0].Description != "Zürich AG" {
+			t.Errorf("unexpected description %q", rows[0].Description)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tcs := []struct {
+			name       string
+			data       string
+			validation bool
+		}{
+			{name: "malformed", data: "<Document><BkToCstmrStmt>"},
+			{name: "no statement", data: "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>", validation: true},
+			{name: "several accounts", data: `<Document><BkToCstmrStmt><Stmt><Acct><Id><IBAN>A</IBAN></Id></Acct></Stmt><Stmt><Acct><Id><IBAN>B</IBAN></Id></Acct></Stmt></BkToCstmrStmt></Document>`, validation: true},
+			{name: "invalid amount", data: strings.Replace(camt053, "1500.00", "x", 1)},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ParseCamt(strings.NewReader(tc.data), nil, nil)
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				var valErr ErrValidation
+				if errors.As(err, &valErr) != tc.validation {
+					t.Errorf("unexpected error type %v", err)
+				}
+			})
+		}
+	})
+}
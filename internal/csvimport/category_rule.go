@@ -20,8 +20,23 @@ type dbCategoryRuleGroup struct {
 	CategoryID uint                    `gorm:"not null;index"`
 	Priority   int                     `gorm:"column:position;not null;index"`
 	Patterns   []dbCategoryRulePattern `gorm:"foreignKey:GroupID"`
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+
+	MatchAny  bool
+	AmountMin *float64
+	AmountMax *float64
+	Direction string
+	AccountID uint
+	ProfileID uint
+	DateFrom  *time.Time
+	DateTo    *time.Time
+	Weekdays  uint8 // bit n is set for time.Weekday(n)
+
+	SetDescription    string
+	AppendNotes       string
+	TransferAccountID uint
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type dbCategoryRulePattern struct {
@@ -33,14 +48,42 @@ type dbCategoryRulePattern struct {
 	UpdatedAt time.Time
 }
 
+// Directions a rule group can be restricted to.
+const (
+	RuleDirectionIncome  = "income"
+	RuleDirectionExpense = "expense"
+)
+
+// CategoryRuleGroup is a rule applied to imported rows and, on reapply, to existing income and
+// expenses. Groups are evaluated by Priority and the first matching group wins.
+//
+// The conditions are the description patterns, which match if any pattern matches, and each of
+// the other conditions that is set. All of them must match, MatchAny requires any of them instead.
 type CategoryRuleGroup struct {
 	ID         uint
 	Name       string
-	CategoryID uint
+	CategoryID uint // 0 keeps the category, allowed when another action is set
 	Priority   int
 	Patterns   []CategoryRulePattern
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+
+	MatchAny  bool
+	AmountMin *float64 // absolute amount, inclusive
+	AmountMax *float64
+	Direction string // RuleDirectionIncome or RuleDirectionExpense, empty for both
+	AccountID uint
+	ProfileID uint       // import profile of the import, or of the account on reapply
+	DateFrom  *time.Time // inclusive
+	DateTo    *time.Time // inclusive
+	Weekdays  []time.Weekday
+
+	SetDescription    string // replaces the description
+	AppendNotes       string // added to the notes unless they contain it already
+	TransferAccountID uint   // books the row as a transfer with this account
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	scope ruleScope // see ScopeRules
 }
 
 type CategoryRulePattern struct {
@@ -54,12 +97,24 @@ type CategoryRulePattern struct {
 
 func dbToGroup(in dbCategoryRuleGroup) CategoryRuleGroup {
 	g := CategoryRuleGroup{
-		ID:         in.ID,
-		Name:       in.Name,
-		CategoryID: in.CategoryID,
-		Priority:   in.Priority,
-		CreatedAt:  in.CreatedAt,
-		UpdatedAt:  in.UpdatedAt,
+		ID:                in.ID,
+		Name:              in.Name,
+		CategoryID:        in.CategoryID,
+		Priority:          in.Priority,
+		MatchAny:          in.MatchAny,
+		AmountMin:         in.AmountMin,
+		AmountMax:         in.AmountMax,
+		Direction:         in.Direction,
+		AccountID:         in.AccountID,
+		ProfileID:         in.ProfileID,
+		DateFrom:          in.DateFrom,
+		DateTo:            in.DateTo,
+		Weekdays:          weekdaysFromMask(in.Weekdays),
+		SetDescription:    in.SetDescription,
+		AppendNotes:       in.AppendNotes,
+		TransferAccountID: in.TransferAccountID,
+		CreatedAt:         in.CreatedAt,
+		UpdatedAt:         in.UpdatedAt,
 	}
 	for _, p := range in.Patterns {
 		g.Patterns = append(g.Patterns, CategoryRulePattern(p))
@@ -67,18 +122,76 @@ func dbToGroup(in dbCategoryRuleGroup) CategoryRuleGroup {
 	return g
 }
 
-func (s *Store) CreateCategoryRuleGroup(ctx context.Context, g CategoryRuleGroup) (uint, error) {
+func weekdaysFromMask(mask uint8) []time.Weekday {
+	var days []time.Weekday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if mask&(1<<d) != 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func weekdaysMask(days []time.Weekday) uint8 {
+	var mask uint8
+	for _, d := range days {
+		mask |= 1 << d
+	}
+	return mask
+}
+
+// validateRuleGroup checks the conditions and actions of a group, not its patterns.
+func validateRuleGroup(g CategoryRuleGroup) error {
 	if g.Name == "" {
-		return 0, ErrValidation("name cannot be empty")
+		return ErrValidation("name cannot be empty")
+	}
+	if g.CategoryID == 0 && g.SetDescription == "" && g.AppendNotes == "" && g.TransferAccountID == 0 {
+		return ErrValidation("category_id cannot be zero")
+	}
+	if (g.AmountMin != nil && *g.AmountMin < 0) || (g.AmountMax != nil && *g.AmountMax < 0) {
+		return ErrValidation("amount range cannot be negative, use the direction to match the sign")
+	}
+	if g.AmountMin != nil && g.AmountMax != nil && *g.AmountMin > *g.AmountMax {
+		return ErrValidation("amount_min cannot be greater than amount_max")
+	}
+	if g.Direction != "" && g.Direction != RuleDirectionIncome && g.Direction != RuleDirectionExpense {
+		return ErrValidation("direction must be income or expense")
+	}
+	if g.DateFrom != nil && g.DateTo != nil && g.DateFrom.After(*g.DateTo) {
+		return ErrValidation("date_from cannot be after date_to")
+	}
+	for _, d := range g.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return ErrValidation("invalid weekday")
+		}
 	}
-	if g.CategoryID == 0 {
-		return 0, ErrValidation("category_id cannot be zero")
+	if g.TransferAccountID != 0 && g.TransferAccountID == g.AccountID {
+		return ErrValidation("transfer_account_id cannot be the account the rule is restricted to")
+	}
+	return nil
+}
+
+func (s *Store) CreateCategoryRuleGroup(ctx context.Context, g CategoryRuleGroup) (uint, error) {
+	if err := validateRuleGroup(g); err != nil {
+		return 0, err
 	}
 
 	row := dbCategoryRuleGroup{
-		Name:       g.Name,
-		CategoryID: g.CategoryID,
-		Priority:   g.Priority,
+		Name:              g.Name,
+		CategoryID:        g.CategoryID,
+		Priority:          g.Priority,
+		MatchAny:          g.MatchAny,
+		AmountMin:         g.AmountMin,
+		AmountMax:         g.AmountMax,
+		Direction:         g.Direction,
+		AccountID:         g.AccountID,
+		ProfileID:         g.ProfileID,
+		DateFrom:          g.DateFrom,
+		DateTo:            g.DateTo,
+		Weekdays:          weekdaysMask(g.Weekdays),
+		SetDescription:    g.SetDescription,
+		AppendNotes:       g.AppendNotes,
+		TransferAccountID: g.TransferAccountID,
 	}
 	for _, p := range g.Patterns {
 		if p.Pattern == "" {
@@ -128,19 +241,29 @@ func (s *Store) ListCategoryRuleGroups(ctx context.Context) ([]CategoryRuleGroup
 }
 
 func (s *Store) UpdateCategoryRuleGroup(ctx context.Context, id uint, g CategoryRuleGroup) error {
-	if g.Name == "" {
-		return ErrValidation("name cannot be empty")
-	}
-	if g.CategoryID == 0 {
-		return ErrValidation("category_id cannot be zero")
+	if err := validateRuleGroup(g); err != nil {
+		return err
 	}
 
 	d := s.db.WithContext(ctx).Model(&dbCategoryRuleGroup{}).Where("id = ?", id).
-		Select("Name", "CategoryID", "Priority").
+		Select("Name", "CategoryID", "Priority", "MatchAny", "AmountMin", "AmountMax", "Direction", "AccountID", "ProfileID",
+			"DateFrom", "DateTo", "Weekdays", "SetDescription", "AppendNotes", "TransferAccountID").
 		Updates(dbCategoryRuleGroup{
-			Name:       g.Name,
-			CategoryID: g.CategoryID,
-			Priority:   g.Priority,
+			Name:              g.Name,
+			CategoryID:        g.CategoryID,
+			Priority:          g.Priority,
+			MatchAny:          g.MatchAny,
+			AmountMin:         g.AmountMin,
+			AmountMax:         g.AmountMax,
+			Direction:         g.Direction,
+			AccountID:         g.AccountID,
+			ProfileID:         g.ProfileID,
+			DateFrom:          g.DateFrom,
+			DateTo:            g.DateTo,
+			Weekdays:          weekdaysMask(g.Weekdays),
+			SetDescription:    g.SetDescription,
+			AppendNotes:       g.AppendNotes,
+			TransferAccountID: g.TransferAccountID,
 		})
 	if d.Error != nil {
 		return d.Error
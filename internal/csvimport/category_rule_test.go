@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 )
 
 func validCategoryRuleGroup() CategoryRuleGroup {
@@ -100,6 +101,93 @@ func TestCreateCategoryRuleGroup(t *testing.T) {
 	})
 }
 
+func TestCategoryRuleGroupConditions(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	ptr := func(v float64) *float64 { return &v }
+	day := func(d int) *time.Time {
+		v := time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		g := validCategoryRuleGroup()
+		g.MatchAny = true
+		g.AmountMin, g.AmountMax = ptr(10), ptr(50)
+		g.Direction = RuleDirectionExpense
+		g.AccountID, g.ProfileID = 3, 4
+		g.DateFrom, g.DateTo = day(1), day(31)
+		g.Weekdays = []time.Weekday{time.Saturday, time.Sunday}
+		g.SetDescription = "Coffee"
+		g.AppendNotes = "weekend"
+		g.TransferAccountID = 5
+		id, err := store.CreateCategoryRuleGroup(ctx, g)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := store.GetCategoryRuleGroup(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.MatchAny || *got.AmountMin != 10 || *got.AmountMax != 50 || got.Direction != RuleDirectionExpense ||
+			got.AccountID != 3 || got.ProfileID != 4 || !got.DateFrom.Equal(*day(1)) || !got.DateTo.Equal(*day(31)) ||
+			got.SetDescription != "Coffee" || got.AppendNotes != "weekend" || got.TransferAccountID != 5 {
+			t.Errorf("unexpected group %+v", got)
+		}
+		if len(got.Weekdays) != 2 || got.Weekdays[0] != time.Sunday || got.Weekdays[1] != time.Saturday {
+			t.Errorf("unexpected weekdays %v", got.Weekdays)
+		}
+
+		u := validCategoryRuleGroup()
+		if err := store.UpdateCategoryRuleGroup(ctx, id, u); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err = store.GetCategoryRuleGroup(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.MatchAny || got.AmountMin != nil || got.DateFrom != nil || len(got.Weekdays) != 0 || got.TransferAccountID != 0 {
+			t.Errorf("expected the update to clear the conditions, got %+v", got)
+		}
+	})
+
+	t.Run("action without category", func(t *testing.T) {
+		g := validCategoryRuleGroup()
+		g.CategoryID = 0
+		g.AppendNotes = "reviewed"
+		if _, err := store.CreateCategoryRuleGroup(ctx, g); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	tcs := []struct {
+		name   string
+		modify func(g *CategoryRuleGroup)
+		want   string
+	}{
+		{"negative amount", func(g *CategoryRuleGroup) { g.AmountMin = ptr(-1) }, "amount range cannot be negative, use the direction to match the sign"},
+		{"inverted amount range", func(g *CategoryRuleGroup) { g.AmountMin, g.AmountMax = ptr(20), ptr(10) }, "amount_min cannot be greater than amount_max"},
+		{"unknown direction", func(g *CategoryRuleGroup) { g.Direction = "sideways" }, "direction must be income or expense"},
+		{"inverted dates", func(g *CategoryRuleGroup) { g.DateFrom, g.DateTo = day(10), day(5) }, "date_from cannot be after date_to"},
+		{"invalid weekday", func(g *CategoryRuleGroup) { g.Weekdays = []time.Weekday{7} }, "invalid weekday"},
+		{"transfer to same account", func(g *CategoryRuleGroup) { g.AccountID, g.TransferAccountID = 2, 2 }, "transfer_account_id cannot be the account the rule is restricted to"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			g := validCategoryRuleGroup()
+			tc.modify(&g)
+			_, err := store.CreateCategoryRuleGroup(ctx, g)
+			var valErr ErrValidation
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected ErrValidation, got %T: %v", err, err)
+			}
+			if err.Error() != tc.want {
+				t.Errorf("expected error %q, got %q", tc.want, err.Error())
+			}
+		})
+	}
+}
+
 func TestGetCategoryRuleGroup(t *testing.T) {
 	store := newTestStore(t)
 	ctx := context.Background()
@@ -0,0 +1,255 @@
+package csvimport
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MinSuggestionConfidence is the confidence below which the classifier makes no suggestion.
+const MinSuggestionConfidence = 0.5
+
+// TrainingSample is a categorized transaction the classifier learns from.
+type TrainingSample struct {
+	ID          uint // transaction id, a sample replaces the previous one with the same id
+	Description string
+	Amount      float64 // negative for expenses
+	AccountID   uint
+	CategoryID  uint
+}
+
+// Suggestion is a category proposed by the classifier for a transaction no rule matched.
+type Suggestion struct {
+	CategoryID uint
+	Confidence float64 // between 0 and 1
+}
+
+// Classifier is a naive Bayes classifier over the description tokens, the amount and the account
+// of categorized transactions. It is trained incrementally: Sync only updates the counts of the
+// samples that changed since the last call. It is safe for concurrent use.
+type Classifier struct {
+	mu      sync.Mutex
+	samples map[uint]TrainingSample
+
+	refreshMu sync.Mutex // serializes Refresh so the version matches the samples
+	version   string     // of the samples of the last Refresh
+
+	docs     map[uint]int            // samples per category
+	features map[uint]map[string]int // feature counts per category
+	total    map[uint]int            // sum of the feature counts per category
+	vocab    map[string]int          // samples containing each feature
+	income   map[uint]bool           // direction of the samples of each category
+}
+
+// NewClassifier returns an untrained classifier.
+func NewClassifier() *Classifier {
+	return &Classifier{
+		samples:  map[uint]TrainingSample{},
+		docs:     map[uint]int{},
+		features: map[uint]map[string]int{},
+		total:    map[uint]int{},
+		vocab:    map[string]int{},
+		income:   map[uint]bool{},
+	}
+}
+
+// Len returns the number of samples the classifier is trained on.
+func (c *Classifier) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.samples)
+}
+
+// Sync trains the classifier on the given samples: samples that are new or changed are learned,
+// the ones no longer present, e.g. deleted or uncategorized transactions, are forgotten.
+// It returns the number of samples that changed.
+func (c *Classifier) Sync(samples []TrainingSample) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := 0
+	seen := make(map[uint]bool, len(samples))
+	for _, s := range samples {
+		if s.CategoryID == 0 {
+			continue
+		}
+		seen[s.ID] = true
+		old, ok := c.samples[s.ID]
+		if ok && old == s {
+			continue
+		}
+		if ok {
+			c.update(old, -1)
+		}
+		c.update(s, 1)
+		c.samples[s.ID] = s
+		changed++
+	}
+	for id, old := range c.samples {
+		if !seen[id] {
+			c.update(old, -1)
+			delete(c.samples, id)
+			changed++
+		}
+	}
+	return changed
+}
+
+// Refresh syncs the classifier with the samples returned by load, unless version is the one of the
+// samples of the previous Refresh: load is then not called. The version is any value that changes
+// when the samples do, e.g. a last modified timestamp.
+func (c *Classifier) Refresh(version string, load func() ([]TrainingSample, error)) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if version != "" && version == c.version {
+		return nil
+	}
+	samples, err := load()
+	if err != nil {
+		return err
+	}
+	c.Sync(samples)
+	c.version = version
+	return nil
+}
+
+// update adds (delta 1) or removes (delta -1) a sample from the counts.
+func (c *Classifier) update(s TrainingSample, delta int) {
+	cat := s.CategoryID
+	c.docs[cat] += delta
+	if c.docs[cat] <= 0 {
+		delete(c.docs, cat)
+		delete(c.features, cat)
+		delete(c.total, cat)
+		delete(c.income, cat)
+	} else if delta > 0 {
+		c.income[cat] = s.Amount > 0
+		if c.features[cat] == nil {
+			c.features[cat] = map[string]int{}
+		}
+	}
+	for _, f := range sampleFeatures(s.Description, s.Amount, s.AccountID) {
+		c.vocab[f] += delta
+		if c.vocab[f] <= 0 {
+			delete(c.vocab, f)
+		}
+		if counts := c.features[cat]; counts != nil {
+			counts[f] += delta
+			if counts[f] <= 0 {
+				delete(counts, f)
+			}
+			c.total[cat] += delta
+		}
+	}
+}
+
+// Suggest returns the most likely category of a transaction among the categories of the same
+// direction, with its posterior probability as confidence. Transactions without any description
+// token seen in those categories, e.g. from unknown merchants, get no suggestion.
+func (c *Classifier) Suggest(in RuleInput) (Suggestion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens := descriptionTokens(in.Description)
+	if len(tokens) == 0 || in.Amount == 0 {
+		return Suggestion{}, false
+	}
+
+	income := in.Amount > 0
+	docs := 0
+	known := false
+	for cat, n := range c.docs {
+		if c.income[cat] != income {
+			continue
+		}
+		docs += n
+		for _, t := range tokens {
+			known = known || c.features[cat][t] > 0
+		}
+	}
+	if !known {
+		return Suggestion{}, false
+	}
+
+	features := sampleFeatures(in.Description, in.Amount, in.AccountID)
+	vocabSize := float64(len(c.vocab))
+	scores := map[uint]float64{}
+	best, bestScore := uint(0), math.Inf(-1)
+	for cat, n := range c.docs {
+		if c.income[cat] != income {
+			continue
+		}
+		score := math.Log(float64(n) / float64(docs))
+		denom := float64(c.total[cat]) + vocabSize
+		for _, f := range features {
+			score += math.Log((float64(c.features[cat][f]) + 1) / denom)
+		}
+		scores[cat] = score
+		// ties go to the lower id so suggestions are stable
+		if score > bestScore || (score == bestScore && cat < best) {
+			best, bestScore = cat, score
+		}
+	}
+
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+	confidence := 1 / sum
+	if confidence < MinSuggestionConfidence {
+		return Suggestion{}, false
+	}
+	return Suggestion{CategoryID: best, Confidence: confidence}, true
+}
+
+// SuggestCategories sets the suggested category of the income and expense rows of an import
+// into the given account that no rule categorized. A nil classifier suggests nothing.
+func (c *Classifier) SuggestCategories(rows []ParsedRow, accountID uint) {
+	if c == nil {
+		return
+	}
+	for i := range rows {
+		row := &rows[i]
+		if row.CategoryID != 0 || row.Error != "" || (row.Type != "income" && row.Type != "expense") {
+			continue
+		}
+		s, ok := c.Suggest(RuleInput{Description: row.Description, Amount: row.Amount, AccountID: accountID})
+		if ok {
+			row.SuggestedCategoryID = s.CategoryID
+			row.SuggestionConfidence = math.Round(s.Confidence*100) / 100
+		}
+	}
+}
+
+// sampleFeatures returns the features of a transaction: its description tokens, the magnitude of
+// the amount and the account.
+func sampleFeatures(description string, amount float64, accountID uint) []string {
+	features := descriptionTokens(description)
+	if amount != 0 {
+		features = append(features, fmt.Sprintf("amount:%d", int(math.Log2(math.Abs(amount)+1))))
+	}
+	if accountID != 0 {
+		features = append(features, fmt.Sprintf("account:%d", accountID))
+	}
+	return features
+}
+
+// descriptionTokens splits a description into distinct lower case words, ignoring numbers and
+// single characters, which are mostly references and dates.
+func descriptionTokens(description string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var tokens []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || !strings.ContainsFunc(f, unicode.IsLetter) || seen[f] {
+			continue
+		}
+		seen[f] = true
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
@@ -0,0 +1,153 @@
+package csvimport
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	catGroceries uint = 1
+	catTransport uint = 2
+	catSalary    uint = 3
+)
+
+func trainingSamples() []TrainingSample {
+	return []TrainingSample{
+		{ID: 1, Description: "MIGROS BASEL 1234", Amount: -54.20, AccountID: 1, CategoryID: catGroceries},
+		{ID: 2, Description: "Migros Zurich HB", Amount: -12.10, AccountID: 1, CategoryID: catGroceries},
+		{ID: 3, Description: "COOP PRONTO 77", Amount: -8.50, AccountID: 1, CategoryID: catGroceries},
+		{ID: 4, Description: "SBB CFF FFS ticket", Amount: -88.00, AccountID: 1, CategoryID: catTransport},
+		{ID: 5, Description: "SBB Mobile ticket", Amount: -4.40, AccountID: 1, CategoryID: catTransport},
+		{ID: 6, Description: "Salary ACME Corp", Amount: 5200, AccountID: 1, CategoryID: catSalary},
+	}
+}
+
+func TestClassifierSuggest(t *testing.T) {
+	c := NewClassifier()
+	if n := c.Sync(trainingSamples()); n != 6 {
+		t.Fatalf("expected 6 learned samples, got %d", n)
+	}
+
+	tcs := []struct {
+		name   string
+		in     RuleInput
+		want   uint
+		wantOk bool
+	}{
+		{name: "known merchant", in: RuleInput{Description: "MIGROS GENEVA 99", Amount: -23.00, AccountID: 1}, want: catGroceries, wantOk: true},
+		{name: "other merchant", in: RuleInput{Description: "SBB ticket Bern", Amount: -30.00, AccountID: 1}, want: catTransport, wantOk: true},
+		{name: "income", in: RuleInput{Description: "Salary ACME Corp March", Amount: 5100, AccountID: 1}, want: catSalary, wantOk: true},
+		{name: "unknown merchant", in: RuleInput{Description: "NETFLIX.COM", Amount: -15.90, AccountID: 1}},
+		{name: "only numbers", in: RuleInput{Description: "123 456", Amount: -15.90}},
+		{name: "no category of the direction", in: RuleInput{Description: "Migros refund", Amount: 10}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := c.Suggest(tc.in)
+			if ok != tc.wantOk {
+				t.Fatalf("expected ok %v, got %v (%+v)", tc.wantOk, ok, got)
+			}
+			if !ok {
+				return
+			}
+			if got.CategoryID != tc.want {
+				t.Errorf("expected category %d, got %d", tc.want, got.CategoryID)
+			}
+			if got.Confidence < MinSuggestionConfidence || got.Confidence > 1 {
+				t.Errorf("unexpected confidence %f", got.Confidence)
+			}
+		})
+	}
+}
+
+func TestClassifierSync(t *testing.T) {
+	c := NewClassifier()
+	samples := trainingSamples()
+	c.Sync(samples)
+
+	if n := c.Sync(samples); n != 0 {
+		t.Errorf("expected no changes syncing the same samples, got %d", n)
+	}
+
+	// the coop purchases are recategorized and the migros ones deleted
+	samples[2].CategoryID = catTransport
+	samples = append(samples[2:3], samples[3:]...)
+	if n := c.Sync(samples); n != 3 {
+		t.Errorf("expected 3 changes, got %d", n)
+	}
+	if c.Len() != 4 {
+		t.Errorf("expected 4 samples, got %d", c.Len())
+	}
+	if _, ok := c.Suggest(RuleInput{Description: "MIGROS BASEL", Amount: -20}); ok {
+		t.Error("expected forgotten samples to give no suggestion")
+	}
+	got, ok := c.Suggest(RuleInput{Description: "COOP PRONTO", Amount: -9})
+	if !ok || got.CategoryID != catTransport {
+		t.Errorf("expected the new category of the recategorized sample, got %+v %v", got, ok)
+	}
+
+	// uncategorized samples are ignored
+	if n := c.Sync([]TrainingSample{{ID: 9, Description: "coop", Amount: -1}}); n != 4 || c.Len() != 0 {
+		t.Errorf("expected all samples to be forgotten, got %d changes and %d samples", n, c.Len())
+	}
+	if len(c.vocab) != 0 || len(c.docs) != 0 || len(c.features) != 0 {
+		t.Errorf("expected empty counts, got vocab %v docs %v", c.vocab, c.docs)
+	}
+}
+
+func TestClassifierSuggestCategories(t *testing.T) {
+	c := NewClassifier()
+	c.Sync(trainingSamples())
+
+	rows := []ParsedRow{
+		{Description: "Migros Basel", Amount: -10, Type: "expense"},
+		{Description: "Migros Basel", Amount: -10, Type: "expense", CategoryID: catTransport},
+		{Description: "Migros Basel", Amount: 100, Type: "balance"},
+		{Description: "Migros Basel", Amount: -10, Type: "expense", Error: "invalid date"},
+	}
+	c.SuggestCategories(rows, 1)
+	if rows[0].SuggestedCategoryID != catGroceries || rows[0].SuggestionConfidence == 0 {
+		t.Errorf("expected a suggestion for the uncategorized row, got %+v", rows[0])
+	}
+	for _, row := range rows[1:] {
+		if row.SuggestedCategoryID != 0 {
+			t.Errorf("expected no suggestion, got %+v", row)
+		}
+	}
+
+	var none *Classifier
+	none.SuggestCategories(rows, 1) // a nil classifier is a no-op
+}
+
+func TestClassifierRefresh(t *testing.T) {
+	c := NewClassifier()
+	loads := 0
+	load := func() ([]TrainingSample, error) {
+		loads++
+		return trainingSamples(), nil
+	}
+
+	for _, version := range []string{"v1", "v1", "v2", "v2"} {
+		if err := c.Refresh(version, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("expected the samples to be loaded once per version, got %d loads", loads)
+	}
+	if c.Len() != len(trainingSamples()) {
+		t.Errorf("expected %d samples, got %d", len(trainingSamples()), c.Len())
+	}
+
+	// a failed load keeps the previous version, so the next call loads again
+	wantErr := errors.New("db down")
+	if err := c.Refresh("v3", func() ([]TrainingSample, error) { return nil, wantErr }); !errors.Is(err, wantErr) {
+		t.Fatalf("expected the load error, got %v", err)
+	}
+	if err := c.Refresh("v3", load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 3 {
+		t.Errorf("expected a reload after the failed one, got %d loads", loads)
+	}
+}
@@ -16,7 +16,8 @@ func NewStore(db *gorm.DB) (*Store, error) {
 		return nil, fmt.Errorf("db cannot be nil")
 	}
 
-	err := db.AutoMigrate(&dbImportProfile{}, &dbCategoryRuleGroup{}, &dbCategoryRulePattern{})
+	err := db.AutoMigrate(&dbImportProfile{}, &dbCategoryRuleGroup{}, &dbCategoryRulePattern{}, &dbImportBatch{}, &dbImportBatchTransaction{},
+		&dbImportInbox{}, &dbPendingImport{})
 	if err != nil {
 		return nil, fmt.Errorf("error running auto migrate: %w", err)
 	}
@@ -68,7 +69,7 @@ func migrateOldCategoryRules(db *gorm.DB) error {
 }
 
 func (s *Store) WipeData(ctx context.Context) error {
-	tables := []string{"db_category_rule_patterns", "db_category_rule_groups", "db_import_profiles"}
+	tables := []string{"db_pending_imports", "db_import_inboxes", "db_import_batch_transactions", "db_import_batches", "db_category_rule_patterns", "db_category_rule_groups", "db_import_profiles"}
 	for _, table := range tables {
 		if err := s.db.WithContext(ctx).Table(table).Where("1 = 1").Delete(nil).Error; err != nil {
 			return fmt.Errorf("failed to delete data in table '%s': %w", table, err)
@@ -0,0 +1,223 @@
+package csvimport
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfidentProfileScore is the score from which a profile matching all its columns is selected
+// for an upload without asking the user.
+const ConfidentProfileScore = 0.75
+
+// weights of the parts of the fingerprint in the score of a profile; the account links add up
+// to the remaining share, see ProfileLinks.
+const (
+	headerWeight         = 0.5
+	dateWeight           = 0.2
+	separatorWeight      = 0.1
+	skipRowsWeight       = 0.05
+	accountProfileWeight = 0.15
+	linkedProfileWeight  = 0.05
+)
+
+// dateSampleRows is the number of data rows whose date is parsed to score the date format.
+const dateSampleRows = 20
+
+// ProfileMatch is an import profile ranked by how well an uploaded file matches its fingerprint.
+type ProfileMatch struct {
+	ProfileID uint    `json:"profileId"`
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"`     // between 0 and 1
+	Confident bool    `json:"confident"` // the profile can be used without asking
+}
+
+// ProfileLinks are the accounts linked to the profiles through Account.ImportProfileID.
+type ProfileLinks struct {
+	AccountProfileID uint         // profile of the account the file is imported into
+	LinkedAccounts   map[uint]int // number of accounts linked to each profile
+}
+
+// RankProfiles scores the CSV or XLSX profiles against an uploaded file, best match first. The
+// fingerprint of a profile is its header row, separator, skip rows and date format: the file is
+// read with the profile's settings and compared to the stored header, or to the mapped columns
+// for profiles without one. Profiles linked to the target account, and to a lesser degree to any
+// account, rank higher. A match is confident when all the mapped columns are present and the
+// score reaches ConfidentProfileScore.
+func RankProfiles(data []byte, format string, profiles []ImportProfile, links ProfileLinks) []ProfileMatch {
+	var detectedSep string
+	var detectedSkip int
+	if format != FormatXLSX {
+		detectedSep, detectedSkip = DetectCSVSettings(data)
+	}
+
+	matches := make([]ProfileMatch, 0, len(profiles))
+	for _, p := range profiles {
+		m := ProfileMatch{ProfileID: p.ID, Name: p.Name}
+		score, complete := profileScore(data, format, p, detectedSep, detectedSkip)
+		switch {
+		case p.ID == links.AccountProfileID:
+			score += accountProfileWeight
+		case links.LinkedAccounts[p.ID] > 0:
+			score += linkedProfileWeight
+		}
+		m.Score = math.Round(math.Min(score, 1)*100) / 100
+		m.Confident = complete && m.Score >= ConfidentProfileScore
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ProfileID < matches[j].ProfileID
+	})
+	return matches
+}
+
+// profileScore returns how well the file matches the fingerprint of the profile, without the
+// account links, and whether all the mapped columns of the profile are present.
+func profileScore(data []byte, format string, p ImportProfile, detectedSep string, detectedSkip int) (float64, bool) {
+	csvData := data
+	if format == FormatXLSX {
+		var err error
+		csvData, _, err = XLSXToCSV(data, p.SheetName, p.DateFormat)
+		if err != nil {
+			return 0, false
+		}
+		detectedSep, detectedSkip = xlsxSeparator, detectSkipRows(csvData)
+		p.CsvSeparator = xlsxSeparator
+	}
+	if p.CsvSeparator == "" {
+		p.CsvSeparator = ","
+	}
+
+	header, dataRows, _, colIndex, err := readCSV(bytes.NewReader(csvData), p)
+	if err != nil {
+		return 0, false
+	}
+
+	columns := profileColumns(p)
+	present := 0
+	for _, c := range columns {
+		if _, ok := colIndex[c]; ok {
+			present++
+		}
+	}
+	complete := len(columns) > 0 && present == len(columns)
+
+	var headerScore float64
+	if len(p.HeaderRow) > 0 {
+		headerScore = headerSimilarity(header, p.HeaderRow)
+	} else if len(columns) > 0 {
+		headerScore = float64(present) / float64(len(columns))
+	}
+	// a renamed or missing column is enough to read the file wrongly
+	complete = complete && headerScore >= 0.8
+
+	score := headerWeight*headerScore + dateWeight*dateScore(dataRows, colIndex, p)
+	if p.CsvSeparator == detectedSep {
+		score += separatorWeight
+	}
+	if p.SkipRows == detectedSkip {
+		score += skipRowsWeight
+	}
+	return score, complete
+}
+
+// ReadHeader returns the header row of a CSV or XLSX file read with the settings of the profile.
+func ReadHeader(data []byte, format string, p ImportProfile) ([]string, error) {
+	if format == FormatXLSX {
+		csvData, _, err := XLSXToCSV(data, p.SheetName, p.DateFormat)
+		if err != nil {
+			return nil, err
+		}
+		data = csvData
+		p.CsvSeparator = xlsxSeparator
+	}
+	header, _, _, _, err := readCSV(bytes.NewReader(data), p)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]string, len(header))
+	for i, h := range header {
+		headers[i] = strings.TrimSpace(h)
+	}
+	return headers, nil
+}
+
+// profileColumns returns the columns mapped by the profile.
+func profileColumns(p ImportProfile) []string {
+	var candidates []string
+	switch {
+	case p.Type == ProfileTypeInvestment:
+		candidates = []string{p.DateColumn, p.SymbolColumn, p.SideColumn, p.QuantityColumn, p.PriceColumn,
+			p.AmountColumn, p.FeesColumn, p.CurrencyColumn, p.OrderIDColumn}
+	case amountMode(p) == "split":
+		candidates = []string{p.DateColumn, p.DescriptionColumn, p.CreditColumn, p.DebitColumn, p.FeesColumn, p.CurrencyColumn}
+	default:
+		candidates = []string{p.DateColumn, p.DescriptionColumn, p.AmountColumn, p.FeesColumn, p.CurrencyColumn}
+	}
+	var columns []string
+	for _, c := range candidates {
+		if c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// headerSimilarity returns the Jaccard index of the column names of two header rows, compared
+// case-insensitively.
+func headerSimilarity(a, b []string) float64 {
+	set := func(header []string) map[string]bool {
+		s := make(map[string]bool, len(header))
+		for _, h := range header {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				s[h] = true
+			}
+		}
+		return s
+	}
+	sa, sb := set(a), set(b)
+	union := len(sb)
+	common := 0
+	for h := range sa {
+		if sb[h] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// dateScore returns the share of the sampled dates that parse with the date format of the profile.
+func dateScore(dataRows [][]string, colIndex map[string]int, p ImportProfile) float64 {
+	idx, ok := colIndex[p.DateColumn]
+	if !ok || p.DateFormat == "" {
+		return 0
+	}
+	total, parsed := 0, 0
+	for _, row := range dataRows {
+		if total == dateSampleRows {
+			break
+		}
+		if idx >= len(row) || strings.TrimSpace(row[idx]) == "" {
+			continue
+		}
+		total++
+		if _, err := time.Parse(p.DateFormat, strings.TrimSpace(row[idx])); err == nil {
+			parsed++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(parsed) / float64(total)
+}
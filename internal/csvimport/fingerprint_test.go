@@ -0,0 +1,88 @@
+package csvimport
+
+import (
+	"testing"
+)
+
+func TestRankProfiles(t *testing.T) {
+	const bankCSV = "Account statement\n" +
+		"Date;Description;Amount;Balance\n" +
+		"05.03.2026;Coffee;-4.50;995.50\n" +
+		"06.03.2026;Salary;3000.00;3995.50\n"
+
+	bank := ImportProfile{
+		ID: 1, Name: "Bank", CsvSeparator: ";", SkipRows: 1, DateColumn: "Date", DateFormat: "02.01.2006",
+		DescriptionColumn: "Description", AmountColumn: "Amount",
+		HeaderRow: []string{"Date", "Description", "Amount", "Balance"},
+	}
+	// same columns, no stored header row, wrong date format
+	legacy := ImportProfile{
+		ID: 2, Name: "Legacy", CsvSeparator: ";", SkipRows: 1, DateColumn: "Date", DateFormat: "2006-01-02",
+		DescriptionColumn: "Description", AmountColumn: "Amount",
+	}
+	card := ImportProfile{
+		ID: 3, Name: "Card", CsvSeparator: ",", DateColumn: "Booked", DateFormat: "2006-01-02",
+		DescriptionColumn: "Merchant", AmountColumn: "Value",
+		HeaderRow: []string{"Booked", "Merchant", "Value"},
+	}
+	profiles := []ImportProfile{card, legacy, bank}
+
+	t.Run("best match first", func(t *testing.T) {
+		got := RankProfiles([]byte(bankCSV), FormatCSV, profiles, ProfileLinks{})
+		if len(got) != 3 {
+			t.Fatalf("expected 3 matches, got %+v", got)
+		}
+		want := []ProfileMatch{
+			{ProfileID: 1, Name: "Bank", Score: 0.85, Confident: true},
+			{ProfileID: 2, Name: "Legacy", Score: 0.65, Confident: false},
+			{ProfileID: 3, Name: "Card", Score: 0, Confident: false},
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("match %d: expected %+v, got %+v", i, want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("linked accounts", func(t *testing.T) {
+		got := RankProfiles([]byte(bankCSV), FormatCSV, profiles, ProfileLinks{AccountProfileID: 2, LinkedAccounts: map[uint]int{1: 1, 2: 1}})
+		if got[0].ProfileID != 1 || got[0].Score != 0.9 {
+			t.Errorf("expected Bank first with 0.9, got %+v", got[0])
+		}
+		if got[1].ProfileID != 2 || got[1].Score != 0.8 || !got[1].Confident {
+			t.Errorf("expected Legacy to be a confident match of the account, got %+v", got[1])
+		}
+	})
+
+	t.Run("renamed column", func(t *testing.T) {
+		data := "Account statement\nDate;Text;Amount;Balance\n05.03.2026;Coffee;-4.50;995.50\n"
+		got := RankProfiles([]byte(data), FormatCSV, []ImportProfile{bank}, ProfileLinks{AccountProfileID: 1})
+		if got[0].Confident {
+			t.Errorf("a missing mapped column should not be a confident match, got %+v", got[0])
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		data := buildXLSX(t, false, [2]string{"Transactions", xlsxStatement})
+		p := ImportProfile{
+			ID: 4, Name: "Sheet", SheetName: "Transactions", SkipRows: 1, DateColumn: "Date", DateFormat: "2006-01-02",
+			DescriptionColumn: "Text", AmountColumn: "Amount",
+		}
+		other := p
+		other.ID, other.SheetName = 5, "Missing"
+		got := RankProfiles(data, FormatXLSX, []ImportProfile{other, p}, ProfileLinks{})
+		if got[0].ProfileID != 4 || got[0].Score != 0.85 || !got[0].Confident {
+			t.Errorf("expected the sheet profile to match, got %+v", got[0])
+		}
+		if got[1].Score != 0 {
+			t.Errorf("expected no score for a missing sheet, got %+v", got[1])
+		}
+		headers, err := ReadHeader(data, FormatXLSX, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(headers) != 3 || headers[0] != "Date" || headers[2] != "Amount" {
+			t.Errorf("unexpected header %v", headers)
+		}
+	})
+}
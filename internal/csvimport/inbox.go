@@ -0,0 +1,245 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInboxNotFound         = errors.New("import inbox not found")
+	ErrPendingImportNotFound = errors.New("pending import not found")
+)
+
+// InboxArchiveDir is the subfolder of an inbox the processed files are moved to.
+const InboxArchiveDir = "archive"
+
+type dbImportInbox struct {
+	ID         uint   `gorm:"primarykey"`
+	AccountID  uint   `gorm:"not null;index"`
+	ProfileID  uint   // 0 = detected from the file
+	Directory  string `gorm:"not null;uniqueIndex"`
+	AutoSubmit bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ImportInbox is a directory scanned on a schedule for statement files of an account. New files
+// are parsed with the inbox's profile, or the one detected from the file, and categorized with
+// the rules; they are then either submitted or kept as pending imports to review.
+type ImportInbox struct {
+	ID         uint
+	AccountID  uint
+	ProfileID  uint   // 0 = the account's profile or the best confident match, see RankProfiles
+	Directory  string // relative to the inbox root of the server
+	AutoSubmit bool   // submit the new rows of files without errors instead of keeping them pending
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func dbToInbox(in dbImportInbox) ImportInbox {
+	return ImportInbox(in)
+}
+
+// validateInbox checks the inbox and cleans its directory, which must stay inside the inbox root.
+func (s *Store) validateInbox(ctx context.Context, id uint, in ImportInbox) (ImportInbox, error) {
+	if in.AccountID == 0 {
+		return in, ErrValidation("account_id cannot be zero")
+	}
+	if in.Directory == "" {
+		return in, ErrValidation("directory cannot be empty")
+	}
+	in.Directory = filepath.Clean(in.Directory)
+	if !filepath.IsLocal(in.Directory) || in.Directory == "." {
+		return in, ErrValidation("directory must be a relative path inside the inbox root")
+	}
+
+	var count int64
+	d := s.db.WithContext(ctx).Model(&dbImportInbox{}).Where("directory = ? AND id <> ?", in.Directory, id).Count(&count)
+	if d.Error != nil {
+		return in, d.Error
+	}
+	if count > 0 {
+		return in, ErrValidation("directory is used by another inbox")
+	}
+	return in, nil
+}
+
+func (s *Store) CreateInbox(ctx context.Context, in ImportInbox) (uint, error) {
+	in, err := s.validateInbox(ctx, 0, in)
+	if err != nil {
+		return 0, err
+	}
+	row := dbImportInbox{
+		AccountID:  in.AccountID,
+		ProfileID:  in.ProfileID,
+		Directory:  in.Directory,
+		AutoSubmit: in.AutoSubmit,
+	}
+	d := s.db.WithContext(ctx).Create(&row)
+	if d.Error != nil {
+		return 0, d.Error
+	}
+	return row.ID, nil
+}
+
+func (s *Store) GetInbox(ctx context.Context, id uint) (ImportInbox, error) {
+	var row dbImportInbox
+	d := s.db.WithContext(ctx).Where("id = ?", id).First(&row)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return ImportInbox{}, ErrInboxNotFound
+		}
+		return ImportInbox{}, d.Error
+	}
+	return dbToInbox(row), nil
+}
+
+// ListInboxes returns the inboxes of an account, all of them for accountID 0.
+func (s *Store) ListInboxes(ctx context.Context, accountID uint) ([]ImportInbox, error) {
+	q := s.db.WithContext(ctx).Order("id ASC")
+	if accountID != 0 {
+		q = q.Where("account_id = ?", accountID)
+	}
+	var rows []dbImportInbox
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	inboxes := make([]ImportInbox, 0, len(rows))
+	for _, row := range rows {
+		inboxes = append(inboxes, dbToInbox(row))
+	}
+	return inboxes, nil
+}
+
+func (s *Store) UpdateInbox(ctx context.Context, id uint, in ImportInbox) error {
+	in, err := s.validateInbox(ctx, id, in)
+	if err != nil {
+		return err
+	}
+	d := s.db.WithContext(ctx).Model(&dbImportInbox{}).Where("id = ?", id).
+		Select("AccountID", "ProfileID", "Directory", "AutoSubmit").
+		Updates(dbImportInbox{
+			AccountID:  in.AccountID,
+			ProfileID:  in.ProfileID,
+			Directory:  in.Directory,
+			AutoSubmit: in.AutoSubmit,
+		})
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrInboxNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteInbox(ctx context.Context, id uint) error {
+	d := s.db.WithContext(ctx).Delete(&dbImportInbox{}, id)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrInboxNotFound
+	}
+	return nil
+}
+
+type dbPendingImport struct {
+	ID        uint `gorm:"primarykey"`
+	InboxID   uint
+	AccountID uint `gorm:"not null;index"`
+	FileName  string
+	FileHash  string
+	Format    string
+	TotalRows int
+	Error     string
+	Data      []byte
+	CreatedAt time.Time
+}
+
+// PendingImport is a file picked up from an inbox that awaits review. The file is kept, so it is
+// parsed again on review against the transactions and rules of that moment.
+type PendingImport struct {
+	ID        uint
+	InboxID   uint
+	AccountID uint
+	FileName  string
+	FileHash  string // see FileHash
+	Format    string
+	TotalRows int    // rows parsed from the file when it was picked up
+	Error     string // why the file was not submitted, e.g. rows that failed to parse
+	Data      []byte // not loaded by ListPendingImports
+	CreatedAt time.Time
+}
+
+func dbToPendingImport(in dbPendingImport) PendingImport {
+	return PendingImport(in)
+}
+
+func (s *Store) CreatePendingImport(ctx context.Context, p PendingImport) (uint, error) {
+	if p.AccountID == 0 {
+		return 0, ErrValidation("account_id cannot be zero")
+	}
+	row := dbPendingImport{
+		InboxID:   p.InboxID,
+		AccountID: p.AccountID,
+		FileName:  p.FileName,
+		FileHash:  p.FileHash,
+		Format:    p.Format,
+		TotalRows: p.TotalRows,
+		Error:     p.Error,
+		Data:      p.Data,
+		CreatedAt: p.CreatedAt, // set when restoring a backup, otherwise filled in on create
+	}
+	d := s.db.WithContext(ctx).Create(&row)
+	if d.Error != nil {
+		return 0, d.Error
+	}
+	return row.ID, nil
+}
+
+func (s *Store) GetPendingImport(ctx context.Context, id uint) (PendingImport, error) {
+	var row dbPendingImport
+	d := s.db.WithContext(ctx).Where("id = ?", id).First(&row)
+	if d.Error != nil {
+		if errors.Is(d.Error, gorm.ErrRecordNotFound) {
+			return PendingImport{}, ErrPendingImportNotFound
+		}
+		return PendingImport{}, d.Error
+	}
+	return dbToPendingImport(row), nil
+}
+
+// ListPendingImports returns the pending imports of an account, all of them for accountID 0,
+// oldest first and without their file content.
+func (s *Store) ListPendingImports(ctx context.Context, accountID uint) ([]PendingImport, error) {
+	q := s.db.WithContext(ctx).Omit("Data").Order("created_at ASC, id ASC")
+	if accountID != 0 {
+		q = q.Where("account_id = ?", accountID)
+	}
+	var rows []dbPendingImport
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]PendingImport, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, dbToPendingImport(row))
+	}
+	return items, nil
+}
+
+func (s *Store) DeletePendingImport(ctx context.Context, id uint) error {
+	d := s.db.WithContext(ctx).Delete(&dbPendingImport{}, id)
+	if d.Error != nil {
+		return d.Error
+	}
+	if d.RowsAffected == 0 {
+		return ErrPendingImportNotFound
+	}
+	return nil
+}
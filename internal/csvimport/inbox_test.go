@@ -0,0 +1,114 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestImportInboxes(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	id, err := store.CreateInbox(ctx, ImportInbox{AccountID: 10, Directory: "bank/./checking/", AutoSubmit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetInbox(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Directory != "bank/checking" || got.AccountID != 10 || !got.AutoSubmit {
+		t.Errorf("unexpected inbox %+v", got)
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		for name, in := range map[string]ImportInbox{
+			"no account":       {Directory: "other"},
+			"no directory":     {AccountID: 10},
+			"absolute":         {AccountID: 10, Directory: "/etc"},
+			"outside the root": {AccountID: 10, Directory: "../other"},
+			"root":             {AccountID: 10, Directory: "."},
+			"duplicate":        {AccountID: 11, Directory: "bank/checking"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				var valErr ErrValidation
+				if _, err := store.CreateInbox(ctx, in); !errors.As(err, &valErr) {
+					t.Errorf("expected a validation error, got %v", err)
+				}
+			})
+		}
+	})
+
+	second, err := store.CreateInbox(ctx, ImportInbox{AccountID: 11, ProfileID: 3, Directory: "card"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.AutoSubmit = false
+	if err := store.UpdateInbox(ctx, id, got); err != nil {
+		t.Fatal(err)
+	}
+	byAccount, err := store.ListInboxes(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 1 || byAccount[0].ID != id || byAccount[0].AutoSubmit {
+		t.Errorf("unexpected inboxes of account 10: %+v", byAccount)
+	}
+	if err := store.DeleteInbox(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := store.ListInboxes(ctx, 0); err != nil || len(all) != 1 {
+		t.Errorf("expected one inbox left, got %+v (%v)", all, err)
+	}
+	if err := store.UpdateInbox(ctx, second, ImportInbox{AccountID: 11, Directory: "card"}); !errors.Is(err, ErrInboxNotFound) {
+		t.Errorf("expected ErrInboxNotFound, got %v", err)
+	}
+	if err := store.DeleteInbox(ctx, second); !errors.Is(err, ErrInboxNotFound) {
+		t.Errorf("expected ErrInboxNotFound, got %v", err)
+	}
+}
+
+func TestPendingImports(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	data := []byte("Date,Description,Amount\n")
+	id, err := store.CreatePendingImport(ctx, PendingImport{
+		InboxID: 1, AccountID: 10, FileName: "march.csv", FileHash: FileHash(data), Format: FormatCSV, TotalRows: 2, Data: data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreatePendingImport(ctx, PendingImport{AccountID: 11, FileName: "april.csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreatePendingImport(ctx, PendingImport{FileName: "no-account.csv"}); err == nil {
+		t.Error("expected an error without account")
+	}
+
+	items, err := store.ListPendingImports(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != id || items[0].FileName != "march.csv" || items[0].Data != nil {
+		t.Errorf("expected the pending import without its content, got %+v", items)
+	}
+	got, err := store.GetPendingImport(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data) != string(data) || got.TotalRows != 2 {
+		t.Errorf("unexpected pending import %+v", got)
+	}
+
+	if err := store.DeletePendingImport(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetPendingImport(ctx, id); !errors.Is(err, ErrPendingImportNotFound) {
+		t.Errorf("expected ErrPendingImportNotFound, got %v", err)
+	}
+	if err := store.DeletePendingImport(ctx, id); !errors.Is(err, ErrPendingImportNotFound) {
+		t.Errorf("expected ErrPendingImportNotFound, got %v", err)
+	}
+}
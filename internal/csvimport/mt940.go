@@ -0,0 +1,311 @@
+package csvimport
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// mt940Tag matches the start of a field, e.g. ":61:" or ":60F:".
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+
+	mt940Reference      = regexp.MustCompile(`(?m)^(\{4:)?:20:`)
+	mt940OpeningBalance = regexp.MustCompile(`(?m)^:60[FM]:`)
+)
+
+// isMT940 reports whether data looks like a SWIFT MT940 statement.
+func isMT940(data []byte) bool {
+	head := data[:min(len(data), 4096)]
+	return mt940Reference.Match(head) && mt940OpeningBalance.Match(head)
+}
+
+// mt940Field is a tag and its value; continuation lines are kept as separate lines.
+type mt940Field struct {
+	tag   string
+	lines []string
+}
+
+func (f mt940Field) value() string {
+	return strings.Join(f.lines, "\n")
+}
+
+// mt940Fields splits the file into fields, dropping the SWIFT envelope and statement separators.
+func mt940Fields(data []byte) []mt940Field {
+	text := strings.ReplaceAll(string(toUTF8(data)), "\r\n", "\n")
+	var fields []mt940Field
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], lines: []string{line[len(m[0]):]}})
+			continue
+		}
+		if len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			last.lines = append(last.lines, line)
+		}
+	}
+	return fields
+}
+
+// readMT940 extracts the bookings and the opening and closing balances of all statements in an
+// MT940 file. Several statements are accepted as long as they belong to the same account.
+func readMT940(data []byte) (statement, error) {
+	fields := mt940Fields(data)
+	var st statement
+	account := ""
+	statements := 0
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		switch f.tag {
+		case "20":
+			statements++
+		case "25":
+			id := strings.TrimSpace(f.value())
+			if account != "" && id != account {
+				return statement{}, ErrValidation("the file contains statements of several accounts, export one account per file")
+			}
+			account = id
+		case "60F", "62F":
+			bal, err := mt940Balance(f.value())
+			if err != nil {
+				return statement{}, fmt.Errorf(":%s: %w", f.tag, err)
+			}
+			if f.tag == "60F" {
+				// the opening balance is the balance at the start of its date
+				bal.Date = bal.Date.AddDate(0, 0, -1)
+			}
+			st.balances = append(st.balances, bal)
+		case "61":
+			var narrative []string
+			if i+1 < len(fields) && fields[i+1].tag == "86" {
+				narrative = fields[i+1].lines
+				i++
+			}
+			line, err := mt940Line(f, narrative)
+			if err != nil {
+				return statement{}, err
+			}
+			st.lines = append(st.lines, line)
+		}
+	}
+	if statements == 0 {
+		return statement{}, ErrValidation("the file contains no MT940 statement")
+	}
+	return st, nil
+}
+
+// mt940Balance parses a balance field: D/C mark, YYMMDD date, currency and amount.
+func mt940Balance(v string) (statementBalance, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 11 || (v[0] != 'C' && v[0] != 'D') {
+		return statementBalance{}, fmt.Errorf("invalid balance %q", v)
+	}
+	date, err := time.Parse("060102", v[1:7])
+	if err != nil {
+		return statementBalance{}, fmt.Errorf("invalid balance date %q", v[1:7])
+	}
+	amount, err := parseMT940Amount(v[10:])
+	if err != nil {
+		return statementBalance{}, err
+	}
+	if v[0] == 'D' {
+		amount = -amount
+	}
+	return statementBalance{Date: date, Amount: amount}, nil
+}
+
+// mt940Statement matches the :61: statement line: value date, optional entry date, debit/credit
+// mark (R for reversals), optional funds code, amount, transaction type, customer reference and
+// optional bank reference.
+var mt940Statement = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([0-9,]+)([NFS][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+
+func mt940Line(f mt940Field, narrative []string) (statementLine, error) {
+	m := mt940Statement.FindStringSubmatch(strings.TrimSpace(f.lines[0]))
+	if m == nil {
+		return statementLine{}, fmt.Errorf("invalid :61: line %q", f.lines[0])
+	}
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		return statementLine{}, fmt.Errorf("invalid :61: date %q", m[1])
+	}
+	date := valueDate
+	if m[2] != "" {
+		// the entry date has no year; it can fall in the year before or after the value date
+		entry, err := time.Parse("0102", m[2])
+		if err != nil {
+			return statementLine{}, fmt.Errorf("invalid :61: entry date %q", m[2])
+		}
+		date = time.Date(valueDate.Year(), entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC)
+		switch {
+		case date.Sub(valueDate) > 180*24*time.Hour:
+			date = date.AddDate(-1, 0, 0)
+		case valueDate.Sub(date) > 180*24*time.Hour:
+			date = date.AddDate(1, 0, 0)
+		}
+	}
+
+	amount, err := parseMT940Amount(m[5])
+	if err != nil {
+		return statementLine{}, err
+	}
+	// a reversal of a credit (RC) is a debit and vice versa
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+
+	ref := strings.TrimSpace(m[8])
+	if ref == "" || strings.EqualFold(ref, "NONREF") {
+		ref = ""
+	}
+
+	desc := mt940Description(narrative)
+	if desc == "" && len(f.lines) > 1 {
+		desc = strings.TrimSpace(strings.Join(f.lines[1:], " "))
+	}
+	if desc == "" && !strings.EqualFold(strings.TrimSpace(m[7]), "NONREF") {
+		desc = strings.TrimSpace(m[7])
+	}
+	return statementLine{ExternalID: ref, Date: date, Description: desc, Amount: amount}, nil
+}
+
+// parseMT940Amount parses an amount with a comma as decimal separator, e.g. "1500,00" or "45,".
+func parseMT940Amount(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	if strings.Count(v, ",") != 1 {
+		return 0, fmt.Errorf("invalid amount %q", v)
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", v)
+	}
+	return math.Round(amount*100) / 100, nil
+}
+
+// mt940Structured matches the start of a German structured :86: narrative: a three digit
+// transaction code followed by the subfield separator, usually '?'.
+var mt940Structured = regexp.MustCompile(`^\d{3}([^A-Za-z0-9 ])`)
+
+// mt940Description builds a description from the :86: narrative. It understands the German
+// structured format (?00 booking text, ?20-?29 and ?60-?63 purpose, ?32-?33 counterparty), the
+// SWIFT keyword format used e.g. by Dutch banks (/NAME/.../REMI/...) and free text.
+func mt940Description(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	// structured narratives are wrapped at a fixed width, words can be split across lines
+	joined := strings.Join(lines, "")
+
+	if m := mt940Structured.FindStringSubmatch(joined); m != nil {
+		sub := map[string]string{}
+		var purpose []string
+		for _, part := range strings.Split(joined[3:], m[1])[1:] {
+			if len(part) < 2 {
+				continue
+			}
+			key, val := part[:2], part[2:]
+			sub[key] += val
+			if (key >= "20" && key <= "29") || (key >= "60" && key <= "63") {
+				purpose = append(purpose, val)
+			}
+		}
+		return joinDescription(sub["32"]+sub["33"], sepaPurpose(strings.Join(purpose, "")), sub["00"])
+	}
+
+	if strings.HasPrefix(joined, "/") && (strings.Contains(joined, "/NAME/") || strings.Contains(joined, "/REMI/")) {
+		kv := mt940Keywords(joined)
+		remi := kv["REMI"]
+		// REMI may itself be structured, e.g. /REMI/USTD//text/
+		remi = strings.TrimPrefix(remi, "USTD//")
+		return joinDescription(kv["NAME"], strings.Trim(remi, "/ "), kv["TRTP"])
+	}
+
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
+
+// mt940KeywordNames are the keywords of the SWIFT narrative format; values may contain slashes.
+var mt940KeywordNames = []string{"TRTP", "IBAN", "BIC", "NAME", "REMI", "EREF", "MARF", "CSID", "ORDP", "BENM", "ULTC", "ULTD", "PURP", "ISDT", "RTRN", "ADDR"}
+
+func mt940Keywords(v string) map[string]string {
+	out := map[string]string{}
+	type pos struct {
+		key        string
+		start, end int
+	}
+	var found []pos
+	for _, k := range mt940KeywordNames {
+		marker := "/" + k + "/"
+		for off := 0; ; {
+			i := strings.Index(v[off:], marker)
+			if i < 0 {
+				break
+			}
+			found = append(found, pos{key: k, start: off + i, end: off + i + len(marker)})
+			off += i + len(marker)
+		}
+	}
+	for _, p := range found {
+		next := len(v)
+		for _, q := range found {
+			if q.start >= p.end && q.start < next {
+				next = q.start
+			}
+		}
+		if _, ok := out[p.key]; !ok {
+			out[p.key] = strings.TrimSpace(v[p.end:next])
+		}
+	}
+	return out
+}
+
+// sepaPurpose returns the free text of a SEPA purpose (SVWZ+), dropping the reference keys.
+func sepaPurpose(v string) string {
+	if i := strings.Index(v, "SVWZ+"); i >= 0 {
+		v = v[i+len("SVWZ+"):]
+		for _, key := range []string{"ABWA+", "ABWE+", "IBAN+", "BIC+"} {
+			if j := strings.Index(v, key); j >= 0 {
+				v = v[:j]
+			}
+		}
+	}
+	return strings.TrimSpace(v)
+}
+
+// joinDescription joins counterparty and purpose, falling back to the booking text.
+func joinDescription(party, purpose, fallback string) string {
+	party, purpose = strings.TrimSpace(party), strings.TrimSpace(purpose)
+	switch {
+	case party != "" && purpose != "":
+		return party + " - " + purpose
+	case party != "":
+		return party
+	case purpose != "":
+		return purpose
+	}
+	return strings.TrimSpace(fallback)
+}
+
+// ParseMT940 reads a SWIFT MT940 statement from r, applies category matching rules and detects
+// duplicates by the bank reference of each booking. The :60F: and :62F: balances are returned
+// as the last rows, with type "balance". It is a pure function with no DB access.
+func ParseMT940(r io.Reader, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading MT940: %w", err)
+	}
+	st, err := readMT940(data)
+	if err != nil {
+		return nil, err
+	}
+	return statementRows(st, groups, existing), nil
+}
@@ -0,0 +1,140 @@
+package csvimport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mt940German is a statement in the German structured format, with the :86: narrative wrapped
+// over several lines and a word split across the wrap.
+const mt940German = `:20:STARTUMSE
+:25:10020030/1234567890
+:28C:00001/001
+:60F:C260228EUR1000,00
+:61:2603010301CR1500,00NTRFNONREF//BANKREF-1
+:86:166?00GUTSCHRIFT?109310?20EREF+SAL-03?21SVWZ+Gehalt Maerz ACM
+?22E Corp?30DEUTDEFF?31DE02100100109307118603?32ACME Corp GmbH
+:61:2603020302DR45,NMSCNONREF
+:86:005?00LASTSCHRIFT?20SVWZ+Rechnung 4711?32Stadtwerke
+:62F:C260302EUR2455,00
+-
+`
+
+// mt940Swift is a statement using the SWIFT keyword narrative, as exported by Dutch banks.
+const mt940Swift = `{1:F01ABNANL2AXXXX0000000000}{2:I940ABNANL2AXXXXN}{4:
+:20:ABN AMRO BANK NV
+:25:NL91ABNA0417164300
+:28C:1/1
+:60F:C261230EUR100,00
+:61:2612311231D12,50N654NONREF//B1
+:86:/TRTP/SEPA OVERBOEKING/IBAN/NL20INGB0001234567/BIC/INGBNL2A/NAME/J
+an Jansen/REMI/USTD//Lunch/EREF/NOTPROVIDED
+:61:2612310102RC5,00NMSCREF/ABC//B2
+:86:Reversal of card payment
+:61:261231C20,00NTRFNONREF
+REFUND SHOP
+:62F:C261231EUR97,50
+-}
+`
+
+func TestParseMT940(t *testing.T) {
+	if got := DetectFormat([]byte(mt940German)); got != FormatMT940 {
+		t.Errorf("expected MT940 to be detected, got %q", got)
+	}
+	if got := DetectFormat([]byte(mt940Swift)); got != FormatMT940 {
+		t.Errorf("expected MT940 with SWIFT envelope to be detected, got %q", got)
+	}
+
+	t.Run("german narrative", func(t *testing.T) {
+		groups := []CategoryRuleGroup{{CategoryID: 7, Patterns: []CategoryRulePattern{{Pattern: "stadtwerke"}}}}
+		rows, err := ParseMT940(strings.NewReader(mt940German), groups, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-03-01", Description: "ACME Corp GmbH - Gehalt Maerz ACME Corp", Amount: 1500, Type: "income", ExternalID: "BANKREF-1"},
+			{RowNumber: 2, Date: "2026-03-02", Description: "Stadtwerke - Rechnung 4711", Amount: -45, Type: "expense", CategoryID: 7},
+			{RowNumber: 3, Date: "2026-02-27", Description: balanceRowDescription, Amount: 1000, Type: "balance"},
+			{RowNumber: 4, Date: "2026-03-02", Description: balanceRowDescription, Amount: 2455, Type: "balance"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("swift narrative", func(t *testing.T) {
+		rows, err := ParseMT940(strings.NewReader(mt940Swift), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-12-31", Description: "Jan Jansen - Lunch", Amount: -12.50, Type: "expense", ExternalID: "B1"},
+			// the entry date falls in the next year; a reversed credit is a debit
+			{RowNumber: 2, Date: "2027-01-02", Description: "Reversal of card payment", Amount: -5, Type: "expense", ExternalID: "B2"},
+			{RowNumber: 3, Date: "2026-12-31", Description: "REFUND SHOP", Amount: 20, Type: "income"},
+			{RowNumber: 4, Date: "2026-12-29", Description: balanceRowDescription, Amount: 100, Type: "balance"},
+			{RowNumber: 5, Date: "2026-12-31", Description: balanceRowDescription, Amount: 97.50, Type: "balance"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		existing := []ExistingTx{
+			{Date: "2026-03-01", Amount: 1500, ExternalID: "BANKREF-1"},
+			// without a bank reference date and amount are compared
+			{Date: "2026-03-02", Amount: -45},
+			{Date: "2026-02-27", Amount: 1000, Balance: true},
+		}
+		rows, err := ParseMT940(strings.NewReader(mt940German), nil, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dups []int
+		for _, r := range rows {
+			if r.IsDuplicate {
+				dups = append(dups, r.RowNumber)
+			}
+		}
+		if len(dups) != 3 || dups[0] != 1 || dups[1] != 2 || dups[2] != 3 {
+			t.Errorf("expected rows 1, 2 and 3 to be duplicates, got %v", dups)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tcs := []struct {
+			name       string
+			data       string
+			validation bool
+		}{
+			{name: "no statement", data: ":25:123\n:60F:C260228EUR1,00\n", validation: true},
+			{name: "several accounts", data: mt940German + strings.Replace(mt940German, "1234567890", "999", 1), validation: true},
+			{name: "invalid statement line", data: strings.Replace(mt940German, "2603020302DR45,", "26030X", 1)},
+			{name: "invalid balance", data: strings.Replace(mt940German, "C260302EUR2455,00", "C260302EUR2455.00", 1)},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ParseMT940(strings.NewReader(tc.data), nil, nil)
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				var valErr ErrValidation
+				if errors.As(err, &valErr) != tc.validation {
+					t.Errorf("unexpected error type %v", err)
+				}
+			})
+		}
+	})
+}
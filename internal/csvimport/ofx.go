@@ -0,0 +1,240 @@
+package csvimport
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// ofxNode is an element of an OFX document. Leaf elements carry a value, aggregates children.
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+// child returns the first direct child with the given name, or nil.
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// childValue returns the value of the first direct child with the given name.
+func (n *ofxNode) childValue(name string) string {
+	if c := n.child(name); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+// findAll returns all descendants with the given name, in document order.
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var out []*ofxNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, c.findAll(name)...)
+	}
+	return out
+}
+
+// isOFX reports whether data looks like an OFX 1.x (SGML) or 2.x (XML) file; QFX files are OFX.
+func isOFX(data []byte) bool {
+	head := data[:min(len(data), 1024)]
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(bytes.ToUpper(head), []byte("<OFX>"))
+}
+
+// ofxLeaves are the leaf elements read from a statement and others that banks commonly leave
+// empty. In SGML an empty leaf is followed directly by the next tag, like an aggregate; without
+// knowing it is a leaf its following siblings would be nested in it.
+var ofxLeaves = map[string]bool{
+	"TRNTYPE": true, "DTPOSTED": true, "DTUSER": true, "DTAVAIL": true, "TRNAMT": true, "FITID": true,
+	"CORRECTFITID": true, "CORRECTACTION": true, "SRVRTID": true, "CHECKNUM": true, "REFNUM": true,
+	"SIC": true, "PAYEEID": true, "NAME": true, "EXTDNAME": true, "MEMO": true, "CURDEF": true,
+	"BANKID": true, "BRANCHID": true, "ACCTID": true, "ACCTTYPE": true, "ACCTKEY": true,
+	"DTSTART": true, "DTEND": true, "BALAMT": true, "DTASOF": true, "TRNUID": true,
+	"CODE": true, "SEVERITY": true, "MESSAGE": true, "DTSERVER": true, "LANGUAGE": true,
+}
+
+// parseOFXTree parses the body of an OFX document. OFX 1.x is SGML where leaf elements have
+// no closing tag, OFX 2.x is XML; both are handled by treating an element followed by text,
+// by its own closing tag or listed in ofxLeaves as a leaf and ignoring its closing tag, if any.
+func parseOFXTree(data []byte) (*ofxNode, error) {
+	doc := string(toUTF8(data))
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+	doc = doc[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	for len(doc) > 0 {
+		open := strings.IndexByte(doc, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag")
+		}
+		tag := strings.TrimSpace(doc[open+1 : open+end])
+		doc = doc[open+end+1:]
+
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+		if tag[0] == '/' {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			// pop to the matching aggregate; closing tags of leaves are not on the stack
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		name := strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/")))
+		if i := strings.IndexAny(name, " \t\r\n"); i >= 0 {
+			name = name[:i] // drop attributes
+		}
+		next := strings.IndexByte(doc, '<')
+		if next < 0 {
+			next = len(doc)
+		}
+		text := strings.TrimSpace(doc[:next])
+
+		node := &ofxNode{name: name}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		switch {
+		case selfClosing:
+		case text != "":
+			node.value = html.UnescapeString(text)
+			doc = doc[next:]
+		case ofxLeaves[name] || isClosingTag(doc[next:], name):
+		default:
+			stack = append(stack, node)
+		}
+	}
+	ofx := root.child("OFX")
+	if ofx == nil {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+	return ofx, nil
+}
+
+// isClosingTag reports whether doc starts with the closing tag of name.
+func isClosingTag(doc, name string) bool {
+	if !strings.HasPrefix(doc, "</") {
+		return false
+	}
+	end := strings.IndexByte(doc, '>')
+	return end > 0 && strings.EqualFold(strings.TrimSpace(doc[2:end]), name)
+}
+
+// parseOFXAmount parses an amount of an OFX file. OFX allows a dot or a comma as decimal
+// separator and no thousands grouping, so "12,5" is 12.50, not 125 as parseAmount would guess.
+func parseOFXAmount(s string) (float64, error) {
+	nf := numberFormat{decimal: "."}
+	if strings.Contains(s, ",") {
+		nf.decimal = ","
+	}
+	return nf.parseAmount(s)
+}
+
+// readOFX extracts the bank or credit card statement of an OFX file.
+func readOFX(data []byte) (statement, error) {
+	ofx, err := parseOFXTree(data)
+	if err != nil {
+		return statement{}, fmt.Errorf("error reading OFX: %w", err)
+	}
+
+	stmts := append(ofx.findAll("STMTRS"), ofx.findAll("CCSTMTRS")...)
+	if len(stmts) == 0 {
+		if len(ofx.findAll("INVSTMTRS")) > 0 {
+			return statement{}, ErrValidation("investment statements are not supported, export a bank or credit card statement")
+		}
+		return statement{}, ErrValidation("the file contains no bank or credit card statement")
+	}
+	if len(stmts) > 1 {
+		return statement{}, ErrValidation(fmt.Sprintf("the file contains %d statements, export one account per file", len(stmts)))
+	}
+	stmt := stmts[0]
+
+	var st statement
+	for _, trn := range stmt.findAll("STMTTRN") {
+		line, err := ofxLine(trn)
+		if err != nil {
+			return statement{}, err
+		}
+		st.lines = append(st.lines, line)
+	}
+
+	if bal := stmt.child("LEDGERBAL"); bal != nil {
+		amount, err := parseOFXAmount(bal.childValue("BALAMT"))
+		if err != nil {
+			return statement{}, fmt.Errorf("invalid ledger balance: %w", err)
+		}
+		date, err := parseCompactDate(bal.childValue("DTASOF"))
+		if err != nil {
+			return statement{}, fmt.Errorf("invalid ledger balance date: %w", err)
+		}
+		st.balances = append(st.balances, statementBalance{Date: date, Amount: amount})
+	}
+	return st, nil
+}
+
+func ofxLine(trn *ofxNode) (statementLine, error) {
+	fitID := trn.childValue("FITID")
+	date, err := parseCompactDate(trn.childValue("DTPOSTED"))
+	if err != nil {
+		return statementLine{}, fmt.Errorf("transaction %q: %w", fitID, err)
+	}
+	amount, err := parseOFXAmount(trn.childValue("TRNAMT"))
+	if err != nil {
+		return statementLine{}, fmt.Errorf("transaction %q: %w", fitID, err)
+	}
+
+	name := trn.childValue("NAME")
+	if name == "" {
+		if payee := trn.child("PAYEE"); payee != nil {
+			name = payee.childValue("NAME")
+		}
+	}
+	desc := name
+	if memo := trn.childValue("MEMO"); memo != "" && !strings.Contains(name, memo) {
+		if desc != "" {
+			desc += " - "
+		}
+		desc += memo
+	}
+
+	return statementLine{ExternalID: fitID, Date: date, Description: desc, Amount: amount}, nil
+}
+
+// ParseOFX reads an OFX or QFX statement from r, applies category matching rules and detects
+// duplicates by the FITID of each transaction. The ledger balance of the statement is returned
+// as the last row, with type "balance". It is a pure function with no DB access.
+func ParseOFX(r io.Reader, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OFX: %w", err)
+	}
+	st, err := readOFX(data)
+	if err != nil {
+		return nil, err
+	}
+	return statementRows(st, groups, existing), nil
+}
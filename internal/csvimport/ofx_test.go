@@ -0,0 +1,284 @@
+package csvimport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const ofxV1 = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS>
+<CODE>0
+<SEVERITY>INFO
+</STATUS>
+<DTSERVER>20260310120000
+<LANGUAGE>ENG
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>CHF
+<BANKACCTFROM>
+<BANKID>123
+<ACCTID>CH9300762011623852957
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20260301
+<DTEND>20260310
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20260301120000.000[-5:EST]
+<TRNAMT>1500.00
+<FITID>2026030101
+<NAME>ACME Corp
+<MEMO>Salary March
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20260302
+<TRNAMT>-45.30
+<FITID>2026030201
+<NAME>Migros &amp; Co
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>2454.70
+<DTASOF>20260310
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxV2 = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <CCSTMTRS>
+        <CURDEF>EUR</CURDEF>
+        <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <DTSTART>20260301</DTSTART>
+          <DTEND>20260331</DTEND>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20260305</DTPOSTED>
+            <TRNAMT>-12.50</TRNAMT>
+            <FITID>cc-1</FITID>
+            <PAYEE><NAME>Café Central</NAME></PAYEE>
+            <MEMO></MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>-312.40</BALAMT>
+          <DTASOF>20260331120000</DTASOF>
+        </LEDGERBAL>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestDetectFormat(t *testing.T) {
+	tcs := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "ofx 1", data: ofxV1, want: FormatOFX},
+		{name: "ofx 2", data: ofxV2, want: FormatOFX},
+		{name: "csv", data: "Date,Description,Amount\n01/03/2026,Salary,1500.00\n", want: FormatCSV},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tc.data)); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// ofxEmptyLeaves is an SGML statement with empty leaves, each directly followed by a sibling,
+// and amounts with a decimal comma.
+const ofxEmptyLeaves = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20260304
+<MEMO>
+<TRNAMT>-12,5
+<FITID>e-1
+<NAME>Bakery
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20260305
+<TRNAMT>+1000
+<NAME>
+<FITID>e-2
+<MEMO>Refund
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>987,50
+<DTASOF>20260305
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	t.Run("sgml", func(t *testing.T) {
+		groups := []CategoryRuleGroup{
+			{CategoryID: 3, Patterns: []CategoryRulePattern{{Pattern: "salary"}}},
+			{CategoryID: 7, Patterns: []CategoryRulePattern{{Pattern: "migros"}}},
+		}
+		rows, err := ParseOFX(strings.NewReader(ofxV1), groups, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-03-01", Description: "ACME Corp - Salary March", Amount: 1500, Type: "income", CategoryID: 3, ExternalID: "2026030101"},
+			{RowNumber: 2, Date: "2026-03-02", Description: "Migros & Co", Amount: -45.30, Type: "expense", CategoryID: 7, ExternalID: "2026030201"},
+			{RowNumber: 3, Date: "2026-03-10", Description: balanceRowDescription, Amount: 2454.70, Type: "balance"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("xml credit card", func(t *testing.T) {
+		rows, err := ParseOFX(strings.NewReader(ofxV2), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows, got %+v", rows)
+		}
+		if rows[0].Description != "Café Central" || rows[0].ExternalID != "cc-1" || rows[0].Amount != -12.5 {
+			t.Errorf("unexpected transaction row %+v", rows[0])
+		}
+		if rows[1].Type != "balance" || rows[1].Amount != -312.40 || rows[1].Date != "2026-03-31" {
+			t.Errorf("unexpected balance row %+v", rows[1])
+		}
+	})
+
+	t.Run("empty leaves and decimal comma", func(t *testing.T) {
+		rows, err := ParseOFX(strings.NewReader(ofxEmptyLeaves), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-03-04", Description: "Bakery", Amount: -12.5, Type: "expense", ExternalID: "e-1"},
+			{RowNumber: 2, Date: "2026-03-05", Description: "Refund", Amount: 1000, Type: "income", ExternalID: "e-2"},
+			{RowNumber: 3, Date: "2026-03-05", Description: balanceRowDescription, Amount: 987.5, Type: "balance"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("windows-1252", func(t *testing.T) {
+		data := strings.Replace(ofxV1, "Migros &amp; Co", "Caf\xe9 \x80 Bar", 1)
+		rows, err := ParseOFX(strings.NewReader(data), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[1].Description != "Café € Bar" {
+			t.Errorf("unexpected description %q", rows[1].Description)
+		}
+	})
+
+	t.Run("duplicates by fitid", func(t *testing.T) {
+		existing := []ExistingTx{
+			// same date and amount as the salary but a different id: not a duplicate
+			{Date: "2026-03-01", Amount: 1500, ExternalID: "other"},
+			{Date: "2026-02-28", Amount: -45.30, ExternalID: "2026030201"},
+			{Date: "2026-03-10", Amount: 2454.70, Balance: true},
+		}
+		rows, err := ParseOFX(strings.NewReader(ofxV1), nil, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []bool{rows[0].IsDuplicate, rows[1].IsDuplicate, rows[2].IsDuplicate}
+		if got[0] || !got[1] || !got[2] {
+			t.Errorf("unexpected duplicate flags %v", got)
+		}
+	})
+
+	t.Run("repeated fitid in the file", func(t *testing.T) {
+		data := strings.Replace(ofxV1, "<FITID>2026030201", "<FITID>2026030101", 1)
+		rows, err := ParseOFX(strings.NewReader(data), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0].IsDuplicate || !rows[1].IsDuplicate {
+			t.Errorf("expected only the second occurrence to be a duplicate: %+v", rows)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tcs := []struct {
+			name       string
+			data       string
+			validation bool
+		}{
+			{name: "no ofx element", data: "OFXHEADER:100\n"},
+			{name: "investment statement", data: "<OFX><INVSTMTMSGSRSV1><INVSTMTTRNRS><INVSTMTRS></INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1></OFX>", validation: true},
+			{name: "two statements", data: "<OFX><STMTRS></STMTRS><STMTRS></STMTRS></OFX>", validation: true},
+			{name: "invalid amount", data: strings.Replace(ofxV1, "<TRNAMT>-45.30", "<TRNAMT>abc", 1)},
+			{name: "thousands separator", data: strings.Replace(ofxV1, "<TRNAMT>1500.00", "<TRNAMT>1,500.00", 1)},
+			{name: "grouped balance", data: strings.Replace(ofxV1, "<BALAMT>2454.70", "<BALAMT>2.454,70", 1)},
+			{name: "invalid date", data: strings.Replace(ofxV1, "<DTPOSTED>20260302", "<DTPOSTED>2026", 1)},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ParseOFX(strings.NewReader(tc.data), nil, nil)
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				var valErr ErrValidation
+				if errors.As(err, &valErr) != tc.validation {
+					t.Errorf("unexpected error type %v", err)
+				}
+			})
+		}
+	})
+}
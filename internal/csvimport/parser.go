@@ -20,13 +20,36 @@ type ParsedRow struct {
 	Type        string  `json:"type"`
 	CategoryID  uint    `json:"categoryId"`
 	IsDuplicate bool    `json:"isDuplicate"`
+	ExternalID  string  `json:"externalId,omitempty"` // id assigned by the bank, e.g. the OFX FITID
 	Error       string  `json:"error,omitempty"`
+
+	// Set by the actions of category rules; Type is "transfer" for rows marked as transfer.
+	Notes             string `json:"notes,omitempty"`
+	TransferAccountID uint   `json:"transferAccountId,omitempty"`
+
+	// Category learned from the transaction history for rows no rule categorized, see Classifier.
+	SuggestedCategoryID  uint    `json:"suggestedCategoryId,omitempty"`
+	SuggestionConfidence float64 `json:"suggestionConfidence,omitempty"`
+
+	// Set for rows of investment profiles, where Type is also "buy" or "sell" and Amount is the
+	// cash moved on the cash account, fees included.
+	Symbol       string  `json:"symbol,omitempty"`
+	Quantity     float64 `json:"quantity,omitempty"`
+	Price        float64 `json:"price,omitempty"`
+	Fees         float64 `json:"fees,omitempty"`
+	Currency     string  `json:"currency,omitempty"`
+	InstrumentID uint    `json:"instrumentId,omitempty"` // 0 when no instrument has the symbol
+
+	// Amount in Currency of statement rows converted into the account currency.
+	OriginalAmount float64 `json:"originalAmount,omitempty"`
 }
 
 // ExistingTx holds minimal info for duplicate detection.
 type ExistingTx struct {
-	Date   string // YYYY-MM-DD
-	Amount float64
+	Date       string // YYYY-MM-DD
+	Amount     float64
+	ExternalID string // set for transactions created by a statement or trade import
+	Balance    bool   // a balance status; only compared against statement balances
 }
 
 // PreviewResult holds the result of a CSV preview parse.
@@ -38,6 +61,7 @@ type PreviewResult struct {
 	DetectedSkipRows   int              `json:"detectedSkipRows"`
 	DetectedDateFormat string           `json:"detectedDateFormat,omitempty"`
 	DetectedColumns    *DetectedColumns `json:"detectedColumns,omitempty"`
+	Sheets             []string         `json:"sheets,omitempty"` // sheets of an XLSX workbook
 }
 
 // DetectedColumns holds auto-detected column mappings.
@@ -77,25 +101,7 @@ func DetectCSVSettings(data []byte) (separator string, skipRows int) {
 			continue
 		}
 
-		// Count column counts per row
-		countFreq := make(map[int]int)
-		for _, row := range allRows {
-			countFreq[len(row)]++
-		}
-
-		// Find mode column count (excluding count=1)
-		modeCount := 0
-		modeFreq := 0
-		for colCount, freq := range countFreq {
-			if colCount <= 1 {
-				continue
-			}
-			if freq > modeFreq || (freq == modeFreq && colCount > modeCount) {
-				modeCount = colCount
-				modeFreq = freq
-			}
-		}
-
+		modeCount, modeFreq := dominantColumnCount(allRows)
 		score := modeFreq
 
 		// Tie-break: prefer earlier candidate (,  >  ;  >  \t)
@@ -125,6 +131,25 @@ func DetectCSVSettings(data []byte) (separator string, skipRows int) {
 	return best.sep, skipRows
 }
 
+// dominantColumnCount returns the most common column count of the rows, ignoring single
+// column rows, and how many rows have it.
+func dominantColumnCount(rows [][]string) (modeCount, modeFreq int) {
+	countFreq := make(map[int]int)
+	for _, row := range rows {
+		countFreq[len(row)]++
+	}
+	for colCount, freq := range countFreq {
+		if colCount <= 1 {
+			continue
+		}
+		if freq > modeFreq || (freq == modeFreq && colCount > modeCount) {
+			modeCount = colCount
+			modeFreq = freq
+		}
+	}
+	return modeCount, modeFreq
+}
+
 // columnProfile describes the detected type of a single CSV column based on content analysis.
 type columnProfile struct {
 	index       int
@@ -363,8 +388,9 @@ func ParsePreviewWithAutoDetect(data []byte, profile ImportProfile) (PreviewResu
 		profile.SkipRows = detectedSkip
 	}
 
+	// column detection only knows statement columns
 	var detectedCols *DetectedColumns
-	if !hasMappings(profile) {
+	if profile.Type != ProfileTypeInvestment && !hasMappings(profile) {
 		detectedCols, profile = autoDetectColumns(data, profile)
 	}
 
@@ -455,6 +481,7 @@ func autoDetectDateFormat(data []byte, profile ImportProfile) string {
 // and split (credit/debit) column modes.
 // Returns (amount, type, errorString). errorString is "" on success.
 func resolveAmount(row []string, colIndex map[string]int, mode string, profile ImportProfile) (float64, string, string) {
+	nf := profileNumberFormat(profile)
 	switch mode {
 	case "split":
 		creditIdx, creditOK := colIndex[profile.CreditColumn]
@@ -479,7 +506,7 @@ func resolveAmount(row []string, colIndex map[string]int, mode string, profile I
 		}
 
 		if hasCredit {
-			amt, err := parseAmount(creditStr)
+			amt, err := nf.parseAmount(creditStr)
 			if err != nil {
 				return 0, "", fmt.Sprintf("invalid credit amount %q: %v", creditStr, err)
 			}
@@ -487,7 +514,7 @@ func resolveAmount(row []string, colIndex map[string]int, mode string, profile I
 		}
 
 		// hasDebit
-		amt, err := parseAmount(debitStr)
+		amt, err := nf.parseAmount(debitStr)
 		if err != nil {
 			return 0, "", fmt.Sprintf("invalid debit amount %q: %v", debitStr, err)
 		}
@@ -499,10 +526,13 @@ func resolveAmount(row []string, colIndex map[string]int, mode string, profile I
 			return 0, "", fmt.Sprintf("amount column %q not accessible", profile.AmountColumn)
 		}
 		rawAmount := strings.TrimSpace(row[amtIdx])
-		amount, err := parseAmount(rawAmount)
+		amount, err := nf.parseAmount(rawAmount)
 		if err != nil {
 			return 0, "", fmt.Sprintf("invalid amount %q: %v", rawAmount, err)
 		}
+		if profile.InvertSign {
+			amount = -amount
+		}
 		txType := "income"
 		if amount < 0 {
 			txType = "expense"
@@ -723,6 +753,17 @@ func ParsePreview(r io.Reader, profile ImportProfile) (PreviewResult, error) {
 		TotalRows: len(dataRows),
 	}
 
+	if profile.Type == ProfileTypeInvestment {
+		result.Rows = []ParsedRow{}
+		if hasTradeMappings(profile) {
+			result.Rows, err = tradeRows(dataRows[:min(len(dataRows), 10)], 1, colIndex, profile, nil, nil, nil)
+			if err != nil {
+				return PreviewResult{}, err
+			}
+		}
+		return result, nil
+	}
+
 	if !hasMappings(profile) {
 		result.Rows = []ParsedRow{}
 		return result, nil
@@ -751,6 +792,9 @@ func ParsePreview(r io.Reader, profile ImportProfile) (PreviewResult, error) {
 			return PreviewResult{}, fmt.Errorf("required column %q not found in headers", profile.AmountColumn)
 		}
 	}
+	if err := checkStatementColumns(colIndex, profile); err != nil {
+		return PreviewResult{}, err
+	}
 
 	// Parse up to 10 rows
 	maxRows := 10
@@ -797,6 +841,9 @@ func ParsePreview(r io.Reader, profile ImportProfile) (PreviewResult, error) {
 		}
 		parsed.Amount = amount
 		parsed.Type = txType
+		if errStr := statementFees(&parsed, row, colIndex, profile); errStr != "" {
+			parsed.Error = errStr
+		}
 
 		rows = append(rows, parsed)
 	}
@@ -808,7 +855,11 @@ func ParsePreview(r io.Reader, profile ImportProfile) (PreviewResult, error) {
 // Parse reads a CSV from r using the given profile's column mappings, applies
 // category matching rules, and detects duplicates against existing transactions.
 // It is a pure function with no DB access.
-func Parse(r io.Reader, profile ImportProfile, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+func Parse(r io.Reader, profile ImportProfile, groups []CategoryRuleGroup, existing []ExistingTx, opts ...ParseOption) ([]ParsedRow, error) {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	header, dataRows, skip, colIndex, err := readCSV(r, profile)
 	if err != nil {
 		return nil, err
@@ -838,10 +889,16 @@ func Parse(r io.Reader, profile ImportProfile, groups []CategoryRuleGroup, exist
 			return nil, fmt.Errorf("required column %q not found in headers", profile.AmountColumn)
 		}
 	}
+	if err := checkStatementColumns(colIndex, profile); err != nil {
+		return nil, err
+	}
 
 	// Build duplicate detection set from existing transactions
 	dupSet := make(map[string]struct{}, len(existing))
 	for _, ex := range existing {
+		if ex.Balance {
+			continue
+		}
 		key := dupKey(ex.Date, ex.Amount)
 		dupSet[key] = struct{}{}
 	}
@@ -887,9 +944,19 @@ func Parse(r io.Reader, profile ImportProfile, groups []CategoryRuleGroup, exist
 		}
 		parsed.Amount = amount
 		parsed.Type = txType
+		if errStr := statementFees(&parsed, row, colIndex, profile); errStr != "" {
+			parsed.Error = errStr
+			result = append(result, parsed)
+			continue
+		}
+		if errStr := o.convert(&parsed, t); errStr != "" {
+			parsed.Error = errStr
+			result = append(result, parsed)
+			continue
+		}
 
-		// Match category
-		parsed.CategoryID = MatchCategory(parsed.Description, groups)
+		// Apply the category rules
+		applyRules(&parsed, groups)
 
 		// Check duplicate
 		key := dupKey(parsed.Date, parsed.Amount)
@@ -909,27 +976,6 @@ func dupKey(date string, amount float64) string {
 	return fmt.Sprintf("%s|%.2f", date, amount)
 }
 
-// MatchCategory iterates groups in order and returns the categoryID of the first
-// group where any pattern matches, or 0 if none match.
-func MatchCategory(description string, groups []CategoryRuleGroup) uint {
-	descLower := strings.ToLower(description)
-	for _, group := range groups {
-		for _, pattern := range group.Patterns {
-			if pattern.IsRegex {
-				matched, err := regexp.MatchString(pattern.Pattern, description)
-				if err == nil && matched {
-					return group.CategoryID
-				}
-			} else {
-				if strings.Contains(descLower, strings.ToLower(pattern.Pattern)) {
-					return group.CategoryID
-				}
-			}
-		}
-	}
-	return 0
-}
-
 // parseAmount parses a string amount handling various formats:
 //   - Standard:        "1234.56"
 //   - Comma decimal:   "1234,56"
@@ -937,7 +983,20 @@ func MatchCategory(description string, groups []CategoryRuleGroup) uint {
 //   - US mixed:        "1,234.56" (comma = thousands, dot = decimal)
 //   - Negative:        "-45.60"
 //   - Empty → error
+//
+// The result is rounded to 2 decimal places.
 func parseAmount(s string) (float64, error) {
+	val, err := parseDecimal(s)
+	if err != nil {
+		return 0, err
+	}
+	// Round to 2 decimal places to avoid floating point artifacts
+	return math.Round(val*100) / 100, nil
+}
+
+// parseDecimal parses a number in the formats accepted by parseAmount without rounding it,
+// for share quantities and prices.
+func parseDecimal(s string) (float64, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return 0, fmt.Errorf("empty amount")
@@ -973,9 +1032,10 @@ func parseAmount(s string) (float64, error) {
 	case hasComma && !hasDot:
 		// Could be "1234,56" (comma as decimal) or "1,234" (comma as thousands)
 		// Heuristic: if exactly 2 digits after the last comma, treat as decimal
+		// or "0,125" (a fraction, as thousands never start with 0)
 		lastComma := strings.LastIndex(s, ",")
 		afterComma := s[lastComma+1:]
-		if len(afterComma) == 2 {
+		if len(afterComma) == 2 || (strings.HasPrefix(s, "0,") && strings.Count(s, ",") == 1) {
 			cleaned = strings.Replace(s, ",", ".", 1)
 		} else {
 			// Thousands separator only
@@ -996,9 +1056,5 @@ func parseAmount(s string) (float64, error) {
 	if negative {
 		val = -val
 	}
-
-	// Round to 2 decimal places to avoid floating point artifacts
-	val = math.Round(val*100) / 100
-
 	return val, nil
 }
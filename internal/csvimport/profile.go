@@ -2,6 +2,7 @@ package csvimport
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -10,21 +11,42 @@ import (
 
 var ErrProfileNotFound = errors.New("import profile not found")
 
+// Profile types: statement profiles import income and expense rows into the account, investment
+// profiles import a broker's trade history as stock buys and sells.
+const (
+	ProfileTypeStatement  = "statement"
+	ProfileTypeInvestment = "investment"
+)
+
 // dbImportProfile is the DB internal representation of an ImportProfile.
 type dbImportProfile struct {
-	ID                uint   `gorm:"primarykey"`
-	Name              string `gorm:"not null"`
-	CsvSeparator      string `gorm:"default:','"`
-	SkipRows          int    `gorm:"default:0"`
-	DateColumn        string `gorm:"not null"`
-	DateFormat        string `gorm:"not null"`
-	DescriptionColumn string `gorm:"not null"`
-	AmountColumn      string `gorm:"not null"`
-	AmountMode        string `gorm:"default:'single'"`
-	CreditColumn      string
-	DebitColumn       string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	ID                 uint   `gorm:"primarykey"`
+	Name               string `gorm:"not null"`
+	CsvSeparator       string `gorm:"default:','"`
+	SheetName          string
+	SkipRows           int    `gorm:"default:0"`
+	DateColumn         string `gorm:"not null"`
+	DateFormat         string `gorm:"not null"`
+	DescriptionColumn  string `gorm:"not null"`
+	AmountColumn       string `gorm:"not null"`
+	AmountMode         string `gorm:"default:'single'"`
+	CreditColumn       string
+	DebitColumn        string
+	DecimalSeparator   string
+	ThousandsSeparator string
+	InvertSign         bool
+	Type               string `gorm:"default:'statement'"`
+	SymbolColumn       string
+	SideColumn         string
+	QuantityColumn     string
+	PriceColumn        string
+	FeesColumn         string
+	CurrencyColumn     string
+	OrderIDColumn      string
+	CashAccountID      uint
+	HeaderRow          string // JSON list of the header columns
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 // ImportProfile is the public-facing representation of a CSV import profile.
@@ -32,6 +54,7 @@ type ImportProfile struct {
 	ID                uint
 	Name              string
 	CsvSeparator      string
+	SheetName         string // sheet of XLSX uploads; empty for the first sheet
 	SkipRows          int
 	DateColumn        string
 	DateFormat        string
@@ -40,63 +63,204 @@ type ImportProfile struct {
 	AmountMode        string
 	CreditColumn      string
 	DebitColumn       string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	Type              string // ProfileTypeStatement or ProfileTypeInvestment
+
+	// Number locale of the files. Without a decimal separator it is guessed for each value,
+	// which misreads values like "1.234"; with one the thousands separator is the only other
+	// character allowed. XLSX cells hold numbers and ignore it.
+	DecimalSeparator   string // "." or ","
+	ThousandsSeparator string // ",", ".", " ", "'" or empty for none
+	InvertSign         bool   // single amount column with money going out as positive, e.g. credit cards
+
+	// Optional for statement profiles: the fees are deducted from the amount of the row, and
+	// rows in another currency than the account's are converted, see WithCurrencyConversion.
+	FeesColumn     string
+	CurrencyColumn string
+
+	// Investment profiles only. AmountColumn is optional and holds the net cash amount of a
+	// row, fees included; DescriptionColumn is optional as well.
+	SymbolColumn   string // ticker symbol or ISIN, matched against the instrument symbols
+	SideColumn     string // buy, sell, dividend or fee; without it the quantity sign tells buys from sells
+	QuantityColumn string
+	PriceColumn    string
+	OrderIDColumn  string // broker order id, used to detect duplicate imports
+	CashAccountID  uint   // default account the cash legs are booked on
+
+	// HeaderRow is the header of the files the profile reads. Together with the separator, the
+	// skip rows and the date format it is the fingerprint uploads are matched against, see
+	// RankProfiles.
+	HeaderRow []string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func dbToProfile(in dbImportProfile) ImportProfile {
-	return ImportProfile(in)
+	p := ImportProfile{
+		ID:                 in.ID,
+		Name:               in.Name,
+		CsvSeparator:       in.CsvSeparator,
+		SheetName:          in.SheetName,
+		SkipRows:           in.SkipRows,
+		DateColumn:         in.DateColumn,
+		DateFormat:         in.DateFormat,
+		DescriptionColumn:  in.DescriptionColumn,
+		AmountColumn:       in.AmountColumn,
+		AmountMode:         in.AmountMode,
+		CreditColumn:       in.CreditColumn,
+		DebitColumn:        in.DebitColumn,
+		DecimalSeparator:   in.DecimalSeparator,
+		ThousandsSeparator: in.ThousandsSeparator,
+		InvertSign:         in.InvertSign,
+		Type:               in.Type,
+		SymbolColumn:       in.SymbolColumn,
+		SideColumn:         in.SideColumn,
+		QuantityColumn:     in.QuantityColumn,
+		PriceColumn:        in.PriceColumn,
+		FeesColumn:         in.FeesColumn,
+		CurrencyColumn:     in.CurrencyColumn,
+		OrderIDColumn:      in.OrderIDColumn,
+		CashAccountID:      in.CashAccountID,
+		CreatedAt:          in.CreatedAt,
+		UpdatedAt:          in.UpdatedAt,
+	}
+	if in.HeaderRow != "" {
+		_ = json.Unmarshal([]byte(in.HeaderRow), &p.HeaderRow)
+	}
+	return p
 }
 
-func (s *Store) CreateProfile(ctx context.Context, p ImportProfile) (uint, error) {
+// headerRowJSON returns the header row as stored in the DB, empty without a header row.
+func headerRowJSON(headers []string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(headers)
+	return string(b)
+}
+
+// validateProfile checks the column mappings required by the profile type and returns the
+// profile with the defaults applied.
+func validateProfile(p ImportProfile) (ImportProfile, error) {
 	if p.Name == "" {
-		return 0, ErrValidation("name cannot be empty")
+		return p, ErrValidation("name cannot be empty")
 	}
 	if p.DateColumn == "" {
-		return 0, ErrValidation("date_column cannot be empty")
+		return p, ErrValidation("date_column cannot be empty")
 	}
 	if p.DateFormat == "" {
-		return 0, ErrValidation("date_format cannot be empty")
+		return p, ErrValidation("date_format cannot be empty")
 	}
-	if p.DescriptionColumn == "" {
-		return 0, ErrValidation("description_column cannot be empty")
+	if p.CsvSeparator == "" {
+		p.CsvSeparator = ","
 	}
-	amountMode := p.AmountMode
-	if amountMode == "" {
-		amountMode = "single"
+	if p.AmountMode == "" {
+		p.AmountMode = "single"
 	}
-	switch amountMode {
+	if err := validateNumberFormat(p); err != nil {
+		return p, err
+	}
+
+	switch p.Type {
+	case "", ProfileTypeStatement:
+		p.Type = ProfileTypeStatement
+	case ProfileTypeInvestment:
+		if p.SymbolColumn == "" {
+			return p, ErrValidation("symbol_column cannot be empty")
+		}
+		if p.QuantityColumn == "" {
+			return p, ErrValidation("quantity_column cannot be empty")
+		}
+		if p.PriceColumn == "" && p.AmountColumn == "" {
+			return p, ErrValidation("price_column or amount_column must be set")
+		}
+		if p.AmountMode != "single" {
+			return p, ErrValidation("investment profiles only support the 'single' amount_mode")
+		}
+		if p.InvertSign {
+			return p, ErrValidation("invert_sign is not supported by investment profiles")
+		}
+		return p, nil
+	default:
+		return p, ErrValidation("invalid type: must be 'statement' or 'investment'")
+	}
+
+	if p.DescriptionColumn == "" {
+		return p, ErrValidation("description_column cannot be empty")
+	}
+	switch p.AmountMode {
 	case "single":
 		if p.AmountColumn == "" {
-			return 0, ErrValidation("amount_column cannot be empty")
+			return p, ErrValidation("amount_column cannot be empty")
 		}
 	case "split":
+		if p.InvertSign {
+			return p, ErrValidation("invert_sign requires the 'single' amount_mode")
+		}
 		if p.CreditColumn == "" {
-			return 0, ErrValidation("credit_column cannot be empty")
+			return p, ErrValidation("credit_column cannot be empty")
 		}
 		if p.DebitColumn == "" {
-			return 0, ErrValidation("debit_column cannot be empty")
+			return p, ErrValidation("debit_column cannot be empty")
 		}
 	default:
-		return 0, ErrValidation("invalid amount_mode: must be 'single' or 'split'")
+		return p, ErrValidation("invalid amount_mode: must be 'single' or 'split'")
 	}
+	return p, nil
+}
 
-	csvSep := p.CsvSeparator
-	if csvSep == "" {
-		csvSep = ","
+// validateNumberFormat checks the decimal and thousands separators of the profile.
+func validateNumberFormat(p ImportProfile) error {
+	switch p.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return ErrValidation("invalid decimal_separator: must be '.' or ','")
+	}
+	switch p.ThousandsSeparator {
+	case "", ",", ".", " ", "'":
+	default:
+		return ErrValidation("invalid thousands_separator: must be ',', '.', ' ' or an apostrophe")
+	}
+	if p.ThousandsSeparator != "" && p.DecimalSeparator == "" {
+		return ErrValidation("thousands_separator requires a decimal_separator")
+	}
+	if p.ThousandsSeparator != "" && p.ThousandsSeparator == p.DecimalSeparator {
+		return ErrValidation("thousands_separator must differ from decimal_separator")
+	}
+	return nil
+}
+
+func (s *Store) CreateProfile(ctx context.Context, p ImportProfile) (uint, error) {
+	p, err := validateProfile(p)
+	if err != nil {
+		return 0, err
 	}
 
 	row := dbImportProfile{
-		Name:              p.Name,
-		CsvSeparator:      csvSep,
-		SkipRows:          p.SkipRows,
-		DateColumn:        p.DateColumn,
-		DateFormat:        p.DateFormat,
-		DescriptionColumn: p.DescriptionColumn,
-		AmountColumn:      p.AmountColumn,
-		AmountMode:        amountMode,
-		CreditColumn:      p.CreditColumn,
-		DebitColumn:       p.DebitColumn,
+		Name:               p.Name,
+		CsvSeparator:       p.CsvSeparator,
+		SheetName:          p.SheetName,
+		SkipRows:           p.SkipRows,
+		DateColumn:         p.DateColumn,
+		DateFormat:         p.DateFormat,
+		DescriptionColumn:  p.DescriptionColumn,
+		AmountColumn:       p.AmountColumn,
+		AmountMode:         p.AmountMode,
+		CreditColumn:       p.CreditColumn,
+		DebitColumn:        p.DebitColumn,
+		DecimalSeparator:   p.DecimalSeparator,
+		ThousandsSeparator: p.ThousandsSeparator,
+		InvertSign:         p.InvertSign,
+		Type:               p.Type,
+		SymbolColumn:       p.SymbolColumn,
+		SideColumn:         p.SideColumn,
+		QuantityColumn:     p.QuantityColumn,
+		PriceColumn:        p.PriceColumn,
+		FeesColumn:         p.FeesColumn,
+		CurrencyColumn:     p.CurrencyColumn,
+		OrderIDColumn:      p.OrderIDColumn,
+		CashAccountID:      p.CashAccountID,
+		HeaderRow:          headerRowJSON(p.HeaderRow),
 	}
 
 	d := s.db.WithContext(ctx).Create(&row)
@@ -133,58 +297,54 @@ func (s *Store) ListProfiles(ctx context.Context) ([]ImportProfile, error) {
 }
 
 func (s *Store) UpdateProfile(ctx context.Context, id uint, p ImportProfile) error {
-	if p.Name == "" {
-		return ErrValidation("name cannot be empty")
-	}
-	if p.DateColumn == "" {
-		return ErrValidation("date_column cannot be empty")
-	}
-	if p.DateFormat == "" {
-		return ErrValidation("date_format cannot be empty")
-	}
-	if p.DescriptionColumn == "" {
-		return ErrValidation("description_column cannot be empty")
+	p, err := validateProfile(p)
+	if err != nil {
+		return err
 	}
 
-	amountMode := p.AmountMode
-	if amountMode == "" {
-		amountMode = "single"
-	}
-	switch amountMode {
-	case "single":
-		if p.AmountColumn == "" {
-			return ErrValidation("amount_column cannot be empty")
-		}
-	case "split":
-		if p.CreditColumn == "" {
-			return ErrValidation("credit_column cannot be empty")
-		}
-		if p.DebitColumn == "" {
-			return ErrValidation("debit_column cannot be empty")
-		}
-	default:
-		return ErrValidation("invalid amount_mode: must be 'single' or 'split'")
+	d := s.db.WithContext(ctx).Model(&dbImportProfile{}).Where("id = ?", id).
+		Select("Name", "CsvSeparator", "SheetName", "SkipRows", "DateColumn", "DateFormat", "DescriptionColumn", "AmountColumn", "AmountMode", "CreditColumn", "DebitColumn",
+			"DecimalSeparator", "ThousandsSeparator", "InvertSign", "Type", "SymbolColumn", "SideColumn", "QuantityColumn", "PriceColumn", "FeesColumn", "CurrencyColumn", "OrderIDColumn", "CashAccountID", "HeaderRow").
+		Updates(dbImportProfile{
+			Name:               p.Name,
+			CsvSeparator:       p.CsvSeparator,
+			SheetName:          p.SheetName,
+			SkipRows:           p.SkipRows,
+			DateColumn:         p.DateColumn,
+			DateFormat:         p.DateFormat,
+			DescriptionColumn:  p.DescriptionColumn,
+			AmountColumn:       p.AmountColumn,
+			AmountMode:         p.AmountMode,
+			CreditColumn:       p.CreditColumn,
+			DebitColumn:        p.DebitColumn,
+			DecimalSeparator:   p.DecimalSeparator,
+			ThousandsSeparator: p.ThousandsSeparator,
+			InvertSign:         p.InvertSign,
+			Type:               p.Type,
+			SymbolColumn:       p.SymbolColumn,
+			SideColumn:         p.SideColumn,
+			QuantityColumn:     p.QuantityColumn,
+			PriceColumn:        p.PriceColumn,
+			FeesColumn:         p.FeesColumn,
+			CurrencyColumn:     p.CurrencyColumn,
+			OrderIDColumn:      p.OrderIDColumn,
+			CashAccountID:      p.CashAccountID,
+			HeaderRow:          headerRowJSON(p.HeaderRow),
+		})
+	if d.Error != nil {
+		return d.Error
 	}
-
-	csvSep := p.CsvSeparator
-	if csvSep == "" {
-		csvSep = ","
+	if d.RowsAffected == 0 {
+		return ErrProfileNotFound
 	}
+	return nil
+}
 
+// SetProfileHeaderRow stores the header row of the profile's files, used to recognize the files
+// of profiles created before header rows were recorded.
+func (s *Store) SetProfileHeaderRow(ctx context.Context, id uint, headers []string) error {
 	d := s.db.WithContext(ctx).Model(&dbImportProfile{}).Where("id = ?", id).
-		Select("Name", "CsvSeparator", "SkipRows", "DateColumn", "DateFormat", "DescriptionColumn", "AmountColumn", "AmountMode", "CreditColumn", "DebitColumn").
-		Updates(dbImportProfile{
-			Name:              p.Name,
-			CsvSeparator:      csvSep,
-			SkipRows:          p.SkipRows,
-			DateColumn:        p.DateColumn,
-			DateFormat:        p.DateFormat,
-			DescriptionColumn: p.DescriptionColumn,
-			AmountColumn:      p.AmountColumn,
-			AmountMode:        amountMode,
-			CreditColumn:      p.CreditColumn,
-			DebitColumn:       p.DebitColumn,
-		})
+		Update("header_row", headerRowJSON(headers))
 	if d.Error != nil {
 		return d.Error
 	}
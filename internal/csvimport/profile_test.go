@@ -3,6 +3,7 @@ package csvimport
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 
 	"github.com/glebarez/sqlite"
@@ -408,6 +409,115 @@ func TestCreateProfile_SingleMode_BackwardCompat(t *testing.T) {
 	}
 }
 
+func TestCreateProfile_NumberFormat(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	p := validProfile()
+	p.DecimalSeparator = ","
+	p.ThousandsSeparator = "."
+	p.InvertSign = true
+	p.FeesColumn = "Fee"
+	p.CurrencyColumn = "Currency"
+	id, err := store.CreateProfile(ctx, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := store.GetProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.DecimalSeparator != "," || got.ThousandsSeparator != "." || !got.InvertSign {
+		t.Errorf("expected number format , . inverted, got %q %q %v", got.DecimalSeparator, got.ThousandsSeparator, got.InvertSign)
+	}
+	if got.FeesColumn != "Fee" || got.CurrencyColumn != "Currency" {
+		t.Errorf("expected fee and currency columns, got %q %q", got.FeesColumn, got.CurrencyColumn)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *ImportProfile)
+	}{
+		{name: "unknown decimal separator", modify: func(p *ImportProfile) { p.DecimalSeparator = ";" }},
+		{name: "unknown thousands separator", modify: func(p *ImportProfile) { p.DecimalSeparator = ","; p.ThousandsSeparator = "_" }},
+		{name: "thousands without decimal", modify: func(p *ImportProfile) { p.ThousandsSeparator = "." }},
+		{name: "same separators", modify: func(p *ImportProfile) { p.DecimalSeparator = ","; p.ThousandsSeparator = "," }},
+		{name: "inverted split mode", modify: func(p *ImportProfile) {
+			p.AmountMode = "split"
+			p.CreditColumn = "Credit"
+			p.DebitColumn = "Debit"
+			p.InvertSign = true
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := validProfile()
+			tc.modify(&p)
+			_, err := store.CreateProfile(ctx, p)
+			var valErr ErrValidation
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected ErrValidation, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestCreateProfile_Investment(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	p := ImportProfile{
+		Name:           "Broker",
+		Type:           ProfileTypeInvestment,
+		DateColumn:     "Date",
+		DateFormat:     "2006-01-02",
+		SymbolColumn:   "Symbol",
+		SideColumn:     "Side",
+		QuantityColumn: "Quantity",
+		PriceColumn:    "Price",
+		FeesColumn:     "Commission",
+		OrderIDColumn:  "Order",
+		CashAccountID:  7,
+	}
+	id, err := store.CreateProfile(ctx, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := store.GetProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Type != ProfileTypeInvestment || got.SymbolColumn != "Symbol" || got.OrderIDColumn != "Order" || got.CashAccountID != 7 {
+		t.Errorf("unexpected profile %+v", got)
+	}
+
+	statement, err := store.CreateProfile(ctx, validProfile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := store.GetProfile(ctx, statement); got.Type != ProfileTypeStatement {
+		t.Errorf("expected the statement type by default, got %q", got.Type)
+	}
+
+	for name, change := range map[string]func(p *ImportProfile){
+		"missing symbol":          func(p *ImportProfile) { p.SymbolColumn = "" },
+		"missing quantity":        func(p *ImportProfile) { p.QuantityColumn = "" },
+		"missing price or amount": func(p *ImportProfile) { p.PriceColumn = "" },
+		"invalid type":            func(p *ImportProfile) { p.Type = "pension" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := p
+			change(&invalid)
+			_, err := store.CreateProfile(ctx, invalid)
+			var valErr ErrValidation
+			if !errors.As(err, &valErr) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
 func TestDeleteProfile(t *testing.T) {
 	store := newTestStore(t)
 	ctx := context.Background()
@@ -437,3 +547,51 @@ func TestDeleteProfile(t *testing.T) {
 		}
 	})
 }
+
+func TestProfileHeaderRow(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	p := validProfile()
+	p.HeaderRow = []string{"Date", "Description", "Amount"}
+	id, err := store.CreateProfile(ctx, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := store.GetProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(got.HeaderRow, p.HeaderRow) {
+		t.Errorf("expected header row %v, got %v", p.HeaderRow, got.HeaderRow)
+	}
+
+	headers := []string{"Date", "Description", "Amount", "Balance"}
+	if err := store.SetProfileHeaderRow(ctx, id, headers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = store.GetProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(got.HeaderRow, headers) {
+		t.Errorf("expected header row %v, got %v", headers, got.HeaderRow)
+	}
+
+	// updating the profile without a header row clears it
+	got.HeaderRow = nil
+	if err := store.UpdateProfile(ctx, id, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = store.GetProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.HeaderRow) != 0 {
+		t.Errorf("expected no header row, got %v", got.HeaderRow)
+	}
+
+	if err := store.SetProfileHeaderRow(ctx, 99999, headers); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("expected ErrProfileNotFound, got %v", err)
+	}
+}
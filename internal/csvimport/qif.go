@@ -0,0 +1,324 @@
+package csvimport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QIF section types written by WriteQIF.
+const (
+	QIFBank = "Bank"
+	QIFCash = "Cash"
+)
+
+// qifTransactionSections are the QIF sections whose records are imported; investment, category,
+// class and memorized transaction lists are skipped.
+var qifTransactionSections = map[string]bool{"bank": true, "cash": true, "ccard": true}
+
+// isQIF reports whether data looks like a QIF file, which always starts with a ! header line.
+func isQIF(data []byte) bool {
+	head := bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(data[:min(len(data), 256)], []byte("\xef\xbb\xbf"))))
+	return bytes.HasPrefix(head, []byte("!type:")) || bytes.HasPrefix(head, []byte("!account")) ||
+		bytes.HasPrefix(head, []byte("!option:"))
+}
+
+// qifRecord is a transaction of a QIF bank, cash or credit card section, with its date still
+// unparsed as the day/month order is only known once the whole file has been read.
+type qifRecord struct {
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+	splits   []qifSplit
+}
+
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// readQIFRecords returns the transaction records of all bank, cash and credit card sections.
+// Files exported with several accounts (!Account blocks) are rejected, like other formats.
+func readQIFRecords(data []byte) ([]qifRecord, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(toUTF8(data)))
+	var (
+		records   []qifRecord
+		rec       qifRecord
+		section   string
+		inAccount bool
+		account   string
+		accounts  = map[string]struct{}{}
+	)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" {
+			continue
+		}
+		if line[0] == '!' {
+			header := strings.ToLower(line)
+			switch {
+			case strings.HasPrefix(header, "!type:"):
+				section = strings.TrimSpace(header[len("!type:"):])
+				inAccount = false
+				if qifTransactionSections[section] {
+					accounts[account] = struct{}{}
+				}
+			case header == "!account":
+				inAccount = true
+			}
+			// !Option and !Clear lines only toggle how Quicken reads the file
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		if inAccount {
+			if code == 'N' {
+				account = value
+			}
+			if code == '^' {
+				inAccount = false
+			}
+			continue
+		}
+		if !qifTransactionSections[section] {
+			continue
+		}
+		switch code {
+		case 'D':
+			rec.date = value
+		case 'T', 'U':
+			// U is a higher precision copy of T written by newer Quicken versions
+			if rec.amount == "" || code == 'T' {
+				rec.amount = value
+			}
+		case 'P':
+			rec.payee = value
+		case 'M':
+			rec.memo = value
+		case 'L':
+			rec.category = value
+		case 'S':
+			rec.splits = append(rec.splits, qifSplit{category: value})
+		case 'E':
+			if n := len(rec.splits); n > 0 {
+				rec.splits[n-1].memo = value
+			}
+		case '$':
+			if n := len(rec.splits); n > 0 {
+				rec.splits[n-1].amount = value
+			}
+		case '^':
+			if rec.date != "" || rec.amount != "" {
+				records = append(records, rec)
+			}
+			rec = qifRecord{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading QIF: %w", err)
+	}
+	if len(accounts) > 1 {
+		return nil, ErrValidation("the file contains transactions of several accounts, export one account per file")
+	}
+	if len(records) == 0 {
+		return nil, ErrValidation("the file contains no bank, cash or credit card transactions")
+	}
+	return records, nil
+}
+
+// qifDateParts splits a QIF date such as "3/1/26", "3/ 1'26", "01.03.2026" or "2026-03-01".
+// An apostrophe before the year marks years from 2000 on, Quicken pads single digits with spaces.
+var qifDateParts = regexp.MustCompile(`^(\d{1,4})\s*[/.-]\s*(\d{1,2})\s*([/.'-])\s*(\d{1,4})$`)
+
+// qifDayFirst reports whether the dates of a file are written day first. QIF has no fixed date
+// format: US exports are month first, European exports are usually day first and use dots.
+func qifDayFirst(records []qifRecord) bool {
+	for _, rec := range records {
+		m := qifDateParts.FindStringSubmatch(rec.date)
+		if m == nil || len(m[1]) == 4 {
+			continue
+		}
+		if strings.Contains(rec.date, ".") {
+			return true
+		}
+		if first, _ := strconv.Atoi(m[1]); first > 12 {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQIFDate(v string, dayFirst bool) (time.Time, error) {
+	m := qifDateParts.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[2])
+	c, _ := strconv.Atoi(m[4])
+
+	var year, month, day int
+	switch {
+	case len(m[1]) == 4:
+		year, month, day = a, b, c
+	case dayFirst:
+		day, month, year = a, b, c
+	default:
+		month, day, year = a, b, c
+	}
+	if len(m[1]) != 4 && len(m[4]) <= 2 {
+		switch {
+		case m[3] == "'" || year < 70:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	return t, nil
+}
+
+// CategoryPaths maps category paths, as built by CategoryPath, to category ids. Income and
+// expense categories are kept apart as the same name can exist in both trees.
+type CategoryPaths struct {
+	Income  map[string]uint
+	Expense map[string]uint
+}
+
+// CategoryPath returns the lookup key of a category given the names from the root down,
+// e.g. ["Food", "Groceries"] becomes "food:groceries".
+func CategoryPath(names []string) string {
+	parts := make([]string, 0, len(names))
+	for _, n := range names {
+		parts = append(parts, strings.ToLower(strings.TrimSpace(n)))
+	}
+	return strings.Join(parts, ":")
+}
+
+// lookup returns the id of the category named by a QIF category field, or 0 if there is none.
+// Transfers ([Account]) have no category and a class suffix (Category/Class) is ignored.
+func (p CategoryPaths) lookup(qifCategory string, amount float64) uint {
+	if qifCategory == "" || strings.HasPrefix(qifCategory, "[") {
+		return 0
+	}
+	if i := strings.Index(qifCategory, "/"); i >= 0 {
+		qifCategory = qifCategory[:i]
+	}
+	key := CategoryPath(strings.Split(qifCategory, ":"))
+	if amount < 0 {
+		return p.Expense[key]
+	}
+	return p.Income[key]
+}
+
+// readQIF converts the QIF records into statement lines, one per split line for split
+// transactions.
+func readQIF(data []byte, categories CategoryPaths) (statement, error) {
+	records, err := readQIFRecords(data)
+	if err != nil {
+		return statement{}, err
+	}
+	dayFirst := qifDayFirst(records)
+
+	var st statement
+	for i, rec := range records {
+		date, err := parseQIFDate(rec.date, dayFirst)
+		if err != nil {
+			return statement{}, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		if len(rec.splits) == 0 {
+			amount, err := parseAmount(rec.amount)
+			if err != nil {
+				return statement{}, fmt.Errorf("transaction %d: %w", i+1, err)
+			}
+			st.lines = append(st.lines, statementLine{
+				Date:        date,
+				Description: joinDescription(rec.payee, rec.memo, ""),
+				Amount:      amount,
+				CategoryID:  categories.lookup(rec.category, amount),
+			})
+			continue
+		}
+		for _, split := range rec.splits {
+			amount, err := parseAmount(split.amount)
+			if err != nil {
+				return statement{}, fmt.Errorf("transaction %d: split %q: %w", i+1, split.category, err)
+			}
+			memo := split.memo
+			if memo == "" {
+				memo = rec.memo
+			}
+			st.lines = append(st.lines, statementLine{
+				Date:        date,
+				Description: joinDescription(rec.payee, memo, ""),
+				Amount:      amount,
+				CategoryID:  categories.lookup(split.category, amount),
+			})
+		}
+	}
+	return st, nil
+}
+
+// ParseQIF reads the bank, cash and credit card transactions of a QIF file from r. Split
+// transactions become one row per split line. Categories named in the file are matched to
+// existing categories by their name path, rows without a match get the category matching
+// rules applied. Duplicates are detected by date and amount. It is a pure function with no
+// DB access.
+func ParseQIF(r io.Reader, groups []CategoryRuleGroup, existing []ExistingTx, categories CategoryPaths) ([]ParsedRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading QIF: %w", err)
+	}
+	st, err := readQIF(data, categories)
+	if err != nil {
+		return nil, err
+	}
+	return statementRows(st, groups, existing), nil
+}
+
+// QIFTransaction is a transaction written by WriteQIF.
+type QIFTransaction struct {
+	Date     time.Time
+	Amount   float64 // negative for money leaving the account
+	Payee    string
+	Memo     string
+	Category string // category names joined with ':', or the other account in brackets for transfers
+}
+
+// WriteQIF writes the transactions of one account as a QIF section of the given type, QIFBank
+// or QIFCash. Dates are written month first with a four digit year, which all common tools read.
+func WriteQIF(w io.Writer, sectionType string, txs []QIFTransaction) error {
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(bw, "!Type:%s\n", sectionType)
+	for _, tx := range txs {
+		_, _ = fmt.Fprintf(bw, "D%s\n", tx.Date.Format("01/02/2006"))
+		_, _ = fmt.Fprintf(bw, "T%s\n", strconv.FormatFloat(tx.Amount, 'f', 2, 64))
+		if tx.Payee != "" {
+			_, _ = fmt.Fprintf(bw, "P%s\n", qifValue(tx.Payee))
+		}
+		if tx.Memo != "" {
+			_, _ = fmt.Fprintf(bw, "M%s\n", qifValue(tx.Memo))
+		}
+		if tx.Category != "" {
+			_, _ = fmt.Fprintf(bw, "L%s\n", qifValue(tx.Category))
+		}
+		_, _ = bw.WriteString("^\n")
+	}
+	return bw.Flush()
+}
+
+// qifValue keeps a value on a single line, QIF has no escaping.
+func qifValue(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
@@ -0,0 +1,178 @@
+package csvimport
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const qifBank = `!Type:Cat
+NFood
+E
+^
+!Type:Bank
+D3/1'26
+T1,500.00
+PACME Corp
+MSalary March
+LSalary
+^
+D3/2'26
+U-120.00
+T-120.00
+PSupermarket
+LFood:Groceries
+SFood:Groceries
+EWeekly shopping
+$-100.00
+SHousehold
+$-20.00
+^
+D3/5/26
+T-50.00
+PATM
+L[Cash wallet]
+^
+D3/6/26
+T-9.90
+PStreaming Co
+LSubscriptions/Family
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	if got := DetectFormat([]byte(qifBank)); got != FormatQIF {
+		t.Errorf("expected QIF to be detected, got %q", got)
+	}
+
+	categories := CategoryPaths{
+		Income:  map[string]uint{CategoryPath([]string{"Salary"}): 1},
+		Expense: map[string]uint{CategoryPath([]string{"Food", "Groceries"}): 2, CategoryPath([]string{"Subscriptions"}): 3},
+	}
+
+	t.Run("bank section with splits", func(t *testing.T) {
+		groups := []CategoryRuleGroup{{CategoryID: 9, Patterns: []CategoryRulePattern{{Pattern: "supermarket"}}}}
+		rows, err := ParseQIF(strings.NewReader(qifBank), groups, nil, categories)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []ParsedRow{
+			{RowNumber: 1, Date: "2026-03-01", Description: "ACME Corp - Salary March", Amount: 1500, Type: "income", CategoryID: 1},
+			{RowNumber: 2, Date: "2026-03-02", Description: "Supermarket - Weekly shopping", Amount: -100, Type: "expense", CategoryID: 2},
+			// the Household category does not exist, the rules apply
+			{RowNumber: 3, Date: "2026-03-02", Description: "Supermarket", Amount: -20, Type: "expense", CategoryID: 9},
+			// transfers have no category
+			{RowNumber: 4, Date: "2026-03-05", Description: "ATM", Amount: -50, Type: "expense"},
+			// the class suffix is ignored
+			{RowNumber: 5, Date: "2026-03-06", Description: "Streaming Co", Amount: -9.90, Type: "expense", CategoryID: 3},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		existing := []ExistingTx{{Date: "2026-03-02", Amount: -100}}
+		rows, err := ParseQIF(strings.NewReader(qifBank), nil, existing, categories)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			if r.IsDuplicate != (r.RowNumber == 2) {
+				t.Errorf("row %d: unexpected duplicate flag %v", r.RowNumber, r.IsDuplicate)
+			}
+		}
+	})
+
+	t.Run("day first dates", func(t *testing.T) {
+		data := "!Type:CCard\nD01.03.2026\nT-1.234,50\nPHotel\n^\nD02.03.2026\nT-10,00\n^\n"
+		rows, err := ParseQIF(strings.NewReader(data), nil, nil, CategoryPaths{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0].Date != "2026-03-01" || rows[0].Amount != -1234.50 || rows[1].Date != "2026-03-02" {
+			t.Errorf("unexpected rows %+v", rows)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tcs := []struct {
+			name       string
+			data       string
+			validation bool
+		}{
+			{name: "investment only", data: "!Type:Invst\nD3/1/26\nNBuy\nT100.00\n^\n", validation: true},
+			{name: "several accounts", data: "!Account\nNChecking\n^\n!Type:Bank\nD3/1/26\nT1.00\n^\n!Account\nNSavings\n^\n!Type:Bank\nD3/1/26\nT2.00\n^\n", validation: true},
+			{name: "invalid date", data: "!Type:Bank\nD13/13/26\nT1.00\n^\n"},
+			{name: "invalid amount", data: "!Type:Bank\nD3/1/26\nTabc\n^\n"},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := ParseQIF(strings.NewReader(tc.data), nil, nil, CategoryPaths{})
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				var valErr ErrValidation
+				if errors.As(err, &valErr) != tc.validation {
+					t.Errorf("unexpected error type %v", err)
+				}
+			})
+		}
+	})
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tcs := []struct {
+		in       string
+		dayFirst bool
+		want     string
+	}{
+		{in: "3/1'26", want: "2026-03-01"},
+		{in: "3/ 1' 6", want: "2006-03-01"},
+		{in: "12/31/99", want: "1999-12-31"},
+		{in: "03/01/2026", want: "2026-03-01"},
+		{in: "01/03/2026", dayFirst: true, want: "2026-03-01"},
+		{in: "2026-03-01", dayFirst: true, want: "2026-03-01"},
+	}
+	for _, tc := range tcs {
+		got, err := parseQIFDate(tc.in, tc.dayFirst)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got.Format("2006-01-02"), tc.want)
+		}
+	}
+}
+
+func TestWriteQIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteQIF(&buf, QIFBank, []QIFTransaction{
+		{Date: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Amount: 1500, Payee: "ACME Corp", Category: "Salary"},
+		{Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Amount: -45.3, Payee: "Shop", Memo: "two\nlines", Category: "[Savings]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "!Type:Bank\nD03/01/2026\nT1500.00\nPACME Corp\nLSalary\n^\nD03/02/2026\nT-45.30\nPShop\nMtwo lines\nL[Savings]\n^\n"
+	if buf.String() != want {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	// the written file is read back by the importer
+	rows, err := ParseQIF(&buf, nil, nil, CategoryPaths{Income: map[string]uint{"salary": 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].CategoryID != 5 || rows[1].Amount != -45.30 {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+}
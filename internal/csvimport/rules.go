@@ -0,0 +1,142 @@
+package csvimport
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RuleInput is a transaction the category rules are evaluated against.
+type RuleInput struct {
+	Description string
+	Amount      float64 // negative for expenses
+	Date        time.Time
+	AccountID   uint
+	ProfileID   uint
+}
+
+// ruleScope is the account and profile of an import, set on the groups passed to the parsers.
+type ruleScope struct {
+	accountID uint
+	profileID uint
+}
+
+// ScopeRules returns the groups bound to the account and import profile of an import, so the
+// parsers, which only see the rows of a file, can evaluate the account and profile conditions.
+func ScopeRules(groups []CategoryRuleGroup, accountID, profileID uint) []CategoryRuleGroup {
+	out := make([]CategoryRuleGroup, len(groups))
+	for i, g := range groups {
+		g.scope = ruleScope{accountID: accountID, profileID: profileID}
+		out[i] = g
+	}
+	return out
+}
+
+// Matches reports whether the conditions of the group match a transaction. A group without
+// conditions matches nothing.
+func (g CategoryRuleGroup) Matches(in RuleInput) bool {
+	var terms []bool
+	if len(g.Patterns) > 0 {
+		terms = append(terms, matchPatterns(g.Patterns, in.Description))
+	}
+	if g.AmountMin != nil || g.AmountMax != nil {
+		abs := math.Abs(in.Amount)
+		terms = append(terms, (g.AmountMin == nil || abs >= *g.AmountMin) && (g.AmountMax == nil || abs <= *g.AmountMax))
+	}
+	switch g.Direction {
+	case RuleDirectionIncome:
+		terms = append(terms, in.Amount > 0)
+	case RuleDirectionExpense:
+		terms = append(terms, in.Amount < 0)
+	}
+	if g.AccountID != 0 {
+		terms = append(terms, in.AccountID == g.AccountID)
+	}
+	if g.ProfileID != 0 {
+		terms = append(terms, in.ProfileID == g.ProfileID)
+	}
+	if g.DateFrom != nil || g.DateTo != nil {
+		day := in.Date.Format("2006-01-02")
+		terms = append(terms, !in.Date.IsZero() &&
+			(g.DateFrom == nil || day >= g.DateFrom.Format("2006-01-02")) &&
+			(g.DateTo == nil || day <= g.DateTo.Format("2006-01-02")))
+	}
+	if len(g.Weekdays) > 0 {
+		terms = append(terms, !in.Date.IsZero() && slices.Contains(g.Weekdays, in.Date.Weekday()))
+	}
+
+	if len(terms) == 0 {
+		return false
+	}
+	if g.MatchAny {
+		return slices.Contains(terms, true)
+	}
+	return !slices.Contains(terms, false)
+}
+
+// matchPatterns reports whether any pattern matches the description, substrings are matched
+// case-insensitively.
+func matchPatterns(patterns []CategoryRulePattern, description string) bool {
+	descLower := strings.ToLower(description)
+	for _, pattern := range patterns {
+		if pattern.IsRegex {
+			matched, err := regexp.MatchString(pattern.Pattern, description)
+			if err == nil && matched {
+				return true
+			}
+		} else if strings.Contains(descLower, strings.ToLower(pattern.Pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchRule returns the first group in order whose conditions match the transaction.
+func MatchRule(in RuleInput, groups []CategoryRuleGroup) (CategoryRuleGroup, bool) {
+	for _, g := range groups {
+		if g.Matches(in) {
+			return g, true
+		}
+	}
+	return CategoryRuleGroup{}, false
+}
+
+// AppendNote adds a note on a new line, unless the notes contain it already.
+func AppendNote(notes, note string) string {
+	switch {
+	case note == "" || strings.Contains(notes, note):
+		return notes
+	case notes == "":
+		return note
+	default:
+		return notes + "\n" + note
+	}
+}
+
+// applyRules applies the actions of the first group matching an income or expense row. The
+// account and profile conditions use the scope of the groups, see ScopeRules.
+func applyRules(row *ParsedRow, groups []CategoryRuleGroup) {
+	in := RuleInput{Description: row.Description, Amount: row.Amount}
+	in.Date, _ = time.Parse("2006-01-02", row.Date)
+	for _, g := range groups {
+		in.AccountID, in.ProfileID = g.scope.accountID, g.scope.profileID
+		if !g.Matches(in) {
+			continue
+		}
+		if g.CategoryID != 0 {
+			row.CategoryID = g.CategoryID
+		}
+		if g.SetDescription != "" {
+			row.Description = g.SetDescription
+		}
+		row.Notes = AppendNote(row.Notes, g.AppendNotes)
+		if g.TransferAccountID != 0 && g.TransferAccountID != in.AccountID {
+			row.Type = "transfer"
+			row.TransferAccountID = g.TransferAccountID
+			row.CategoryID = 0
+		}
+		return
+	}
+}
@@ -0,0 +1,171 @@
+package csvimport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCategoryRuleGroupMatches(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	saturday := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	coffee := []CategoryRulePattern{{Pattern: "coffee"}}
+
+	tcs := []struct {
+		name  string
+		group CategoryRuleGroup
+		in    RuleInput
+		want  bool
+	}{
+		{
+			name:  "no conditions",
+			group: CategoryRuleGroup{},
+			in:    RuleInput{Description: "Coffee", Amount: -4},
+			want:  false,
+		},
+		{
+			name:  "pattern",
+			group: CategoryRuleGroup{Patterns: coffee},
+			in:    RuleInput{Description: "COFFEE SHOP", Amount: -4},
+			want:  true,
+		},
+		{
+			name:  "amount range uses the absolute amount",
+			group: CategoryRuleGroup{AmountMin: ptr(3), AmountMax: ptr(5)},
+			in:    RuleInput{Description: "anything", Amount: -4},
+			want:  true,
+		},
+		{
+			name:  "amount above the range",
+			group: CategoryRuleGroup{AmountMax: ptr(5)},
+			in:    RuleInput{Amount: -40},
+			want:  false,
+		},
+		{
+			name:  "pattern and amount range",
+			group: CategoryRuleGroup{Patterns: coffee, AmountMin: ptr(3), AmountMax: ptr(5)},
+			in:    RuleInput{Description: "Bakery", Amount: -4},
+			want:  false,
+		},
+		{
+			name:  "pattern and amount range both match",
+			group: CategoryRuleGroup{Patterns: coffee, AmountMin: ptr(3), AmountMax: ptr(5)},
+			in:    RuleInput{Description: "Coffee", Amount: -4},
+			want:  true,
+		},
+		{
+			name:  "direction",
+			group: CategoryRuleGroup{Direction: RuleDirectionIncome},
+			in:    RuleInput{Amount: -4},
+			want:  false,
+		},
+		{
+			name:  "any condition matches",
+			group: CategoryRuleGroup{Patterns: coffee, Direction: RuleDirectionIncome, MatchAny: true},
+			in:    RuleInput{Description: "Coffee", Amount: -4},
+			want:  true,
+		},
+		{
+			name:  "all conditions match by default",
+			group: CategoryRuleGroup{Patterns: coffee, Direction: RuleDirectionIncome},
+			in:    RuleInput{Description: "Coffee", Amount: -4},
+			want:  false,
+		},
+		{
+			name:  "account and profile",
+			group: CategoryRuleGroup{AccountID: 2, ProfileID: 3},
+			in:    RuleInput{AccountID: 2, ProfileID: 3},
+			want:  true,
+		},
+		{
+			name:  "other account",
+			group: CategoryRuleGroup{AccountID: 2},
+			in:    RuleInput{AccountID: 1},
+			want:  false,
+		},
+		{
+			name:  "weekday",
+			group: CategoryRuleGroup{Weekdays: []time.Weekday{time.Saturday, time.Sunday}},
+			in:    RuleInput{Date: saturday},
+			want:  true,
+		},
+		{
+			name:  "weekday on a workday",
+			group: CategoryRuleGroup{Weekdays: []time.Weekday{time.Saturday, time.Sunday}},
+			in:    RuleInput{Date: monday},
+			want:  false,
+		},
+		{
+			name:  "date range is inclusive",
+			group: CategoryRuleGroup{DateFrom: &saturday, DateTo: &monday},
+			in:    RuleInput{Date: monday},
+			want:  true,
+		},
+		{
+			name:  "date range without a date",
+			group: CategoryRuleGroup{DateFrom: &saturday},
+			in:    RuleInput{},
+			want:  false,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.group.Matches(tc.in); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	groups := []CategoryRuleGroup{
+		{CategoryID: 1, Patterns: []CategoryRulePattern{{Pattern: "coffee"}}, AccountID: 9},
+		{Patterns: []CategoryRulePattern{{Pattern: "savings"}}, SetDescription: "To savings", AppendNotes: "monthly", TransferAccountID: 7},
+		{CategoryID: 2, Patterns: []CategoryRulePattern{{Pattern: "coffee"}}, AppendNotes: "caffeine"},
+	}
+
+	t.Run("account condition uses the scope", func(t *testing.T) {
+		row := ParsedRow{Description: "Coffee", Amount: -4, Type: "expense", Date: "2026-03-07"}
+		applyRules(&row, ScopeRules(groups, 9, 0))
+		if row.CategoryID != 1 || row.Notes != "" {
+			t.Errorf("expected the account rule to win, got %+v", row)
+		}
+
+		row = ParsedRow{Description: "Coffee", Amount: -4, Type: "expense", Date: "2026-03-07"}
+		applyRules(&row, ScopeRules(groups, 1, 0))
+		if row.CategoryID != 2 || row.Notes != "caffeine" {
+			t.Errorf("expected the generic rule on another account, got %+v", row)
+		}
+	})
+
+	t.Run("transfer action", func(t *testing.T) {
+		row := ParsedRow{Description: "SAVINGS ORDER 123", Amount: -100, Type: "expense", Notes: "monthly"}
+		applyRules(&row, ScopeRules(groups, 1, 0))
+		want := ParsedRow{Description: "To savings", Amount: -100, Type: "transfer", Notes: "monthly", TransferAccountID: 7}
+		if row != want {
+			t.Errorf("expected %+v, got %+v", want, row)
+		}
+	})
+
+	t.Run("no transfer to the imported account", func(t *testing.T) {
+		row := ParsedRow{Description: "savings", Amount: 100, Type: "income"}
+		applyRules(&row, ScopeRules(groups, 7, 0))
+		if row.Type != "income" || row.TransferAccountID != 0 || row.Description != "To savings" {
+			t.Errorf("unexpected row %+v", row)
+		}
+	})
+}
+
+func TestAppendNote(t *testing.T) {
+	tcs := []struct{ notes, note, want string }{
+		{"", "", ""},
+		{"", "a", "a"},
+		{"x", "a", "x\na"},
+		{"x\na", "a", "x\na"},
+	}
+	for _, tc := range tcs {
+		if got := AppendNote(tc.notes, tc.note); got != tc.want {
+			t.Errorf("AppendNote(%q, %q) = %q, want %q", tc.notes, tc.note, got, tc.want)
+		}
+	}
+}
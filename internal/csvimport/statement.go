@@ -0,0 +1,169 @@
+package csvimport
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// File formats recognised by DetectFormat.
+const (
+	FormatCSV   = "csv"
+	FormatOFX   = "ofx"
+	FormatCamt  = "camt"
+	FormatMT940 = "mt940"
+	FormatQIF   = "qif"
+	FormatXLSX  = "xlsx"
+)
+
+// DetectFormat inspects the content of an uploaded statement and returns its format.
+// Anything that is not recognised as a structured statement format is treated as CSV.
+func DetectFormat(data []byte) string {
+	switch {
+	case isOFX(data):
+		return FormatOFX
+	case isCamt(data):
+		return FormatCamt
+	case isMT940(data):
+		return FormatMT940
+	case isQIF(data):
+		return FormatQIF
+	case isXLSX(data):
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// statement is the format independent content of a bank statement file.
+type statement struct {
+	lines    []statementLine
+	balances []statementBalance
+}
+
+// statementLine is a single booking of a statement.
+type statementLine struct {
+	ExternalID  string // stable id assigned by the bank, e.g. the OFX FITID; may be empty
+	Date        time.Time
+	Description string
+	Amount      float64 // negative for debits
+	CategoryID  uint    // category given by the file itself, e.g. a QIF category; 0 applies the rules
+}
+
+// statementBalance is a balance reported by the statement, e.g. its closing (ledger) balance.
+type statementBalance struct {
+	Date   time.Time
+	Amount float64
+}
+
+// balanceRowDescription is used for the row that records the statement balance.
+const balanceRowDescription = "Statement balance"
+
+// statementRows converts a statement into rows for the import preview, applying category
+// matching and duplicate detection. Lines with an external id are duplicates if an existing
+// transaction carries the same id, lines without one fall back to matching date and amount.
+// The statement balances, if any, are added as the last rows with type "balance".
+func statementRows(st statement, groups []CategoryRuleGroup, existing []ExistingTx) []ParsedRow {
+	externalIDs := make(map[string]struct{})
+	dupSet := make(map[string]struct{}, len(existing))
+	balances := make(map[string]struct{})
+	for _, ex := range existing {
+		switch {
+		case ex.Balance:
+			balances[dupKey(ex.Date, ex.Amount)] = struct{}{}
+		default:
+			dupSet[dupKey(ex.Date, ex.Amount)] = struct{}{}
+			if ex.ExternalID != "" {
+				externalIDs[ex.ExternalID] = struct{}{}
+			}
+		}
+	}
+
+	rows := make([]ParsedRow, 0, len(st.lines)+len(st.balances))
+	for i, line := range st.lines {
+		parsed := ParsedRow{
+			RowNumber:   i + 1,
+			Date:        line.Date.Format("2006-01-02"),
+			Description: line.Description,
+			Amount:      line.Amount,
+			Type:        "income",
+			ExternalID:  line.ExternalID,
+		}
+		if line.Amount < 0 {
+			parsed.Type = "expense"
+		}
+		// a category from the file, e.g. a QIF category, takes precedence over the rules
+		parsed.CategoryID = line.CategoryID
+		if parsed.CategoryID == 0 {
+			applyRules(&parsed, groups)
+		}
+
+		if line.ExternalID != "" {
+			if _, found := externalIDs[line.ExternalID]; found {
+				parsed.IsDuplicate = true
+			}
+			// the same id twice in one file is a duplicate as well
+			externalIDs[line.ExternalID] = struct{}{}
+		} else if _, found := dupSet[dupKey(parsed.Date, parsed.Amount)]; found {
+			parsed.IsDuplicate = true
+		}
+		rows = append(rows, parsed)
+	}
+
+	for _, bal := range st.balances {
+		parsed := ParsedRow{
+			RowNumber:   len(rows) + 1,
+			Date:        bal.Date.Format("2006-01-02"),
+			Description: balanceRowDescription,
+			Amount:      bal.Amount,
+			Type:        "balance",
+		}
+		key := dupKey(parsed.Date, parsed.Amount)
+		if _, found := balances[key]; found {
+			parsed.IsDuplicate = true
+		}
+		balances[key] = struct{}{}
+		rows = append(rows, parsed)
+	}
+	return rows
+}
+
+// cp1252 maps the bytes 0x80-0x9F of Windows-1252 to unicode; the remaining bytes are identical to Latin-1.
+var cp1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// toUTF8 returns data unchanged if it is valid UTF-8, otherwise it decodes it as Windows-1252,
+// the usual encoding of statement files that are not UTF-8.
+func toUTF8(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) + len(data)/4)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			buf.WriteByte(b)
+		case b < 0xa0:
+			buf.WriteRune(cp1252[b-0x80])
+		default:
+			buf.WriteRune(rune(b))
+		}
+	}
+	return buf.Bytes()
+}
+
+// parseCompactDate parses the YYYYMMDD prefix of a date or date-time value.
+func parseCompactDate(v string) (time.Time, error) {
+	if len(v) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	t, err := time.Parse("20060102", v[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	return t, nil
+}
@@ -0,0 +1,314 @@
+package csvimport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TradeInstrument is an existing instrument a trade row can be matched to.
+type TradeInstrument struct {
+	ID       uint
+	Currency string // ISO code, e.g. "USD"
+}
+
+// tradeSides maps the side values written by common brokers to the row types: buys and sells
+// become stock trades, dividends and fees income and expenses on the cash account.
+var tradeSides = map[string]string{
+	"buy": "buy", "b": "buy", "bot": "buy", "bought": "buy", "purchase": "buy",
+	"kauf": "buy", "achat": "buy", "compra": "buy", "acquisto": "buy",
+	"sell": "sell", "s": "sell", "sld": "sell", "sold": "sell", "sale": "sell",
+	"verkauf": "sell", "vente": "sell", "venta": "sell", "vendita": "sell",
+	"dividend": "income", "dividends": "income", "div": "income", "dividende": "income",
+	"dividendo": "income", "distribution": "income", "interest": "income",
+	"fee": "expense", "fees": "expense", "commission": "expense", "gebühr": "expense",
+	"tax": "expense", "withholding": "expense",
+}
+
+// tradeSide returns the row type of a side value, trying the first word when the whole value
+// is unknown, e.g. "Buy to open" or "Dividend (ordinary)".
+func tradeSide(v string) (string, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if t, ok := tradeSides[v]; ok {
+		return t, true
+	}
+	if fields := strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == '(' || r == '-' || r == '_' }); len(fields) > 0 {
+		t, ok := tradeSides[fields[0]]
+		return t, ok
+	}
+	return "", false
+}
+
+// hasTradeMappings reports whether an investment profile maps the columns needed to parse rows.
+func hasTradeMappings(profile ImportProfile) bool {
+	return profile.DateColumn != "" && profile.SymbolColumn != "" && profile.QuantityColumn != "" &&
+		(profile.PriceColumn != "" || profile.AmountColumn != "")
+}
+
+// tradeColumns returns the indexes of the mapped columns of an investment profile, -1 for the
+// optional columns that are not mapped.
+func tradeColumns(profile ImportProfile, colIndex map[string]int) (map[string]int, error) {
+	cols := map[string]int{}
+	for _, c := range []struct {
+		role, name string
+		required   bool
+	}{
+		{"date", profile.DateColumn, true},
+		{"symbol", profile.SymbolColumn, true},
+		{"quantity", profile.QuantityColumn, true},
+		{"price", profile.PriceColumn, false},
+		{"amount", profile.AmountColumn, false},
+		{"fees", profile.FeesColumn, false},
+		{"side", profile.SideColumn, false},
+		{"currency", profile.CurrencyColumn, false},
+		{"orderId", profile.OrderIDColumn, false},
+		{"description", profile.DescriptionColumn, false},
+	} {
+		if c.name == "" {
+			if c.required {
+				return nil, fmt.Errorf("the %s column is not mapped", c.role)
+			}
+			cols[c.role] = -1
+			continue
+		}
+		idx, ok := colIndex[c.name]
+		if !ok {
+			return nil, fmt.Errorf("required column %q not found in headers", c.name)
+		}
+		cols[c.role] = idx
+	}
+	return cols, nil
+}
+
+// tradeRows converts the data rows of an investment profile. firstRow is the row number of the
+// first data row. Unknown symbols keep InstrumentID 0 and order ids seen in existing or in an
+// earlier row mark a row as duplicate.
+func tradeRows(dataRows [][]string, firstRow int, colIndex map[string]int, profile ImportProfile,
+	instruments map[string]TradeInstrument, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	cols, err := tradeColumns(profile, colIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIDs := make(map[string]struct{}, len(existing))
+	for _, ex := range existing {
+		if ex.ExternalID != "" {
+			orderIDs[ex.ExternalID] = struct{}{}
+		}
+	}
+
+	result := make([]ParsedRow, 0, len(dataRows))
+	for i, row := range dataRows {
+		parsed, err := tradeRow(row, cols, profile, instruments)
+		parsed.RowNumber = firstRow + i
+		if err != nil {
+			parsed.Error = err.Error()
+			result = append(result, parsed)
+			continue
+		}
+
+		if parsed.Type == "income" || parsed.Type == "expense" {
+			applyRules(&parsed, groups)
+		}
+		if parsed.ExternalID != "" {
+			if _, found := orderIDs[parsed.ExternalID]; found {
+				parsed.IsDuplicate = true
+			}
+			orderIDs[parsed.ExternalID] = struct{}{}
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+// ambiguousDecimal matches numbers with a single comma that parseDecimal can only guess at, like
+// "1,5" or "12,345": the comma is a decimal separator for some brokers and a thousands separator
+// for others, and a wrong guess multiplies the quantity or price silently.
+var ambiguousDecimal = regexp.MustCompile(`^[+-]?[1-9][0-9]*,([0-9]|[0-9]{3,})$`)
+
+// tradeRow parses a single row of an investment profile.
+func tradeRow(row []string, cols map[string]int, profile ImportProfile, instruments map[string]TradeInstrument) (ParsedRow, error) {
+	field := func(role string) string {
+		idx := cols[role]
+		if idx < 0 || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+	number := func(role string) (float64, error) {
+		v := field(role)
+		if v == "" {
+			return 0, nil
+		}
+		if profile.DecimalSeparator == "" && ambiguousDecimal.MatchString(v) {
+			return 0, fmt.Errorf("ambiguous %s %q: set the decimal separator of the import profile", role, v)
+		}
+		n, err := profileNumberFormat(profile).parse(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", role, v)
+		}
+		return n, nil
+	}
+
+	var parsed ParsedRow
+	rawDate := field("date")
+	if rawDate == "" {
+		return parsed, fmt.Errorf("empty date")
+	}
+	t, err := time.Parse(profile.DateFormat, rawDate)
+	if err != nil {
+		return parsed, fmt.Errorf("invalid date %q: %v", rawDate, err)
+	}
+	parsed.Date = t.Format("2006-01-02")
+	parsed.Symbol = strings.ToUpper(field("symbol"))
+	parsed.Currency = strings.ToUpper(field("currency"))
+	parsed.ExternalID = field("orderId")
+
+	quantity, err := number("quantity")
+	if err != nil {
+		return parsed, err
+	}
+	price, err := number("price")
+	if err != nil {
+		return parsed, err
+	}
+	amount, err := number("amount")
+	if err != nil {
+		return parsed, err
+	}
+	fees, err := number("fees")
+	if err != nil {
+		return parsed, err
+	}
+	price, amount, fees = math.Abs(price), math.Abs(amount), roundMoney(math.Abs(fees))
+
+	if cols["side"] >= 0 {
+		side := field("side")
+		txType, ok := tradeSide(side)
+		if !ok {
+			return parsed, fmt.Errorf("unknown side %q", side)
+		}
+		parsed.Type = txType
+	} else {
+		parsed.Type = "buy"
+		if quantity < 0 {
+			parsed.Type = "sell"
+		}
+	}
+	quantity = math.Abs(quantity)
+
+	switch parsed.Type {
+	case "buy", "sell":
+		if parsed.Symbol == "" {
+			return parsed, fmt.Errorf("empty symbol")
+		}
+		if quantity == 0 {
+			return parsed, fmt.Errorf("quantity must not be zero")
+		}
+		// the price is derived from the net amount when the file has none
+		if price == 0 && amount > 0 {
+			if parsed.Type == "buy" {
+				price = (amount - fees) / quantity
+			} else {
+				price = (amount + fees) / quantity
+			}
+		}
+		if price <= 0 {
+			return parsed, fmt.Errorf("no price or amount")
+		}
+		if amount == 0 {
+			amount = quantity * price
+			if parsed.Type == "buy" {
+				amount += fees
+			} else {
+				amount -= fees
+			}
+		}
+		if parsed.Type == "buy" {
+			amount = -amount
+		}
+		parsed.Quantity = quantity
+		parsed.Price = price
+		parsed.Fees = fees
+
+		if inst, ok := instruments[parsed.Symbol]; ok {
+			if parsed.Currency != "" && inst.Currency != "" && parsed.Currency != inst.Currency {
+				return parsed, fmt.Errorf("currency %s does not match the instrument currency %s", parsed.Currency, inst.Currency)
+			}
+			parsed.InstrumentID = inst.ID
+		}
+	default:
+		// dividends and fees book a single amount on the cash account
+		if amount == 0 {
+			amount = quantity * price
+		}
+		if amount == 0 && parsed.Type == "expense" {
+			amount = fees
+		}
+		if amount == 0 {
+			return parsed, fmt.Errorf("no amount")
+		}
+		if parsed.Type == "expense" {
+			amount = -amount
+		}
+	}
+	parsed.Amount = roundMoney(amount)
+
+	parsed.Description = field("description")
+	if parsed.Description == "" {
+		parsed.Description = tradeDescription(parsed)
+	}
+	return parsed, nil
+}
+
+// tradeDescription builds a description for rows of files without a description column,
+// e.g. "Buy 10 AAPL" or "Dividend MSFT".
+func tradeDescription(row ParsedRow) string {
+	var parts []string
+	switch row.Type {
+	case "buy", "sell":
+		parts = []string{strings.ToUpper(row.Type[:1]) + row.Type[1:], strconv.FormatFloat(row.Quantity, 'f', -1, 64)}
+	case "income":
+		parts = []string{"Dividend"}
+	default:
+		parts = []string{"Fee"}
+	}
+	if row.Symbol != "" {
+		parts = append(parts, row.Symbol)
+	}
+	return strings.Join(parts, " ")
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ParseTrades reads a broker trade history CSV with an investment profile's column mappings.
+// Buys and sells are matched to instruments by symbol (upper-cased keys of instruments), rows of
+// unknown symbols keep InstrumentID 0 so the caller can create the instrument. Dividends and
+// fees get the category matching rules applied. Rows are flagged as duplicates by their broker
+// order id, rows without one are never flagged. It is a pure function with no DB access.
+func ParseTrades(r io.Reader, profile ImportProfile, instruments map[string]TradeInstrument, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	_, dataRows, skip, colIndex, err := readCSV(r, profile)
+	if err != nil {
+		return nil, err
+	}
+	return tradeRows(dataRows, skip+2, colIndex, profile, instruments, groups, existing)
+}
+
+// ParseTradesXLSX reads the profile's sheet of an XLSX workbook like ParseTrades does for CSV
+// files.
+func ParseTradesXLSX(data []byte, profile ImportProfile, instruments map[string]TradeInstrument, groups []CategoryRuleGroup, existing []ExistingTx) ([]ParsedRow, error) {
+	csvData, _, err := XLSXToCSV(data, profile.SheetName, profile.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	profile.CsvSeparator = xlsxSeparator
+	profile.DecimalSeparator, profile.ThousandsSeparator = "", ""
+	return ParseTrades(bytes.NewReader(csvData), profile, instruments, groups, existing)
+}
@@ -0,0 +1,184 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+)
+
+func brokerProfile() ImportProfile {
+	return ImportProfile{
+		Type:           ProfileTypeInvestment,
+		CsvSeparator:   ";",
+		DateColumn:     "Trade date",
+		DateFormat:     "02.01.2006",
+		SymbolColumn:   "Symbol",
+		SideColumn:     "Side",
+		QuantityColumn: "Qty",
+		PriceColumn:    "Price",
+		AmountColumn:   "Net amount",
+		FeesColumn:     "Fees",
+		CurrencyColumn: "Ccy",
+		OrderIDColumn:  "Order",
+	}
+}
+
+const brokerCSV = `Trade date;Order;Side;Symbol;Qty;Price;Fees;Net amount;Ccy
+02.03.2026;A-1;BUY;aapl;10;150,25;1,50;;USD
+03.03.2026;A-2;Sell;AAPL;4;160;1,00;639,00;USD
+04.03.2026;A-3;Buy to open;NEWCO;0,125;80;;;USD
+05.03.2026;A-4;Dividend;MSFT;;;;12,30;USD
+06.03.2026;A-5;Commission;;;;2,50;;USD
+07.03.2026;A-6;Transfer;AAPL;1;1;;;USD
+08.03.2026;A-7;Buy;MSFT;1;400;;;EUR
+09.03.2026;A-1;Buy;AAPL;10;150,25;1,50;;USD
+10.03.2026;A-8;Buy;AAPL;1,5;150;;;USD
+11.03.2026;A-9;Buy;AAPL;1;12,345;;;USD
+`
+
+func TestParseTrades(t *testing.T) {
+	instruments := map[string]TradeInstrument{
+		"AAPL": {ID: 1, Currency: "USD"},
+		"MSFT": {ID: 2, Currency: "USD"},
+	}
+	groups := []CategoryRuleGroup{{CategoryID: 5, Patterns: []CategoryRulePattern{{Pattern: "dividend"}}}}
+
+	rows, err := ParseTrades(strings.NewReader(brokerCSV), brokerProfile(), instruments, groups, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ParsedRow{
+		{RowNumber: 2, Date: "2026-03-02", Description: "Buy 10 AAPL", Amount: -1504, Type: "buy", ExternalID: "A-1",
+			Symbol: "AAPL", Quantity: 10, Price: 150.25, Fees: 1.5, Currency: "USD", InstrumentID: 1},
+		// the net amount is used as is, the price comes from the file
+		{RowNumber: 3, Date: "2026-03-03", Description: "Sell 4 AAPL", Amount: 639, Type: "sell", ExternalID: "A-2",
+			Symbol: "AAPL", Quantity: 4, Price: 160, Fees: 1, Currency: "USD", InstrumentID: 1},
+		// unknown symbols keep no instrument, fractions are not rounded
+		{RowNumber: 4, Date: "2026-03-04", Description: "Buy 0.125 NEWCO", Amount: -10, Type: "buy", ExternalID: "A-3",
+			Symbol: "NEWCO", Quantity: 0.125, Price: 80, Currency: "USD"},
+		{RowNumber: 5, Date: "2026-03-05", Description: "Dividend MSFT", Amount: 12.3, Type: "income", CategoryID: 5, ExternalID: "A-4",
+			Symbol: "MSFT", Currency: "USD"},
+		{RowNumber: 6, Date: "2026-03-06", Description: "Fee", Amount: -2.5, Type: "expense", ExternalID: "A-5", Currency: "USD"},
+		{RowNumber: 7, Error: `unknown side "Transfer"`},
+		{RowNumber: 8, Error: "currency EUR does not match the instrument currency USD"},
+		// the order id was already seen in the file
+		{RowNumber: 9, Date: "2026-03-09", Description: "Buy 10 AAPL", Amount: -1504, Type: "buy", ExternalID: "A-1", IsDuplicate: true,
+			Symbol: "AAPL", Quantity: 10, Price: 150.25, Fees: 1.5, Currency: "USD", InstrumentID: 1},
+		// without decimal separator in the profile a single comma could be either separator
+		{RowNumber: 10, Error: `ambiguous quantity "1,5": set the decimal separator of the import profile`},
+		{RowNumber: 11, Error: `ambiguous price "12,345": set the decimal separator of the import profile`},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %d: %+v", len(want), len(rows), rows)
+	}
+	for i := range want {
+		got := rows[i]
+		if want[i].Error != "" {
+			if got.Error != want[i].Error {
+				t.Errorf("row %d: got error %q, want %q", i, got.Error, want[i].Error)
+			}
+			continue
+		}
+		if got != want[i] {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, got, want[i])
+		}
+	}
+
+	t.Run("duplicates by order id", func(t *testing.T) {
+		existing := []ExistingTx{{Date: "2026-03-03", Amount: 639, ExternalID: "A-2"}, {Date: "2026-03-02", Amount: -1504}}
+		rows, err := ParseTrades(strings.NewReader(brokerCSV), brokerProfile(), instruments, nil, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			if want := r.RowNumber == 3 || r.RowNumber == 9; r.IsDuplicate != want {
+				t.Errorf("row %d: unexpected duplicate flag %v", r.RowNumber, r.IsDuplicate)
+			}
+		}
+	})
+
+	t.Run("signed quantity and net amount", func(t *testing.T) {
+		profile := ImportProfile{
+			Type:              ProfileTypeInvestment,
+			DateColumn:        "Date",
+			DateFormat:        "2006-01-02",
+			DescriptionColumn: "Text",
+			SymbolColumn:      "ISIN",
+			QuantityColumn:    "Shares",
+			AmountColumn:      "Amount",
+			FeesColumn:        "Fee",
+		}
+		data := "Date,Text,ISIN,Shares,Amount,Fee\n" +
+			"2026-03-02,Order 1,CH0012032048,5,\"1,005.00\",5\n" +
+			"2026-03-03,Order 2,CH0012032048,-2,-398.00,2\n"
+		rows, err := ParseTrades(strings.NewReader(data), profile, map[string]TradeInstrument{"CH0012032048": {ID: 9}}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("unexpected rows %+v", rows)
+		}
+		if r := rows[0]; r.Type != "buy" || r.Price != 200 || r.Amount != -1005 || r.InstrumentID != 9 || r.Description != "Order 1" {
+			t.Errorf("unexpected buy %+v", r)
+		}
+		if r := rows[1]; r.Type != "sell" || r.Quantity != 2 || r.Price != 200 || r.Amount != 398 {
+			t.Errorf("unexpected sell %+v", r)
+		}
+	})
+
+	t.Run("fractional shares with a decimal comma", func(t *testing.T) {
+		profile := brokerProfile()
+		profile.DecimalSeparator = ","
+		profile.ThousandsSeparator = "."
+		rows, err := ParseTrades(strings.NewReader(brokerCSV), profile, instruments, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := rows[8]; r.Error != "" || r.Quantity != 1.5 || r.Price != 150 {
+			t.Errorf("expected 1.5 shares, got %+v", r)
+		}
+		if r := rows[9]; r.Error != "" || r.Price != 12.345 {
+			t.Errorf("expected a price of 12.345, got %+v", r)
+		}
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		profile := brokerProfile()
+		profile.FeesColumn = "Commission"
+		if _, err := ParseTrades(strings.NewReader(brokerCSV), profile, nil, nil, nil); err == nil {
+			t.Error("expected an error for a missing column")
+		}
+	})
+}
+
+func TestParsePreview_Investment(t *testing.T) {
+	result, err := ParsePreview(strings.NewReader(brokerCSV), brokerProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRows != 10 || len(result.Rows) != 10 {
+		t.Fatalf("unexpected preview %+v", result)
+	}
+	if r := result.Rows[0]; r.RowNumber != 1 || r.Type != "buy" || r.InstrumentID != 0 || r.CategoryID != 0 {
+		t.Errorf("unexpected first row %+v", r)
+	}
+}
+
+func TestTradeSide(t *testing.T) {
+	tcs := map[string]string{
+		"BUY":                 "buy",
+		"Bought":              "buy",
+		"Kauf":                "buy",
+		"sell to close":       "sell",
+		"SLD":                 "sell",
+		"Dividend (ordinary)": "income",
+		"Withholding tax":     "expense",
+		"":                    "",
+		"split":               "",
+	}
+	for in, want := range tcs {
+		got, ok := tradeSide(in)
+		if got != want || ok != (want != "") {
+			t.Errorf("%q: got %q %v, want %q", in, got, ok, want)
+		}
+	}
+}
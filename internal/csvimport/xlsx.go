@@ -0,0 +1,391 @@
+package csvimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxSeparator is the separator of the CSV an XLSX sheet is converted to before it goes
+// through the CSV pipeline.
+const xlsxSeparator = ","
+
+// xlsxMaxPartSize bounds the uncompressed size of a single part of the workbook.
+const xlsxMaxPartSize = 64 << 20
+
+// isXLSX reports whether data is an Office Open XML workbook: a zip file with an xl/ folder.
+func isXLSX(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) && bytes.Contains(data, []byte("xl/workbook.xml"))
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string, either plain or split into rich text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// xlsxFile gives access to the parts of a workbook.
+type xlsxFile struct {
+	files map[string]*zip.File
+}
+
+// decode unmarshals a part of the workbook; a missing optional part leaves v untouched.
+func (f xlsxFile) decode(name string, v any, optional bool) error {
+	zf, ok := f.files[name]
+	if !ok {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("%s not found", name)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	if err := xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxBuiltinDateFormat reports whether a built-in number format id displays a date or time.
+func xlsxBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// xlsxDateFormatCode reports whether a custom number format displays a date, i.e. it contains
+// date or time placeholders outside of quoted text, escapes and [color]/[$currency] sections.
+func xlsxDateFormatCode(code string) bool {
+	if strings.EqualFold(code, "General") {
+		return false
+	}
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // the next character is literal or padding
+		case strings.IndexByte("dmyhsDMYHS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxSerialToTime converts an Excel date serial number. Serials count days since 1899-12-30,
+// which absorbs Excel's fictitious 1900-02-29, or since 1904-01-01 in 1904 date system workbooks.
+func xlsxSerialToTime(serial float64, date1904 bool) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+// xlsxColumn returns the zero based column of a cell reference such as "C12", or -1.
+func xlsxColumn(ref string) int {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+// XLSXToCSV converts a sheet of an XLSX workbook to CSV so it can be read with an import
+// profile. The sheet is chosen by name, the first sheet is used if name is empty. Numbers are
+// written without formatting. Cells with a date format are written with dateFormat if it is
+// set, otherwise as ISO dates, with the time if it is not midnight. It also returns the names
+// of all sheets of the workbook.
+func XLSXToCSV(data []byte, sheetName, dateFormat string) (csvData []byte, sheets []string, err error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading XLSX: %w", err)
+	}
+	f := xlsxFile{files: make(map[string]*zip.File, len(zr.File))}
+	for _, zf := range zr.File {
+		f.files[zf.Name] = zf
+	}
+
+	var wb xlsxWorkbook
+	if err := f.decode("xl/workbook.xml", &wb, false); err != nil {
+		return nil, nil, fmt.Errorf("error reading XLSX: %w", err)
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, nil, ErrValidation("the workbook has no sheets")
+	}
+	var rels xlsxRelationships
+	if err := f.decode("xl/_rels/workbook.xml.rels", &rels, false); err != nil {
+		return nil, nil, fmt.Errorf("error reading XLSX: %w", err)
+	}
+
+	sheetIdx := -1
+	for i, s := range wb.Sheets {
+		sheets = append(sheets, s.Name)
+		if sheetIdx < 0 && (sheetName == "" || s.Name == sheetName) {
+			sheetIdx = i
+		}
+	}
+	if sheetIdx < 0 {
+		return nil, sheets, ErrValidation(fmt.Sprintf("sheet %q not found, the workbook has: %s", sheetName, strings.Join(sheets, ", ")))
+	}
+	target := ""
+	for _, rel := range rels.Items {
+		if rel.ID == wb.Sheets[sheetIdx].RID {
+			target = rel.Target
+		}
+	}
+	if target == "" {
+		return nil, sheets, fmt.Errorf("error reading XLSX: sheet %q has no data", wb.Sheets[sheetIdx].Name)
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := f.decode("xl/sharedStrings.xml", &shared, true); err != nil {
+		return nil, sheets, fmt.Errorf("error reading XLSX: %w", err)
+	}
+	var styles xlsxStyles
+	if err := f.decode("xl/styles.xml", &styles, true); err != nil {
+		return nil, sheets, fmt.Errorf("error reading XLSX: %w", err)
+	}
+	customDate := map[int]bool{}
+	for _, nf := range styles.NumFmts {
+		customDate[nf.ID] = xlsxDateFormatCode(nf.Code)
+	}
+	isDateStyle := func(style int) bool {
+		if style < 0 || style >= len(styles.CellXfs) {
+			return false
+		}
+		id := styles.CellXfs[style].NumFmtID
+		if date, ok := customDate[id]; ok {
+			return date
+		}
+		return xlsxBuiltinDateFormat(id)
+	}
+
+	var ws xlsxWorksheet
+	if err := f.decode(target, &ws, false); err != nil {
+		return nil, sheets, fmt.Errorf("error reading XLSX: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = rune(xlsxSeparator[0])
+	for _, row := range ws.Rows {
+		var record []string
+		for i, c := range row.Cells {
+			col := xlsxColumn(c.Ref)
+			if col < 0 {
+				col = i // the reference is optional, cells are then consecutive
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			value, err := xlsxCellValue(c, shared.Items, isDateStyle(c.Style), wb.Properties.Date1904, dateFormat)
+			if err != nil {
+				return nil, sheets, fmt.Errorf("error reading XLSX: cell %s: %w", c.Ref, err)
+			}
+			record[col] = value
+		}
+		// trailing empty cells are dropped, so preamble rows keep fewer columns than the table
+		for len(record) > 0 && strings.TrimSpace(record[len(record)-1]) == "" {
+			record = record[:len(record)-1]
+		}
+		if len(record) == 0 {
+			continue
+		}
+		if err := w.Write(record); err != nil {
+			return nil, sheets, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, sheets, err
+	}
+	return buf.Bytes(), sheets, nil
+}
+
+// xlsxCellValue returns the text of a cell as it is written to the CSV.
+func xlsxCellValue(c xlsxCell, shared []xlsxText, dateStyle, date1904 bool, dateFormat string) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || i < 0 || i >= len(shared) {
+			return "", fmt.Errorf("invalid shared string index %q", c.Value)
+		}
+		return strings.TrimSpace(shared[i].String()), nil
+	case "inlineStr":
+		return strings.TrimSpace(c.Inline.String()), nil
+	case "str", "e", "d":
+		// formula results, errors such as #N/A and ISO 8601 dates are kept as they are
+		return strings.TrimSpace(c.Value), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+
+	v := strings.TrimSpace(c.Value)
+	if v == "" {
+		return "", nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q", v)
+	}
+	if dateStyle {
+		t := xlsxSerialToTime(n, date1904)
+		if dateFormat != "" {
+			return t.Format(dateFormat), nil
+		}
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+			return t.Format("2006-01-02"), nil
+		}
+		return t.Format("2006-01-02 15:04:05"), nil
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64), nil
+}
+
+// detectSkipRows returns the number of preamble rows before the table of a converted sheet:
+// the index of the first row that has the most common column count.
+func detectSkipRows(csvData []byte) int {
+	reader := csv.NewReader(bytes.NewReader(csvData))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return 0
+	}
+	modeCount, _ := dominantColumnCount(rows)
+	for i, row := range rows {
+		if len(row) == modeCount {
+			return i
+		}
+	}
+	return 0
+}
+
+// ParsePreviewXLSX converts the profile's sheet of an XLSX workbook and previews it like a CSV
+// file, detecting the preamble rows, columns and date format where the profile leaves them
+// empty. The result lists the sheets of the workbook.
+func ParsePreviewXLSX(data []byte, profile ImportProfile) (PreviewResult, error) {
+	csvData, sheets, err := XLSXToCSV(data, profile.SheetName, profile.DateFormat)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	detectSkip := profile.CsvSeparator == ""
+	profile.CsvSeparator = xlsxSeparator
+	profile.DecimalSeparator, profile.ThousandsSeparator = "", ""
+	if detectSkip {
+		profile.SkipRows = detectSkipRows(csvData)
+	}
+
+	result, err := ParsePreviewWithAutoDetect(csvData, profile)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	result.Sheets = sheets
+	if detectSkip {
+		result.DetectedSeparator = xlsxSeparator
+		result.DetectedSkipRows = profile.SkipRows
+	}
+	return result, nil
+}
+
+// ParseXLSX reads the profile's sheet of an XLSX workbook with the profile's column mappings,
+// applies category matching rules and detects duplicates, like Parse does for CSV files.
+func ParseXLSX(data []byte, profile ImportProfile, groups []CategoryRuleGroup, existing []ExistingTx, opts ...ParseOption) ([]ParsedRow, error) {
+	csvData, _, err := XLSXToCSV(data, profile.SheetName, profile.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	profile.CsvSeparator = xlsxSeparator
+	profile.DecimalSeparator, profile.ThousandsSeparator = "", ""
+	return Parse(bytes.NewReader(csvData), profile, groups, existing, opts...)
+}
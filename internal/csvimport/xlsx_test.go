@@ -0,0 +1,181 @@
+package csvimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// buildXLSX returns a minimal workbook with the given sheets (name -> sheetData XML).
+func buildXLSX(t *testing.T, date1904 bool, sheets ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	props := ""
+	if date1904 {
+		props = `<workbookPr date1904="1"/>`
+	}
+	wb := `<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` + props + `<sheets>`
+	rels := `<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	for i, s := range sheets {
+		id := string(rune('1' + i))
+		wb += `<sheet name="` + s[0] + `" sheetId="` + id + `" r:id="rId` + id + `"/>`
+		rels += `<Relationship Id="rId` + id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + id + `.xml"/>`
+		write("xl/worksheets/sheet"+id+".xml", `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+s[1]+`</sheetData></worksheet>`)
+	}
+	write("xl/workbook.xml", wb+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", rels+`</Relationships>`)
+	write("xl/sharedStrings.xml", `<?xml version="1.0" encoding="UTF-8"?><sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<si><t>Date</t></si><si><t>Text</t></si><si><t>Amount</t></si><si><r><t>Super</t></r><r><t>market</t></r></si><si><t>Account statement</t></si></sst>`)
+	// style 0: general, 1: built-in date (14), 2: custom date, 3: custom currency
+	write("xl/styles.xml", `<?xml version="1.0" encoding="UTF-8"?><styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<numFmts><numFmt numFmtId="164" formatCode="dd\.mm\.yyyy\ hh:mm"/><numFmt numFmtId="165" formatCode="[$CHF]\ #,##0.00;[Red]\-#,##0.00"/></numFmts>`+
+		`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const xlsxStatement = `<row r="1"><c r="A1" t="s"><v>4</v></c></row>
+<row r="3"><c r="A3" t="s"><v>0</v></c><c r="B3" t="s"><v>1</v></c><c r="C3" t="s"><v>2</v></c></row>
+<row r="4"><c r="A4" s="1"><v>46082</v></c><c r="B4" t="inlineStr"><is><t>ACME Corp</t></is></c><c r="C4" s="3"><v>1500</v></c></row>
+<row r="5"><c r="A5" s="2"><v>46083.5</v></c><c r="B5" t="s"><v>3</v></c><c r="C5"><v>-45.300000000000004</v></c></row>
+<row r="6"><c r="A6" s="1"><v>46086</v></c><c r="B6" t="str"><v>Rent</v></c><c r="C6"><v>-1.2E3</v></c></row>`
+
+func TestXLSXToCSV(t *testing.T) {
+	data := buildXLSX(t, false, [2]string{"Info", `<row r="1"><c r="A1" t="s"><v>1</v></c></row>`}, [2]string{"Transactions", xlsxStatement})
+	if got := DetectFormat(data); got != FormatXLSX {
+		t.Errorf("expected XLSX to be detected, got %q", got)
+	}
+
+	csvData, sheets, err := XLSXToCSV(data, "Transactions", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 || sheets[0] != "Info" || sheets[1] != "Transactions" {
+		t.Errorf("unexpected sheets %v", sheets)
+	}
+	want := "Account statement\n" +
+		"Date,Text,Amount\n" +
+		"2026-03-01,ACME Corp,1500\n" +
+		"2026-03-02 12:00:00,Supermarket,-45.300000000000004\n" +
+		"2026-03-05,Rent,-1200\n"
+	if string(csvData) != want {
+		t.Errorf("unexpected CSV:\n%s", csvData)
+	}
+
+	t.Run("first sheet by default", func(t *testing.T) {
+		csvData, _, err := XLSXToCSV(data, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(csvData) != "Text\n" {
+			t.Errorf("unexpected CSV %q", csvData)
+		}
+	})
+
+	t.Run("profile date format", func(t *testing.T) {
+		csvData, _, err := XLSXToCSV(data, "Transactions", "02.01.2006")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(csvData, []byte("02.03.2026,Supermarket")) {
+			t.Errorf("expected dates in the profile format, got:\n%s", csvData)
+		}
+	})
+
+	t.Run("1904 date system", func(t *testing.T) {
+		data := buildXLSX(t, true, [2]string{"Sheet1", `<row r="1"><c r="A1" s="1"><v>44620</v></c></row>`})
+		csvData, _, err := XLSXToCSV(data, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(csvData) != "2026-03-01\n" {
+			t.Errorf("unexpected CSV %q", csvData)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := XLSXToCSV(data, "Missing", "")
+		var valErr ErrValidation
+		if !errors.As(err, &valErr) {
+			t.Errorf("expected a validation error for a missing sheet, got %v", err)
+		}
+		if _, _, err := XLSXToCSV([]byte("PK\x03\x04 not a zip"), "", ""); err == nil {
+			t.Error("expected an error for a broken file")
+		}
+	})
+}
+
+func TestParseXLSX(t *testing.T) {
+	data := buildXLSX(t, false, [2]string{"Transactions", xlsxStatement})
+
+	preview, err := ParsePreviewXLSX(data, ImportProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.DetectedSkipRows != 1 || preview.DetectedDateFormat != "2006-01-02" || len(preview.Sheets) != 1 {
+		t.Errorf("unexpected detection %+v", preview)
+	}
+	if preview.DetectedColumns == nil || preview.DetectedColumns.DateColumn != "Date" || preview.DetectedColumns.AmountColumn != "Amount" {
+		t.Fatalf("unexpected columns %+v", preview.DetectedColumns)
+	}
+
+	profile := ImportProfile{
+		SkipRows:          preview.DetectedSkipRows,
+		DateColumn:        "Date",
+		DateFormat:        "02.01.2006",
+		DescriptionColumn: "Text",
+		AmountColumn:      "Amount",
+	}
+	groups := []CategoryRuleGroup{{CategoryID: 3, Patterns: []CategoryRulePattern{{Pattern: "rent"}}}}
+	existing := []ExistingTx{{Date: "2026-03-01", Amount: 1500}}
+	rows, err := ParseXLSX(data, profile, groups, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ParsedRow{
+		{RowNumber: 3, Date: "2026-03-01", Description: "ACME Corp", Amount: 1500, Type: "income", IsDuplicate: true},
+		{RowNumber: 4, Date: "2026-03-02", Description: "Supermarket", Amount: -45.30, Type: "expense"},
+		{RowNumber: 5, Date: "2026-03-05", Description: "Rent", Amount: -1200, Type: "expense", CategoryID: 3},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestXLSXDateFormatCode(t *testing.T) {
+	tcs := map[string]bool{
+		"General":                    false,
+		"#,##0.00":                   false,
+		`[$CHF]\ #,##0.00`:           false,
+		`"days: "0`:                  false,
+		"yyyy-mm-dd":                 true,
+		`dd\.mm\.yyyy`:               true,
+		"[$-407]dd/mm/yyyy;@":        true,
+		"[h]:mm:ss":                  true,
+		`_-* #,##0.00_-;[Red]-#,##0`: false,
+	}
+	for code, want := range tcs {
+		if got := xlsxDateFormatCode(code); got != want {
+			t.Errorf("%q: got %v, want %v", code, got, want)
+		}
+	}
+}
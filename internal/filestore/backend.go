@@ -0,0 +1,174 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrObjectNotFound is returned by a Backend when no content is stored under a key.
+var ErrObjectNotFound = errors.New("stored object not found")
+
+// Backend stores the content of attachments; metadata always stays in the database.
+// Keys are slash separated relative paths like "2025/03/15_a1b2c3d4.jpg".
+type Backend interface {
+	// Name identifies the backend in logs and messages.
+	Name() string
+	Put(ctx context.Context, key string, content []byte) error
+	// Get returns ErrObjectNotFound if the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Stat returns the size of the stored content without reading it, or ErrObjectNotFound.
+	Stat(ctx context.Context, key string) (int64, error)
+	// Delete removes the content of a key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalBackend stores attachments as files below a base directory.
+type LocalBackend struct {
+	baseDir string
+}
+
+// NewLocalBackend returns a backend storing files below baseDir.
+func NewLocalBackend(baseDir string) (*LocalBackend, error) {
+	if baseDir == "" {
+		return nil, fmt.Errorf("baseDir cannot be empty")
+	}
+	return &LocalBackend{baseDir: baseDir}, nil
+}
+
+func (b *LocalBackend) Name() string { return "local" }
+
+// Path returns the absolute file path of a key.
+// It prevents path traversal by verifying the resolved path is within baseDir.
+func (b *LocalBackend) Path(key string) (string, error) {
+	absPath := filepath.Join(b.baseDir, filepath.FromSlash(key))
+	absPath, err := filepath.Abs(absPath)
+	if err != nil {
+		return "", fmt.Errorf("resolving path: %w", err)
+	}
+
+	// Prevent path traversal
+	absBase, err := filepath.Abs(b.baseDir)
+	if err != nil {
+		return "", fmt.Errorf("resolving base: %w", err)
+	}
+	if !strings.HasPrefix(absPath, absBase+string(filepath.Separator)) {
+		return "", fmt.Errorf("path traversal detected")
+	}
+
+	return absPath, nil
+}
+
+func (b *LocalBackend) Put(_ context.Context, key string, content []byte) error {
+	absPath, err := b.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o750); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(absPath, content, 0o600); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Get(_ context.Context, key string) ([]byte, error) {
+	absPath, err := b.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(absPath) //nolint:gosec // path checked against baseDir
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	return content, nil
+}
+
+func (b *LocalBackend) Stat(_ context.Context, key string) (int64, error) {
+	absPath, err := b.Path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, ErrObjectNotFound
+		}
+		return 0, fmt.Errorf("reading file info: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (b *LocalBackend) Delete(_ context.Context, key string) error {
+	absPath, err := b.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(absPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing file: %w", err)
+	}
+	return nil
+}
+
+// MigrationResult summarizes a MigrateTo run.
+type MigrationResult struct {
+	// Copied is the number of objects (files and thumbnails) written to the target backend.
+	Copied int
+	// Missing lists the keys referenced in the database that were not found in the source backend.
+	Missing []string
+}
+
+// MigrateTo copies the content of every attachment, including thumbnails, from the current backend
+// to dst, encrypting it if the store is configured with encryption. When deleteSource is set,
+// objects are removed from the current backend once copied. The Store keeps using its current
+// backend; restart with the new backend configured afterwards.
+// Running it again after an interruption is safe.
+func (s *Store) MigrateTo(ctx context.Context, dst Backend, deleteSource bool) (MigrationResult, error) {
+	var result MigrationResult
+	if s.cipher != nil {
+		if _, ok := dst.(*encryptedBackend); !ok {
+			dst = &encryptedBackend{Backend: dst, cipher: s.cipher}
+		}
+	}
+	var records []dbAttachment
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&records).Error; err != nil {
+		return result, fmt.Errorf("fetching attachments: %w", err)
+	}
+
+	for _, rec := range records {
+		for _, key := range []string{rec.StoragePath, rec.ThumbnailPath} {
+			if key == "" {
+				continue
+			}
+			content, err := s.backend.Get(ctx, key)
+			if err != nil {
+				if errors.Is(err, ErrObjectNotFound) {
+					// already moved by an interrupted run
+					if _, err := dst.Get(ctx, key); err == nil {
+						continue
+					}
+					result.Missing = append(result.Missing, key)
+					continue
+				}
+				return result, fmt.Errorf("reading %s from %s: %w", key, s.backend.Name(), err)
+			}
+			if err := dst.Put(ctx, key, content); err != nil {
+				return result, fmt.Errorf("writing %s to %s: %w", key, dst.Name(), err)
+			}
+			result.Copied++
+			if deleteSource {
+				if err := s.backend.Delete(ctx, key); err != nil {
+					return result, fmt.Errorf("deleting %s from %s: %w", key, s.backend.Name(), err)
+				}
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownKey = errors.New("content is encrypted with an unknown key")
+	ErrNoKey      = errors.New("encryption is not configured")
+)
+
+// MinSecretLength is the minimum length of an encryption secret.
+const MinSecretLength = 16
+
+// encryptedMagic prefixes encrypted content; it is followed by the key fingerprint, the nonce and
+// the AES-GCM ciphertext. Content without it is plain, e.g. stored before encryption was enabled.
+var encryptedMagic = []byte("ETNAENC1")
+
+const fingerprintSize = 8
+
+// encryptionOverhead is the number of bytes Encrypt adds to the content: the magic, the key
+// fingerprint, the 12 byte GCM nonce and the 16 byte authentication tag.
+const encryptionOverhead = 8 + fingerprintSize + 12 + 16
+
+type aesKey struct {
+	aead        cipher.AEAD
+	fingerprint []byte
+}
+
+// Cipher encrypts attachment content with AES-256-GCM. Keys are derived from secrets with HKDF-SHA256;
+// the current key encrypts, the previous keys are only used to decrypt content written before a rotation.
+type Cipher struct {
+	current *aesKey
+	keys    map[string]*aesKey
+}
+
+// NewCipher returns a cipher encrypting with the key derived from secret and decrypting with it and
+// the previous secrets. An empty secret with previous secrets gives a cipher that only decrypts,
+// used to turn encryption off again.
+func NewCipher(secret string, previous ...string) (*Cipher, error) {
+	c := &Cipher{keys: map[string]*aesKey{}}
+	if secret != "" {
+		k, err := deriveKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		c.current = k
+		c.keys[string(k.fingerprint)] = k
+	}
+	for _, p := range previous {
+		k, err := deriveKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("previous secret: %w", err)
+		}
+		c.keys[string(k.fingerprint)] = k
+	}
+	return c, nil
+}
+
+func deriveKey(secret string) (*aesKey, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("encryption secret must be at least %d characters long", MinSecretLength)
+	}
+	raw, err := hkdf.Key(sha256.New, []byte(secret), nil, "etna-finance attachments v1", 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &aesKey{aead: aead, fingerprint: sum[:fingerprintSize]}, nil
+}
+
+// CanEncrypt reports whether the cipher has a current key.
+func (c *Cipher) CanEncrypt() bool {
+	return c != nil && c.current != nil
+}
+
+// Encrypt encrypts content with the current key.
+func (c *Cipher) Encrypt(content []byte) ([]byte, error) {
+	if !c.CanEncrypt() {
+		return nil, ErrNoKey
+	}
+	nonceSize := c.current.aead.NonceSize()
+	out := make([]byte, 0, len(encryptedMagic)+fingerprintSize+nonceSize+len(content)+c.current.aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, c.current.fingerprint...)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	out = append(out, nonce...)
+	return c.current.aead.Seal(out, nonce, content, nil), nil
+}
+
+// Decrypt returns the plain content; content that is not encrypted is returned unchanged.
+func (c *Cipher) Decrypt(content []byte) ([]byte, error) {
+	if !IsEncrypted(content) {
+		return content, nil
+	}
+	if c == nil {
+		return nil, ErrNoKey
+	}
+	rest := content[len(encryptedMagic):]
+	if len(rest) < fingerprintSize {
+		return nil, fmt.Errorf("truncated encrypted content")
+	}
+	k, ok := c.keys[string(rest[:fingerprintSize])]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	rest = rest[fingerprintSize:]
+	nonceSize := k.aead.NonceSize()
+	if len(rest) < nonceSize {
+		return nil, fmt.Errorf("truncated encrypted content")
+	}
+	plain, err := k.aead.Open(nil, rest[:nonceSize], rest[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting content: %w", err)
+	}
+	return plain, nil
+}
+
+// IsEncrypted reports whether content was produced by Cipher.Encrypt.
+func IsEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, encryptedMagic)
+}
+
+// encryptedBackend encrypts content written to the wrapped backend and decrypts it when read.
+type encryptedBackend struct {
+	Backend
+	cipher *Cipher
+}
+
+func (b *encryptedBackend) Put(ctx context.Context, key string, content []byte) error {
+	if b.cipher.CanEncrypt() {
+		enc, err := b.cipher.Encrypt(content)
+		if err != nil {
+			return err
+		}
+		content = enc
+	}
+	return b.Backend.Put(ctx, key, content)
+}
+
+func (b *encryptedBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	content, err := b.Backend.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return b.cipher.Decrypt(content)
+}
+
+// Reencrypt rewrites every stored file and thumbnail with the current key of the configured cipher,
+// or in plain if it only holds previous keys. It is used after rotating the encryption secret and to
+// encrypt files stored before encryption was enabled.
+func (s *Store) Reencrypt(ctx context.Context) (MigrationResult, error) {
+	if s.cipher == nil {
+		return MigrationResult{}, ErrNoKey
+	}
+	return s.MigrateTo(ctx, s.backend, false)
+}
+
+// ExportContent returns the content of an attachment for a backup; it is encrypted with the current
+// key when the store is configured to encrypt backups.
+func (s *Store) ExportContent(ctx context.Context, id uint) (content []byte, encrypted bool, err error) {
+	content, err = s.ReadFile(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !s.encryptBackups || !s.cipher.CanEncrypt() {
+		return content, false, nil
+	}
+	content, err = s.cipher.Encrypt(content)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// DecryptExported returns the plain content of an attachment exported with ExportContent.
+func (s *Store) DecryptExported(content []byte) ([]byte, error) {
+	return s.cipher.Decrypt(content)
+}
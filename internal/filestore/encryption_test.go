@@ -0,0 +1,260 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-bumbu/testdbs"
+)
+
+const (
+	testSecret    = "correct horse battery staple"
+	testNewSecret = "another long enough secret"
+)
+
+func testCipher(t *testing.T, secret string, previous ...string) *Cipher {
+	t.Helper()
+	c, err := NewCipher(secret, previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipher(t *testing.T) {
+	c := testCipher(t, testSecret)
+	plain := []byte("%PDF-1.4 secret content")
+
+	enc, err := c.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) || bytes.Contains(enc, plain) {
+		t.Fatal("expected encrypted content")
+	}
+	got, err := c.Decrypt(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("unexpected plain content %q", got)
+	}
+
+	t.Run("plain content passes through", func(t *testing.T) {
+		got, err := c.Decrypt(plain)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("expected plain content unchanged, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		if _, err := testCipher(t, testNewSecret).Decrypt(enc); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+	})
+
+	t.Run("previous key decrypts", func(t *testing.T) {
+		rotated := testCipher(t, testNewSecret, testSecret)
+		got, err := rotated.Decrypt(enc)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("expected content readable with the previous key, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("tampered content", func(t *testing.T) {
+		tampered := bytes.Clone(enc)
+		tampered[len(tampered)-1] ^= 0xff
+		if _, err := c.Decrypt(tampered); err == nil {
+			t.Error("expected error for tampered content")
+		}
+	})
+
+	t.Run("decrypt only", func(t *testing.T) {
+		c := testCipher(t, "", testSecret)
+		if _, err := c.Encrypt(plain); !errors.Is(err, ErrNoKey) {
+			t.Errorf("expected ErrNoKey, got %v", err)
+		}
+	})
+
+	t.Run("short secret", func(t *testing.T) {
+		if _, err := NewCipher("short"); err == nil {
+			t.Error("expected error for a short secret")
+		}
+	})
+}
+
+func TestEncryptedStore(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			conn := db.ConnDbName("TestEncryptedStore")
+			baseDir := t.TempDir()
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+			// a file stored before encryption was enabled
+			plainStore, err := New(conn, baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			legacy := []byte("%PDF-1.4 stored in plain")
+			legacyID, err := plainStore.SaveRaw(ctx, date, legacy, "legacy.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := New(conn, baseDir, 10*1024*1024, WithEncryption(testCipher(t, testSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			png := testPNG(t, 400, 200)
+			pngID, err := store.SaveRaw(ctx, date, png, "photo.png", "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			local, _ := NewLocalBackend(baseDir)
+			onDisk := func(id uint) []byte {
+				t.Helper()
+				att, err := store.Get(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, err := local.Get(ctx, att.StoragePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return content
+			}
+			if !IsEncrypted(onDisk(pngID)) {
+				t.Error("expected the new file to be encrypted on disk")
+			}
+			if IsEncrypted(onDisk(legacyID)) {
+				t.Error("expected the legacy file to stay in plain until re-encrypted")
+			}
+
+			for id, want := range map[uint][]byte{pngID: png, legacyID: legacy} {
+				got, err := store.ReadFile(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("attachment %d: unexpected content", id)
+				}
+			}
+			if _, err := store.ReadThumbnail(ctx, pngID); err != nil {
+				t.Errorf("thumbnail not readable: %v", err)
+			}
+			if _, err := store.GetFilePath(ctx, pngID); !errors.Is(err, ErrNoLocalPath) {
+				t.Errorf("expected ErrNoLocalPath for encrypted files, got %v", err)
+			}
+			report, err := store.CheckIntegrity(ctx, map[uint]int{pngID: 1, legacyID: 1}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.SizeMismatches) != 0 || len(report.Missing) != 0 {
+				t.Errorf("expected encrypted and plain files to pass the check, got %+v", report)
+			}
+
+			// rotate the secret
+			rotated, err := New(conn, baseDir, 10*1024*1024, WithEncryption(testCipher(t, testNewSecret, testSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := rotated.Reencrypt(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// two files and the thumbnail of the png
+			if res.Copied != 3 || len(res.Missing) != 0 {
+				t.Errorf("unexpected result %+v", res)
+			}
+			if !IsEncrypted(onDisk(legacyID)) {
+				t.Error("expected the legacy file to be encrypted after Reencrypt")
+			}
+
+			// the old secret is no longer needed
+			current, err := New(conn, baseDir, 10*1024*1024, WithEncryption(testCipher(t, testNewSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, want := range map[uint][]byte{pngID: png, legacyID: legacy} {
+				got, err := current.ReadFile(ctx, id)
+				if err != nil {
+					t.Fatalf("attachment %d not readable with the new secret: %v", id, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("attachment %d: unexpected content", id)
+				}
+			}
+			if _, err := current.ReadThumbnail(ctx, pngID); err != nil {
+				t.Errorf("thumbnail not readable with the new secret: %v", err)
+			}
+
+			// deduplication compares plain content
+			dupID, err := current.SaveRaw(ctx, date, png, "copy.png", "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dupID != pngID {
+				t.Errorf("expected the duplicate to reuse attachment %d, got %d", pngID, dupID)
+			}
+
+			if _, err := plainStore.Reencrypt(ctx); !errors.Is(err, ErrNoKey) {
+				t.Errorf("expected ErrNoKey without encryption, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExportContent(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			conn := db.ConnDbName("TestExportContent")
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+			content := []byte("%PDF-1.4 exported")
+
+			plainBackups, err := New(conn, t.TempDir(), 10*1024*1024, WithEncryption(testCipher(t, testSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := plainBackups.SaveRaw(ctx, date, content, "a.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, encrypted, err := plainBackups.ExportContent(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted || !bytes.Equal(got, content) {
+				t.Errorf("expected plain export, got encrypted=%v", encrypted)
+			}
+
+			encBackups, err := New(conn, t.TempDir(), 10*1024*1024, WithEncryption(testCipher(t, testSecret)), WithEncryptedBackups(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err = encBackups.SaveRaw(ctx, date, []byte("%PDF-1.4 exported encrypted"), "b.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, encrypted, err = encBackups.ExportContent(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !encrypted || !IsEncrypted(got) {
+				t.Fatal("expected encrypted export")
+			}
+			plain, err := encBackups.DecryptExported(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plain) != "%PDF-1.4 exported encrypted" {
+				t.Errorf("unexpected decrypted content %q", plain)
+			}
+		})
+	}
+}
@@ -3,15 +3,13 @@ package filestore
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,6 +19,7 @@ var (
 	ErrNotFound       = errors.New("attachment not found")
 	ErrMimeNotAllowed = errors.New("mime type not allowed")
 	ErrTooLarge       = errors.New("file too large")
+	ErrNoLocalPath    = errors.New("attachment is not stored on the local filesystem")
 )
 
 var allowedMimeTypes = map[string]bool{
@@ -44,9 +43,18 @@ type dbAttachment struct {
 	StoragePath  string `gorm:"size:512;not null"`
 	MimeType     string `gorm:"size:100;not null"`
 	FileSize     int64  `gorm:"not null"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	// ContentHash is the hex SHA-256 of the content, used to store identical uploads only once.
+	// It is NULL for deleted records and for files stored before deduplication that were not hashed yet.
+	ContentHash *string `gorm:"size:64;uniqueIndex:idx_attachment_content_hash"`
+	// HashSkipped marks files stored before deduplication that BackfillContentHashes could not hash,
+	// because the file is missing or has the content of another record; they are not retried.
+	HashSkipped bool `gorm:"not null;default:false"`
+	// RefCount is the number of Save calls sharing this record; the file is removed when it drops to 0.
+	RefCount      int    `gorm:"not null;default:1"`
+	ThumbnailPath string `gorm:"size:512"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
 }
 
 // Attachment is the public metadata struct returned to callers.
@@ -56,34 +64,154 @@ type Attachment struct {
 	StoragePath  string
 	MimeType     string
 	FileSize     int64
+	ContentHash  string
+	RefCount     int
 }
 
-// Store manages file attachments on disk and in the database.
+// Store manages file attachments, keeping metadata in the database and content in a Backend.
 type Store struct {
-	db      *gorm.DB
-	baseDir string
-	maxSize int64
+	db             *gorm.DB
+	backend        Backend
+	maxSize        int64
+	cipher         *Cipher
+	encryptBackups bool
 }
 
-// New creates a new Store, running AutoMigrate for the attachment table.
-func New(db *gorm.DB, baseDir string, maxSize int64) (*Store, error) {
+// Option configures optional Store behaviour.
+type Option func(*Store)
+
+// WithEncryption encrypts stored files and thumbnails with the given cipher; files stored in plain
+// before remain readable, see Store.Reencrypt.
+func WithEncryption(c *Cipher) Option {
+	return func(s *Store) {
+		s.cipher = c
+	}
+}
+
+// WithEncryptedBackups makes ExportContent encrypt the exported attachments with the current key.
+func WithEncryptedBackups(enabled bool) Option {
+	return func(s *Store) {
+		s.encryptBackups = enabled
+	}
+}
+
+// New creates a new Store keeping files on the local filesystem below baseDir,
+// running AutoMigrate for the attachment table.
+func New(db *gorm.DB, baseDir string, maxSize int64, opts ...Option) (*Store, error) {
+	backend, err := NewLocalBackend(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithBackend(db, backend, maxSize, opts...)
+}
+
+// NewWithBackend creates a new Store keeping file content in the given backend,
+// running AutoMigrate for the attachment table.
+func NewWithBackend(db *gorm.DB, backend Backend, maxSize int64, opts ...Option) (*Store, error) {
 	if db == nil {
 		return nil, fmt.Errorf("db cannot be nil")
 	}
-	if baseDir == "" {
-		return nil, fmt.Errorf("baseDir cannot be empty")
+	if backend == nil {
+		return nil, fmt.Errorf("backend cannot be nil")
 	}
 
-	err := db.AutoMigrate(&dbAttachment{})
+	if err := prepareContentHashIndex(db); err != nil {
+		return nil, fmt.Errorf("preparing content hash index: %w", err)
+	}
+	err := db.AutoMigrate(&dbAttachment{}, &dbIntegrityReport{})
 	if err != nil {
 		return nil, fmt.Errorf("auto migrate: %w", err)
 	}
 
-	return &Store{
+	s := &Store{
 		db:      db,
-		baseDir: baseDir,
+		backend: backend,
 		maxSize: maxSize,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.cipher != nil {
+		s.backend = &encryptedBackend{Backend: backend, cipher: s.cipher}
+	}
+	return s, nil
+}
+
+// prepareContentHashIndex readies existing records for the unique content hash index, which replaced
+// a plain one: hashes of deleted records, empty hashes and all but the oldest of duplicate hashes
+// are cleared. The cleared duplicates are hashed again by BackfillContentHashes, which leaves them
+// as separate files.
+func prepareContentHashIndex(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&dbAttachment{}) || m.HasIndex(&dbAttachment{}, "idx_attachment_content_hash") {
+		return nil
+	}
+	if m.HasIndex(&dbAttachment{}, "idx_db_attachments_content_hash") {
+		if err := m.DropIndex(&dbAttachment{}, "idx_db_attachments_content_hash"); err != nil {
+			return err
+		}
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE db_attachments SET content_hash = NULL
+			WHERE content_hash = '' OR deleted_at IS NOT NULL`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE db_attachments SET content_hash = NULL
+			WHERE content_hash IS NOT NULL AND id NOT IN (
+				SELECT id FROM (SELECT MIN(id) AS id FROM db_attachments WHERE content_hash IS NOT NULL GROUP BY content_hash) AS keep
+			)`).Error
+	})
+}
+
+// BackfillContentHashes computes the content hash of attachments stored before deduplication
+// was introduced and returns the number of attachments hashed. Attachments whose file is missing
+// or duplicates another one are marked and skipped in later runs; other read errors stop the
+// backfill, so it is resumed on the next run.
+func (s *Store) BackfillContentHashes(ctx context.Context) (int, error) {
+	var records []dbAttachment
+	if err := s.db.WithContext(ctx).Where("content_hash IS NULL AND hash_skipped = ?", false).
+		Order("id ASC").Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("fetching attachments: %w", err)
+	}
+	hashed := 0
+	for _, rec := range records {
+		content, err := s.backend.Get(ctx, rec.StoragePath)
+		if errors.Is(err, ErrObjectNotFound) {
+			if err := s.skipHash(ctx, rec.Id); err != nil {
+				return hashed, err
+			}
+			continue
+		}
+		if err != nil {
+			return hashed, fmt.Errorf("reading attachment %d: %w", rec.Id, err)
+		}
+		hash := contentHash(content)
+		// a file stored twice before deduplication keeps its own record
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("content_hash = ?", hash).Count(&count).Error; err != nil {
+			return hashed, fmt.Errorf("querying attachment: %w", err)
+		}
+		if count > 0 {
+			if err := s.skipHash(ctx, rec.Id); err != nil {
+				return hashed, err
+			}
+			continue
+		}
+		if err := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ?", rec.Id).
+			Update("content_hash", hash).Error; err != nil {
+			return hashed, fmt.Errorf("updating attachment %d: %w", rec.Id, err)
+		}
+		hashed++
+	}
+	return hashed, nil
+}
+
+func (s *Store) skipHash(ctx context.Context, id uint) error {
+	if err := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ?", id).
+		Update("hash_skipped", true).Error; err != nil {
+		return fmt.Errorf("updating attachment %d: %w", id, err)
+	}
+	return nil
 }
 
 // Save validates and stores a file, returning the attachment ID.
@@ -111,44 +239,7 @@ func (s *Store) Save(ctx context.Context, date time.Time, file multipart.File, h
 		return 0, ErrMimeNotAllowed
 	}
 
-	// Generate storage path: YYYY/MM/DD_<8-char-hex>.<ext>
-	ext := mimeToExt[mimeType]
-	randBytes := make([]byte, 4)
-	if _, err := rand.Read(randBytes); err != nil {
-		return 0, fmt.Errorf("generating random name: %w", err)
-	}
-	randHex := hex.EncodeToString(randBytes)
-
-	storagePath := fmt.Sprintf("%04d/%02d/%02d_%s%s",
-		date.Year(), date.Month(), date.Day(), randHex, ext)
-
-	absPath := filepath.Join(s.baseDir, storagePath)
-
-	// Create directories
-	dir := filepath.Dir(absPath)
-	if err := os.MkdirAll(dir, 0o750); err != nil {
-		return 0, fmt.Errorf("creating directory: %w", err)
-	}
-
-	// Write file to disk
-	if err := os.WriteFile(absPath, content, 0o600); err != nil {
-		return 0, fmt.Errorf("writing file: %w", err)
-	}
-
-	// Insert DB record
-	record := dbAttachment{
-		OriginalName: header.Filename,
-		StoragePath:  storagePath,
-		MimeType:     mimeType,
-		FileSize:     int64(len(content)),
-	}
-	if err := s.db.WithContext(ctx).Create(&record).Error; err != nil {
-		// Clean up file on DB error
-		_ = os.Remove(absPath)
-		return 0, fmt.Errorf("inserting record: %w", err)
-	}
-
-	return record.Id, nil
+	return s.store(ctx, date, content, header.Filename, mimeType)
 }
 
 // SaveRaw validates and stores raw bytes as an attachment, returning the attachment ID.
@@ -165,6 +256,21 @@ func (s *Store) SaveRaw(ctx context.Context, date time.Time, content []byte, ori
 		return 0, ErrMimeNotAllowed
 	}
 
+	return s.store(ctx, date, content, originalName, mimeType)
+}
+
+// store writes validated content to disk and records it. If a file with the same content is
+// already stored, its reference count is incremented and its ID is returned instead, so every
+// call must be balanced by a Delete. The shared record keeps the name and MIME type of the first
+// upload; callers wanting a name per use keep it with their reference, e.g. as caption.
+func (s *Store) store(ctx context.Context, date time.Time, content []byte, originalName, mimeType string) (uint, error) {
+	hash := contentHash(content)
+
+	id, err := s.addRefByHash(ctx, hash)
+	if err != nil || id != 0 {
+		return id, err
+	}
+
 	// Generate storage path: YYYY/MM/DD_<8-char-hex>.<ext>
 	ext := mimeToExt[mimeType]
 	randBytes := make([]byte, 4)
@@ -176,35 +282,85 @@ func (s *Store) SaveRaw(ctx context.Context, date time.Time, content []byte, ori
 	storagePath := fmt.Sprintf("%04d/%02d/%02d_%s%s",
 		date.Year(), date.Month(), date.Day(), randHex, ext)
 
-	absPath := filepath.Join(s.baseDir, storagePath)
-
-	// Create directories
-	dir := filepath.Dir(absPath)
-	if err := os.MkdirAll(dir, 0o750); err != nil {
-		return 0, fmt.Errorf("creating directory: %w", err)
+	if err := s.backend.Put(ctx, storagePath, content); err != nil {
+		return 0, err
 	}
 
-	// Write file to disk
-	if err := os.WriteFile(absPath, content, 0o600); err != nil {
-		return 0, fmt.Errorf("writing file: %w", err)
+	// A missing preview is not an error, it is retried when the thumbnail is requested
+	thumb, _, err := s.storeThumbnail(ctx, storagePath, content, mimeType)
+	if err != nil {
+		thumb = ""
 	}
 
 	// Insert DB record
 	record := dbAttachment{
-		OriginalName: originalName,
-		StoragePath:  storagePath,
-		MimeType:     mimeType,
-		FileSize:     int64(len(content)),
+		OriginalName:  originalName,
+		StoragePath:   storagePath,
+		MimeType:      mimeType,
+		FileSize:      int64(len(content)),
+		ContentHash:   &hash,
+		RefCount:      1,
+		ThumbnailPath: thumb,
 	}
 	if err := s.db.WithContext(ctx).Create(&record).Error; err != nil {
-		// Clean up file on DB error
-		_ = os.Remove(absPath)
+		// Clean up files on DB error
+		_ = s.backend.Delete(ctx, storagePath)
+		if thumb != "" {
+			_ = s.backend.Delete(ctx, thumb)
+		}
+		// the unique hash index rejects the record when the same content was stored concurrently
+		if id, refErr := s.addRefByHash(ctx, hash); refErr == nil && id != 0 {
+			return id, nil
+		}
 		return 0, fmt.Errorf("inserting record: %w", err)
 	}
 
 	return record.Id, nil
 }
 
+// addRefByHash adds a reference to the attachment with the given content hash and returns its ID,
+// or 0 if no such attachment is stored. The lookup and the increment run in one transaction.
+func (s *Store) addRefByHash(ctx context.Context, hash string) (uint, error) {
+	var id uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []dbAttachment
+		if err := tx.Where("content_hash = ?", hash).Limit(1).Find(&existing).Error; err != nil {
+			return fmt.Errorf("querying attachment: %w", err)
+		}
+		if len(existing) == 0 {
+			return nil
+		}
+		if err := tx.Model(&dbAttachment{}).Where("id = ?", existing[0].Id).
+			Update("ref_count", gorm.Expr("ref_count + 1")).Error; err != nil {
+			return fmt.Errorf("updating reference count: %w", err)
+		}
+		id = existing[0].Id
+		return nil
+	})
+	return id, err
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AddRefs adds n references to an existing attachment, as if it had been saved n more times.
+func (s *Store) AddRefs(ctx context.Context, id uint, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	d := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ?", id).
+		Update("ref_count", gorm.Expr("ref_count + ?", n))
+	if d.Error != nil {
+		return fmt.Errorf("updating reference count: %w", d.Error)
+	}
+	if d.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // Get returns the attachment metadata for the given ID.
 func (s *Store) Get(ctx context.Context, id uint) (*Attachment, error) {
 	var record dbAttachment
@@ -222,33 +378,33 @@ func (s *Store) Get(ctx context.Context, id uint) (*Attachment, error) {
 		StoragePath:  record.StoragePath,
 		MimeType:     record.MimeType,
 		FileSize:     record.FileSize,
+		ContentHash:  deref(record.ContentHash),
+		RefCount:     record.RefCount,
 	}, nil
 }
 
-// GetFilePath returns the absolute file path for the given attachment ID.
-// It prevents path traversal by verifying the resolved path is within baseDir.
-func (s *Store) GetFilePath(ctx context.Context, id uint) (string, error) {
+// ReadFile returns the content of the given attachment.
+func (s *Store) ReadFile(ctx context.Context, id uint) ([]byte, error) {
 	att, err := s.Get(ctx, id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return s.backend.Get(ctx, att.StoragePath)
+}
 
-	absPath := filepath.Join(s.baseDir, att.StoragePath)
-	absPath, err = filepath.Abs(absPath)
-	if err != nil {
-		return "", fmt.Errorf("resolving path: %w", err)
+// GetFilePath returns the absolute file path for the given attachment ID.
+// It is only available with the local backend without encryption, otherwise ErrNoLocalPath is returned;
+// use ReadFile to access the content independently of the backend.
+func (s *Store) GetFilePath(ctx context.Context, id uint) (string, error) {
+	local, ok := s.backend.(*LocalBackend)
+	if !ok {
+		return "", ErrNoLocalPath
 	}
-
-	// Prevent path traversal
-	absBase, err := filepath.Abs(s.baseDir)
+	att, err := s.Get(ctx, id)
 	if err != nil {
-		return "", fmt.Errorf("resolving base: %w", err)
-	}
-	if !strings.HasPrefix(absPath, absBase+string(filepath.Separator)) {
-		return "", fmt.Errorf("path traversal detected")
+		return "", err
 	}
-
-	return absPath, nil
+	return local.Path(att.StoragePath)
 }
 
 // TotalSize returns the sum of file sizes for all non-deleted attachments.
@@ -284,20 +440,27 @@ func (s *Store) WipeData(ctx context.Context) error {
 		return fmt.Errorf("fetching all attachments: %w", err)
 	}
 
-	// Remove each file from disk (ignore errors — file may already be missing)
+	// Remove each file from storage (ignore errors — file may already be missing)
 	for _, rec := range records {
-		_ = os.Remove(filepath.Join(s.baseDir, rec.StoragePath))
+		_ = s.backend.Delete(ctx, rec.StoragePath)
+		if rec.ThumbnailPath != "" {
+			_ = s.backend.Delete(ctx, rec.ThumbnailPath)
+		}
 	}
 
 	// Hard-delete all DB records
 	if err := s.db.WithContext(ctx).Unscoped().Where("1 = 1").Delete(&dbAttachment{}).Error; err != nil {
 		return fmt.Errorf("deleting all attachments: %w", err)
 	}
+	if err := s.db.WithContext(ctx).Where("1 = 1").Delete(&dbIntegrityReport{}).Error; err != nil {
+		return fmt.Errorf("deleting integrity report: %w", err)
+	}
 
 	return nil
 }
 
-// Delete removes the file from disk and soft-deletes the DB record.
+// Delete releases one reference to the attachment. When the last reference is released the
+// file and its thumbnail are removed from disk and the DB record is soft-deleted.
 func (s *Store) Delete(ctx context.Context, id uint) error {
 	var record dbAttachment
 	err := s.db.WithContext(ctx).First(&record, id).Error
@@ -308,14 +471,87 @@ func (s *Store) Delete(ctx context.Context, id uint) error {
 		return fmt.Errorf("querying attachment: %w", err)
 	}
 
-	// Remove file from disk (ignore error if already missing)
-	absPath := filepath.Join(s.baseDir, record.StoragePath)
-	_ = os.Remove(absPath)
-
-	// Soft-delete DB record
-	if err := s.db.WithContext(ctx).Delete(&record).Error; err != nil {
-		return fmt.Errorf("deleting record: %w", err)
+	// Both updates are conditional on the reference count, so a reference added concurrently
+	// by store is never lost: the record is only removed while it holds the last reference.
+	for {
+		d := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ? AND ref_count > 1", id).
+			Update("ref_count", gorm.Expr("ref_count - 1"))
+		if d.Error != nil {
+			return fmt.Errorf("updating reference count: %w", d.Error)
+		}
+		if d.RowsAffected > 0 {
+			return nil
+		}
+		d = softDelete(s.db.WithContext(ctx).Where("ref_count <= 1"), id)
+		if d.Error != nil {
+			return fmt.Errorf("deleting record: %w", d.Error)
+		}
+		if d.RowsAffected > 0 {
+			break
+		}
+		if err := s.db.WithContext(ctx).First(&dbAttachment{}, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("querying attachment: %w", err)
+		}
 	}
 
+	// Remove files from storage (ignore error if already missing)
+	_ = s.backend.Delete(ctx, record.StoragePath)
+	if record.ThumbnailPath != "" {
+		_ = s.backend.Delete(ctx, record.ThumbnailPath)
+	}
 	return nil
 }
+
+// softDelete soft-deletes the record with the given id, clearing its content hash so that the same
+// content can be stored again.
+func softDelete(tx *gorm.DB, id uint) *gorm.DB {
+	return tx.Model(&dbAttachment{}).Where("id = ?", id).
+		Updates(map[string]any{"content_hash": nil, "deleted_at": time.Now()})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ReadThumbnail returns the JPEG thumbnail of an attachment, generating it if it does not
+// exist yet. ErrNoThumbnail is returned for content that cannot be previewed.
+func (s *Store) ReadThumbnail(ctx context.Context, id uint) ([]byte, error) {
+	var record dbAttachment
+	err := s.db.WithContext(ctx).First(&record, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("querying attachment: %w", err)
+	}
+
+	if record.ThumbnailPath != "" {
+		thumb, err := s.backend.Get(ctx, record.ThumbnailPath)
+		if err == nil {
+			return thumb, nil
+		}
+		if !errors.Is(err, ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+
+	content, err := s.backend.Get(ctx, record.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	thumbPath, thumb, err := s.storeThumbnail(ctx, record.StoragePath, content, record.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ?", id).
+		Update("thumbnail_path", thumbPath).Error; err != nil {
+		return nil, fmt.Errorf("updating record: %w", err)
+	}
+	return thumb, nil
+}
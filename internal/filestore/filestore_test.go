@@ -3,6 +3,9 @@ package filestore
 import (
 	"bytes"
 	"context"
+	"image"
+	"image/color"
+	"image/png"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -656,6 +659,22 @@ func TestTotalSize(t *testing.T) {
 
 // readTestFile reads a file at the given path. It is used in tests where the
 // path is constructed from t.TempDir() and is therefore safe.
+// testPNG returns a decodable w x h gradient PNG.
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255}) //nolint:gosec // wrapping is fine for a test pattern
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
 func readTestFile(t *testing.T, path string) []byte {
 	t.Helper()
 	data, err := os.ReadFile(filepath.Clean(path))
@@ -664,3 +683,266 @@ func readTestFile(t *testing.T, path string) []byte {
 	}
 	return data
 }
+
+func TestDeduplication(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			baseDir := t.TempDir()
+			store, err := New(db.ConnDbName("TestDeduplication"), baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+			content := []byte("%PDF-1.4 same invoice")
+			id1, err := store.SaveRaw(ctx, date, content, "invoice.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, fh := fakeFileHeader(t, "copy.pdf", content)
+			defer func() { _ = f.Close() }()
+			id2, err := store.Save(ctx, date.AddDate(0, 1, 0), f, fh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id1 != id2 {
+				t.Fatalf("expected identical content to share the record, got %d and %d", id1, id2)
+			}
+
+			att, err := store.Get(ctx, id1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if att.RefCount != 2 {
+				t.Errorf("expected ref count 2, got %d", att.RefCount)
+			}
+			if att.OriginalName != "invoice.pdf" {
+				t.Errorf("expected the first name to be kept, got %q", att.OriginalName)
+			}
+			total, err := store.TotalSize(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != int64(len(content)) {
+				t.Errorf("expected the content to be counted once, got %d", total)
+			}
+
+			path, err := store.GetFilePath(ctx, id1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the first delete only releases a reference
+			if err := store.Delete(ctx, id1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("expected file to be kept while referenced: %v", err)
+			}
+			if err := store.Delete(ctx, id1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Error("expected file to be removed after the last reference is released")
+			}
+			if _, err := store.Get(ctx, id1); err != ErrNotFound {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			// once deleted, the same content is stored again as a new file
+			id3, err := store.SaveRaw(ctx, date, content, "invoice.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id3 == id1 {
+				t.Error("expected a new record after the previous one was deleted")
+			}
+		})
+	}
+}
+
+func TestContentHashIndexMigration(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			conn := db.ConnDbName("TestContentHashIndexMigration")
+			baseDir := t.TempDir()
+			store, err := New(conn, baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+			content := []byte("%PDF-1.4 stored twice")
+			id1, err := store.SaveRaw(ctx, date, content, "a.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			id2, err := store.SaveRaw(ctx, date, []byte("%PDF-1.4 other"), "b.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// go back to the plain index, where a race could store the same content twice
+			m := conn.Migrator()
+			if err := m.DropIndex(&dbAttachment{}, "idx_attachment_content_hash"); err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.Exec("CREATE INDEX idx_db_attachments_content_hash ON db_attachments (content_hash)").Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.Model(&dbAttachment{}).Where("id = ?", id2).Update("content_hash", contentHash(content)).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			store, err = New(conn, baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatalf("expected the migration to resolve duplicate hashes: %v", err)
+			}
+			if !m.HasIndex(&dbAttachment{}, "idx_attachment_content_hash") {
+				t.Error("expected the unique content hash index")
+			}
+			att, err := store.Get(ctx, id1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if att.ContentHash != contentHash(content) {
+				t.Errorf("expected the oldest record to keep its hash, got %q", att.ContentHash)
+			}
+			id3, err := store.SaveRaw(ctx, date, content, "c.pdf", "application/pdf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id3 != id1 {
+				t.Errorf("expected the content to be deduplicated on %d, got %d", id1, id3)
+			}
+		})
+	}
+}
+
+func TestBackfillContentHashes(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			conn := db.ConnDbName("TestBackfillContentHashes")
+			store, err := New(conn, t.TempDir(), 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+			kept := []byte("%PDF-1.4 kept")
+			var ids []uint
+			for _, content := range [][]byte{kept, []byte("%PDF-1.4 lost")} {
+				id, err := store.SaveRaw(ctx, date, content, "a.pdf", "application/pdf")
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			// the same content stored twice, as before deduplication
+			dup := dbAttachment{OriginalName: "b.pdf", StoragePath: "2025/03/15_dup.pdf", MimeType: "application/pdf", FileSize: int64(len(kept)), RefCount: 1}
+			if err := store.backend.Put(ctx, dup.StoragePath, kept); err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.Create(&dup).Error; err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, dup.Id)
+			// none of them hashed yet, and the file of the second one is gone
+			if err := conn.Model(&dbAttachment{}).Where("id IN ?", ids).Update("content_hash", nil).Error; err != nil {
+				t.Fatal(err)
+			}
+			lost, err := store.Get(ctx, ids[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.backend.Delete(ctx, lost.StoragePath); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := store.BackfillContentHashes(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("expected 1 attachment hashed, got %d", n)
+			}
+			var skipped []uint
+			if err := conn.Model(&dbAttachment{}).Where("hash_skipped = ?", true).Order("id ASC").Pluck("id", &skipped).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(skipped) != 2 || skipped[0] != ids[1] || skipped[1] != ids[2] {
+				t.Errorf("expected the missing file and the duplicate to be skipped, got %v", skipped)
+			}
+			if n, err := store.BackfillContentHashes(ctx); err != nil || n != 0 {
+				t.Errorf("expected nothing left to hash, got %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			baseDir := t.TempDir()
+			store, err := New(db.ConnDbName("TestThumbnail"), baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+			id, err := store.SaveRaw(ctx, date, testPNG(t, 800, 400), "scan.png", "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			thumbContent, err := store.ReadThumbnail(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			thumb, format, err := image.Decode(bytes.NewReader(thumbContent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != "jpeg" {
+				t.Errorf("expected a jpeg thumbnail, got %s", format)
+			}
+			if got := thumb.Bounds().Size(); got != image.Pt(ThumbnailSize, ThumbnailSize/2) {
+				t.Errorf("unexpected thumbnail size %v", got)
+			}
+
+			// a missing thumbnail is regenerated on demand
+			att, err := store.Get(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			thumbPath := filepath.Join(baseDir, thumbnailPath(att.StoragePath))
+			if err := os.Remove(thumbPath); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.ReadThumbnail(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(thumbPath); err != nil {
+				t.Errorf("expected thumbnail to be stored again: %v", err)
+			}
+
+			// content that cannot be decoded has no thumbnail
+			fakeJpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0x00}, 100)...)
+			fakeID, err := store.SaveRaw(ctx, date, fakeJpeg, "broken.jpg", "image/jpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.ReadThumbnail(ctx, fakeID); err != ErrNoThumbnail {
+				t.Errorf("expected ErrNoThumbnail, got %v", err)
+			}
+
+			if err := store.Delete(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(thumbPath); !os.IsNotExist(err) {
+				t.Error("expected thumbnail to be removed with the attachment")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IntegrityReport is the result of CheckIntegrity.
+type IntegrityReport struct {
+	CheckedAt   time.Time
+	Attachments int // number of attachment records checked
+	// Orphans are attachments not referenced by any transaction or case study, leaving out the ones
+	// saved within OrphanGracePeriod.
+	Orphans     []uint
+	OrphanBytes int64
+	// Missing are attachments whose file is not found in the storage backend.
+	Missing []uint
+	// SizeMismatches are attachments whose stored file size differs from the recorded one.
+	SizeMismatches []uint
+	// RefCountMismatches are referenced attachments whose reference count differs from the number of references.
+	RefCountMismatches []uint
+	// Purged is the number of orphans deleted, Repaired the number of reference counts corrected.
+	Purged   int
+	Repaired int
+}
+
+// dbIntegrityReport keeps the last integrity report so it can be shown without re-running the check.
+type dbIntegrityReport struct {
+	ID        uint `gorm:"primaryKey"`
+	CheckedAt time.Time
+	Report    string `gorm:"type:text"` // IntegrityReport as JSON
+}
+
+const integrityReportID = 1
+
+// OrphanGracePeriod is the time an unreferenced attachment is left alone after it was saved or reused
+// by a new upload: the transaction or case study referencing it may not be stored yet.
+const OrphanGracePeriod = 24 * time.Hour
+
+// CheckIntegrity cross-references all attachments against references, the number of times each
+// attachment ID is referenced by transactions and case studies, and verifies that their files exist
+// with the recorded size; files are not downloaded, only their size is queried from the backend.
+// When purge is set, orphans are deleted together with their files and the reference counts of
+// referenced attachments are corrected. The report is stored and can be read
+// back with LastIntegrityReport.
+func (s *Store) CheckIntegrity(ctx context.Context, references map[uint]int, purge bool) (IntegrityReport, error) {
+	report := IntegrityReport{CheckedAt: time.Now().UTC()}
+
+	var records []dbAttachment
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&records).Error; err != nil {
+		return report, fmt.Errorf("fetching attachments: %w", err)
+	}
+	report.Attachments = len(records)
+	graceStart := report.CheckedAt.Add(-OrphanGracePeriod)
+
+	for _, rec := range records {
+		size, err := s.backend.Stat(ctx, rec.StoragePath)
+		switch {
+		case errors.Is(err, ErrObjectNotFound):
+			report.Missing = append(report.Missing, rec.Id)
+		case err != nil:
+			return report, fmt.Errorf("reading attachment %d: %w", rec.Id, err)
+		case !s.storedSizeMatches(size, rec.FileSize):
+			report.SizeMismatches = append(report.SizeMismatches, rec.Id)
+		}
+
+		refs := references[rec.Id]
+		if refs == 0 {
+			if rec.CreatedAt.After(graceStart) || rec.UpdatedAt.After(graceStart) {
+				continue
+			}
+			report.Orphans = append(report.Orphans, rec.Id)
+			report.OrphanBytes += rec.FileSize
+			if purge {
+				if err := s.purge(ctx, rec); err != nil {
+					return report, err
+				}
+				report.Purged++
+			}
+			continue
+		}
+		if refs != rec.RefCount {
+			report.RefCountMismatches = append(report.RefCountMismatches, rec.Id)
+			if purge {
+				if err := s.db.WithContext(ctx).Model(&dbAttachment{}).Where("id = ?", rec.Id).
+					Update("ref_count", refs).Error; err != nil {
+					return report, fmt.Errorf("updating reference count: %w", err)
+				}
+				report.Repaired++
+			}
+		}
+	}
+
+	if err := s.saveIntegrityReport(ctx, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// storedSizeMatches reports whether a stored object of the given size holds fileSize bytes of content,
+// in plain or, with encryption configured, encrypted.
+func (s *Store) storedSizeMatches(stored, fileSize int64) bool {
+	return stored == fileSize || (s.cipher != nil && stored == fileSize+encryptionOverhead)
+}
+
+// purge removes an attachment regardless of its reference count.
+func (s *Store) purge(ctx context.Context, rec dbAttachment) error {
+	_ = s.backend.Delete(ctx, rec.StoragePath)
+	if rec.ThumbnailPath != "" {
+		_ = s.backend.Delete(ctx, rec.ThumbnailPath)
+	}
+	if err := softDelete(s.db.WithContext(ctx), rec.Id).Error; err != nil {
+		return fmt.Errorf("deleting attachment %d: %w", rec.Id, err)
+	}
+	return nil
+}
+
+func (s *Store) saveIntegrityReport(ctx context.Context, report IntegrityReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encoding integrity report: %w", err)
+	}
+	row := dbIntegrityReport{ID: integrityReportID, CheckedAt: report.CheckedAt, Report: string(data)}
+	if err := s.db.WithContext(ctx).Save(&row).Error; err != nil {
+		return fmt.Errorf("storing integrity report: %w", err)
+	}
+	return nil
+}
+
+// LastIntegrityReport returns the report of the last CheckIntegrity run, or nil if it never ran.
+func (s *Store) LastIntegrityReport(ctx context.Context) (*IntegrityReport, error) {
+	var row dbIntegrityReport
+	err := s.db.WithContext(ctx).First(&row, integrityReportID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("querying integrity report: %w", err)
+	}
+	var report IntegrityReport
+	if err := json.Unmarshal([]byte(row.Report), &report); err != nil {
+		return nil, fmt.Errorf("decoding integrity report: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,132 @@
+package filestore
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			baseDir := t.TempDir()
+			store, err := New(db.ConnDbName("TestCheckIntegrity"), baseDir, 10*1024*1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			date := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+			save := func(content string) uint {
+				id, err := store.SaveRaw(ctx, date, []byte("%PDF-1.4 "+content), content+".pdf", "application/pdf")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return id
+			}
+			used := save("used")
+			orphan := save("orphan")
+			missing := save("missing")
+			resized := save("resized")
+
+			path, err := store.GetFilePath(ctx, missing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			path, err = store.GetFilePath(ctx, resized)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("truncated"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			// age the records past the grace period, then upload one that is not linked yet
+			conn := db.ConnDbName("TestCheckIntegrity")
+			old := time.Now().Add(-2 * OrphanGracePeriod)
+			if err := conn.Model(&dbAttachment{}).Where("id IN ?", []uint{used, orphan, missing, resized}).
+				UpdateColumns(map[string]any{"created_at": old, "updated_at": old}).Error; err != nil {
+				t.Fatal(err)
+			}
+			fresh := save("fresh")
+
+			report, err := store.LastIntegrityReport(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report != nil {
+				t.Fatalf("expected no report before the first check, got %+v", report)
+			}
+
+			// "used" is referenced twice although it was saved once
+			refs := map[uint]int{used: 2, missing: 1, resized: 1}
+			got, err := store.CheckIntegrity(ctx, refs, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := IntegrityReport{
+				Attachments:        5,
+				Orphans:            []uint{orphan},
+				OrphanBytes:        int64(len("%PDF-1.4 orphan")),
+				Missing:            []uint{missing},
+				SizeMismatches:     []uint{resized},
+				RefCountMismatches: []uint{used},
+			}
+			ignoreTime := cmpopts.IgnoreFields(IntegrityReport{}, "CheckedAt")
+			if diff := cmp.Diff(want, got, ignoreTime); diff != "" {
+				t.Errorf("unexpected report (-want +got):\n%s", diff)
+			}
+			if _, err := store.Get(ctx, orphan); err != nil {
+				t.Errorf("a check without purge must not delete orphans: %v", err)
+			}
+
+			stored, err := store.LastIntegrityReport(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored == nil {
+				t.Fatal("expected the report to be stored")
+			}
+			if diff := cmp.Diff(want, *stored, ignoreTime); diff != "" {
+				t.Errorf("unexpected stored report (-want +got):\n%s", diff)
+			}
+
+			got, err = store.CheckIntegrity(ctx, refs, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Purged != 1 || got.Repaired != 1 {
+				t.Errorf("expected 1 purged and 1 repaired, got %+v", got)
+			}
+			if _, err := store.Get(ctx, orphan); err != ErrNotFound {
+				t.Errorf("expected orphan to be purged, got %v", err)
+			}
+			if _, err := store.Get(ctx, fresh); err != nil {
+				t.Errorf("an attachment within the grace period must not be purged: %v", err)
+			}
+			att, err := store.Get(ctx, used)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if att.RefCount != 2 {
+				t.Errorf("expected ref count to be repaired to 2, got %d", att.RefCount)
+			}
+
+			got, err = store.CheckIntegrity(ctx, refs, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Orphans) != 0 || len(got.RefCountMismatches) != 0 || got.Attachments != 4 {
+				t.Errorf("expected only the file issues to remain, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // register PNG decoder
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/webp" // register WebP decoder
+)
+
+// ErrNoThumbnail is returned when no thumbnail can be produced for an attachment.
+var ErrNoThumbnail = errors.New("thumbnail not available")
+
+// ThumbnailSize is the maximum width and height of generated thumbnails.
+const ThumbnailSize = 256
+
+// thumbnailQuality is the JPEG quality used to encode thumbnails.
+const thumbnailQuality = 80
+
+// pdfRenderTimeout bounds the external renderer used for PDF previews.
+const pdfRenderTimeout = 20 * time.Second
+
+// thumbnailPath returns the storage path of the thumbnail of a file: <name>_thumb.jpg next to it.
+func thumbnailPath(storagePath string) string {
+	return strings.TrimSuffix(storagePath, path.Ext(storagePath)) + "_thumb.jpg"
+}
+
+// storeThumbnail generates the thumbnail of a file and stores it next to it, returning its storage path and content.
+func (s *Store) storeThumbnail(ctx context.Context, storagePath string, content []byte, mimeType string) (string, []byte, error) {
+	thumb, err := generateThumbnail(ctx, content, mimeType)
+	if err != nil {
+		return "", nil, err
+	}
+	thumbPath := thumbnailPath(storagePath)
+	if err := s.backend.Put(ctx, thumbPath, thumb); err != nil {
+		return "", nil, fmt.Errorf("writing thumbnail: %w", err)
+	}
+	return thumbPath, thumb, nil
+}
+
+// generateThumbnail creates a JPEG thumbnail for the given content.
+// Images are decoded in-process; PDFs are rendered with pdftoppm (poppler) when it is installed.
+// It returns ErrNoThumbnail when the content cannot be previewed.
+func generateThumbnail(ctx context.Context, content []byte, mimeType string) ([]byte, error) {
+	var img image.Image
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp":
+		var err error
+		img, _, err = image.Decode(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrNoThumbnail
+		}
+	case "application/pdf":
+		var err error
+		img, err = renderPDFFirstPage(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrNoThumbnail
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleToFit(img, ThumbnailSize), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("encoding thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderPDFFirstPage renders the first page of a PDF using pdftoppm; ErrNoThumbnail is returned
+// if the tool is not available or fails on the document.
+func renderPDFFirstPage(ctx context.Context, content []byte) (image.Image, error) {
+	bin, err := exec.LookPath("pdftoppm")
+	if err != nil {
+		return nil, ErrNoThumbnail
+	}
+	ctx, cancel := context.WithTimeout(ctx, pdfRenderTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "etna-pdf-thumb")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	src := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(src, content, 0o600); err != nil {
+		return nil, fmt.Errorf("writing temp file: %w", err)
+	}
+
+	// with -singlefile the page is written to <root>.png
+	root := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, bin, "-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", fmt.Sprint(ThumbnailSize*2), src, root) //nolint:gosec // fixed binary, paths in our temp dir
+	if err := cmd.Run(); err != nil {
+		return nil, ErrNoThumbnail
+	}
+	out, err := os.ReadFile(root + ".png") //nolint:gosec // path in our temp dir
+	if err != nil {
+		return nil, ErrNoThumbnail
+	}
+	img, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, ErrNoThumbnail
+	}
+	return img, nil
+}
+
+// scaleToFit downsizes an image to fit in a max x max box keeping the aspect ratio, averaging the
+// source pixels covered by each target pixel; transparent areas are flattened on white.
+func scaleToFit(src image.Image, maxSize int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return src
+	}
+	tw, th := w, h
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			tw, th = maxSize, max(1, h*maxSize/w)
+		} else {
+			tw, th = max(1, w*maxSize/h), maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					// flatten on white: premultiplied color + (1 - alpha) * white
+					r += uint64(pr + 0xffff - pa)
+					g += uint64(pg + 0xffff - pa)
+					bl += uint64(pb + 0xffff - pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff}) //nolint:gosec // averages of 16-bit values
+		}
+	}
+	return dst
+}
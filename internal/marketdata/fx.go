@@ -50,7 +50,7 @@ func (s *Store) RegisterPair(ctx context.Context, main, secondary string) error
 	if main == "" || secondary == "" {
 		return fmt.Errorf("main and secondary currency cannot be empty")
 	}
-	if err := s.store.DefineSeries(ctx, fxSeries(main, secondary)); err != nil {
+	if err := s.defineSeries(ctx, fxSeries(main, secondary)); err != nil {
 		return fmt.Errorf("failed to define series for %s/%s: %w", main, secondary, err)
 	}
 	return nil
@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Provider names, used in the configured priorities and as the data source recorded on the series.
+const (
+	ProviderMassive = "massive"
+	ProviderHTTP    = "http"
+	ProviderECB     = "ecb"
+	ProviderYahoo   = "yahoo"
+)
+
+// SourceTracker is implemented by the provider chains. Source reports the provider that last
+// served a key (a symbol, or FXKey for a pair); Prefer makes a provider, e.g. one remembered from
+// an earlier run, the first to try for that key.
+type SourceTracker interface {
+	Source(key string) string
+	Prefer(key, provider string)
+}
+
+// FXKey is the key of a currency pair in a SourceTracker.
+func FXKey(main, secondary string) string {
+	return main + "/" + secondary
+}
+
+// chain holds the provider order and the provider that last served each key. Unlike the pools,
+// which rotate over interchangeable API keys of one provider, a chain always starts with the
+// provider that served the key before and otherwise keeps the configured priority.
+type chain struct {
+	names     []string
+	mu        sync.Mutex
+	preferred map[string]int
+}
+
+func newChain(names []string) (*chain, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one provider is required")
+	}
+	seen := map[string]bool{}
+	for _, n := range names {
+		if n == "" {
+			return nil, fmt.Errorf("provider name cannot be empty")
+		}
+		if seen[n] {
+			return nil, fmt.Errorf("duplicate provider %q", n)
+		}
+		seen[n] = true
+	}
+	return &chain{names: names, preferred: map[string]int{}}, nil
+}
+
+// Providers returns the provider names in priority order.
+func (c *chain) Providers() []string {
+	return append([]string(nil), c.names...)
+}
+
+// order returns the provider indexes to try for key.
+func (c *chain) order(key string) []int {
+	c.mu.Lock()
+	first, ok := c.preferred[key]
+	c.mu.Unlock()
+	out := make([]int, 0, len(c.names))
+	if ok {
+		out = append(out, first)
+	}
+	for i := range c.names {
+		if !ok || i != first {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func (c *chain) served(key string, idx int) {
+	c.mu.Lock()
+	c.preferred[key] = idx
+	c.mu.Unlock()
+}
+
+// Source implements SourceTracker.
+func (c *chain) Source(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if idx, ok := c.preferred[key]; ok {
+		return c.names[idx]
+	}
+	return ""
+}
+
+// Prefer implements SourceTracker. Unknown providers are ignored.
+func (c *chain) Prefer(key, provider string) {
+	for i, n := range c.names {
+		if n == provider {
+			c.served(key, i)
+			return
+		}
+	}
+}
+
+// failed wraps the errors of the providers that failed when none returned data; each is prefixed
+// with the provider name. The errors stay inspectable, so a rate limit still reads as a 429.
+func (c *chain) failed(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("no provider returned data: %w", errors.Join(errs...))
+}
+
+// ChainClient is a Client that tries an ordered list of different providers (e.g. Massive, then
+// Yahoo). A provider that errors or returns no data for the symbol is skipped; the provider that
+// served a symbol is tried first on the next call for that symbol.
+type ChainClient struct {
+	*chain
+	clients []Client
+}
+
+// NewChainClient returns a ChainClient over the given providers in priority order.
+func NewChainClient(names []string, clients []Client) (*ChainClient, error) {
+	if len(names) != len(clients) {
+		return nil, fmt.Errorf("got %d provider names for %d clients", len(names), len(clients))
+	}
+	for _, c := range clients {
+		if c == nil {
+			return nil, fmt.Errorf("client cannot be nil")
+		}
+	}
+	ch, err := newChain(names)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainClient{chain: ch, clients: clients}, nil
+}
+
+// FetchDailyPrices implements Client. It returns the first non-empty result. Otherwise it returns
+// the errors of the providers that failed, e.g. a rate limit the caller can retry, or no points
+// without error when every provider came back empty.
+func (c *ChainClient) FetchDailyPrices(ctx context.Context, symbol string, start, end time.Time) ([]PricePoint, error) {
+	var errs []error
+	for _, idx := range c.order(symbol) {
+		points, err := c.clients[idx].FetchDailyPrices(ctx, symbol, start, end)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.names[idx], err))
+			continue
+		}
+		if len(points) == 0 {
+			continue
+		}
+		c.served(symbol, idx)
+		return points, nil
+	}
+	if len(errs) > 0 {
+		return nil, c.failed(errs)
+	}
+	return nil, nil
+}
+
+// FXChainClient is the FXClient counterpart of ChainClient; keys are FXKey(main, secondary).
+type FXChainClient struct {
+	*chain
+	clients []FXClient
+}
+
+// NewFXChainClient returns an FXChainClient over the given providers in priority order.
+func NewFXChainClient(names []string, clients []FXClient) (*FXChainClient, error) {
+	if len(names) != len(clients) {
+		return nil, fmt.Errorf("got %d provider names for %d FX clients", len(names), len(clients))
+	}
+	for _, c := range clients {
+		if c == nil {
+			return nil, fmt.Errorf("FX client cannot be nil")
+		}
+	}
+	ch, err := newChain(names)
+	if err != nil {
+		return nil, err
+	}
+	return &FXChainClient{chain: ch, clients: clients}, nil
+}
+
+// FetchDailyRates implements FXClient, with the same failover as ChainClient.FetchDailyPrices.
+func (c *FXChainClient) FetchDailyRates(ctx context.Context, main, secondary string, start, end time.Time) ([]RatePoint, error) {
+	key := FXKey(main, secondary)
+	var errs []error
+	for _, idx := range c.order(key) {
+		points, err := c.clients[idx].FetchDailyRates(ctx, main, secondary, start, end)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.names[idx], err))
+			continue
+		}
+		if len(points) == 0 {
+			continue
+		}
+		c.served(key, idx)
+		return points, nil
+	}
+	if len(errs) > 0 {
+		return nil, c.failed(errs)
+	}
+	return nil, nil
+}
+
+// ReferenceChainClient is the ReferenceClient counterpart of ChainClient. A provider that does not
+// know the symbol (Found false) is skipped like one that errors.
+type ReferenceChainClient struct {
+	*chain
+	clients []ReferenceClient
+}
+
+// NewReferenceChainClient returns a ReferenceChainClient over the given providers in priority order.
+func NewReferenceChainClient(names []string, clients []ReferenceClient) (*ReferenceChainClient, error) {
+	if len(names) != len(clients) {
+		return nil, fmt.Errorf("got %d provider names for %d reference clients", len(names), len(clients))
+	}
+	for _, c := range clients {
+		if c == nil {
+			return nil, fmt.Errorf("reference client cannot be nil")
+		}
+	}
+	ch, err := newChain(names)
+	if err != nil {
+		return nil, err
+	}
+	return &ReferenceChainClient{chain: ch, clients: clients}, nil
+}
+
+// GetTickerDetails implements ReferenceClient. When no provider knows the symbol it returns the
+// errors of the providers that failed, or Found false without error if none failed.
+func (c *ReferenceChainClient) GetTickerDetails(ctx context.Context, symbol string) (TickerDetails, error) {
+	var errs []error
+	for _, idx := range c.order(symbol) {
+		details, err := c.clients[idx].GetTickerDetails(ctx, symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.names[idx], err))
+			continue
+		}
+		if !details.Found {
+			continue
+		}
+		c.served(symbol, idx)
+		return details, nil
+	}
+	if len(errs) > 0 {
+		return TickerDetails{}, c.failed(errs)
+	}
+	return TickerDetails{}, nil
+}
@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// symbolClient serves prices for the symbols it knows and nothing for the others.
+type symbolClient struct {
+	points map[string][]PricePoint
+	calls  int
+}
+
+func (c *symbolClient) FetchDailyPrices(_ context.Context, symbol string, _, _ time.Time) ([]PricePoint, error) {
+	c.calls++
+	return c.points[symbol], nil
+}
+
+func TestNewChainClient_validation(t *testing.T) {
+	ok := &mockClient{}
+	tcs := []struct {
+		name    string
+		names   []string
+		clients []Client
+	}{
+		{name: "no providers"},
+		{name: "names do not match clients", names: []string{"a", "b"}, clients: []Client{ok}},
+		{name: "nil client", names: []string{"a"}, clients: []Client{nil}},
+		{name: "empty name", names: []string{""}, clients: []Client{ok}},
+		{name: "duplicate name", names: []string{"a", "a"}, clients: []Client{ok, ok}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewChainClient(tc.names, tc.clients); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestChainClient_FetchDailyPrices(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("fails over and remembers the provider per symbol", func(t *testing.T) {
+		massive := &symbolClient{points: map[string][]PricePoint{"AAPL": {{Time: day, Close: 1}}}}
+		yahoo := &symbolClient{points: map[string][]PricePoint{"AAPL": {{Time: day, Close: 2}}, "NESN.SW": {{Time: day, Close: 3}}}}
+		c, err := NewChainClient([]string{ProviderMassive, ProviderYahoo}, []Client{massive, yahoo})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := c.FetchDailyPrices(ctx, "NESN.SW", day, day)
+		if err != nil || len(got) != 1 || got[0].Close != 3 {
+			t.Fatalf("expected the Yahoo prices, got %+v, %v", got, err)
+		}
+		if c.Source("NESN.SW") != ProviderYahoo {
+			t.Errorf("expected source %q, got %q", ProviderYahoo, c.Source("NESN.SW"))
+		}
+		if got, _ := c.FetchDailyPrices(ctx, "AAPL", day, day); len(got) != 1 || got[0].Close != 1 {
+			t.Errorf("expected AAPL from Massive by priority, got %+v", got)
+		}
+
+		massive.calls, yahoo.calls = 0, 0
+		if _, err := c.FetchDailyPrices(ctx, "NESN.SW", day, day); err != nil {
+			t.Fatal(err)
+		}
+		if massive.calls != 0 || yahoo.calls != 1 {
+			t.Errorf("expected only Yahoo to be asked again, got massive=%d yahoo=%d", massive.calls, yahoo.calls)
+		}
+	})
+
+	t.Run("errors of all providers are returned", func(t *testing.T) {
+		c, err := NewChainClient([]string{ProviderMassive, ProviderYahoo}, []Client{
+			&mockClient{err: errors.New("401 unauthorized")},
+			&mockClient{err: errors.New("429 too many requests")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.FetchDailyPrices(ctx, "AAPL", day, day)
+		if err == nil || !strings.Contains(err.Error(), "massive: 401") || !strings.Contains(err.Error(), "yahoo: 429") {
+			t.Errorf("expected both provider errors, got %v", err)
+		}
+		if c.Source("AAPL") != "" {
+			t.Errorf("expected no source after a failure, got %q", c.Source("AAPL"))
+		}
+	})
+
+	t.Run("no data is not an error", func(t *testing.T) {
+		c, err := NewChainClient([]string{ProviderMassive, ProviderYahoo}, []Client{&mockClient{}, &mockClient{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := c.FetchDailyPrices(ctx, "AAPL", day, day); err != nil || len(got) != 0 {
+			t.Errorf("expected no points without error, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("a rate limit is returned when the other providers have no data", func(t *testing.T) {
+		limited := errors.New("429 too many requests")
+		c, err := NewChainClient([]string{ProviderMassive, ProviderYahoo}, []Client{
+			&mockClient{err: limited},
+			&mockClient{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.FetchDailyPrices(ctx, "AAPL", day, day)
+		if !errors.Is(err, limited) || !IsRateLimit429(err) {
+			t.Errorf("expected the rate limit error, got %v", err)
+		}
+	})
+
+	t.Run("preferred provider is tried first", func(t *testing.T) {
+		massive := &symbolClient{points: map[string][]PricePoint{"AAPL": {{Time: day, Close: 1}}}}
+		yahoo := &symbolClient{points: map[string][]PricePoint{"AAPL": {{Time: day, Close: 2}}}}
+		c, err := NewChainClient([]string{ProviderMassive, ProviderYahoo}, []Client{massive, yahoo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Prefer("AAPL", ProviderYahoo)
+		c.Prefer("AAPL", "unknown")
+		if got, _ := c.FetchDailyPrices(ctx, "AAPL", day, day); len(got) != 1 || got[0].Close != 2 || massive.calls != 0 {
+			t.Errorf("expected AAPL from Yahoo only, got %+v (massive calls %d)", got, massive.calls)
+		}
+	})
+}
+
+func TestFXChainClient_FetchDailyRates(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	down := &mockFXClient{err: errors.New("503 unavailable")}
+	ecb := &mockFXClient{points: []RatePoint{{Time: day, Rate: 1.1}}}
+
+	c, err := NewFXChainClient([]string{ProviderMassive, ProviderECB}, []FXClient{down, ecb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.FetchDailyRates(ctx, "CHF", "USD", day, day)
+	if err != nil || len(got) != 1 || got[0].Rate != 1.1 {
+		t.Fatalf("expected the ECB rate, got %+v, %v", got, err)
+	}
+	if c.Source(FXKey("CHF", "USD")) != ProviderECB {
+		t.Errorf("expected source %q, got %q", ProviderECB, c.Source(FXKey("CHF", "USD")))
+	}
+	if got := c.Providers(); len(got) != 2 || got[0] != ProviderMassive || got[1] != ProviderECB {
+		t.Errorf("unexpected providers: %v", got)
+	}
+
+	ecb.points = nil
+	if _, err := c.FetchDailyRates(ctx, "CHF", "USD", day, day); err == nil || !strings.Contains(err.Error(), "massive: 503") {
+		t.Errorf("expected the failing provider's error when no rate is returned, got %v", err)
+	}
+}
+
+func TestReferenceChainClient_GetTickerDetails(t *testing.T) {
+	ctx := context.Background()
+	unknown := &fakeRef{details: TickerDetails{Found: false}}
+	yahoo := &fakeRef{details: TickerDetails{Name: "Nestle SA", Found: true}}
+
+	c, err := NewReferenceChainClient([]string{ProviderMassive, ProviderYahoo}, []ReferenceClient{unknown, yahoo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetTickerDetails(ctx, "NESN.SW")
+	if err != nil || !got.Found || got.Name != "Nestle SA" {
+		t.Fatalf("expected the Yahoo details, got %+v, %v", got, err)
+	}
+
+	yahoo.details = TickerDetails{Found: false}
+	got, err = c.GetTickerDetails(ctx, "NOPE")
+	if err != nil || got.Found {
+		t.Errorf("expected not found without error, got %+v, %v", got, err)
+	}
+
+	unknown.err = errors.New("429 too many requests")
+	if _, err := c.GetTickerDetails(ctx, "NOPE"); !IsRateLimit429(err) {
+		t.Errorf("expected the rate limit error when no provider knows the symbol, got %v", err)
+	}
+
+	failing, err := NewReferenceChainClient([]string{ProviderMassive}, []ReferenceClient{&fakeRef{err: errors.New("boom")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := failing.GetTickerDetails(ctx, "AAPL"); err == nil {
+		t.Error("expected an error when every provider fails")
+	}
+}
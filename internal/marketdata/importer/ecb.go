@@ -0,0 +1,269 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ecbHistoryURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+	ecbRecentURL  = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+
+	// ecbRecentDays is how far back the 90 days file is used instead of the full history.
+	ecbRecentDays = 85
+	// ecbCacheTTL is how long a downloaded file is reused; the ECB publishes once a day around 16:00 CET.
+	ecbCacheTTL = time.Hour
+	ecbMaxBody  = 64 << 20
+)
+
+// ECBClient is an FXClient for the euro foreign exchange reference rates of the European Central
+// Bank. It needs no API key. The ECB only publishes rates against EUR; any other pair is derived
+// through EUR, e.g. CHF/USD = (EUR/USD) / (EUR/CHF). Rates exist for TARGET working days only.
+//
+// The history is downloaded as a whole, the recent file for the last 90 days or the full file for
+// older ranges, and cached for an hour so that the pairs of one import share a download.
+type ECBClient struct {
+	historyURL string
+	recentURL  string
+	http       *http.Client
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]ecbCacheEntry
+}
+
+type ecbCacheEntry struct {
+	fetched time.Time
+	days    []ecbDay
+}
+
+// ecbDay holds the rates of one day as units of currency per 1 EUR.
+type ecbDay struct {
+	Date  time.Time
+	Rates map[string]float64
+}
+
+// NewECBClient creates a client for the ECB reference rates.
+func NewECBClient() *ECBClient {
+	return &ECBClient{
+		historyURL: ecbHistoryURL,
+		recentURL:  ecbRecentURL,
+		http:       &http.Client{Timeout: 60 * time.Second},
+		now:        time.Now,
+		cache:      map[string]ecbCacheEntry{},
+	}
+}
+
+// FetchDailyRates implements FXClient. It returns 1 main = X secondary for every day in
+// [start, end] on which the ECB published both currencies.
+func (c *ECBClient) FetchDailyRates(ctx context.Context, main, secondary string, start, end time.Time) ([]RatePoint, error) {
+	if main == "" || secondary == "" {
+		return nil, fmt.Errorf("main and secondary currency are required")
+	}
+	main, secondary = strings.ToUpper(main), strings.ToUpper(secondary)
+
+	u := c.historyURL
+	if c.now().Sub(start) < ecbRecentDays*24*time.Hour {
+		u = c.recentURL
+	}
+	days, err := c.history(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []RatePoint
+	seenMain, seenSecondary := main == "EUR", secondary == "EUR"
+	for _, d := range days {
+		rMain, okMain := ecbRate(d, main)
+		rSecondary, okSecondary := ecbRate(d, secondary)
+		seenMain = seenMain || okMain
+		seenSecondary = seenSecondary || okSecondary
+		if !okMain || !okSecondary || !inDayRange(d.Date, start, end) {
+			continue
+		}
+		points = append(points, RatePoint{Time: d.Date, Rate: rSecondary / rMain})
+	}
+	for _, cur := range []struct {
+		code string
+		seen bool
+	}{{main, seenMain}, {secondary, seenSecondary}} {
+		if !cur.seen {
+			return nil, fmt.Errorf("currency %s is not published by the ECB", cur.code)
+		}
+	}
+	return points, nil
+}
+
+// ecbRate returns the rate of a currency per 1 EUR on day d.
+func ecbRate(d ecbDay, currency string) (float64, bool) {
+	if currency == "EUR" {
+		return 1, true
+	}
+	r, ok := d.Rates[currency]
+	return r, ok && r > 0
+}
+
+// history returns the days of the file at u, downloading it when the cached copy is stale.
+func (c *ECBClient) history(ctx context.Context, u string) ([]ecbDay, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.cache[u]; ok && c.now().Sub(e.fetched) < ecbCacheTTL {
+		return e.days, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ecb rates: unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, ecbMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	days, err := parseECBHistory(body)
+	if err != nil {
+		return nil, fmt.Errorf("ecb rates: %w", err)
+	}
+	c.cache[u] = ecbCacheEntry{fetched: c.now(), days: days}
+	return days, nil
+}
+
+// parseECBHistory parses the ECB history in its XML format, its CSV format or the zip archive
+// holding the CSV file. Days are returned oldest first.
+func parseECBHistory(data []byte) ([]ecbDay, error) {
+	var days []ecbDay
+	var err error
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("PK")):
+		days, err = parseECBZip(data)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		days, err = parseECBXML(data)
+	default:
+		days, err = parseECBCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(days, func(a, b ecbDay) int { return a.Date.Compare(b.Date) })
+	return days, nil
+}
+
+// ecbEnvelope is the gesmes envelope of eurofxref-hist.xml: Cube > Cube time > Cube currency rate.
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func parseECBXML(data []byte) ([]ecbDay, error) {
+	var env ecbEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("unable to decode XML: %w", err)
+	}
+	days := make([]ecbDay, 0, len(env.Cube.Days))
+	for _, d := range env.Cube.Days {
+		date, err := time.Parse("2006-01-02", d.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", d.Time)
+		}
+		day := ecbDay{Date: date, Rates: make(map[string]float64, len(d.Rates))}
+		for _, r := range d.Rates {
+			rate, err := strconv.ParseFloat(r.Rate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate %q of %s on %s", r.Rate, r.Currency, d.Time)
+			}
+			day.Rates[strings.ToUpper(r.Currency)] = rate
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseECBCSV parses the CSV history: a Date column followed by one column per currency, with
+// N/A or an empty cell for days a currency was not published.
+func parseECBCSV(data []byte) ([]ecbDay, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV: %w", err)
+	}
+	if len(records) == 0 || !strings.EqualFold(strings.TrimSpace(records[0][0]), "Date") {
+		return nil, fmt.Errorf("expected a Date column")
+	}
+	header := records[0]
+	days := make([]ecbDay, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		if len(rec) == 0 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", rec[0])
+		}
+		day := ecbDay{Date: date, Rates: map[string]float64{}}
+		for i := 1; i < len(rec) && i < len(header); i++ {
+			currency := strings.ToUpper(strings.TrimSpace(header[i]))
+			cell := strings.TrimSpace(rec[i])
+			if currency == "" || cell == "" || cell == "N/A" {
+				continue
+			}
+			rate, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate %q of %s on %s", cell, currency, rec[0])
+			}
+			day.Rates[currency] = rate
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseECBZip parses eurofxref-hist.zip, which holds the CSV history.
+func parseECBZip(data []byte) ([]ecbDay, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open zip: %w", err)
+	}
+	for _, f := range zr.File {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, ecbMaxBody))
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		return parseECBCSV(content)
+	}
+	return nil, fmt.Errorf("no CSV file in the zip")
+}
@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// ecbServer serves a fixture file from testdata on every path and counts the requests.
+func ecbServer(t *testing.T, fixture string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	body := readFixture(t, fixture)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func ecbClientForServer(srv *httptest.Server, now time.Time) *ECBClient {
+	c := NewECBClient()
+	c.historyURL = srv.URL + "/hist"
+	c.recentURL = srv.URL + "/recent"
+	c.now = func() time.Time { return now }
+	return c
+}
+
+func TestParseECBHistory(t *testing.T) {
+	zipped := func(t *testing.T) []byte {
+		csvData := readFixture(t, "ecb-hist.csv")
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		f, err := zw.Create("eurofxref-hist.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write(csvData)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tcs := []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{name: "xml", data: func(t *testing.T) []byte { return readFixture(t, "ecb-hist.xml") }},
+		{name: "csv", data: func(t *testing.T) []byte { return readFixture(t, "ecb-hist.csv") }},
+		{name: "zip", data: zipped},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			days, err := parseECBHistory(tc.data(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(days) != 3 {
+				t.Fatalf("got %d days, want 3", len(days))
+			}
+			first, last := days[0], days[2]
+			if !first.Date.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)) || !last.Date.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("expected days oldest first, got %s .. %s", first.Date, last.Date)
+			}
+			if last.Rates["USD"] != 1.0389 || last.Rates["CHF"] != 0.9401 {
+				t.Errorf("unexpected rates: %v", last.Rates)
+			}
+			if _, ok := first.Rates["CHF"]; ok {
+				t.Errorf("expected no CHF rate on the first day, got %v", first.Rates)
+			}
+			if _, ok := last.Rates["CYP"]; ok {
+				t.Errorf("expected N/A cells skipped, got %v", last.Rates)
+			}
+		})
+	}
+
+	if _, err := parseECBHistory([]byte("Currency,USD\nfoo,1")); err == nil {
+		t.Error("expected an error for a CSV without Date column")
+	}
+}
+
+func TestECBClient_FetchDailyRates(t *testing.T) {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	tcs := []struct {
+		name      string
+		main      string
+		secondary string
+		want      []RatePoint
+	}{
+		{
+			name: "eur to usd is the published rate", main: "EUR", secondary: "USD",
+			want: []RatePoint{{Time: start, Rate: 1.0321}, {Time: start.AddDate(0, 0, 1), Rate: 1.0299}, {Time: end, Rate: 1.0389}},
+		},
+		{
+			name: "usd to eur is inverted", main: "usd", secondary: "eur",
+			want: []RatePoint{{Time: start, Rate: 1 / 1.0321}, {Time: start.AddDate(0, 0, 1), Rate: 1 / 1.0299}, {Time: end, Rate: 1 / 1.0389}},
+		},
+		{
+			// CHF is missing on the first day
+			name: "chf to usd is triangulated through eur", main: "CHF", secondary: "USD",
+			want: []RatePoint{{Time: start.AddDate(0, 0, 1), Rate: 1.0299 / 0.9371}, {Time: end, Rate: 1.0389 / 0.9401}},
+		},
+	}
+	for _, fixture := range []string{"ecb-hist.xml", "ecb-hist.csv"} {
+		for _, tc := range tcs {
+			t.Run(fixture+" "+tc.name, func(t *testing.T) {
+				srv, _ := ecbServer(t, fixture)
+				got, err := ecbClientForServer(srv, end).FetchDailyRates(context.Background(), tc.main, tc.secondary, start, end)
+				if err != nil {
+					t.Fatalf("FetchDailyRates: %v", err)
+				}
+				if len(got) != len(tc.want) {
+					t.Fatalf("got %+v, want %+v", got, tc.want)
+				}
+				for i := range tc.want {
+					if !got[i].Time.Equal(tc.want[i].Time) || !near(got[i].Rate, tc.want[i].Rate) {
+						t.Errorf("point %d: got %+v, want %+v", i, got[i], tc.want[i])
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("range is applied", func(t *testing.T) {
+		srv, _ := ecbServer(t, "ecb-hist.xml")
+		got, err := ecbClientForServer(srv, end).FetchDailyRates(context.Background(), "EUR", "JPY", end, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Rate != 163.62 {
+			t.Errorf("expected only the last day, got %+v", got)
+		}
+	})
+
+	t.Run("unknown currency", func(t *testing.T) {
+		srv, _ := ecbServer(t, "ecb-hist.xml")
+		if _, err := ecbClientForServer(srv, end).FetchDailyRates(context.Background(), "CHF", "XYZ", start, end); err == nil {
+			t.Error("expected an error for a currency the ECB does not publish")
+		}
+	})
+
+	t.Run("server errors surface", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		if _, err := ecbClientForServer(srv, end).FetchDailyRates(context.Background(), "EUR", "USD", start, end); err == nil {
+			t.Error("expected an error for a 503 response")
+		}
+	})
+}
+
+func TestECBClient_Download(t *testing.T) {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	t.Run("recent or full history by range", func(t *testing.T) {
+		var paths []string
+		body := readFixture(t, "ecb-hist.xml")
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			_, _ = w.Write(body)
+		}))
+		defer srv.Close()
+
+		if _, err := ecbClientForServer(srv, end).FetchDailyRates(context.Background(), "EUR", "USD", start, end); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ecbClientForServer(srv, end.AddDate(1, 0, 0)).FetchDailyRates(context.Background(), "EUR", "USD", start, end); err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 2 || paths[0] != "/recent" || paths[1] != "/hist" {
+			t.Errorf("expected the recent file then the full history, got %v", paths)
+		}
+	})
+
+	t.Run("download is cached", func(t *testing.T) {
+		srv, hits := ecbServer(t, "ecb-hist.xml")
+		now := end
+		c := ecbClientForServer(srv, end)
+		c.now = func() time.Time { return now }
+		for _, pair := range [][2]string{{"CHF", "USD"}, {"EUR", "GBP"}} {
+			if _, err := c.FetchDailyRates(context.Background(), pair[0], pair[1], start, end); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if hits.Load() != 1 {
+			t.Errorf("expected one download for two pairs, got %d", hits.Load())
+		}
+		now = now.Add(2 * ecbCacheTTL)
+		if _, err := c.FetchDailyRates(context.Background(), "CHF", "USD", start, end); err != nil {
+			t.Fatal(err)
+		}
+		if hits.Load() != 2 {
+			t.Errorf("expected a new download after the cache expired, got %d", hits.Load())
+		}
+	})
+
+	t.Run("usable in an FX pool", func(t *testing.T) {
+		srv, _ := ecbServer(t, "ecb-hist.csv")
+		pool, err := NewFXPool(ecbClientForServer(srv, end))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := pool.FetchDailyRates(context.Background(), "EUR", "CHF", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Errorf("expected the two days with a CHF rate, got %+v", got)
+		}
+	})
+}
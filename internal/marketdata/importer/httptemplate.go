@@ -0,0 +1,398 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HTTPFormatJSON = "json"
+	HTTPFormatCSV  = "csv"
+
+	// HTTPDateKey as the Date field of a JSON feed whose rows are an object keyed by date,
+	// e.g. {"2025-01-02": {"close": 100}}.
+	HTTPDateKey = "$key"
+
+	httpMaxBody = 32 << 20
+)
+
+// HTTPTemplateConfig describes a self-hosted or third-party feed of daily prices and rates, so it
+// can be used without a dedicated client. The URLs accept the placeholders {symbol}, {main},
+// {secondary}, {start} and {end} (formatted with DateLayout), {startUnix} and {endUnix}.
+type HTTPTemplateConfig struct {
+	PricesURL    string // e.g. https://feed.example.com/prices/{symbol}?from={start}&to={end}
+	RatesURL     string // e.g. https://feed.example.com/fx/{main}/{secondary}?from={start}&to={end}
+	Format       string // json (default) | csv
+	DateLayout   string // Go layout of the {start} and {end} placeholders, default 2006-01-02
+	CsvSeparator string // default ","
+	AuthHeader   string // optional header sent with every request, e.g. Authorization
+	AuthValue    string // e.g. "Bearer <token>"
+	Fields       HTTPFieldMapping
+}
+
+// HTTPFieldMapping maps the fields of a feed to a candle. For JSON, Items is the dot path to the
+// rows (empty: the document itself) and the other fields are keys of a row; for CSV they are
+// column headers. Only Date and Close are required.
+type HTTPFieldMapping struct {
+	Items      string
+	Date       string // HTTPDateKey when the rows are an object keyed by date
+	DateFormat string // Go layout, "unix" or "unixms"; default 2006-01-02
+	Open       string
+	High       string
+	Low        string
+	Close      string
+	Volume     string
+	Rate       string // field of the rate in the rates feed, default Close
+}
+
+// HTTPTemplateClient implements Client and FXClient for any HTTP feed described by an HTTPTemplateConfig.
+type HTTPTemplateClient struct {
+	cfg  HTTPTemplateConfig
+	http *http.Client
+}
+
+// NewHTTPTemplateClient validates the config and returns a client for it.
+func NewHTTPTemplateClient(cfg HTTPTemplateConfig) (*HTTPTemplateClient, error) {
+	if cfg.PricesURL == "" && cfg.RatesURL == "" {
+		return nil, fmt.Errorf("a prices or rates URL is required")
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = HTTPFormatJSON
+	case HTTPFormatJSON, HTTPFormatCSV:
+	default:
+		return nil, fmt.Errorf("unknown format %q: must be %q or %q", cfg.Format, HTTPFormatJSON, HTTPFormatCSV)
+	}
+	if cfg.Fields.Date == "" {
+		return nil, fmt.Errorf("the date field is required")
+	}
+	if cfg.Fields.Date == HTTPDateKey && cfg.Format != HTTPFormatJSON {
+		return nil, fmt.Errorf("%s dates are only supported for JSON feeds", HTTPDateKey)
+	}
+	if cfg.Fields.Close == "" && (cfg.PricesURL != "" || cfg.Fields.Rate == "") {
+		return nil, fmt.Errorf("the close field is required")
+	}
+	if cfg.Fields.Rate == "" {
+		cfg.Fields.Rate = cfg.Fields.Close
+	}
+	if cfg.DateLayout == "" {
+		cfg.DateLayout = "2006-01-02"
+	}
+	if cfg.Fields.DateFormat == "" {
+		cfg.Fields.DateFormat = "2006-01-02"
+	}
+	if cfg.CsvSeparator == "" {
+		cfg.CsvSeparator = ","
+	}
+	if len([]rune(cfg.CsvSeparator)) != 1 {
+		return nil, fmt.Errorf("the CSV separator must be a single character")
+	}
+	return &HTTPTemplateClient{cfg: cfg, http: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// expandURL fills the placeholders of a URL template; values are escaped for use in paths and queries.
+func (c *HTTPTemplateClient) expandURL(tmpl string, vars map[string]string, start, end time.Time) string {
+	pairs := []string{
+		"{start}", url.QueryEscape(start.Format(c.cfg.DateLayout)),
+		"{end}", url.QueryEscape(end.Format(c.cfg.DateLayout)),
+		"{startUnix}", strconv.FormatInt(start.Unix(), 10),
+		"{endUnix}", strconv.FormatInt(end.Unix(), 10),
+	}
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", url.PathEscape(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+// fetch returns the body of a GET request to u.
+func (c *HTTPTemplateClient) fetch(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.AuthHeader != "" {
+		req.Header.Set(c.cfg.AuthHeader, c.cfg.AuthValue)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, httpMaxBody))
+}
+
+// FetchDailyPrices implements Client using the PricesURL template.
+func (c *HTTPTemplateClient) FetchDailyPrices(ctx context.Context, symbol string, start, end time.Time) ([]PricePoint, error) {
+	if c.cfg.PricesURL == "" {
+		return nil, fmt.Errorf("no prices URL configured")
+	}
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	body, err := c.fetch(ctx, c.expandURL(c.cfg.PricesURL, map[string]string{"symbol": symbol}, start, end))
+	if err != nil {
+		return nil, fmt.Errorf("prices of %s: %w", symbol, err)
+	}
+	rows, err := c.parseRows(body)
+	if err != nil {
+		return nil, fmt.Errorf("prices of %s: %w", symbol, err)
+	}
+
+	f := c.cfg.Fields
+	var points []PricePoint
+	for _, row := range rows {
+		t, err := row.date(f.Date, f.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("prices of %s: %w", symbol, err)
+		}
+		if !inDayRange(t, start, end) {
+			continue
+		}
+		closeVal, ok, err := row.number(f.Close)
+		if err != nil {
+			return nil, fmt.Errorf("prices of %s: %w", symbol, err)
+		}
+		if !ok {
+			continue
+		}
+		p := PricePoint{Time: t, Close: closeVal}
+		for _, field := range []struct {
+			name string
+			dst  *float64
+		}{{f.Open, &p.Open}, {f.High, &p.High}, {f.Low, &p.Low}, {f.Volume, &p.Volume}} {
+			if field.name == "" {
+				continue
+			}
+			if *field.dst, _, err = row.number(field.name); err != nil {
+				return nil, fmt.Errorf("prices of %s: %w", symbol, err)
+			}
+		}
+		points = append(points, p)
+	}
+	slices.SortFunc(points, func(a, b PricePoint) int { return a.Time.Compare(b.Time) })
+	return points, nil
+}
+
+// FetchDailyRates implements FXClient using the RatesURL template.
+func (c *HTTPTemplateClient) FetchDailyRates(ctx context.Context, main, secondary string, start, end time.Time) ([]RatePoint, error) {
+	if c.cfg.RatesURL == "" {
+		return nil, fmt.Errorf("no rates URL configured")
+	}
+	if main == "" || secondary == "" {
+		return nil, fmt.Errorf("main and secondary currency are required")
+	}
+	pair := strings.ToUpper(main) + "/" + strings.ToUpper(secondary)
+	vars := map[string]string{"main": strings.ToUpper(main), "secondary": strings.ToUpper(secondary)}
+	body, err := c.fetch(ctx, c.expandURL(c.cfg.RatesURL, vars, start, end))
+	if err != nil {
+		return nil, fmt.Errorf("rates of %s: %w", pair, err)
+	}
+	rows, err := c.parseRows(body)
+	if err != nil {
+		return nil, fmt.Errorf("rates of %s: %w", pair, err)
+	}
+
+	var points []RatePoint
+	for _, row := range rows {
+		t, err := row.date(c.cfg.Fields.Date, c.cfg.Fields.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("rates of %s: %w", pair, err)
+		}
+		if !inDayRange(t, start, end) {
+			continue
+		}
+		rate, ok, err := row.number(c.cfg.Fields.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("rates of %s: %w", pair, err)
+		}
+		if ok {
+			points = append(points, RatePoint{Time: t, Rate: rate})
+		}
+	}
+	slices.SortFunc(points, func(a, b RatePoint) int { return a.Time.Compare(b.Time) })
+	return points, nil
+}
+
+// inDayRange reports whether t falls on a day in [start, end].
+func inDayRange(t, start, end time.Time) bool {
+	day := func(v time.Time) time.Time { return time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC) }
+	d := day(t)
+	return !d.Before(day(start)) && !d.After(day(end))
+}
+
+// httpRow is one row of a feed: the fields of a JSON object or the cells of a CSV line.
+type httpRow struct {
+	key    string // key of the row when the JSON rows are an object keyed by date
+	fields map[string]any
+}
+
+func (c *HTTPTemplateClient) parseRows(body []byte) ([]httpRow, error) {
+	if c.cfg.Format == HTTPFormatCSV {
+		return parseCSVRows(body, []rune(c.cfg.CsvSeparator)[0])
+	}
+	return parseJSONRows(body, c.cfg.Fields.Items)
+}
+
+func parseCSVRows(body []byte, sep rune) ([]httpRow, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.Comma = sep
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	rows := make([]httpRow, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		fields := make(map[string]any, len(header))
+		for i, name := range header {
+			if i < len(rec) {
+				fields[strings.TrimSpace(name)] = rec[i]
+			}
+		}
+		rows = append(rows, httpRow{fields: fields})
+	}
+	return rows, nil
+}
+
+func parseJSONRows(body []byte, itemsPath string) ([]httpRow, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON: %w", err)
+	}
+	items := doc
+	if itemsPath != "" {
+		var ok bool
+		if items, ok = lookupPath(doc, itemsPath); !ok {
+			return nil, fmt.Errorf("items %q not found in the response", itemsPath)
+		}
+	}
+
+	var rows []httpRow
+	switch v := items.(type) {
+	case []any:
+		for _, item := range v {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("items must be objects")
+			}
+			rows = append(rows, httpRow{fields: obj})
+		}
+	case map[string]any:
+		for key, item := range v {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("items must be objects")
+			}
+			rows = append(rows, httpRow{key: key, fields: obj})
+		}
+	default:
+		return nil, fmt.Errorf("items must be a list or an object")
+	}
+	return rows, nil
+}
+
+// lookupPath resolves a dot path in a decoded JSON document.
+func lookupPath(doc any, path string) (any, bool) {
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// value returns the field of the row; a key containing dots is tried as is before as a path.
+func (r httpRow) value(name string) (any, bool) {
+	if v, ok := r.fields[name]; ok {
+		return v, v != nil
+	}
+	if strings.Contains(name, ".") {
+		v, ok := lookupPath(r.fields, name)
+		return v, ok && v != nil
+	}
+	return nil, false
+}
+
+// number returns the field as a float; ok is false when the field is missing or empty.
+func (r httpRow) number(name string) (float64, bool, error) {
+	v, ok := r.value(name)
+	if !ok {
+		return 0, false, nil
+	}
+	var s string
+	switch n := v.(type) {
+	case json.Number:
+		s = n.String()
+	case string:
+		s = strings.TrimSpace(n)
+	default:
+		return 0, false, fmt.Errorf("field %q is not a number", name)
+	}
+	if s == "" || s == "null" {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("field %q: invalid number %q", name, s)
+	}
+	return f, true, nil
+}
+
+// date returns the date of the row at UTC midnight.
+func (r httpRow) date(name, format string) (time.Time, error) {
+	var raw string
+	if name == HTTPDateKey {
+		raw = r.key
+	} else {
+		v, ok := r.value(name)
+		if !ok {
+			return time.Time{}, fmt.Errorf("date field %q missing", name)
+		}
+		raw = strings.TrimSpace(fmt.Sprint(v))
+	}
+
+	var t time.Time
+	switch format {
+	case "unix", "unixms":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", raw)
+		}
+		if format == "unixms" {
+			t = time.UnixMilli(n).UTC()
+		} else {
+			t = time.Unix(n, 0).UTC()
+		}
+	default:
+		var err error
+		if t, err = time.Parse(format, raw); err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q: %w", raw, err)
+		}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
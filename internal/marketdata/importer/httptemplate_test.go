@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPTemplateClient(t *testing.T) {
+	tcs := []struct {
+		name    string
+		cfg     HTTPTemplateConfig
+		wantErr bool
+	}{
+		{name: "valid", cfg: HTTPTemplateConfig{PricesURL: "http://x/{symbol}", Fields: HTTPFieldMapping{Date: "date", Close: "close"}}},
+		{name: "rates only with a rate field", cfg: HTTPTemplateConfig{RatesURL: "http://x", Fields: HTTPFieldMapping{Date: "date", Rate: "rate"}}},
+		{name: "no url", cfg: HTTPTemplateConfig{Fields: HTTPFieldMapping{Date: "date", Close: "close"}}, wantErr: true},
+		{name: "no close", cfg: HTTPTemplateConfig{PricesURL: "http://x", Fields: HTTPFieldMapping{Date: "date"}}, wantErr: true},
+		{name: "unknown format", cfg: HTTPTemplateConfig{PricesURL: "http://x", Format: "xml", Fields: HTTPFieldMapping{Date: "date", Close: "close"}}, wantErr: true},
+		{name: "date keys in csv", cfg: HTTPTemplateConfig{PricesURL: "http://x", Format: HTTPFormatCSV, Fields: HTTPFieldMapping{Date: HTTPDateKey, Close: "close"}}, wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHTTPTemplateClient(tc.cfg)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHTTPTemplateClient_FetchDailyPrices(t *testing.T) {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	t.Run("json list with auth header", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/prices/BRK.B" || r.URL.Query().Get("from") != "02.01.2025" || r.URL.Query().Get("to") != "03.01.2025" {
+				t.Errorf("unexpected request: %s", r.URL)
+			}
+			if r.Header.Get("X-Api-Key") != "secret" {
+				t.Errorf("expected the auth header, got %q", r.Header.Get("X-Api-Key"))
+			}
+			// out of range and unsorted rows, prices as strings or numbers
+			writeJSON(w, `{"data":{"rows":[
+				{"day":"2025-01-03","last":"101.5","vol":10},
+				{"day":"2025-01-02","last":100,"first":99,"vol":20},
+				{"day":"2025-01-01","last":90}
+			]}}`)
+		}))
+		defer srv.Close()
+
+		c, err := NewHTTPTemplateClient(HTTPTemplateConfig{
+			PricesURL:  srv.URL + "/prices/{symbol}?from={start}&to={end}",
+			DateLayout: "02.01.2006",
+			AuthHeader: "X-Api-Key",
+			AuthValue:  "secret",
+			Fields:     HTTPFieldMapping{Items: "data.rows", Date: "day", Open: "first", Close: "last", Volume: "vol"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.FetchDailyPrices(context.Background(), "BRK.B", start, end)
+		if err != nil {
+			t.Fatalf("FetchDailyPrices: %v", err)
+		}
+		want := []PricePoint{
+			{Time: start, Open: 99, Close: 100, Volume: 20},
+			{Time: end, Close: 101.5, Volume: 10},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("point %d: got %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("json object keyed by date", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, `{"Time Series (Daily)":{"2025-01-02":{"1. open":"99","4. close":"100"}}}`)
+		}))
+		defer srv.Close()
+
+		c, err := NewHTTPTemplateClient(HTTPTemplateConfig{
+			PricesURL: srv.URL,
+			Fields:    HTTPFieldMapping{Items: "Time Series (Daily)", Date: HTTPDateKey, Open: "1. open", Close: "4. close"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.FetchDailyPrices(context.Background(), "IBM", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Open != 99 || got[0].Close != 100 || !got[0].Time.Equal(start) {
+			t.Errorf("unexpected points: %+v", got)
+		}
+	})
+
+	t.Run("csv with unix timestamps", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ts;close\n1735776000;100\n1735862400;\n"))
+		}))
+		defer srv.Close()
+
+		c, err := NewHTTPTemplateClient(HTTPTemplateConfig{
+			PricesURL:    srv.URL,
+			Format:       HTTPFormatCSV,
+			CsvSeparator: ";",
+			Fields:       HTTPFieldMapping{Date: "ts", DateFormat: "unix", Close: "close"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.FetchDailyPrices(context.Background(), "X", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Close != 100 || !got[0].Time.Equal(start) {
+			t.Errorf("expected the empty close skipped, got %+v", got)
+		}
+	})
+
+	t.Run("errors surface", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		c, err := NewHTTPTemplateClient(HTTPTemplateConfig{PricesURL: srv.URL, Fields: HTTPFieldMapping{Date: "d", Close: "c"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.FetchDailyPrices(context.Background(), "X", start, end); err == nil {
+			t.Error("expected an error for a 401 response")
+		}
+		if _, err := c.FetchDailyRates(context.Background(), "CHF", "USD", start, end); err == nil {
+			t.Error("expected an error without a rates URL")
+		}
+	})
+}
+
+func TestHTTPTemplateClient_FetchDailyRates(t *testing.T) {
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fx/CHF/USD" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		writeJSON(w, `[{"date":"2025-01-02","rate":1.1}]`)
+	}))
+	defer srv.Close()
+
+	c, err := NewHTTPTemplateClient(HTTPTemplateConfig{
+		RatesURL: srv.URL + "/fx/{main}/{secondary}",
+		Fields:   HTTPFieldMapping{Date: "date", Rate: "rate"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.FetchDailyRates(context.Background(), "chf", "usd", day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Rate != 1.1 || !got[0].Time.Equal(day) {
+		t.Errorf("unexpected rates: %+v", got)
+	}
+}
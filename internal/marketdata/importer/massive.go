@@ -95,6 +95,51 @@ func (c *MassiveClient) GetTickerDetails(ctx context.Context, symbol string) (Ti
 	}, nil
 }
 
+// massiveTypeToAppType maps raw Massive ticker type codes to the app's Type values.
+// Anything not listed passes through unchanged (shown via the instrument dialog's "Other" field).
+var massiveTypeToAppType = map[string]string{
+	"CS":   "Stock",
+	"ADRC": "Stock",
+	"ETF":  "ETF",
+	"BOND": "Bond",
+}
+
+// massiveMicToExchange maps Massive primary-exchange MIC codes to the app's exchange names.
+// Anything not listed passes through unchanged (shown via the instrument dialog's "Other" field).
+var massiveMicToExchange = map[string]string{
+	"XNYS": "NYSE",
+	"XNAS": "NASDAQ",
+	"XLON": "LSE",
+	"XTSE": "TSX",
+	"XPAR": "Euronext",
+	"XAMS": "Euronext",
+	"XBRU": "Euronext",
+	"XETR": "XETRA",
+	"XSWX": "SIX",
+	"XTKS": "JPX (Tokyo)",
+	"XHKG": "HKEX",
+	"XASX": "ASX",
+	"XMAD": "BME (Madrid)",
+	"XMIL": "Borsa Italiana",
+	"XSTO": "Nasdaq Nordic",
+}
+
+// MassiveAppType maps a raw Massive ticker type code to the app's instrument type.
+func MassiveAppType(code string) string {
+	if mapped, ok := massiveTypeToAppType[code]; ok {
+		return mapped
+	}
+	return code
+}
+
+// MassiveAppExchange maps a raw Massive primary-exchange MIC code to the app's exchange name.
+func MassiveAppExchange(mic string) string {
+	if mapped, ok := massiveMicToExchange[mic]; ok {
+		return mapped
+	}
+	return mic
+}
+
 // forexTicker returns the Massive/Polygon forex ticker for the pair (main/secondary).
 // Format is C:MAINSEC e.g. C:CHFUSD for 1 CHF = X USD.
 func forexTicker(main, secondary string) string {
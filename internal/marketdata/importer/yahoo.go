@@ -0,0 +1,242 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	yahooBaseURL   = "https://query1.finance.yahoo.com"
+	yahooUserAgent = "Mozilla/5.0 (compatible; etna)"
+)
+
+// YahooClient fetches daily prices, forex rates and ticker metadata from the public Yahoo Finance
+// chart API. It needs no API key, which makes it a fallback when no Massive key is configured; the
+// API is unofficial and may throttle or change without notice.
+type YahooClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewYahooClient creates a client for the Yahoo Finance chart API.
+func NewYahooClient() *YahooClient {
+	return &YahooClient{baseURL: yahooBaseURL, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// yahooChart is the subset of the /v8/finance/chart response used by the client.
+type yahooChart struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				Currency       string `json:"currency"`
+				Symbol         string `json:"symbol"`
+				ExchangeName   string `json:"exchangeName"`
+				FullExchange   string `json:"fullExchangeName"`
+				InstrumentType string `json:"instrumentType"`
+				LongName       string `json:"longName"`
+				ShortName      string `json:"shortName"`
+				GmtOffset      int64  `json:"gmtoffset"`
+			} `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Open   []*float64 `json:"open"`
+					High   []*float64 `json:"high"`
+					Low    []*float64 `json:"low"`
+					Close  []*float64 `json:"close"`
+					Volume []*float64 `json:"volume"`
+				} `json:"quote"`
+			} `json:"indicators"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// errYahooNotFound is returned by chart when Yahoo does not know the symbol.
+var errYahooNotFound = errors.New("symbol not found")
+
+// chart calls the chart API for symbol with the given query parameters.
+func (c *YahooClient) chart(ctx context.Context, symbol string, params url.Values) (yahooChart, error) {
+	var out yahooChart
+	u := fmt.Sprintf("%s/v8/finance/chart/%s?%s", c.baseURL, url.PathEscape(symbol), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return out, err
+	}
+	req.Header.Set("User-Agent", yahooUserAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return out, errYahooNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return out, fmt.Errorf("yahoo chart %s: unexpected status %s", symbol, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return out, fmt.Errorf("yahoo chart %s: unable to decode response: %w", symbol, err)
+	}
+	if e := out.Chart.Error; e != nil {
+		if e.Code == "Not Found" {
+			return out, errYahooNotFound
+		}
+		return out, fmt.Errorf("yahoo chart %s: %s: %s", symbol, e.Code, e.Description)
+	}
+	if len(out.Chart.Result) == 0 {
+		return out, errYahooNotFound
+	}
+	return out, nil
+}
+
+// yahooDailyRange returns the chart parameters for daily candles in [start, end] (inclusive).
+func yahooDailyRange(start, end time.Time) url.Values {
+	return url.Values{
+		"period1":  {strconv.FormatInt(start.Unix(), 10)},
+		"period2":  {strconv.FormatInt(end.AddDate(0, 0, 1).Unix(), 10)},
+		"interval": {"1d"},
+	}
+}
+
+// FetchDailyPrices implements Client. Candles are timestamped at UTC midnight of the trading day
+// in the exchange's time zone; days without a close are skipped.
+func (c *YahooClient) FetchDailyPrices(ctx context.Context, symbol string, start, end time.Time) ([]PricePoint, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	res, err := c.chart(ctx, symbol, yahooDailyRange(start, end))
+	if err != nil {
+		return nil, err
+	}
+	r := res.Chart.Result[0]
+	if len(r.Indicators.Quote) == 0 {
+		return nil, nil
+	}
+	q := r.Indicators.Quote[0]
+	value := func(vals []*float64, i int) float64 {
+		if i < len(vals) && vals[i] != nil {
+			return *vals[i]
+		}
+		return 0
+	}
+
+	var points []PricePoint
+	for i, ts := range r.Timestamp {
+		if i >= len(q.Close) || q.Close[i] == nil {
+			continue
+		}
+		points = append(points, PricePoint{
+			Time:   yahooDay(ts, r.Meta.GmtOffset),
+			Open:   value(q.Open, i),
+			High:   value(q.High, i),
+			Low:    value(q.Low, i),
+			Close:  *q.Close[i],
+			Volume: value(q.Volume, i),
+		})
+	}
+	return points, nil
+}
+
+// yahooDay returns UTC midnight of the day ts falls on in the exchange's time zone.
+func yahooDay(ts, gmtOffset int64) time.Time {
+	t := time.Unix(ts+gmtOffset, 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// yahooForexSymbol returns the Yahoo symbol of the pair (main/secondary), e.g. CHFUSD=X for 1 CHF = X USD.
+func yahooForexSymbol(main, secondary string) string {
+	return strings.ToUpper(main) + strings.ToUpper(secondary) + "=X"
+}
+
+// FetchDailyRates implements FXClient using the close of the daily forex candles as the rate.
+func (c *YahooClient) FetchDailyRates(ctx context.Context, main, secondary string, start, end time.Time) ([]RatePoint, error) {
+	if main == "" || secondary == "" {
+		return nil, fmt.Errorf("main and secondary currency are required")
+	}
+	prices, err := c.FetchDailyPrices(ctx, yahooForexSymbol(main, secondary), start, end)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]RatePoint, 0, len(prices))
+	for _, p := range prices {
+		points = append(points, RatePoint{Time: p.Time, Rate: p.Close})
+	}
+	return points, nil
+}
+
+// yahooTypeToMassive maps Yahoo instrument types to the Massive type codes, so that
+// MassiveAppType applies to the details of both providers.
+var yahooTypeToMassive = map[string]string{
+	"EQUITY": "CS",
+	"ETF":    "ETF",
+}
+
+// yahooExchangeToMic maps Yahoo exchange codes to MIC codes, so that MassiveAppExchange applies.
+var yahooExchangeToMic = map[string]string{
+	"NMS": "XNAS",
+	"NGM": "XNAS",
+	"NCM": "XNAS",
+	"NYQ": "XNYS",
+	"LSE": "XLON",
+	"TOR": "XTSE",
+	"PAR": "XPAR",
+	"AMS": "XAMS",
+	"BRU": "XBRU",
+	"GER": "XETR",
+	"EBS": "XSWX",
+	"JPX": "XTKS",
+	"HKG": "XHKG",
+	"ASX": "XASX",
+	"MCE": "XMAD",
+	"MIL": "XMIL",
+	"STO": "XSTO",
+}
+
+// GetTickerDetails implements ReferenceClient from the metadata of the chart API. A symbol Yahoo
+// does not know returns TickerDetails{Found: false} with a nil error.
+func (c *YahooClient) GetTickerDetails(ctx context.Context, symbol string) (TickerDetails, error) {
+	if symbol == "" {
+		return TickerDetails{}, fmt.Errorf("symbol is required")
+	}
+	res, err := c.chart(ctx, strings.ToUpper(symbol), url.Values{"range": {"1d"}, "interval": {"1d"}})
+	if err != nil {
+		if errors.Is(err, errYahooNotFound) {
+			return TickerDetails{Found: false}, nil
+		}
+		return TickerDetails{}, err
+	}
+	m := res.Chart.Result[0].Meta
+	name := m.LongName
+	if name == "" {
+		name = m.ShortName
+	}
+	typ, ok := yahooTypeToMassive[m.InstrumentType]
+	if !ok {
+		typ = m.InstrumentType
+	}
+	exchange, ok := yahooExchangeToMic[m.ExchangeName]
+	if !ok {
+		exchange = m.ExchangeName
+	}
+	return TickerDetails{
+		Name:     name,
+		Currency: strings.ToUpper(m.Currency),
+		Type:     typ,
+		Exchange: exchange,
+		Notes:    m.FullExchange,
+		Found:    name != "" || m.InstrumentType != "",
+	}, nil
+}
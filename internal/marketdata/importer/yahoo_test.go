@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func yahooClientForServer(srv *httptest.Server) *YahooClient {
+	c := NewYahooClient()
+	c.baseURL = srv.URL
+	return c
+}
+
+func TestYahooClient_FetchDailyPrices(t *testing.T) {
+	day1 := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	// candles are stamped at the market open, 14:30 UTC for a New York listing
+	open1 := strconv.FormatInt(day1.Add(14*time.Hour+30*time.Minute).Unix(), 10)
+	open2 := strconv.FormatInt(day2.Add(14*time.Hour+30*time.Minute).Unix(), 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v8/finance/chart/AAPL" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("interval") != "1d" || r.URL.Query().Get("period1") != strconv.FormatInt(day1.Unix(), 10) {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		// the second day has no close and must be skipped
+		writeJSON(w, `{"chart":{"result":[{"meta":{"currency":"USD","gmtoffset":-18000},`+
+			`"timestamp":[`+open1+`,`+open2+`],`+
+			`"indicators":{"quote":[{"open":[98,null],"high":[101,null],"low":[97,null],"close":[100,null],"volume":[1000,null]}]}}],"error":null}}`)
+	}))
+	defer srv.Close()
+
+	got, err := yahooClientForServer(srv).FetchDailyPrices(context.Background(), "AAPL", day1, day2)
+	if err != nil {
+		t.Fatalf("FetchDailyPrices: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d points, want 1", len(got))
+	}
+	p := got[0]
+	if !p.Time.Equal(day1) || p.Open != 98 || p.High != 101 || p.Low != 97 || p.Close != 100 || p.Volume != 1000 {
+		t.Errorf("unexpected point: %+v", p)
+	}
+}
+
+func TestYahooClient_FetchDailyRates(t *testing.T) {
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v8/finance/chart/CHFUSD=X" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		writeJSON(w, `{"chart":{"result":[{"meta":{"currency":"USD","gmtoffset":0},`+
+			`"timestamp":[`+strconv.FormatInt(day.Unix(), 10)+`],`+
+			`"indicators":{"quote":[{"close":[1.1]}]}}],"error":null}}`)
+	}))
+	defer srv.Close()
+
+	got, err := yahooClientForServer(srv).FetchDailyRates(context.Background(), "chf", "usd", day, day)
+	if err != nil {
+		t.Fatalf("FetchDailyRates: %v", err)
+	}
+	if len(got) != 1 || !got[0].Time.Equal(day) || got[0].Rate != 1.1 {
+		t.Errorf("unexpected rates: %+v", got)
+	}
+}
+
+func TestYahooClient_GetTickerDetails(t *testing.T) {
+	t.Run("maps metadata to the Massive codes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, `{"chart":{"result":[{"meta":{"currency":"usd","symbol":"AAPL","exchangeName":"NMS",`+
+				`"fullExchangeName":"NasdaqGS","instrumentType":"EQUITY","longName":"Apple Inc."}}],"error":null}}`)
+		}))
+		defer srv.Close()
+
+		got, err := yahooClientForServer(srv).GetTickerDetails(context.Background(), "aapl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := TickerDetails{Name: "Apple Inc.", Currency: "USD", Type: "CS", Exchange: "XNAS", Notes: "NasdaqGS", Found: true}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		if MassiveAppType(got.Type) != "Stock" || MassiveAppExchange(got.Exchange) != "NASDAQ" {
+			t.Errorf("expected the codes to map to app values, got %q and %q", MassiveAppType(got.Type), MassiveAppExchange(got.Exchange))
+		}
+	})
+
+	t.Run("unknown symbol is not found", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, `{"chart":{"result":null,"error":{"code":"Not Found","description":"No data found"}}}`)
+		}))
+		defer srv.Close()
+
+		got, err := yahooClientForServer(srv).GetTickerDetails(context.Background(), "NOPE")
+		if err != nil || got.Found {
+			t.Errorf("expected not found without error, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("server errors surface", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "throttled", http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		if _, err := yahooClientForServer(srv).GetTickerDetails(context.Background(), "AAPL"); err == nil {
+			t.Error("expected an error for a 429 response")
+		}
+	})
+}
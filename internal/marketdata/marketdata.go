@@ -207,7 +207,7 @@ func (s *Store) RegisterInstrument(ctx context.Context, symbol string) error {
 	if symbol == "" {
 		return fmt.Errorf("instrument symbol cannot be empty")
 	}
-	if err := s.store.DefineSeries(ctx, ohlcvSeries(symbol)); err != nil {
+	if err := s.defineSeries(ctx, ohlcvSeries(symbol)); err != nil {
 		return fmt.Errorf("failed to define series for %q: %w", symbol, err)
 	}
 	return nil
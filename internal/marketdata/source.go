@@ -0,0 +1,79 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-bumbu/timeseries"
+)
+
+// labelSource is the series label holding the market data provider that imported the data (e.g.
+// "massive", "yahoo"). Unlike the identity labels it is not part of the static series definitions,
+// so every re-define goes through defineSeries, which carries it over.
+const labelSource = "source"
+
+// defineSeries defines cfg, keeping the source label recorded on the existing series.
+func (s *Store) defineSeries(ctx context.Context, cfg timeseries.Series) error {
+	existing, err := s.store.GetSeries(ctx, cfg.Name)
+	if err != nil && !errors.Is(err, timeseries.ErrSeriesNotFound) {
+		return err
+	}
+	if src := existing.Labels[labelSource]; src != "" {
+		cfg.Labels[labelSource] = src
+	}
+	return s.store.DefineSeries(ctx, cfg)
+}
+
+// setSeriesSource records source on an existing series; it is a no-op when unchanged.
+func (s *Store) setSeriesSource(ctx context.Context, cfg timeseries.Series, source string) error {
+	if source == "" {
+		return fmt.Errorf("source cannot be empty")
+	}
+	if _, err := s.store.GetSeries(ctx, cfg.Name); err != nil {
+		return err
+	}
+	cfg.Labels[labelSource] = source
+	return s.store.DefineSeries(ctx, cfg)
+}
+
+// seriesSource returns the source label of a series, or "" when none was recorded.
+func (s *Store) seriesSource(ctx context.Context, name string) (string, error) {
+	ts, err := s.store.GetSeries(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return ts.Labels[labelSource], nil
+}
+
+// SetPriceSource records the provider that imported the prices of symbol. The price series must exist.
+func (s *Store) SetPriceSource(ctx context.Context, symbol, source string) error {
+	if symbol == "" {
+		return fmt.Errorf("instrument symbol cannot be empty")
+	}
+	if err := s.setSeriesSource(ctx, ohlcvSeries(symbol), source); err != nil {
+		return fmt.Errorf("failed to set source of %q: %w", symbol, err)
+	}
+	return nil
+}
+
+// PriceSource returns the provider that last imported the prices of symbol, or "" when unknown.
+func (s *Store) PriceSource(ctx context.Context, symbol string) (string, error) {
+	return s.seriesSource(ctx, seriesName(symbol))
+}
+
+// SetRateSource records the provider that imported the rates of the pair. The FX series must exist.
+func (s *Store) SetRateSource(ctx context.Context, main, secondary, source string) error {
+	if main == "" || secondary == "" {
+		return fmt.Errorf("main and secondary currency cannot be empty")
+	}
+	if err := s.setSeriesSource(ctx, fxSeries(main, secondary), source); err != nil {
+		return fmt.Errorf("failed to set source of %s/%s: %w", main, secondary, err)
+	}
+	return nil
+}
+
+// RateSource returns the provider that last imported the rates of the pair, or "" when unknown.
+func (s *Store) RateSource(ctx context.Context, main, secondary string) (string, error) {
+	return s.seriesSource(ctx, fxSeriesName(main, secondary))
+}
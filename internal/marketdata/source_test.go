@@ -0,0 +1,64 @@
+package marketdata
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-bumbu/testdbs"
+	"github.com/go-bumbu/timeseries"
+)
+
+func TestSeriesSource(t *testing.T) {
+	for _, db := range testdbs.DBs() {
+		t.Run(db.DbType(), func(t *testing.T) {
+			ctx := t.Context()
+			store, err := NewStore(db.ConnDbName("TestSeriesSource"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Run("price source survives a re-register", func(t *testing.T) {
+				if err := store.RegisterInstrument(ctx, "AAPL"); err != nil {
+					t.Fatal(err)
+				}
+				if src, err := store.PriceSource(ctx, "AAPL"); err != nil || src != "" {
+					t.Fatalf("expected no source yet, got %q, %v", src, err)
+				}
+				if err := store.SetPriceSource(ctx, "AAPL", "yahoo"); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.RegisterInstrument(ctx, "AAPL"); err != nil {
+					t.Fatal(err)
+				}
+				if src, err := store.PriceSource(ctx, "AAPL"); err != nil || src != "yahoo" {
+					t.Errorf("expected source yahoo, got %q, %v", src, err)
+				}
+				symbols, err := store.ListPriceSymbols(ctx)
+				if err != nil || len(symbols) != 1 || symbols[0] != "AAPL" {
+					t.Errorf("expected the identity labels kept, got %v, %v", symbols, err)
+				}
+			})
+
+			t.Run("rate source", func(t *testing.T) {
+				mustRegisterPair(t, ctx, store, "CHF", "USD")
+				if err := store.SetRateSource(ctx, "CHF", "USD", "ecb"); err != nil {
+					t.Fatal(err)
+				}
+				mustRegisterPair(t, ctx, store, "CHF", "USD")
+				if src, err := store.RateSource(ctx, "CHF", "USD"); err != nil || src != "ecb" {
+					t.Errorf("expected source ecb, got %q, %v", src, err)
+				}
+			})
+
+			t.Run("series must exist", func(t *testing.T) {
+				err := store.SetPriceSource(ctx, "NOPE", "yahoo")
+				if !errors.Is(err, timeseries.ErrSeriesNotFound) {
+					t.Errorf("expected ErrSeriesNotFound, got %v", err)
+				}
+				if err := store.SetRateSource(ctx, "CHF", "USD", ""); err == nil {
+					t.Error("expected an error for an empty source")
+				}
+			})
+		})
+	}
+}
@@ -1,8 +1,12 @@
 import { describe, it, expect, vi, beforeEach, type Mock } from 'vitest'
 import { apiClient } from './client'
 import {
+    listAttachments,
     uploadAttachment,
     getAttachmentUrl,
+    getAttachmentThumbnailUrl,
+    updateAttachmentCaption,
+    reorderAttachments,
     deleteAttachment,
     type AttachmentMeta,
 } from './Attachment'
@@ -18,8 +22,21 @@ const mockMeta: AttachmentMeta = {
     originalName: 'receipt.pdf',
     mimeType: 'application/pdf',
     fileSize: 12345,
+    caption: 'invoice',
+    position: 0,
 }
 
+describe('listAttachments', () => {
+    it('calls GET /fin/entries/:txId/attachment and returns the items', async () => {
+        (apiClient.get as Mock).mockResolvedValue({ data: { items: [mockMeta] } })
+
+        const result = await listAttachments(42)
+
+        expect(apiClient.get).toHaveBeenCalledWith('/fin/entries/42/attachment')
+        expect(result).toEqual([mockMeta])
+    })
+})
+
 describe('uploadAttachment', () => {
     it('calls POST /fin/entries/:txId/attachment with FormData and returns metadata', async () => {
         (apiClient.post as Mock).mockResolvedValue({ data: mockMeta })
@@ -36,6 +53,16 @@ describe('uploadAttachment', () => {
         expect(result).toEqual(mockMeta)
     })
 
+    it('sends the caption when provided', async () => {
+        (apiClient.post as Mock).mockResolvedValue({ data: mockMeta })
+
+        const file = new File(['content'], 'receipt.pdf', { type: 'application/pdf' })
+        await uploadAttachment(42, file, 'invoice')
+
+        const [, formData] = (apiClient.post as Mock).mock.calls[0]
+        expect(formData.get('caption')).toBe('invoice')
+    })
+
     it('propagates errors from apiClient', async () => {
         const error = new Error('upload failed');
         (apiClient.post as Mock).mockRejectedValue(error)
@@ -46,32 +73,55 @@ describe('uploadAttachment', () => {
 })
 
 describe('getAttachmentUrl', () => {
-    it('returns the full URL for the given transaction id', () => {
-        const url = getAttachmentUrl(99)
+    it('returns the full URL for the given transaction and attachment id', () => {
+        const url = getAttachmentUrl(99, 5)
 
-        expect(url).toContain('/fin/entries/99/attachment')
+        expect(url).toContain('/fin/entries/99/attachment/5')
     })
+})
+
+describe('getAttachmentThumbnailUrl', () => {
+    it('returns the thumbnail URL for the given transaction and attachment id', () => {
+        const url = getAttachmentThumbnailUrl(99, 5)
+
+        expect(url).toContain('/fin/entries/99/attachment/5/thumbnail')
+    })
+})
+
+describe('updateAttachmentCaption', () => {
+    it('calls PUT /fin/entries/:txId/attachment/:id with the caption', async () => {
+        (apiClient.put as Mock).mockResolvedValue({})
+
+        await updateAttachmentCaption(42, 5, 'receipt')
+
+        expect(apiClient.put).toHaveBeenCalledWith('/fin/entries/42/attachment/5', { caption: 'receipt' })
+    })
+})
+
+describe('reorderAttachments', () => {
+    it('calls PUT /fin/entries/:txId/attachment/order with the ids', async () => {
+        (apiClient.put as Mock).mockResolvedValue({})
+
+        await reorderAttachments(42, [3, 1, 2])
 
-    it('includes the API base URL prefix', () => {
-        const url = getAttachmentUrl(1)
-        expect(url).toContain('/fin/entries/1/attachment')
+        expect(apiClient.put).toHaveBeenCalledWith('/fin/entries/42/attachment/order', { ids: [3, 1, 2] })
     })
 })
 
 describe('deleteAttachment', () => {
-    it('calls DELETE /fin/entries/:txId/attachment', async () => {
+    it('calls DELETE /fin/entries/:txId/attachment/:id', async () => {
         (apiClient.delete as Mock).mockResolvedValue({})
 
-        await deleteAttachment(42)
+        await deleteAttachment(42, 5)
 
-        expect(apiClient.delete).toHaveBeenCalledWith('/fin/entries/42/attachment')
+        expect(apiClient.delete).toHaveBeenCalledWith('/fin/entries/42/attachment/5')
         expect(apiClient.delete).toHaveBeenCalledTimes(1)
     })
 
     it('returns void', async () => {
         (apiClient.delete as Mock).mockResolvedValue({})
 
-        const result = await deleteAttachment(42)
+        const result = await deleteAttachment(42, 5)
 
         expect(result).toBeUndefined()
     })
@@ -80,6 +130,6 @@ describe('deleteAttachment', () => {
         const error = new Error('delete failed');
         (apiClient.delete as Mock).mockRejectedValue(error)
 
-        await expect(deleteAttachment(1)).rejects.toThrow('delete failed')
+        await expect(deleteAttachment(1, 5)).rejects.toThrow('delete failed')
     })
 })
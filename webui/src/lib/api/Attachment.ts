@@ -7,21 +7,43 @@ export interface AttachmentMeta {
     originalName: string
     mimeType: string
     fileSize: number
+    caption: string
+    position: number
 }
 
-export const uploadAttachment = async (txId: number, file: File): Promise<AttachmentMeta> => {
+export const listAttachments = async (txId: number): Promise<AttachmentMeta[]> => {
+    const { data } = await apiClient.get<{ items: AttachmentMeta[] }>(`/fin/entries/${txId}/attachment`)
+    return data.items ?? []
+}
+
+export const uploadAttachment = async (txId: number, file: File, caption?: string): Promise<AttachmentMeta> => {
     const formData = new FormData()
     formData.append('file', file)
+    if (caption) {
+        formData.append('caption', caption)
+    }
     const { data } = await apiClient.post<AttachmentMeta>(`/fin/entries/${txId}/attachment`, formData, {
         headers: { 'Content-Type': 'multipart/form-data' }
     })
     return data
 }
 
-export const getAttachmentUrl = (txId: number): string => {
-    return `${API_BASE_URL}/fin/entries/${txId}/attachment`
+export const getAttachmentUrl = (txId: number, attachmentId: number): string => {
+    return `${API_BASE_URL}/fin/entries/${txId}/attachment/${attachmentId}`
+}
+
+export const getAttachmentThumbnailUrl = (txId: number, attachmentId: number): string => {
+    return `${API_BASE_URL}/fin/entries/${txId}/attachment/${attachmentId}/thumbnail`
+}
+
+export const updateAttachmentCaption = async (txId: number, attachmentId: number, caption: string): Promise<void> => {
+    await apiClient.put(`/fin/entries/${txId}/attachment/${attachmentId}`, { caption })
+}
+
+export const reorderAttachments = async (txId: number, ids: number[]): Promise<void> => {
+    await apiClient.put(`/fin/entries/${txId}/attachment/order`, { ids })
 }
 
-export const deleteAttachment = async (txId: number): Promise<void> => {
-    await apiClient.delete(`/fin/entries/${txId}/attachment`)
+export const deleteAttachment = async (txId: number, attachmentId: number): Promise<void> => {
+    await apiClient.delete(`/fin/entries/${txId}/attachment/${attachmentId}`)
 }
@@ -1,5 +1,5 @@
 import { apiClient } from './client'
-import type { ImportProfile, CategoryRuleGroup, CategoryRulePattern, ParsedRow, PreviewResult, ReapplyRow, ReapplySubmitItem, AdhocRule } from '@/types/csvimport'
+import type { ImportProfile, CategoryRuleGroup, CategoryRulePattern, ParsedRow, PreviewResult, ReapplyRow, ReapplySubmitItem, AdhocRule, ImportBatch, ImportSource, ParseResult, ImportInbox, PendingImport, BundleImportResult } from '@/types/csvimport'
 
 // Profiles
 export const getProfiles = () => apiClient.get<ImportProfile[]>('/import/profiles').then(r => r.data)
@@ -19,20 +19,81 @@ export const updateCategoryRulePattern = (groupId: number, patternId: number, p:
 export const deleteCategoryRulePattern = (groupId: number, patternId: number) => apiClient.delete(`/import/category-rule-groups/${groupId}/patterns/${patternId}`).then(r => r.data)
 
 // Import
-export const parseCSV = (accountId: number, file: File) => {
+// Without profileId the profile is detected from the file; when no profile fits, the response
+// has no rows but the ranked profiles and a preview of the detected settings.
+export const parseCSV = (accountId: number, file: File, profileId?: number) => {
   const form = new FormData()
   form.append('file', file)
   form.append('accountId', String(accountId))
-  return apiClient.post<{ rows: ParsedRow[] }>('/import/parse', form, {
+  if (profileId) form.append('profileId', String(profileId))
+  return apiClient.post<ParseResult>('/import/parse', form, {
     headers: { 'Content-Type': 'multipart/form-data' }
   }).then(r => r.data)
 }
 
-export const submitImport = (accountId: number, rows: ParsedRow[]) =>
-  apiClient.post<{ created: number }>('/import/submit', { accountId, rows }).then(r => r.data)
+export const submitImport = (accountId: number, rows: ParsedRow[], options: Partial<ImportSource> & { cashAccountId?: number; createInstruments?: boolean } = {}) =>
+  apiClient.post<{ created: number; batchId: number }>('/import/submit', { accountId, rows, ...options }).then(r => r.data)
+
+// Import history
+export const getImportBatches = (accountId?: number) =>
+  apiClient.get<ImportBatch[]>('/import/batches', { params: accountId ? { accountId } : {} }).then(r => r.data)
+export const rollbackImportBatch = (id: number) =>
+  apiClient.post<{ deleted: number }>(`/import/batches/${id}/rollback`).then(r => r.data)
+
+// Inboxes
+export const getImportInboxes = (accountId?: number) =>
+  apiClient.get<ImportInbox[]>('/import/inboxes', { params: accountId ? { accountId } : {} }).then(r => r.data)
+export const createImportInbox = (i: Omit<ImportInbox, 'id'>) => apiClient.post<ImportInbox>('/import/inboxes', i).then(r => r.data)
+export const updateImportInbox = (id: number, i: Omit<ImportInbox, 'id'>) => apiClient.put(`/import/inboxes/${id}`, i).then(r => r.data)
+export const deleteImportInbox = (id: number) => apiClient.delete(`/import/inboxes/${id}`).then(r => r.data)
+
+// Pending imports: inbox files awaiting review. Parsing one returns its pendingImportId, submitting
+// the rows with it removes the pending import.
+export const getPendingImports = (accountId?: number) =>
+  apiClient.get<PendingImport[]>('/import/pending', { params: accountId ? { accountId } : {} }).then(r => r.data)
+export const parsePendingImport = (id: number, profileId?: number) =>
+  apiClient.post<ParseResult>(`/import/pending/${id}/parse`, profileId ? { profileId } : {}).then(r => r.data)
+export const deletePendingImport = (id: number) => apiClient.delete(`/import/pending/${id}`).then(r => r.data)
+
+// Export
+export const downloadQIF = async (accountId: number, startDate: string, endDate: string, filename: string): Promise<void> => {
+  const response = await apiClient.get('/import/export/qif', {
+    params: { accountId, startDate, endDate },
+    responseType: 'blob'
+  })
+  const url = window.URL.createObjectURL(new Blob([response.data]))
+  const link = document.createElement('a')
+  link.href = url
+  link.download = filename
+  document.body.appendChild(link)
+  link.click()
+  document.body.removeChild(link)
+  window.URL.revokeObjectURL(url)
+}
+
+// Bundles: profiles and rule groups shared between instances, with categories and accounts
+// referenced by name. Without ids all profiles and rule groups are exported.
+export const downloadBundle = async (profileIds: number[] = [], ruleGroupIds: number[] = []): Promise<void> => {
+  const params: Record<string, string> = {}
+  if (profileIds.length > 0) params.profileIds = profileIds.join(',')
+  if (ruleGroupIds.length > 0) params.ruleGroupIds = ruleGroupIds.join(',')
+  const response = await apiClient.get('/import/bundle', { params, responseType: 'blob' })
+  const url = window.URL.createObjectURL(new Blob([response.data]))
+  const link = document.createElement('a')
+  link.href = url
+  link.download = 'etna-import-rules.json'
+  document.body.appendChild(link)
+  link.click()
+  document.body.removeChild(link)
+  window.URL.revokeObjectURL(url)
+}
+
+export const importBundle = (bundle: unknown, strategy: 'skip' | 'merge', dryRun: boolean) =>
+  apiClient.post<BundleImportResult>('/import/bundle', bundle, { params: { strategy, dryRun } }).then(r => r.data)
 
 export const previewCSV = (file: File, config: {
   csvSeparator?: string
+  sheetName?: string
   skipRows?: number
   dateColumn?: string
   dateFormat?: string
@@ -41,10 +102,22 @@ export const previewCSV = (file: File, config: {
   amountColumn?: string
   creditColumn?: string
   debitColumn?: string
+  type?: string
+  symbolColumn?: string
+  sideColumn?: string
+  quantityColumn?: string
+  priceColumn?: string
+  feesColumn?: string
+  currencyColumn?: string
+  orderIdColumn?: string
+  decimalSeparator?: string
+  thousandsSeparator?: string
+  invertSign?: boolean
 }) => {
   const form = new FormData()
   form.append('file', file)
   if (config.csvSeparator) form.append('csvSeparator', config.csvSeparator)
+  if (config.sheetName) form.append('sheetName', config.sheetName)
   if (config.skipRows !== undefined) form.append('skipRows', String(config.skipRows))
   if (config.dateColumn) form.append('dateColumn', config.dateColumn)
   if (config.dateFormat) form.append('dateFormat', config.dateFormat)
@@ -53,6 +126,12 @@ export const previewCSV = (file: File, config: {
   if (config.amountColumn) form.append('amountColumn', config.amountColumn)
   if (config.creditColumn) form.append('creditColumn', config.creditColumn)
   if (config.debitColumn) form.append('debitColumn', config.debitColumn)
+  for (const key of ['type', 'symbolColumn', 'sideColumn', 'quantityColumn', 'priceColumn', 'feesColumn', 'currencyColumn', 'orderIdColumn'] as const) {
+    if (config[key]) form.append(key, config[key] as string)
+  }
+  if (config.decimalSeparator) form.append('decimalSeparator', config.decimalSeparator)
+  if (config.thousandsSeparator) form.append('thousandsSeparator', config.thousandsSeparator)
+  if (config.invertSign) form.append('invertSign', 'true')
   return apiClient.post<PreviewResult>('/import/preview', form, {
     headers: { 'Content-Type': 'multipart/form-data' }
   }).then(r => r.data)
@@ -46,6 +46,8 @@ import {
     createStockTransaction,
     createStockGrant,
     createStockTransfer,
+    findDuplicates,
+    resolveDuplicates,
 } from '@/lib/api/Entry'
 import { apiClient } from '@/lib/api/client'
 
@@ -303,4 +305,38 @@ describe('Entry API wrappers', () => {
             expect(result).toEqual(response)
         })
     })
+
+    // -- duplicates ---------------------------------------------------------
+
+    describe('findDuplicates', () => {
+        it('builds query string and returns the groups', async () => {
+            const items = [{ confidence: 0.9, keepId: 2, transactions: [] }]
+            mockedClient.get.mockResolvedValue({ data: { items } })
+
+            const result = await findDuplicates({
+                startDate: new Date(2024, 0, 1),
+                endDate: new Date(2024, 11, 31),
+                accountIds: [1, 4],
+                windowDays: 5,
+            })
+
+            const url: string = mockedClient.get.mock.calls[0][0]
+            expect(url).toContain('/fin/duplicates?')
+            const params = new URLSearchParams(url.split('?')[1])
+            expect(params.get('startDate')).toBe('2024-01-01')
+            expect(params.getAll('accountIds')).toEqual(['1', '4'])
+            expect(params.get('windowDays')).toBe('5')
+            expect(result).toEqual(items)
+        })
+    })
+
+    describe('resolveDuplicates', () => {
+        it('posts the action with the kept and the other ids', async () => {
+            mockedClient.post.mockResolvedValue({})
+
+            await resolveDuplicates('merge', 2, [2, 3])
+
+            expect(mockedClient.post).toHaveBeenCalledWith('/fin/duplicates/resolve', { action: 'merge', keepId: 2, ids: [2, 3] })
+        })
+    })
 })
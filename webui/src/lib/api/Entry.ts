@@ -1,5 +1,5 @@
 import { apiClient } from '@/lib/api/client'
-import type { Entry, CreateEntryDTO, UpdateEntryDTO, PaginatedEntriesResponse } from '@/types/entry'
+import type { Entry, CreateEntryDTO, UpdateEntryDTO, PaginatedEntriesResponse, DuplicateGroup, ResolveDuplicatesAction } from '@/types/entry'
 import { toLocalDateString } from '@/utils/date'
 
 /** Format a Date for API params as local YYYY-MM-DD. */
@@ -93,6 +93,38 @@ export const deleteEntry = async (id: string): Promise<void> => {
     await apiClient.delete(`/fin/entries/${id}`)
 }
 
+export interface FindDuplicatesOptions {
+    startDate: Date
+    endDate: Date
+    accountIds?: number[]
+    windowDays?: number
+}
+
+/**
+ * Scans the entries for likely duplicates, ordered by confidence
+ */
+export const findDuplicates = async (options: FindDuplicatesOptions): Promise<DuplicateGroup[]> => {
+    const { startDate, endDate, accountIds = [], windowDays } = options
+    const params = new URLSearchParams({
+        startDate: formatDate(startDate),
+        endDate: formatDate(endDate)
+    })
+    accountIds.forEach((id) => params.append('accountIds', String(id)))
+    if (windowDays) {
+        params.set('windowDays', String(windowDays))
+    }
+    const { data } = await apiClient.get(`/fin/duplicates?${params}`)
+    return data.items || []
+}
+
+/**
+ * Resolves a group of duplicates: keeps keepId and deletes the other ids, merging their
+ * notes, category and attachments into it first when action is merge
+ */
+export const resolveDuplicates = async (action: ResolveDuplicatesAction, keepId: number, ids: number[]): Promise<void> => {
+    await apiClient.post('/fin/duplicates/resolve', { action, keepId, ids })
+}
+
 /**
  * Payload for creating a stock buy or sell transaction
  */
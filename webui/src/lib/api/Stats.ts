@@ -1,5 +1,16 @@
 import { apiClient } from './client'
 
+export interface AttachmentCheck {
+    checkedAt: string
+    attachments: number
+    orphans: number[]
+    orphanBytes: number
+    missing: number[]
+    sizeMismatches: number[]
+    refCountMismatches: number[]
+    purged: number
+}
+
 export interface AppStats {
     dbSizeBytes: number
     attachmentsSizeBytes: number
@@ -8,6 +19,7 @@ export interface AppStats {
     fxSeries: number
     fxPoints: number
     logLevel: string
+    attachmentCheck?: AttachmentCheck
 }
 
 export const getStats = async (): Promise<AppStats> => {
@@ -19,6 +31,7 @@ export const getStats = async (): Promise<AppStats> => {
         pricePoints: data.pricePoints ?? 0,
         fxSeries: data.fxSeries ?? 0,
         fxPoints: data.fxPoints ?? 0,
-        logLevel: data.logLevel ?? ''
+        logLevel: data.logLevel ?? '',
+        attachmentCheck: data.attachmentCheck
     }
 }
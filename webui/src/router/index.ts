@@ -214,6 +214,7 @@ const router = createRouter({
                 { path: 'categories', name: 'settings-categories', component: () => import('@/views/settings/SettingsCategoriesView.vue') },
                 { path: 'category-rules', name: 'settings-category-rules', component: () => import('@/views/categories/CategoryRulesView.vue') },
                 { path: 'backup-restore', name: 'settings-backup-restore', component: () => import('@/views/backup/BackupRestoreView.vue') },
+                { path: 'duplicates', name: 'settings-duplicates', component: () => import('@/views/entries/DuplicatesView.vue') },
                 { path: 'tasks', name: 'settings-tasks', component: () => import('@/views/tasks/TasksView.vue') },
                 { path: 'about', name: 'settings-about', component: () => import('@/views/settings/AboutView.vue') },
             ]
@@ -2,6 +2,7 @@ export interface ImportProfile {
   id: number
   name: string
   csvSeparator: string
+  sheetName?: string
   skipRows: number
   dateColumn: string
   dateFormat: string
@@ -10,6 +11,30 @@ export interface ImportProfile {
   amountMode: 'single' | 'split'
   creditColumn: string
   debitColumn: string
+  // number locale; without a decimal separator it is guessed for each value
+  decimalSeparator?: string
+  thousandsSeparator?: string
+  invertSign?: boolean // the file writes expenses as positive amounts
+  type?: 'statement' | 'investment'
+  // investment profiles: broker trade history columns
+  symbolColumn?: string
+  sideColumn?: string
+  quantityColumn?: string
+  priceColumn?: string
+  feesColumn?: string
+  currencyColumn?: string
+  orderIdColumn?: string
+  cashAccountId?: number
+  // header of the files the profile reads, used to recognize uploads
+  headerRow?: string[]
+}
+
+// a profile ranked by how well an uploaded file matches it
+export interface ProfileMatch {
+  profileId: number
+  name: string
+  score: number
+  confident: boolean
 }
 
 export interface CategoryRuleGroup {
@@ -18,6 +43,20 @@ export interface CategoryRuleGroup {
   categoryId: number
   priority: number
   patterns: CategoryRulePattern[]
+  // conditions, combined with the patterns as all or, with matchAny, any
+  matchAny?: boolean
+  amountMin?: number
+  amountMax?: number
+  direction?: 'income' | 'expense'
+  accountId?: number
+  profileId?: number
+  dateFrom?: string
+  dateTo?: string
+  weekdays?: number[] // 0 = Sunday
+  // actions besides setting the category
+  setDescription?: string
+  appendNotes?: string
+  transferAccountId?: number
 }
 
 export interface CategoryRulePattern {
@@ -38,6 +77,7 @@ export interface DetectedColumns {
 export interface PreviewResult {
   headers: string[]
   rows: ParsedRow[]
+  sheets?: string[]
   totalRows: number
   detectedSeparator?: string
   detectedSkipRows?: number
@@ -50,10 +90,87 @@ export interface ParsedRow {
   date: string
   description: string
   amount: number
-  type: 'income' | 'expense'
+  type: 'income' | 'expense' | 'balance' | 'buy' | 'sell' | 'transfer' // balance: closing balance of a statement file
   categoryId: number
   isDuplicate: boolean
+  externalId?: string
+  error?: string
+  notes?: string
+  transferAccountId?: number // counter account of transfer rows
+  // category learned from the transaction history for rows no rule categorized
+  suggestedCategoryId?: number
+  suggestionConfidence?: number
+  // fees and currency of the row; statement rows in a foreign currency keep the amount
+  // before the conversion into the account currency
+  fees?: number
+  currency?: string
+  originalAmount?: number
+  // trade rows of investment profiles
+  symbol?: string
+  quantity?: number
+  price?: number
+  instrumentId?: number
+}
+
+export interface ImportBatch {
+  id: number
+  profileId?: number
+  accountId: number
+  fileName: string
+  fileHash: string
+  format: string
+  totalRows: number
+  importedRows: number
+  user: string
+  createdAt: string
+  rolledBackAt?: string
+}
+
+// Source file of a parsed import, recorded on the import batch at submit.
+export interface ImportSource {
+  fileName: string
+  fileHash: string
+  format: string
+  totalRows: number
+  profileId?: number
+  pendingImportId?: number // pending inbox file the rows come from, removed once submitted
+}
+
+// Result of parsing an uploaded or pending file. Without a confidently matching profile it has
+// no rows but the ranked profiles and a preview of the detected settings.
+export interface ParseResult {
+  rows?: ParsedRow[]
+  profileId?: number
+  profileMatches?: ProfileMatch[]
+  preview?: PreviewResult
+  format: string
+  fileName: string
+  fileHash: string
+  previousImports?: ImportBatch[]
+  profileType?: string
+  cashAccountId?: number
+  pendingImportId?: number
+}
+
+// Directory below the server's inbox root scanned for statement files of an account.
+export interface ImportInbox {
+  id: number
+  accountId: number
+  profileId: number // 0 = detected from the file
+  directory: string
+  autoSubmit: boolean
+}
+
+// File picked up from an inbox that awaits review.
+export interface PendingImport {
+  id: number
+  inboxId?: number
+  accountId: number
+  fileName: string
+  format: string
+  totalRows: number
   error?: string
+  createdAt: string
 }
 
 export interface ReapplyRow {
@@ -69,12 +186,21 @@ export interface ReapplyRow {
   newCategoryId: number
   newCategoryName: string
   changed: boolean
+  newDescription?: string
+  newNotes?: string
+  transferAccountId?: number
+  transferAccountName?: string
+  suggested?: boolean // newCategoryId is learned from the transaction history, not a rule
+  confidence?: number
 }
 
 export interface ReapplySubmitItem {
   transactionId: number
   transactionType: 'income' | 'expense'
   newCategoryId: number
+  newDescription?: string
+  newNotes?: string
+  transferAccountId?: number
 }
 
 export interface AdhocRule {
@@ -82,3 +208,18 @@ export interface AdhocRule {
   pattern: string
   isRegex: boolean
 }
+
+// outcome of a profile or rule group of an imported bundle
+export interface BundleItem {
+  name: string
+  action: 'created' | 'merged' | 'skipped'
+  reason?: string
+}
+
+export interface BundleImportResult {
+  dryRun: boolean
+  profiles: BundleItem[]
+  ruleGroups: BundleItem[]
+  unmatchedCategories: string[] // e.g. "expense Food > Groceries"
+  unmatchedAccounts: string[]
+}
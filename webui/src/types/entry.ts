@@ -165,4 +165,12 @@ export interface PaginatedEntriesResponse {
     page: number
     limit: number
     priorBalance: number
-}
\ No newline at end of file
+}
+/** A group of transactions that likely record the same movement. */
+export interface DuplicateGroup {
+    confidence: number
+    keepId: number
+    transactions: Entry[]
+}
+
+export type ResolveDuplicatesAction = 'delete' | 'merge'